@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"todo"
+)
+
+// APIKeyPrefix starts every API key so they are easy to recognize, e.g. when
+// scanning for leaked secrets, and can be told apart from bearer tokens.
+const APIKeyPrefix = "todo_"
+
+// APIKey represents the stored, hashed form of an API key. The secret part of
+// the key is only known at the time it is issued.
+type APIKey struct {
+	// Public identifier of the key. Also embedded in the key itself.
+	ID string `json:"id"`
+
+	// Principal the key authenticates as.
+	PrincipalID string `json:"principalId"`
+
+	// Human-readable description, e.g. the device the key is used on.
+	Name string `json:"name"`
+
+	// SHA-256 hash of the secret part of the key.
+	Hash string `json:"hash"`
+
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// KeyStore stores hashed API keys in a JSON file. Changes made to the file by
+// another process, such as the admin command, are picked up automatically.
+type KeyStore struct {
+	mu      sync.Mutex
+	keys    map[string]*APIKey
+	modTime time.Time
+	size    int64
+
+	// Path of the JSON file holding the keys. If empty, keys are only kept in
+	// memory.
+	Path string
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+// NewKeyStore returns a new instance of KeyStore stored at path.
+func NewKeyStore(path string) *KeyStore {
+	return &KeyStore{
+		keys: make(map[string]*APIKey),
+		Path: path,
+		Now:  time.Now,
+	}
+}
+
+// Issue generates a new API key for principalID. The returned key is the only
+// time the secret is available; only its hash is stored.
+func (s *KeyStore) Issue(principalID, name string) (key string, apiKey *APIKey, err error) {
+	if principalID == "" {
+		return "", nil, todo.Errorf(todo.EINVALID, "Principal required.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return "", nil, err
+	}
+
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+
+	apiKey = &APIKey{
+		ID:          id,
+		PrincipalID: principalID,
+		Name:        name,
+		Hash:        hashSecret(secret),
+		CreatedAt:   s.Now().UTC(),
+	}
+	s.keys[id] = apiKey
+
+	if err := s.save(); err != nil {
+		delete(s.keys, id)
+		return "", nil, err
+	}
+
+	return APIKeyPrefix + id + "_" + secret, apiKey, nil
+}
+
+// Revoke marks the key with the given ID as revoked. Revoked keys are kept so
+// they show up when listing keys but can no longer be used.
+func (s *KeyStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	k, ok := s.keys[id]
+	if !ok {
+		return todo.Errorf(todo.ENOTFOUND, "API key '%s' could not be found.", id)
+	} else if k.RevokedAt != nil {
+		return nil
+	}
+
+	now := s.Now().UTC()
+	k.RevokedAt = &now
+	if err := s.save(); err != nil {
+		k.RevokedAt = nil
+		return err
+	}
+	return nil
+}
+
+// Keys returns all keys, including revoked ones, ordered by creation time.
+func (s *KeyStore) Keys() ([]*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]*APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		other := *k
+		keys = append(keys, &other)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Authenticate returns the principal of a valid, unrevoked API key.
+// Returns EUNAUTHORIZED otherwise.
+func (s *KeyStore) Authenticate(key string) (*todo.Principal, error) {
+	id, secret, ok := parseAPIKey(key)
+	if !ok {
+		return nil, todo.Errorf(todo.EUNAUTHORIZED, "Invalid API key.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	k, ok := s.keys[id]
+	if !ok || k.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hashSecret(secret))) != 1 {
+		return nil, todo.Errorf(todo.EUNAUTHORIZED, "Invalid API key.")
+	}
+	return &todo.Principal{ID: k.PrincipalID, Method: "api_key"}, nil
+}
+
+// reload reads the key file if it changed since it was last read.
+// Must be called with s.mu held.
+func (s *KeyStore) reload() error {
+	if s.Path == "" {
+		return nil
+	}
+
+	fi, err := os.Stat(s.Path)
+	if os.IsNotExist(err) {
+		s.keys, s.modTime, s.size = make(map[string]*APIKey), time.Time{}, 0
+		return nil
+	} else if err != nil {
+		return err
+	} else if fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return nil
+	}
+
+	buf, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	var keys []*APIKey
+	if err := json.Unmarshal(buf, &keys); err != nil {
+		return err
+	}
+
+	s.keys = make(map[string]*APIKey, len(keys))
+	for _, k := range keys {
+		s.keys[k.ID] = k
+	}
+	s.modTime, s.size = fi.ModTime(), fi.Size()
+	return nil
+}
+
+// save atomically writes all keys to the key file.
+// Must be called with s.mu held.
+func (s *KeyStore) save() error {
+	if s.Path == "" {
+		return nil
+	}
+
+	keys := make([]*APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	buf, err := json.MarshalIndent(keys, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0600); err != nil {
+		return err
+	} else if err := os.Rename(tmp, s.Path); err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(s.Path)
+	if err != nil {
+		return err
+	}
+	s.modTime, s.size = fi.ModTime(), fi.Size()
+	return nil
+}
+
+// parseAPIKey splits a key into its ID and secret.
+func parseAPIKey(key string) (id, secret string, ok bool) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, APIKeyPrefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// hashSecret returns the hex encoded SHA-256 hash of secret. API key secrets
+// are random and long, so a fast hash is sufficient.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes encoded with enc.
+func randomString(n int, enc func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return enc(b), nil
+}
@@ -0,0 +1,91 @@
+// Package auth authenticates callers using API keys or signed bearer tokens.
+package auth
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"strings"
+	"todo"
+)
+
+// Authenticator resolves the credentials of a request to a principal. API keys
+// are checked against Keys and everything else is verified as a bearer token
+// by Tokens. Either may be nil to disable that method.
+type Authenticator struct {
+	Keys   *KeyStore
+	Tokens *TokenVerifier
+}
+
+// Authenticate returns the principal for an Authorization header value of the
+// form "Bearer <api key or token>". Returns EUNAUTHORIZED if the credentials
+// are missing or invalid.
+func (a *Authenticator) Authenticate(authorization string) (*todo.Principal, error) {
+	const prefix = "bearer "
+	if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return nil, todo.Errorf(todo.EUNAUTHORIZED, "Authentication required.")
+	}
+	credential := strings.TrimSpace(authorization[len(prefix):])
+
+	if strings.HasPrefix(credential, APIKeyPrefix) {
+		if a.Keys == nil {
+			return nil, todo.Errorf(todo.EUNAUTHORIZED, "Invalid API key.")
+		}
+		return a.Keys.Authenticate(credential)
+	}
+
+	if a.Tokens == nil {
+		return nil, errInvalidToken
+	}
+	return a.Tokens.Verify(credential)
+}
+
+// NewHTTPMiddleware returns a transport middleware that authenticates the
+// Authorization header of every request once, before any other middleware
+// needs the caller. The principal is added to the request context, and
+// failures are kept in it to be reported by NewEndpointMiddleware.
+func NewHTTPMiddleware(a *Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := a.Authenticate(r.Header.Get("Authorization"))
+			ctx := context.WithValue(r.Context(), resultContextKey{}, &result{principal: principal, err: err})
+			if err == nil {
+				ctx = todo.NewContextWithPrincipal(ctx, principal)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// resultContextKey stores the result of authenticating a request by the
+// middleware returned by NewHTTPMiddleware.
+type resultContextKey struct{}
+
+type result struct {
+	principal *todo.Principal
+	err       error
+}
+
+// NewEndpointMiddleware returns an endpoint middleware that authenticates the
+// Authorization header stored in the context by
+// httptransport.PopulateRequestContext, unless the request was already
+// authenticated by NewHTTPMiddleware. The principal is added to the context
+// passed on to the next endpoint. Unauthenticated calls fail with EUNAUTHORIZED.
+func NewEndpointMiddleware(a *Authenticator) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			res, ok := ctx.Value(resultContextKey{}).(*result)
+			if !ok {
+				authorization, _ := ctx.Value(httptransport.ContextKeyRequestAuthorization).(string)
+				res = &result{}
+				res.principal, res.err = a.Authenticate(authorization)
+			}
+			if res.err != nil {
+				return nil, res.err
+			}
+
+			return next(todo.NewContextWithPrincipal(ctx, res.principal), request)
+		}
+	}
+}
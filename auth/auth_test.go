@@ -0,0 +1,110 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+	"todo"
+	"todo/auth"
+)
+
+func TestKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	key, apiKey, err := auth.NewKeyStore(path).Issue("alice", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A separate store, like the one in the server, sees the issued key.
+	s := auth.NewKeyStore(path)
+	if p, err := s.Authenticate(key); err != nil {
+		t.Fatal(err)
+	} else if p.ID != "alice" {
+		t.Fatalf("ID=%q, want %q", p.ID, "alice")
+	}
+
+	if _, err := s.Authenticate(key + "x"); todo.ErrorCode(err) != todo.EUNAUTHORIZED {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+
+	// Revoking through another store is picked up as well.
+	if err := auth.NewKeyStore(path).Revoke(apiKey.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(key); todo.ErrorCode(err) != todo.EUNAUTHORIZED {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
+
+func TestTokenVerifier(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := auth.NewTokenVerifier()
+	v.Secret = []byte("secret")
+	v.PublicKeys = []ed25519.PublicKey{pub}
+	v.Audience = "todo"
+	v.Now = func() time.Time { return now }
+
+	claims := auth.Claims{Subject: "alice", Audience: auth.Audience{"todo"}, ExpiresAt: now.Add(time.Hour).Unix()}
+	hs256, _ := auth.SignHS256([]byte("secret"), claims)
+	eddsa, _ := auth.SignEdDSA(priv, claims)
+	for _, token := range []string{hs256, eddsa} {
+		if p, err := v.Verify(token); err != nil {
+			t.Fatal(err)
+		} else if p.ID != "alice" {
+			t.Fatalf("ID=%q, want %q", p.ID, "alice")
+		}
+	}
+
+	wrongSecret, _ := auth.SignHS256([]byte("other"), claims)
+	expired, _ := auth.SignHS256([]byte("secret"), auth.Claims{Subject: "alice", Audience: auth.Audience{"todo"}, ExpiresAt: now.Add(-time.Hour).Unix()})
+	wrongAudience, _ := auth.SignHS256([]byte("secret"), auth.Claims{Subject: "alice", ExpiresAt: now.Add(time.Hour).Unix()})
+	unsigned := "eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9."
+	for _, token := range []string{wrongSecret, expired, wrongAudience, unsigned, "garbage"} {
+		if _, err := v.Verify(token); todo.ErrorCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("%s: unexpected error: %#v", token, err)
+		}
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	v := auth.NewTokenVerifier()
+	v.Secret = []byte("secret")
+	a := &auth.Authenticator{Tokens: v}
+	token, _ := auth.SignHS256([]byte("secret"), auth.Claims{Subject: "alice", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+
+	// The endpoint middleware uses the result of the HTTP middleware rather
+	// than authenticating the request again: the Authorization header is
+	// never copied to the context it is called with.
+	serve := func(authorization string) (principalID string, err error) {
+		h := auth.NewHTTPMiddleware(a)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err = auth.NewEndpointMiddleware(a)(func(ctx context.Context, request interface{}) (interface{}, error) {
+				principalID = todo.PrincipalIDFromContext(ctx)
+				return nil, nil
+			})(r.Context(), nil)
+		}))
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", authorization)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		return principalID, err
+	}
+
+	if id, err := serve("Bearer " + token); err != nil {
+		t.Fatal(err)
+	} else if id != "alice" {
+		t.Fatalf("ID=%q, want %q", id, "alice")
+	}
+	if _, err := serve("Bearer " + token + "x"); todo.ErrorCode(err) != todo.EUNAUTHORIZED {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
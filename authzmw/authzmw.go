@@ -0,0 +1,46 @@
+// Package authzmw provides service middlewares that let principals access
+// todos shared with them, according to the role they were granted. A todo is
+// shared either on its own or through a share of the list it is in.
+//
+// Backends only ever operate on the data of the owner in the context. When a
+// call fails with ENOTFOUND, the middlewares look up whether the todo or list
+// is shared with the caller and, if their role allows the call, retry it on
+// behalf of the owner. Callers without any share keep getting ENOTFOUND
+// so the existence of other owners' todos is not revealed. Callers whose role
+// is insufficient get EFORBIDDEN.
+package authzmw
+
+import (
+	"context"
+	"todo"
+)
+
+// authorize returns a context operating on the data of the owner of a todo if
+// the todo is shared with the caller with at least the given role.
+func authorize(ctx context.Context, shares todo.ShareService, todoID int, role string) (context.Context, error) {
+	sh, err := shares.GetShare(ctx, todo.GetShareRequest{TodoID: todoID})
+	if err != nil {
+		return nil, err
+	} else if !todo.RoleIncludes(sh.Role, role) {
+		return nil, todo.Errorf(todo.EFORBIDDEN, "Todo with ID '%d' is shared with you as %s, which does not allow this.", todoID, sh.Role)
+	}
+	return todo.NewContextWithOwnerID(ctx, sh.OwnerID), nil
+}
+
+// authorizeList returns a context operating on the data of the owner of a
+// list if the list is shared with the caller with at least the given role.
+func authorizeList(ctx context.Context, shares todo.ShareService, listID int, role string) (context.Context, error) {
+	sh, err := shares.GetShare(ctx, todo.GetShareRequest{ListID: listID})
+	if err != nil {
+		return nil, err
+	} else if !todo.RoleIncludes(sh.Role, role) {
+		return nil, todo.Errorf(todo.EFORBIDDEN, "List with ID '%d' is shared with you as %s, which does not allow this.", listID, sh.Role)
+	}
+	return todo.NewContextWithOwnerID(ctx, sh.OwnerID), nil
+}
+
+// retryable returns true if a call that failed with err may succeed on behalf
+// of another owner. Anonymous callers cannot have anything shared with them.
+func retryable(ctx context.Context, err error) bool {
+	return todo.ErrorCode(err) == todo.ENOTFOUND && todo.PrincipalIDFromContext(ctx) != ""
+}
@@ -0,0 +1,147 @@
+package authzmw_test
+
+import (
+	"context"
+	"testing"
+	"todo"
+	"todo/authzmw"
+	"todo/inmem"
+	"todo/todotest"
+)
+
+func TestTodoAuthorizingMiddleware(t *testing.T) {
+	alice := todotest.NewContextWithPrincipalID(context.Background(), "alice")
+	bob := todotest.NewContextWithPrincipalID(context.Background(), "bob")
+	value := "b"
+
+	t.Run("Viewer", func(t *testing.T) {
+		s, shares := newServices(t)
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		todotest.MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+
+		if got, err := s.GetTodoByID(bob, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.OwnerID != "alice" {
+			t.Fatalf("OwnerID=%q, want %q", got.OwnerID, "alice")
+		}
+		if _, err := s.PatchTodo(bob, todo.PatchTodoRequest{ID: a.ID, Value: &value}); todo.ErrorCode(err) != todo.EFORBIDDEN {
+			t.Fatalf("PatchTodo: unexpected error: %#v", err)
+		}
+		if err := s.DeleteTodo(bob, todo.DeleteTodoRequest{ID: a.ID}); todo.ErrorCode(err) != todo.EFORBIDDEN {
+			t.Fatalf("DeleteTodo: unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("Editor", func(t *testing.T) {
+		s, shares := newServices(t)
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		todotest.MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleEditor})
+
+		if got, err := s.PatchTodo(bob, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		} else if got.Value != "b" || got.OwnerID != "alice" {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+		if _, err := shares.CreateShare(bob, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "carol", Role: todo.RoleViewer}); todo.ErrorCode(err) != todo.EFORBIDDEN {
+			t.Fatalf("CreateShare: unexpected error: %#v", err)
+		}
+
+		// Shared todos are not mixed into the caller's own todos.
+		if resp := todotest.MustListTodos(t, bob, s, todo.ListTodosRequest{}); len(resp.Todos) != 0 {
+			t.Fatalf("unexpected todos: %#v", resp.Todos)
+		}
+	})
+
+	t.Run("Owner", func(t *testing.T) {
+		s, shares := newServices(t)
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		todotest.MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleOwner})
+
+		if _, err := shares.CreateShare(bob, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "carol", Role: todo.RoleViewer}); err != nil {
+			t.Fatal(err)
+		} else if list := todotest.MustListShares(t, bob, shares, todo.ListSharesRequest{TodoID: a.ID}); len(list) != 2 {
+			t.Fatalf("unexpected shares: %#v", list)
+		}
+		if err := s.DeleteTodo(bob, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.GetTodoByID(alice, todo.GetTodoByIDRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("NotShared", func(t *testing.T) {
+		s, shares := newServices(t)
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+
+		if _, err := s.GetTodoByID(bob, todo.GetTodoByIDRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("GetTodoByID: unexpected error: %#v", err)
+		}
+		if _, err := shares.ListShares(bob, todo.ListSharesRequest{TodoID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("ListShares: unexpected error: %#v", err)
+		}
+		if _, err := shares.CreateShare(context.Background(), todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleViewer}); todo.ErrorCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("CreateShare: unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		store := inmem.NewService()
+		s := authzmw.NewTodoAuthorizingMiddleware(store)(store)
+		lists := authzmw.NewListAuthorizingMiddleware(store)(store)
+		l := todotest.MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "l"})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", ListID: l.ID})
+		todotest.MustCreateShare(t, alice, store, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+
+		if got, err := lists.GetListByID(bob, todo.GetListByIDRequest{ID: l.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.OwnerID != "alice" {
+			t.Fatalf("OwnerID=%q, want %q", got.OwnerID, "alice")
+		}
+		if resp := todotest.MustListTodos(t, bob, s, todo.ListTodosRequest{ListID: l.ID}); len(resp.Todos) != 1 || resp.Todos[0].ID != a.ID {
+			t.Fatalf("unexpected todos: %#v", resp.Todos)
+		}
+		if _, err := s.PatchTodo(bob, todo.PatchTodoRequest{ID: a.ID, Value: &value}); todo.ErrorCode(err) != todo.EFORBIDDEN {
+			t.Fatalf("PatchTodo: unexpected error: %#v", err)
+		}
+		if _, err := s.CreateTodo(bob, todo.CreateTodoRequest{Value: "b", ListID: l.ID}); todo.ErrorCode(err) != todo.EFORBIDDEN {
+			t.Fatalf("CreateTodo: unexpected error: %#v", err)
+		}
+
+		// Editors of a list may change its todos and add todos to it.
+		todotest.MustCreateShare(t, alice, store, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "bob", Role: todo.RoleEditor})
+		if _, err := s.PatchTodo(bob, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		} else if got, err := s.CreateTodo(bob, todo.CreateTodoRequest{Value: "b", ListID: l.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.OwnerID != "alice" || got.ListID != l.ID {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		// Lists that are not shared are still reported as invalid.
+		other := todotest.MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "other"})
+		if _, err := s.CreateTodo(bob, todo.CreateTodoRequest{Value: "c", ListID: other.ID}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("CreateTodo: unexpected error: %#v", err)
+		} else if _, err := lists.GetListByID(bob, todo.GetListByIDRequest{ID: other.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("GetListByID: unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("Leave", func(t *testing.T) {
+		s, shares := newServices(t)
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		todotest.MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+
+		if err := shares.DeleteShare(bob, todo.DeleteShareRequest{TodoID: a.ID, PrincipalID: "bob"}); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.GetTodoByID(bob, todo.GetTodoByIDRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
+
+// newServices returns in-memory services wrapped by the authorizing middlewares.
+func newServices(tb testing.TB) (todo.Service, todo.ShareService) {
+	tb.Helper()
+	s := inmem.NewService()
+	return authzmw.NewTodoAuthorizingMiddleware(s)(s), authzmw.NewShareAuthorizingMiddleware()(s)
+}
@@ -0,0 +1,53 @@
+package authzmw
+
+import (
+	"context"
+	"todo"
+)
+
+// NewListAuthorizingMiddleware returns a middleware letting principals read
+// lists shared with them. Only owners may change or delete a list. Shares are
+// looked up in shares.
+func NewListAuthorizingMiddleware(shares todo.ShareService) todo.ListMiddleware {
+	return func(next todo.ListService) todo.ListService {
+		return &listAuthorizingMiddleware{
+			next:   next,
+			shares: shares,
+		}
+	}
+}
+
+type listAuthorizingMiddleware struct {
+	next   todo.ListService
+	shares todo.ShareService
+}
+
+func (mw listAuthorizingMiddleware) CreateList(ctx context.Context, request todo.CreateListRequest) (*todo.List, error) {
+	return mw.next.CreateList(ctx, request)
+}
+
+func (mw listAuthorizingMiddleware) UpdateList(ctx context.Context, request todo.UpdateListRequest) (*todo.List, error) {
+	return mw.next.UpdateList(ctx, request)
+}
+
+func (mw listAuthorizingMiddleware) DeleteList(ctx context.Context, request todo.DeleteListRequest) error {
+	return mw.next.DeleteList(ctx, request)
+}
+
+func (mw listAuthorizingMiddleware) GetListByID(ctx context.Context, request todo.GetListByIDRequest) (*todo.List, error) {
+	l, err := mw.next.GetListByID(ctx, request)
+	if !retryable(ctx, err) {
+		return l, err
+	}
+
+	if ctx, err = authorizeList(ctx, mw.shares, request.ID, todo.RoleViewer); err != nil {
+		return nil, err
+	}
+	return mw.next.GetListByID(ctx, request)
+}
+
+// ListLists only lists the caller's own lists. Lists shared with the caller
+// are found with ListShares.
+func (mw listAuthorizingMiddleware) ListLists(ctx context.Context, request todo.ListListsRequest) ([]*todo.List, error) {
+	return mw.next.ListLists(ctx, request)
+}
@@ -0,0 +1,80 @@
+package authzmw
+
+import (
+	"context"
+	"todo"
+)
+
+// NewShareAuthorizingMiddleware returns a middleware letting principals that
+// were granted the owner role manage the shares of a todo or list, and letting
+// anyone remove a share granted to themselves.
+func NewShareAuthorizingMiddleware() todo.ShareMiddleware {
+	return func(next todo.ShareService) todo.ShareService {
+		return &shareAuthorizingMiddleware{
+			next: next,
+		}
+	}
+}
+
+type shareAuthorizingMiddleware struct {
+	next todo.ShareService
+}
+
+func (mw shareAuthorizingMiddleware) CreateShare(ctx context.Context, request todo.CreateShareRequest) (*todo.Share, error) {
+	if todo.PrincipalIDFromContext(ctx) == "" {
+		return nil, todo.Errorf(todo.EUNAUTHORIZED, "You must be logged in to share todos.")
+	}
+
+	sh, err := mw.next.CreateShare(ctx, request)
+	if !retryable(ctx, err) {
+		return sh, err
+	}
+
+	if ctx, err = mw.authorize(ctx, request.TodoID, request.ListID, todo.RoleOwner); err != nil {
+		return nil, err
+	}
+	return mw.next.CreateShare(ctx, request)
+}
+
+func (mw shareAuthorizingMiddleware) DeleteShare(ctx context.Context, request todo.DeleteShareRequest) error {
+	err := mw.next.DeleteShare(ctx, request)
+	if !retryable(ctx, err) {
+		return err
+	}
+
+	// Anyone may give up a share granted to them, whatever their role.
+	role := todo.RoleOwner
+	if request.PrincipalID == todo.PrincipalIDFromContext(ctx) {
+		role = todo.RoleViewer
+	}
+
+	if ctx, err = mw.authorize(ctx, request.TodoID, request.ListID, role); err != nil {
+		return err
+	}
+	return mw.next.DeleteShare(ctx, request)
+}
+
+func (mw shareAuthorizingMiddleware) GetShare(ctx context.Context, request todo.GetShareRequest) (*todo.Share, error) {
+	return mw.next.GetShare(ctx, request)
+}
+
+func (mw shareAuthorizingMiddleware) ListShares(ctx context.Context, request todo.ListSharesRequest) ([]*todo.Share, error) {
+	shares, err := mw.next.ListShares(ctx, request)
+	if (request.TodoID == 0 && request.ListID == 0) || !retryable(ctx, err) {
+		return shares, err
+	}
+
+	if ctx, err = mw.authorize(ctx, request.TodoID, request.ListID, todo.RoleOwner); err != nil {
+		return nil, err
+	}
+	return mw.next.ListShares(ctx, request)
+}
+
+// authorize authorizes the caller on the list with the given ID, if set, or
+// on the todo otherwise.
+func (mw shareAuthorizingMiddleware) authorize(ctx context.Context, todoID, listID int, role string) (context.Context, error) {
+	if listID != 0 {
+		return authorizeList(ctx, mw.next, listID, role)
+	}
+	return authorize(ctx, mw.next, todoID, role)
+}
@@ -0,0 +1,135 @@
+package authzmw
+
+import (
+	"context"
+	"todo"
+)
+
+// NewTodoAuthorizingMiddleware returns a middleware giving access to todos
+// shared with the caller. Shares are looked up in shares.
+func NewTodoAuthorizingMiddleware(shares todo.ShareService) todo.Middleware {
+	return func(next todo.Service) todo.Service {
+		return &todoAuthorizingMiddleware{
+			next:   next,
+			shares: shares,
+		}
+	}
+}
+
+type todoAuthorizingMiddleware struct {
+	next   todo.Service
+	shares todo.ShareService
+}
+
+// CreateTodo creates a todo in a list shared with the caller on behalf of the
+// list's owner. Backends reject lists of other owners as invalid.
+func (mw todoAuthorizingMiddleware) CreateTodo(ctx context.Context, request todo.CreateTodoRequest) (*todo.Todo, error) {
+	t, err := mw.next.CreateTodo(ctx, request)
+	if request.ListID == 0 || todo.ErrorCode(err) != todo.EINVALID || todo.PrincipalIDFromContext(ctx) == "" {
+		return t, err
+	}
+
+	// Keep reporting the list as invalid if it is not shared at all.
+	ctx, aerr := authorizeList(ctx, mw.shares, request.ListID, todo.RoleEditor)
+	if todo.ErrorCode(aerr) == todo.ENOTFOUND {
+		return nil, err
+	} else if aerr != nil {
+		return nil, aerr
+	}
+	return mw.next.CreateTodo(ctx, request)
+}
+
+func (mw todoAuthorizingMiddleware) UpdateTodo(ctx context.Context, request todo.UpdateTodoRequest) (*todo.Todo, error) {
+	t, err := mw.next.UpdateTodo(ctx, request)
+	if !retryable(ctx, err) {
+		return t, err
+	}
+
+	if ctx, err = authorize(ctx, mw.shares, request.ID, todo.RoleEditor); err != nil {
+		return nil, err
+	}
+	return mw.next.UpdateTodo(ctx, request)
+}
+
+func (mw todoAuthorizingMiddleware) PatchTodo(ctx context.Context, request todo.PatchTodoRequest) (*todo.Todo, error) {
+	t, err := mw.next.PatchTodo(ctx, request)
+	if !retryable(ctx, err) {
+		return t, err
+	}
+
+	if ctx, err = authorize(ctx, mw.shares, request.ID, todo.RoleEditor); err != nil {
+		return nil, err
+	}
+	return mw.next.PatchTodo(ctx, request)
+}
+
+func (mw todoAuthorizingMiddleware) DeleteTodo(ctx context.Context, request todo.DeleteTodoRequest) error {
+	err := mw.next.DeleteTodo(ctx, request)
+	if !retryable(ctx, err) {
+		return err
+	}
+
+	if ctx, err = authorize(ctx, mw.shares, request.ID, todo.RoleOwner); err != nil {
+		return err
+	}
+	return mw.next.DeleteTodo(ctx, request)
+}
+
+func (mw todoAuthorizingMiddleware) GetTodoByID(ctx context.Context, request todo.GetTodoByIDRequest) (*todo.Todo, error) {
+	t, err := mw.next.GetTodoByID(ctx, request)
+	if !retryable(ctx, err) {
+		return t, err
+	}
+
+	if ctx, err = authorize(ctx, mw.shares, request.ID, todo.RoleViewer); err != nil {
+		return nil, err
+	}
+	return mw.next.GetTodoByID(ctx, request)
+}
+
+// ListTodos only lists the caller's own todos, unless it lists the todos of a
+// list shared with the caller. Todos shared on their own are found with
+// ListShares.
+func (mw todoAuthorizingMiddleware) ListTodos(ctx context.Context, request todo.ListTodosRequest) (*todo.ListTodosResponse, error) {
+	resp, err := mw.next.ListTodos(ctx, request)
+	if request.ListID == 0 || !retryable(ctx, err) {
+		return resp, err
+	}
+
+	if ctx, err = authorizeList(ctx, mw.shares, request.ListID, todo.RoleViewer); err != nil {
+		return nil, err
+	}
+	return mw.next.ListTodos(ctx, request)
+}
+
+func (mw todoAuthorizingMiddleware) CompleteTodo(ctx context.Context, request todo.CompleteTodoRequest) (*todo.CompleteTodoResponse, error) {
+	resp, err := mw.next.CompleteTodo(ctx, request)
+	if !retryable(ctx, err) {
+		return resp, err
+	}
+
+	if ctx, err = authorize(ctx, mw.shares, request.ID, todo.RoleEditor); err != nil {
+		return nil, err
+	}
+	return mw.next.CompleteTodo(ctx, request)
+}
+
+// BatchTodos only changes the caller's own todos. Todos shared with the caller
+// are changed one at a time.
+func (mw todoAuthorizingMiddleware) BatchTodos(ctx context.Context, request todo.BatchTodosRequest) (*todo.BatchTodosResponse, error) {
+	return mw.next.BatchTodos(ctx, request)
+}
+
+// MoveTodo moves a shared todo in the manual order of its owner, so the
+// anchors must be todos of the same owner.
+func (mw todoAuthorizingMiddleware) MoveTodo(ctx context.Context, request todo.MoveTodoRequest) (*todo.Todo, error) {
+	t, err := mw.next.MoveTodo(ctx, request)
+	if !retryable(ctx, err) {
+		return t, err
+	}
+
+	if ctx, err = authorize(ctx, mw.shares, request.ID, todo.RoleEditor); err != nil {
+		return nil, err
+	}
+	return mw.next.MoveTodo(ctx, request)
+}
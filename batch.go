@@ -0,0 +1,128 @@
+package todo
+
+// MaxBatchOps is the maximum number of operations in a single batch.
+const MaxBatchOps = 1000
+
+// Batch modes, see BatchTodosRequest.Mode.
+const (
+	// All operations are applied or none of them is. The batch fails with
+	// the error of the first operation that fails.
+	BatchAtomic = "atomic"
+
+	// Every operation is applied on its own and reports its own result, so
+	// a failed operation does not prevent the others from being applied.
+	BatchBestEffort = "bestEffort"
+)
+
+// Batch operations, see BatchOp.Op.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchPatch  = "patch"
+	BatchDelete = "delete"
+)
+
+// BatchTodosRequest applies several operations on todos in a single call.
+// Operations are applied in order, so later operations see the changes made
+// by earlier ones.
+type BatchTodosRequest struct {
+	// Either BatchAtomic or BatchBestEffort. Defaults to BatchAtomic.
+	Mode string `json:"mode"`
+
+	Ops []BatchOp `json:"ops"`
+}
+
+// BatchOp is a single operation of a batch. Only the request matching Op is
+// set. Patches follow PatchTodoRequest rather than the JSON merge patch of
+// PATCH /api/todos/{id}, so dates are removed by setting them to the zero
+// time instead of null.
+type BatchOp struct {
+	Op     string             `json:"op"`
+	Create *CreateTodoRequest `json:"create,omitempty"`
+	Update *UpdateTodoRequest `json:"update,omitempty"`
+	Patch  *PatchTodoRequest  `json:"patch,omitempty"`
+	Delete *DeleteTodoRequest `json:"delete,omitempty"`
+}
+
+// BatchTodosResponse holds the result of every operation of a batch, in the
+// order of the operations.
+type BatchTodosResponse struct {
+	Results []*BatchResult `json:"results"`
+}
+
+// BatchResult is the result of a single operation of a batch.
+type BatchResult struct {
+	// Todo created or changed by the operation. Nil for deletes and failed
+	// operations.
+	Todo *Todo `json:"todo,omitempty"`
+
+	// Error the operation failed with, if any. Use ErrorCode to get its
+	// code. Only set in the BatchBestEffort mode.
+	Err error `json:"-"`
+}
+
+// Validate normalizes the mode and returns EINVALID if the batch is empty,
+// too large or holds an operation not matching its request.
+func (r *BatchTodosRequest) Validate() error {
+	if r.Mode == "" {
+		r.Mode = BatchAtomic
+	}
+	if r.Mode != BatchAtomic && r.Mode != BatchBestEffort {
+		return Errorf(EINVALID, "Invalid batch mode '%s'.", r.Mode)
+	} else if len(r.Ops) == 0 {
+		return Errorf(EINVALID, "Batch must contain at least one operation.")
+	} else if len(r.Ops) > MaxBatchOps {
+		return Errorf(EINVALID, "Batch must contain at most %d operations.", MaxBatchOps)
+	}
+
+	for i, op := range r.Ops {
+		var n int
+		for _, set := range []bool{op.Create != nil, op.Update != nil, op.Patch != nil, op.Delete != nil} {
+			if set {
+				n++
+			}
+		}
+
+		var ok bool
+		switch op.Op {
+		case BatchCreate:
+			ok = op.Create != nil
+		case BatchUpdate:
+			ok = op.Update != nil
+		case BatchPatch:
+			ok = op.Patch != nil
+		case BatchDelete:
+			ok = op.Delete != nil
+		default:
+			return Errorf(EINVALID, "Operation %d: invalid operation '%s'.", i, op.Op)
+		}
+		if !ok || n != 1 {
+			return Errorf(EINVALID, "Operation %d: exactly the '%s' request must be set.", i, op.Op)
+		}
+	}
+	return nil
+}
+
+// BatchOpError returns the error an atomic batch fails with when its i-th
+// operation fails with err. Application errors keep their code and name the
+// operation in their message, other errors are returned as is.
+func BatchOpError(i int, err error) error {
+	if code := ErrorCode(err); code != EINTERNAL {
+		return Errorf(code, "Operation %d: %s", i, ErrorMessage(err))
+	}
+	return err
+}
+
+// TodoID returns the ID of the todo the operation changes, or zero for
+// creates.
+func (op *BatchOp) TodoID() int {
+	switch {
+	case op.Update != nil:
+		return op.Update.ID
+	case op.Patch != nil:
+		return op.Patch.ID
+	case op.Delete != nil:
+		return op.Delete.ID
+	}
+	return 0
+}
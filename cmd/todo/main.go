@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics"
@@ -10,10 +14,19 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"os"
 	"os/signal"
+	"strconv"
+	"time"
+	"todo"
+	"todo/auth"
+	"todo/authzmw"
+	"todo/eventmw"
 	"todo/http"
 	"todo/inmem"
 	"todo/instrmw"
 	"todo/logmw"
+	"todo/revmw"
+	"todo/scheduler"
+	"todo/sqlite"
 )
 
 func main() {
@@ -28,6 +41,33 @@ func main() {
 	m := NewMain()
 	m.HTTPServer.Addr = ":8080"
 
+	// Parse command line settings.
+	fs := flag.NewFlagSet("todo", flag.ExitOnError)
+	fs.StringVar(&m.DSN, "dsn", os.Getenv("TODO_DSN"), "SQLite database path; in-memory storage is used if empty")
+	fs.StringVar(&m.DataDir, "data-dir", os.Getenv("TODO_DATA_DIR"), "directory persisting in-memory storage; unused with -dsn")
+	fs.StringVar(&m.APIKeysPath, "api-keys", os.Getenv("TODO_API_KEYS"), "path of the API key file managed by todoadmin")
+	fs.StringVar(&m.TokenPublicKey, "token-public-key", os.Getenv("TODO_TOKEN_PUBLIC_KEY"), "base64 Ed25519 public key for EdDSA bearer tokens")
+	fs.BoolVar(&m.AllowOpenChildren, "allow-open-children", os.Getenv("TODO_ALLOW_OPEN_CHILDREN") == "true", "allow completing todos with open subtasks")
+	fs.StringVar(&m.WorkflowPath, "workflow", os.Getenv("TODO_WORKFLOW"), "path of a JSON file defining the workflow of todos; the default workflow is used if empty")
+	fs.IntVar(&m.Retention.MaxRevisions, "revisions-max", intEnv("TODO_REVISIONS_MAX"), "number of revisions kept per todo; all are kept if zero")
+	fs.DurationVar(&m.Retention.MaxAge, "revisions-max-age", durationEnv("TODO_REVISIONS_MAX_AGE"), "age after which revisions are deleted, e.g. 720h; revisions never expire if zero")
+	if v := durationEnv("TODO_TRASH_RETENTION"); v != 0 {
+		m.TrashRetention = v
+	}
+	fs.DurationVar(&m.TrashRetention, "trash-retention", m.TrashRetention, "time deleted todos are kept in the trash before they are purged")
+	if v := durationEnv("TODO_POLICY_INTERVAL"); v != 0 {
+		m.PolicyInterval = v
+	}
+	fs.DurationVar(&m.PolicyInterval, "policy-interval", m.PolicyInterval, "time between two runs of the retention policies of lists")
+	if v := durationEnv("TODO_IDEMPOTENCY_TTL"); v != 0 {
+		m.IdempotencyTTL = v
+	}
+	fs.DurationVar(&m.IdempotencyTTL, "idempotency-ttl", m.IdempotencyTTL, "time responses to POST requests with an Idempotency-Key header are kept for retries; the header is ignored if zero")
+	fs.StringVar(&m.WebhookURL, "webhook-url", os.Getenv("TODO_WEBHOOK_URL"), "URL reminders are POSTed to; reminders are logged if empty")
+	m.TokenSecret = os.Getenv("TODO_TOKEN_SECRET")
+	m.WebhookSecret = os.Getenv("TODO_WEBHOOK_SECRET")
+	_ = fs.Parse(os.Args[1:])
+
 	// Execute program.
 	if err := m.Run(ctx); err != nil {
 		_ = m.Close()
@@ -48,6 +88,65 @@ func main() {
 
 // Main represents the program.
 type Main struct {
+	// Datasource name of the SQLite database. If empty, todos are only kept
+	// in memory and are lost when the program exits.
+	DSN string
+
+	// SQLite database used by SQLite service implementations.
+	// Only opened when DSN is set.
+	DB *sqlite.DB
+
+	// Directory where the in-memory service persists its write-ahead log and
+	// snapshots when DSN is not set. If empty, nothing is persisted.
+	DataDir string
+
+	// In-memory service used when DSN is not set.
+	InmemService *inmem.Service
+
+	// If set, todos can be completed while some of their subtasks are open.
+	AllowOpenChildren bool
+
+	// Path of a JSON file defining the states of todos and the transitions
+	// between them. If empty, todo.DefaultWorkflow is used.
+	WorkflowPath string
+
+	// Limits the revisions kept of each todo. Everything is kept if zero.
+	Retention todo.RetentionPolicy
+
+	// Time deleted todos are kept in the trash before they are purged.
+	TrashRetention time.Duration
+
+	// Time between two runs of the retention policies of lists.
+	PolicyInterval time.Duration
+
+	// Time responses to POST requests with an Idempotency-Key header are
+	// replayed to retries. The header is ignored if zero.
+	IdempotencyTTL time.Duration
+
+	// Authentication settings. The API requires authentication if any of
+	// these are set, otherwise it is open to anonymous callers.
+	APIKeysPath    string // API keys issued with todoadmin
+	TokenSecret    string // shared secret for HS256 bearer tokens
+	TokenPublicKey string // base64 Ed25519 public key for EdDSA bearer tokens
+
+	// Webhook reminders are delivered to. If empty, reminders are logged.
+	// Requests are signed with the secret if it is set.
+	WebhookURL    string
+	WebhookSecret string
+
+	// Scheduler sending reminders in the background.
+	Scheduler *scheduler.Scheduler
+
+	// Purger deleting todos from the trash in the background.
+	Purger *scheduler.Purger
+
+	// Pruner deleting expired revisions in the background. Only set if
+	// revisions expire.
+	RevisionPruner *scheduler.RevisionPruner
+
+	// Enforcer applying the retention policies of lists in the background.
+	Enforcer *scheduler.Enforcer
+
 	// HTTP server for handling HTTP communication.
 	// SQLite services are attached to it before running.
 	HTTPServer *http.Server
@@ -56,18 +155,52 @@ type Main struct {
 // NewMain returns a new instance of Main.
 func NewMain() *Main {
 	return &Main{
-		HTTPServer: http.NewServer(),
+		TrashRetention: scheduler.DefaultTrashRetention,
+		PolicyInterval: scheduler.DefaultEnforceInterval,
+		IdempotencyTTL: http.DefaultIdempotencyTTL,
+		HTTPServer:     http.NewServer(),
 	}
 }
 
-// Close gracefully stops the program.
-func (m *Main) Close() error {
+// Close gracefully stops the program. Every component is closed even if
+// closing another one fails, and the first error is returned.
+func (m *Main) Close() (err error) {
+	if m.Scheduler != nil {
+		if e := m.Scheduler.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	if m.Purger != nil {
+		if e := m.Purger.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	if m.RevisionPruner != nil {
+		if e := m.RevisionPruner.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	if m.Enforcer != nil {
+		if e := m.Enforcer.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
 	if m.HTTPServer != nil {
-		if err := m.HTTPServer.Close(); err != nil {
-			return err
+		if e := m.HTTPServer.Close(); e != nil && err == nil {
+			err = e
 		}
 	}
-	return nil
+	if m.InmemService != nil {
+		if e := m.InmemService.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	if m.DB != nil {
+		if e := m.DB.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
 }
 
 // Run executes the program. The configuration should already be set up before
@@ -76,12 +209,174 @@ func (m *Main) Run(ctx context.Context) (err error) {
 	m.HTTPServer.Logger = createLogger()
 	requestCount, errorCount, requestDuration := setupMetrics()
 
+	workflow, err := m.workflow()
+	if err != nil {
+		return err
+	}
+
 	// Initialize services.
-	todoService := logmw.NewTodoLoggingMiddleware(m.HTTPServer.Logger)(inmem.NewService())
+	var todoService todo.Service
+	var shareService todo.ShareService
+	var listService todo.ListService
+	var dependencyService todo.DependencyService
+	var tagService todo.TagService
+	var workflowService todo.WorkflowService
+	var searchService todo.SearchService
+	var reminderService todo.ReminderService
+	var revisionStore todo.RevisionStore
+	var trashService todo.TrashService
+	var purgeService todo.PurgeService
+	var policyService todo.PolicyService
+	var policyEnforcer todo.PolicyEnforcer
+	if m.DSN != "" {
+		m.DB = sqlite.NewDB(m.DSN)
+		m.DB.Workflow = workflow
+		if err := m.DB.Open(); err != nil {
+			return fmt.Errorf("cannot open db: %w", err)
+		}
+		ts := sqlite.NewTodoService(m.DB)
+		ts.AllowOpenChildren = m.AllowOpenChildren
+		todoService = ts
+		shareService = sqlite.NewShareService(m.DB)
+		listService = sqlite.NewListService(m.DB)
+		dependencyService = sqlite.NewDependencyService(m.DB)
+		tagService = sqlite.NewTagService(m.DB)
+		workflowService = sqlite.NewWorkflowService(m.DB)
+		searchService = sqlite.NewSearchService(m.DB)
+		reminderService = sqlite.NewReminderService(m.DB)
+		revisionStore = sqlite.NewRevisionStore(m.DB)
+		trash := sqlite.NewTrashService(m.DB)
+		trashService, purgeService = trash, trash
+		policies := sqlite.NewPolicyService(m.DB)
+		policyService, policyEnforcer = policies, policies
+	} else {
+		m.InmemService = inmem.NewService()
+		m.InmemService.Dir = m.DataDir
+		m.InmemService.AllowOpenChildren = m.AllowOpenChildren
+		m.InmemService.Workflow = workflow
+		if err := m.InmemService.Open(); err != nil {
+			return fmt.Errorf("cannot open data dir: %w", err)
+		}
+		todoService = m.InmemService
+		shareService = m.InmemService
+		listService = m.InmemService
+		dependencyService = m.InmemService
+		tagService = m.InmemService
+		workflowService = m.InmemService
+		searchService = m.InmemService
+		reminderService = m.InmemService
+		revisionStore = m.InmemService
+		trashService = m.InmemService
+		purgeService = m.InmemService
+		policyService = m.InmemService
+		policyEnforcer = m.InmemService
+	}
+
+	// Give access to shared todos & lists. Shares are looked up in the underlying
+	// service so lookups are not logged as calls of their own.
+	shares := shareService
+	todoService = authzmw.NewTodoAuthorizingMiddleware(shares)(todoService)
+	listService = authzmw.NewListAuthorizingMiddleware(shares)(listService)
+	shareService = authzmw.NewShareAuthorizingMiddleware()(shareService)
+
+	// Publish every change to the event feed of the owner and the sharees of
+	// the todo, including revision restores, trash restores, tag renames and
+	// changes made by the policies of lists.
+	bus := eventmw.NewBus()
+	tagService = eventmw.NewTagEventMiddleware(bus, shares, todoService)(tagService)
+	todoService = eventmw.NewTodoEventMiddleware(bus, shares)(todoService)
+	trashService = eventmw.NewTrashEventMiddleware(bus, shares)(trashService)
+	policyEnforcer = eventmw.NewPolicyEnforcerEventMiddleware(bus, shares)(policyEnforcer)
+	var eventService todo.EventService = bus
+
+	// Record a revision of every change, including trash restores and todos
+	// archived by the policies of lists. Revision restores go through the
+	// authorized todo service, so only editors can restore a todo.
+	todoService = revmw.NewTodoRevisionMiddleware(revisionStore, m.Retention, m.HTTPServer.Logger)(todoService)
+	trashService = revmw.NewTrashRevisionMiddleware(revisionStore, m.Retention, m.HTTPServer.Logger)(trashService)
+	policyEnforcer = revmw.NewPolicyEnforcerRevisionMiddleware(revisionStore, m.Retention, m.HTTPServer.Logger)(policyEnforcer)
+	revisionService := revmw.NewRevisionService(todoService, revisionStore)
+
+	todoService = logmw.NewTodoLoggingMiddleware(m.HTTPServer.Logger)(todoService)
 	todoService = instrmw.NewTodoInstrumentingMiddleware(requestCount, errorCount, requestDuration)(todoService)
+	shareService = logmw.NewShareLoggingMiddleware(m.HTTPServer.Logger)(shareService)
+	shareService = instrmw.NewShareInstrumentingMiddleware(requestCount, errorCount, requestDuration)(shareService)
+	listService = logmw.NewListLoggingMiddleware(m.HTTPServer.Logger)(listService)
+	listService = instrmw.NewListInstrumentingMiddleware(requestCount, errorCount, requestDuration)(listService)
+	dependencyService = logmw.NewDependencyLoggingMiddleware(m.HTTPServer.Logger)(dependencyService)
+	dependencyService = instrmw.NewDependencyInstrumentingMiddleware(requestCount, errorCount, requestDuration)(dependencyService)
+	tagService = logmw.NewTagLoggingMiddleware(m.HTTPServer.Logger)(tagService)
+	tagService = instrmw.NewTagInstrumentingMiddleware(requestCount, errorCount, requestDuration)(tagService)
+	workflowService = logmw.NewWorkflowLoggingMiddleware(m.HTTPServer.Logger)(workflowService)
+	workflowService = instrmw.NewWorkflowInstrumentingMiddleware(requestCount, errorCount, requestDuration)(workflowService)
+	searchService = logmw.NewSearchLoggingMiddleware(m.HTTPServer.Logger)(searchService)
+	searchService = instrmw.NewSearchInstrumentingMiddleware(requestCount, errorCount, requestDuration)(searchService)
+	revisionService = logmw.NewRevisionLoggingMiddleware(m.HTTPServer.Logger)(revisionService)
+	revisionService = instrmw.NewRevisionInstrumentingMiddleware(requestCount, errorCount, requestDuration)(revisionService)
+	trashService = logmw.NewTrashLoggingMiddleware(m.HTTPServer.Logger)(trashService)
+	trashService = instrmw.NewTrashInstrumentingMiddleware(requestCount, errorCount, requestDuration)(trashService)
+	policyService = logmw.NewPolicyLoggingMiddleware(m.HTTPServer.Logger)(policyService)
+	policyService = instrmw.NewPolicyInstrumentingMiddleware(requestCount, errorCount, requestDuration)(policyService)
+	eventService = logmw.NewEventLoggingMiddleware(m.HTTPServer.Logger)(eventService)
+	eventService = instrmw.NewEventInstrumentingMiddleware(requestCount, errorCount, requestDuration)(eventService)
 
-	// Attach underlying service to the HTTP server.
+	// Attach underlying services to the HTTP server.
 	m.HTTPServer.TodoService = todoService
+	m.HTTPServer.ShareService = shareService
+	m.HTTPServer.ListService = listService
+	m.HTTPServer.DependencyService = dependencyService
+	m.HTTPServer.TagService = tagService
+	m.HTTPServer.WorkflowService = workflowService
+	m.HTTPServer.SearchService = searchService
+	m.HTTPServer.RevisionService = revisionService
+	m.HTTPServer.TrashService = trashService
+	m.HTTPServer.PolicyService = policyService
+	m.HTTPServer.EventService = eventService
+
+	if m.HTTPServer.Authenticator, err = m.authenticator(); err != nil {
+		return err
+	}
+	if m.IdempotencyTTL > 0 {
+		m.HTTPServer.Idempotency = http.NewIdempotencyStore()
+		m.HTTPServer.Idempotency.TTL = m.IdempotencyTTL
+	}
+
+	// Send reminders in the background. Reminders are claimed in storage
+	// before they are sent, so restarting never sends one twice.
+	m.Scheduler = scheduler.NewScheduler(reminderService, m.notifier())
+	m.Scheduler.Logger = m.HTTPServer.Logger
+	if err := m.Scheduler.Open(); err != nil {
+		return err
+	}
+
+	// Permanently delete todos once they have been in the trash for longer
+	// than the retention window.
+	m.Purger = scheduler.NewPurger(purgeService, m.TrashRetention)
+	m.Purger.Logger = m.HTTPServer.Logger
+	if err := m.Purger.Open(); err != nil {
+		return err
+	}
+
+	// Delete revisions of all todos once they are older than the retention
+	// policy allows, including those of todos that no longer change.
+	if m.Retention.MaxAge > 0 {
+		m.RevisionPruner = scheduler.NewRevisionPruner(revisionStore, m.Retention.MaxAge)
+		m.RevisionPruner.Logger = m.HTTPServer.Logger
+		if err := m.RevisionPruner.Open(); err != nil {
+			return err
+		}
+	}
+
+	// Archive and purge todos as the policies of their list require.
+	if m.PolicyInterval <= 0 {
+		return fmt.Errorf("policy interval must be positive: %s", m.PolicyInterval)
+	}
+	m.Enforcer = scheduler.NewEnforcer(policyEnforcer)
+	m.Enforcer.Interval = m.PolicyInterval
+	m.Enforcer.Logger = m.HTTPServer.Logger
+	if err := m.Enforcer.Open(); err != nil {
+		return err
+	}
 
 	m.HTTPServer.RegisterRoute("/metrics", promhttp.Handler())
 
@@ -92,6 +387,76 @@ func (m *Main) Run(ctx context.Context) (err error) {
 	return nil
 }
 
+// authenticator returns the authenticator configured by the authentication
+// settings, or nil if none are set.
+func (m *Main) authenticator() (*auth.Authenticator, error) {
+	if m.APIKeysPath == "" && m.TokenSecret == "" && m.TokenPublicKey == "" {
+		return nil, nil
+	}
+
+	a := &auth.Authenticator{}
+	if m.APIKeysPath != "" {
+		a.Keys = auth.NewKeyStore(m.APIKeysPath)
+	}
+	if m.TokenSecret != "" || m.TokenPublicKey != "" {
+		a.Tokens = auth.NewTokenVerifier()
+		a.Tokens.Secret = []byte(m.TokenSecret)
+		if m.TokenPublicKey != "" {
+			key, err := base64.StdEncoding.DecodeString(m.TokenPublicKey)
+			if err != nil || len(key) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("invalid token public key")
+			}
+			a.Tokens.PublicKeys = []ed25519.PublicKey{key}
+		}
+	}
+	return a, nil
+}
+
+// workflow returns the workflow read from WorkflowPath, or nil if it is not
+// set so services use the default workflow.
+func (m *Main) workflow() (*todo.Workflow, error) {
+	if m.WorkflowPath == "" {
+		return nil, nil
+	}
+
+	buf, err := os.ReadFile(m.WorkflowPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read workflow: %w", err)
+	}
+	var w todo.Workflow
+	if err := json.Unmarshal(buf, &w); err != nil {
+		return nil, fmt.Errorf("cannot parse workflow: %w", err)
+	} else if err := w.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid workflow: %w", err)
+	}
+	return &w, nil
+}
+
+// notifier returns the notifier reminders are delivered through.
+func (m *Main) notifier() todo.Notifier {
+	if m.WebhookURL == "" {
+		return scheduler.NewLogNotifier(m.HTTPServer.Logger)
+	}
+
+	n := scheduler.NewWebhookNotifier(m.WebhookURL)
+	n.Secret = []byte(m.WebhookSecret)
+	return n
+}
+
+// intEnv returns the integer value of the environment variable name, or zero
+// if it is not set or invalid.
+func intEnv(name string) int {
+	v, _ := strconv.Atoi(os.Getenv(name))
+	return v
+}
+
+// durationEnv returns the duration value of the environment variable name, or
+// zero if it is not set or invalid.
+func durationEnv(name string) time.Duration {
+	v, _ := time.ParseDuration(os.Getenv(name))
+	return v
+}
+
 func createLogger() log.Logger {
 	var logger log.Logger
 	logger = log.NewLogfmtLogger(os.Stderr)
@@ -0,0 +1,161 @@
+// Command todoadmin manages credentials for the todo API.
+//
+// Usage:
+//
+//	todoadmin issue  -keys PATH -principal ID [-name NAME]
+//	todoadmin revoke -keys PATH KEY_ID
+//	todoadmin list   -keys PATH
+//	todoadmin token  -principal ID [-ttl DURATION]
+//
+// The token command signs an HS256 bearer token with the secret in the
+// TODO_TOKEN_SECRET environment variable.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+	"todo"
+	"todo/auth"
+)
+
+func main() {
+	if err := Run(os.Args[1:], os.Stdout); err == flag.ErrHelp {
+		os.Exit(2)
+	} else if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Run executes the subcommand named by the first argument.
+func Run(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: todoadmin <issue|revoke|list|token> [arguments]")
+		return flag.ErrHelp
+	}
+
+	switch args[0] {
+	case "issue":
+		return runIssue(args[1:], w)
+	case "revoke":
+		return runRevoke(args[1:], w)
+	case "list":
+		return runList(args[1:], w)
+	case "token":
+		return runToken(args[1:], w)
+	default:
+		return fmt.Errorf("todoadmin %s: unknown command", args[0])
+	}
+}
+
+// runIssue creates a new API key and prints it. The key cannot be shown again.
+func runIssue(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("todoadmin-issue", flag.ContinueOnError)
+	keys := fs.String("keys", os.Getenv("TODO_API_KEYS"), "path of the API key file")
+	principal := fs.String("principal", "", "principal the key authenticates as")
+	name := fs.String("name", "", "description of the key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if *keys == "" {
+		return errors.New("-keys required")
+	}
+
+	key, apiKey, err := auth.NewKeyStore(*keys).Issue(*principal, *name)
+	if err != nil {
+		return formatError(err)
+	}
+
+	_, _ = fmt.Fprintf(w, "Issued key %s for %s.\n", apiKey.ID, apiKey.PrincipalID)
+	_, _ = fmt.Fprintln(w, key)
+	return nil
+}
+
+// runRevoke revokes the API key with the given ID.
+func runRevoke(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("todoadmin-revoke", flag.ContinueOnError)
+	keys := fs.String("keys", os.Getenv("TODO_API_KEYS"), "path of the API key file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if *keys == "" {
+		return errors.New("-keys required")
+	} else if fs.NArg() != 1 {
+		return errors.New("key ID required")
+	}
+
+	if err := auth.NewKeyStore(*keys).Revoke(fs.Arg(0)); err != nil {
+		return formatError(err)
+	}
+
+	_, _ = fmt.Fprintf(w, "Revoked key %s.\n", fs.Arg(0))
+	return nil
+}
+
+// runList prints all API keys.
+func runList(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("todoadmin-list", flag.ContinueOnError)
+	keys := fs.String("keys", os.Getenv("TODO_API_KEYS"), "path of the API key file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if *keys == "" {
+		return errors.New("-keys required")
+	}
+
+	apiKeys, err := auth.NewKeyStore(*keys).Keys()
+	if err != nil {
+		return formatError(err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tPRINCIPAL\tNAME\tCREATED\tREVOKED")
+	for _, k := range apiKeys {
+		revoked := "-"
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", k.ID, k.PrincipalID, k.Name, k.CreatedAt.Format(time.RFC3339), revoked)
+	}
+	return tw.Flush()
+}
+
+// runToken prints a signed HS256 bearer token.
+func runToken(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("todoadmin-token", flag.ContinueOnError)
+	principal := fs.String("principal", "", "principal the token authenticates as")
+	ttl := fs.Duration("ttl", 24*time.Hour, "lifetime of the token")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if *principal == "" {
+		return errors.New("-principal required")
+	}
+
+	secret := os.Getenv("TODO_TOKEN_SECRET")
+	if secret == "" {
+		return errors.New("TODO_TOKEN_SECRET required")
+	}
+
+	now := time.Now()
+	token, err := auth.SignHS256([]byte(secret), auth.Claims{
+		Subject:   *principal,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(*ttl).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(w, token)
+	return nil
+}
+
+// formatError returns the user message of application errors.
+func formatError(err error) error {
+	if todo.ErrorCode(err) != todo.EINTERNAL {
+		return errors.New(todo.ErrorMessage(err))
+	}
+	return err
+}
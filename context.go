@@ -0,0 +1,63 @@
+package todo
+
+import "context"
+
+// Principal represents an authenticated caller of the application.
+type Principal struct {
+	// Unique identifier of the caller, e.g. a user name.
+	ID string `json:"id"`
+
+	// How the caller authenticated, e.g. "api_key" or "token".
+	Method string `json:"method"`
+}
+
+// contextKey represents an internal key for adding context fields.
+// This is considered best practice as it prevents other packages from
+// interfering with our context keys.
+type contextKey int
+
+// List of context keys.
+// These are used to store request-scoped information.
+const (
+	// Stores the authenticated principal for the request.
+	principalContextKey = contextKey(iota + 1)
+
+	// Stores the owner whose data the request operates on.
+	ownerContextKey
+)
+
+// NewContextWithPrincipal returns a new context with the given principal.
+func NewContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal, if any.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey).(*Principal)
+	return principal
+}
+
+// PrincipalIDFromContext is a helper function that returns the ID of the
+// authenticated principal. Returns an empty string if no one is logged in.
+func PrincipalIDFromContext(ctx context.Context) string {
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		return principal.ID
+	}
+	return ""
+}
+
+// NewContextWithOwnerID returns a new context that operates on the data of the
+// given owner instead of the authenticated principal's own. It is used once
+// access to another owner's data has been authorized.
+func NewContextWithOwnerID(ctx context.Context, ownerID string) context.Context {
+	return context.WithValue(ctx, ownerContextKey, ownerID)
+}
+
+// OwnerIDFromContext returns the ID of the owner whose data the request
+// operates on. Defaults to the ID of the authenticated principal.
+func OwnerIDFromContext(ctx context.Context) string {
+	if ownerID, ok := ctx.Value(ownerContextKey).(string); ok {
+		return ownerID
+	}
+	return PrincipalIDFromContext(ctx)
+}
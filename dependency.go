@@ -0,0 +1,149 @@
+package todo
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// DependencyService manages which todos block which other todos. Unlike
+// subtasks, dependencies may cross lists. Both todos of a dependency must be
+// owned by the caller.
+type DependencyService interface {
+	CreateDependency(ctx context.Context, request CreateDependencyRequest) (*Dependency, error)
+	DeleteDependency(ctx context.Context, request DeleteDependencyRequest) error
+	ListDependencies(ctx context.Context, request ListDependenciesRequest) ([]*Dependency, error)
+
+	// ListActionableTodos returns the caller's open todos in an order they
+	// can be worked on. See ListActionableTodosRequest.
+	ListActionableTodos(ctx context.Context, request ListActionableTodosRequest) ([]*Todo, error)
+}
+
+// DependencyMiddleware describes a service middleware for the DependencyService.
+type DependencyMiddleware func(service DependencyService) DependencyService
+
+// Dependency records that a todo cannot be completed before another one.
+type Dependency struct {
+	// Todo that is blocked & the todo blocking it.
+	TodoID      int `json:"todoId"`
+	BlockedByID int `json:"blockedById"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateDependencyRequest marks a todo as blocked by another one. Creating an
+// existing dependency again returns it unchanged. Dependencies that would
+// make a todo transitively block itself are rejected with EINVALID.
+type CreateDependencyRequest struct {
+	TodoID      int `json:"todoId"`
+	BlockedByID int `json:"blockedById"`
+}
+
+// Validate returns EINVALID if the request is malformed.
+func (r *CreateDependencyRequest) Validate() error {
+	if r.TodoID == r.BlockedByID {
+		return Errorf(EINVALID, "Todo with ID '%d' cannot block itself.", r.TodoID)
+	}
+	return nil
+}
+
+type DeleteDependencyRequest struct {
+	TodoID      int `json:"todoId"`
+	BlockedByID int `json:"blockedById"`
+}
+
+// ListDependenciesRequest lists the dependencies of a todo in either
+// direction, i.e. the todos it is blocked by and the todos it blocks. If
+// TodoID is zero, every dependency of the caller is listed. Dependencies are
+// ordered by blocked todo, then by blocking todo.
+type ListDependenciesRequest struct {
+	TodoID int `json:"todoId"`
+}
+
+// ListActionableTodosRequest lists the todos that can be worked on next.
+type ListActionableTodosRequest struct {
+	// Limits the todos to those in a list. Blockers in other lists still
+	// count. Zero means every list.
+	ListID int `json:"listId"`
+
+	// Set to also return blocked todos. Todos are always returned in
+	// topological order, so the result is a plan for every open todo.
+	// Otherwise only todos without open blockers are returned.
+	IncludeBlocked bool `json:"includeBlocked"`
+
+	// Maximum number of todos to return. Defaults to DefaultListLimit and may
+	// not exceed MaxListLimit.
+	Limit int `json:"limit"`
+}
+
+// Normalize sets the default limit and returns EINVALID if the request is out
+// of bounds.
+func (r *ListActionableTodosRequest) Normalize() error {
+	if r.Limit == 0 {
+		r.Limit = DefaultListLimit
+	} else if r.Limit < 0 || r.Limit > MaxListLimit {
+		return Errorf(EINVALID, "Limit must be between 1 and %d.", MaxListLimit)
+	}
+	return nil
+}
+
+// CheckUnblocked returns ECONFLICT if t is being completed while it is blocked
+// by open todos, unless force is set.
+func (t *Todo) CheckUnblocked(prev *Todo, force bool) error {
+	if t.Complete && (prev == nil || !prev.Complete) && t.Blocked && !force {
+		return Errorf(ECONFLICT, "Todo with ID '%d' is blocked by open todos.", t.ID)
+	}
+	return nil
+}
+
+// ActionableTodos returns the open todos among todos that match request, in
+// topological order: every todo comes after the open todos blocking it, and
+// ties are broken by ID. todos must hold every todo of the caller so blockers
+// outside the requested list are taken into account. The Blocked field of the
+// returned todos is set.
+func ActionableTodos(todos []*Todo, deps []*Dependency, request ListActionableTodosRequest) []*Todo {
+	open := make(map[int]*Todo)
+	for _, t := range todos {
+		if !t.Complete {
+			open[t.ID] = t
+		}
+	}
+
+	// Count the open blockers of every open todo & index who they block.
+	blockers, blocks := make(map[int]int), make(map[int][]int)
+	for _, d := range deps {
+		if open[d.TodoID] != nil && open[d.BlockedByID] != nil {
+			blockers[d.TodoID]++
+			blocks[d.BlockedByID] = append(blocks[d.BlockedByID], d.TodoID)
+		}
+	}
+
+	// Kahn's algorithm, always taking the ready todo with the lowest ID.
+	ready := make([]int, 0)
+	for id := range open {
+		open[id].Blocked = blockers[id] > 0
+		if blockers[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	sort.Ints(ready)
+
+	result := make([]*Todo, 0)
+	for len(ready) > 0 && len(result) < request.Limit {
+		id := ready[0]
+		ready = ready[1:]
+
+		if t := open[id]; (request.ListID == 0 || t.ListID == request.ListID) && (request.IncludeBlocked || !t.Blocked) {
+			result = append(result, t)
+		}
+		for _, other := range blocks[id] {
+			if blockers[other]--; blockers[other] == 0 {
+				i := sort.SearchInts(ready, other)
+				ready = append(ready, 0)
+				copy(ready[i+1:], ready[i:])
+				ready[i] = other
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,41 @@
+package todo_test
+
+import (
+	"reflect"
+	"testing"
+	"todo"
+)
+
+func TestActionableTodos(t *testing.T) {
+	todos := []*todo.Todo{
+		{ID: 1, ListID: 1},
+		{ID: 2, ListID: 2},
+		{ID: 3, ListID: 1},
+		{ID: 4, ListID: 2, Complete: true},
+		{ID: 5, ListID: 1},
+	}
+	deps := []*todo.Dependency{
+		{TodoID: 1, BlockedByID: 5},
+		{TodoID: 3, BlockedByID: 2},
+		{TodoID: 5, BlockedByID: 4}, // complete blockers are ignored
+	}
+
+	for _, tt := range []struct {
+		request todo.ListActionableTodosRequest
+		want    []int
+	}{
+		{todo.ListActionableTodosRequest{Limit: 10}, []int{2, 5}},
+		{todo.ListActionableTodosRequest{Limit: 10, IncludeBlocked: true}, []int{2, 3, 5, 1}},
+		{todo.ListActionableTodosRequest{Limit: 10, ListID: 1}, []int{5}},
+		{todo.ListActionableTodosRequest{Limit: 10, ListID: 1, IncludeBlocked: true}, []int{3, 5, 1}},
+		{todo.ListActionableTodosRequest{Limit: 2, IncludeBlocked: true}, []int{2, 3}},
+	} {
+		var got []int
+		for _, t := range todo.ActionableTodos(todos, deps, tt.request) {
+			got = append(got, t.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: got %v, want %v", tt.request, got, tt.want)
+		}
+	}
+}
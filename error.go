@@ -12,6 +12,7 @@ import (
 // these should be expanded as needed (or introduce subcodes).
 const (
 	ECONFLICT       = "conflict"
+	EFORBIDDEN      = "forbidden"
 	EINTERNAL       = "internal"
 	EINVALID        = "invalid"
 	ENOTFOUND       = "not_found"
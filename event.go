@@ -0,0 +1,85 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// EventService streams the changes made to todos, see
+// eventmw.NewTodoEventMiddleware. Events are only kept in memory, so their
+// sequence numbers start over when the process restarts.
+type EventService interface {
+	Subscribe(ctx context.Context, request SubscribeRequest) (*Subscription, error)
+}
+
+// EventMiddleware describes a service middleware for the EventService.
+type EventMiddleware func(service EventService) EventService
+
+// Event types. Todos restored from the trash are created again.
+const (
+	EventTodoCreated = "todo.created"
+	EventTodoUpdated = "todo.updated"
+	EventTodoDeleted = "todo.deleted"
+)
+
+// Event represents a change made to a todo.
+type Event struct {
+	// Sequence number of the event. Each event is numbered one more than
+	// the event before it. The changes made to a todo through the Service
+	// are numbered in the order they were made. Other changes, such as
+	// renaming a tag, may be numbered out of order with concurrent changes
+	// to the same todo, so clients should only apply an event if its todo
+	// has a newer Version than the one they know.
+	Seq  int    `json:"seq"`
+	Type string `json:"type"`
+
+	// Owner of the todo. Events are delivered to the owner and to the
+	// principals the todo was shared with when it changed.
+	OwnerID      string   `json:"ownerId"`
+	PrincipalIDs []string `json:"-"`
+
+	TodoID int `json:"todoId"`
+
+	// List the todo is in after the change, and the list it was in before
+	// if the change moved it to another list.
+	ListID     int `json:"listId"`
+	FromListID int `json:"fromListId,omitempty"`
+
+	// Todo after the change. Nil for deletes.
+	Todo *Todo `json:"todo,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SubscribeRequest subscribes to the events of the caller's todos and of the
+// todos shared with the caller.
+type SubscribeRequest struct {
+	// Only events of todos in, or moved out of, this list are delivered.
+	// Zero for all todos.
+	ListID int `json:"listId"`
+
+	// Sequence number of the last event received, if resuming an earlier
+	// subscription. The events after it are delivered first.
+	After *int `json:"after"`
+}
+
+// Subscription delivers events until the context passed to Subscribe is done.
+// The channel is closed when the subscription ends, which also happens if
+// the subscriber does not keep up with the events. It can then resume from
+// the last event it received.
+type Subscription struct {
+	Events <-chan *Event
+
+	// Set if some events after SubscribeRequest.After are no longer
+	// available, so the subscriber must reload the todos it knows.
+	Reset bool
+}
+
+// Match returns true if e is to be delivered to the subscriber with the given
+// principal ID.
+func (r *SubscribeRequest) Match(e *Event, principalID string) bool {
+	if e.OwnerID != principalID && !contains(e.PrincipalIDs, principalID) {
+		return false
+	}
+	return r.ListID == 0 || e.ListID == r.ListID || e.FromListID == r.ListID
+}
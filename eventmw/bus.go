@@ -0,0 +1,186 @@
+package eventmw
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"todo"
+)
+
+const (
+	// DefaultBufferSize is the number of events kept for subscribers
+	// resuming a subscription when Bus.BufferSize is not set.
+	DefaultBufferSize = 1000
+
+	// DefaultQueueSize is the number of events queued for a subscriber that
+	// has not received them yet when Bus.QueueSize is not set.
+	DefaultQueueSize = 100
+)
+
+// Ensure service implements interface.
+var _ todo.EventService = (*Bus)(nil)
+
+// Bus numbers the events published to it and delivers them to subscribers.
+// The most recent events are kept in a bounded replay buffer so subscribers
+// can resume where they left off.
+type Bus struct {
+	mu     sync.Mutex
+	seq    int
+	buffer []*todo.Event // ring of the most recent events
+	next   int           // position of the next event in buffer
+	subs   map[*subscriber]struct{}
+
+	// Locks serializing the changes to todos published by the middlewares,
+	// see lock.
+	locks [lockStripes]sync.Mutex
+
+	// Number of events kept for resuming subscriptions.
+	// Defaults to DefaultBufferSize.
+	BufferSize int
+
+	// Number of events queued for each subscriber. A subscriber falling
+	// further behind is dropped. Defaults to DefaultQueueSize.
+	QueueSize int
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+type subscriber struct {
+	ch          chan *todo.Event
+	principalID string
+	request     todo.SubscribeRequest
+}
+
+// lockStripes is the number of locks changes to todos are serialized with.
+const lockStripes = 64
+
+// NewBus returns a new instance of Bus.
+func NewBus() *Bus {
+	return &Bus{
+		subs:       make(map[*subscriber]struct{}),
+		BufferSize: DefaultBufferSize,
+		QueueSize:  DefaultQueueSize,
+		Now:        time.Now,
+	}
+}
+
+// Publish assigns the next sequence number & the creation time to e and
+// delivers it to the matching subscribers. It never blocks on subscribers.
+func (b *Bus) Publish(e *todo.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.Seq = b.seq
+	e.CreatedAt = b.Now().UTC()
+
+	if b.BufferSize > 0 {
+		if len(b.buffer) < b.BufferSize {
+			b.buffer = append(b.buffer, e)
+		} else {
+			b.buffer[b.next] = e
+		}
+		b.next = (b.next + 1) % b.BufferSize
+	}
+
+	for sub := range b.subs {
+		if !sub.request.Match(e, sub.principalID) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			b.drop(sub)
+		}
+	}
+}
+
+// Subscribe delivers the events of the todos owned by or shared with the caller
+// until ctx is done. When
+// resuming, the buffered events after request.After are delivered first, and
+// the subscription is reset if some of them were already dropped from the
+// buffer or were published before the process restarted.
+func (b *Bus) Subscribe(ctx context.Context, request todo.SubscribeRequest) (*todo.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscriber{principalID: todo.PrincipalIDFromContext(ctx), request: request}
+	var replay []*todo.Event
+	var reset bool
+	if after := request.After; after != nil {
+		oldest := b.seq - len(b.buffer) + 1
+		if *after < oldest-1 || *after > b.seq {
+			reset = true
+		}
+		for _, e := range b.buffered() {
+			if e.Seq > *after && request.Match(e, sub.principalID) {
+				replay = append(replay, e)
+			}
+		}
+	}
+
+	sub.ch = make(chan *todo.Event, len(replay)+b.QueueSize)
+	for _, e := range replay {
+		sub.ch <- e
+	}
+	b.subs[sub] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.drop(sub)
+	}()
+
+	return &todo.Subscription{Events: sub.ch, Reset: reset}, nil
+}
+
+// lock serializes the changes to the todos with the given IDs made through the
+// middlewares publishing to b, so their events are numbered in the order the
+// changes were made. Todos share a fixed number of locks, which are taken in
+// order so batches cannot deadlock. Returns the function releasing the locks.
+func (b *Bus) lock(ids ...int) func() {
+	seen := make(map[int]bool, len(ids))
+	var stripes []int
+	for _, id := range ids {
+		if i := int(uint(id) % lockStripes); !seen[i] {
+			seen[i] = true
+			stripes = append(stripes, i)
+		}
+	}
+	sort.Ints(stripes)
+
+	for _, i := range stripes {
+		b.locks[i].Lock()
+	}
+	return func() {
+		for _, i := range stripes {
+			b.locks[i].Unlock()
+		}
+	}
+}
+
+// buffered returns the events in the replay buffer, oldest first.
+// Must be called with b.mu held.
+func (b *Bus) buffered() []*todo.Event {
+	if len(b.buffer) < b.BufferSize {
+		return b.buffer
+	}
+	return append(append([]*todo.Event(nil), b.buffer[b.next:]...), b.buffer[:b.next]...)
+}
+
+// drop ends the subscription of sub, if not already ended.
+// Must be called with b.mu held.
+func (b *Bus) drop(sub *subscriber) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
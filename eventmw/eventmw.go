@@ -0,0 +1,236 @@
+// Package eventmw publishes the changes made to todos to an in-process bus
+// for any backend.
+//
+// The todo middleware publishes an event to a Bus after every change made
+// through it. Changes to a todo are serialized until their event is
+// published, so the events of a todo are numbered in the order of its
+// changes. Subtasks deleted along with their parent do not get events of
+// their own.
+//
+// The policy enforcer middleware publishes the todos archived and purged by
+// the retention policies of lists as updated and deleted. The trash middleware
+// publishes restored todos as created, and the tag middleware publishes the
+// todos changed by renaming or merging tags as updated. Their changes are not
+// serialized with those made through the todo middleware.
+//
+// Events are delivered to the owner of a todo and to the principals it is
+// shared with, directly or through its list, when the event is published.
+// Shares are looked up in a todo.ShareService, which must not authorize calls.
+//
+// The bus is also the todo.EventService, delivering the events of the todos
+// owned by or shared with the caller to subscribers as they are published.
+package eventmw
+
+import (
+	"context"
+	"todo"
+)
+
+// NewTodoEventMiddleware returns a middleware publishing an event to bus for
+// every todo created, changed or deleted through it. The event is delivered
+// to the principals the todo is shared with according to shares, or only to
+// its owner if shares is nil.
+func NewTodoEventMiddleware(bus *Bus, shares todo.ShareService) todo.Middleware {
+	return func(next todo.Service) todo.Service {
+		return &todoEventMiddleware{
+			publisher: publisher{bus: bus, shares: shares},
+			next:      next,
+		}
+	}
+}
+
+type todoEventMiddleware struct {
+	publisher
+	next todo.Service
+}
+
+func (mw todoEventMiddleware) CreateTodo(ctx context.Context, request todo.CreateTodoRequest) (*todo.Todo, error) {
+	t, err := mw.next.CreateTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.publish(ctx, todo.EventTodoCreated, t, nil)
+	return t, nil
+}
+
+func (mw todoEventMiddleware) UpdateTodo(ctx context.Context, request todo.UpdateTodoRequest) (*todo.Todo, error) {
+	defer mw.bus.lock(request.ID)()
+	prev := mw.find(ctx, request.ID)
+	t, err := mw.next.UpdateTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.publish(ctx, todo.EventTodoUpdated, t, prev)
+	return t, nil
+}
+
+func (mw todoEventMiddleware) PatchTodo(ctx context.Context, request todo.PatchTodoRequest) (*todo.Todo, error) {
+	defer mw.bus.lock(request.ID)()
+	var prev *todo.Todo
+	if request.ListID != nil {
+		prev = mw.find(ctx, request.ID)
+	}
+	t, err := mw.next.PatchTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.publish(ctx, todo.EventTodoUpdated, t, prev)
+	return t, nil
+}
+
+// DeleteTodo looks up who the todo is shared with before it is deleted along
+// with its shares.
+func (mw todoEventMiddleware) DeleteTodo(ctx context.Context, request todo.DeleteTodoRequest) error {
+	defer mw.bus.lock(request.ID)()
+	e := mw.deleteEvent(ctx, request.ID, mw.find(ctx, request.ID))
+	if err := mw.next.DeleteTodo(ctx, request); err != nil {
+		return err
+	}
+	mw.bus.Publish(e)
+	return nil
+}
+
+func (mw todoEventMiddleware) GetTodoByID(ctx context.Context, request todo.GetTodoByIDRequest) (*todo.Todo, error) {
+	return mw.next.GetTodoByID(ctx, request)
+}
+
+func (mw todoEventMiddleware) ListTodos(ctx context.Context, request todo.ListTodosRequest) (*todo.ListTodosResponse, error) {
+	return mw.next.ListTodos(ctx, request)
+}
+
+func (mw todoEventMiddleware) CompleteTodo(ctx context.Context, request todo.CompleteTodoRequest) (*todo.CompleteTodoResponse, error) {
+	defer mw.bus.lock(request.ID)()
+	resp, err := mw.next.CompleteTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.publish(ctx, todo.EventTodoUpdated, resp.Todo, nil)
+	if resp.Next != nil {
+		mw.publish(ctx, todo.EventTodoCreated, resp.Next, nil)
+	}
+	return resp, nil
+}
+
+func (mw todoEventMiddleware) MoveTodo(ctx context.Context, request todo.MoveTodoRequest) (*todo.Todo, error) {
+	defer mw.bus.lock(request.ID)()
+	t, err := mw.next.MoveTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.publish(ctx, todo.EventTodoUpdated, t, nil)
+	return t, nil
+}
+
+// BatchTodos publishes an event for every operation of the batch that
+// succeeded, in the order of the operations.
+func (mw todoEventMiddleware) BatchTodos(ctx context.Context, request todo.BatchTodosRequest) (*todo.BatchTodosResponse, error) {
+	var ids []int
+	for _, op := range request.Ops {
+		if id := op.TodoID(); id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	defer mw.bus.lock(ids...)()
+
+	prevs := make(map[int]*todo.Todo)
+	deletes := make(map[int]*todo.Event)
+	for _, op := range request.Ops {
+		if id := op.TodoID(); id != 0 && prevs[id] == nil {
+			prevs[id] = mw.find(ctx, id)
+		}
+		if op.Op == todo.BatchDelete && deletes[op.Delete.ID] == nil {
+			deletes[op.Delete.ID] = mw.deleteEvent(ctx, op.Delete.ID, prevs[op.Delete.ID])
+		}
+	}
+
+	resp, err := mw.next.BatchTodos(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range resp.Results {
+		if r.Err != nil || i >= len(request.Ops) {
+			continue
+		}
+		switch op := request.Ops[i]; op.Op {
+		case todo.BatchCreate:
+			mw.publish(ctx, todo.EventTodoCreated, r.Todo, nil)
+		case todo.BatchDelete:
+			mw.bus.Publish(deletes[op.Delete.ID])
+		default:
+			mw.publish(ctx, todo.EventTodoUpdated, r.Todo, prevs[r.Todo.ID])
+			prevs[r.Todo.ID] = r.Todo
+		}
+	}
+	return resp, nil
+}
+
+// find returns the todo with the given ID before it is changed, or nil if it
+// cannot be read, in which case the change fails as well.
+func (mw todoEventMiddleware) find(ctx context.Context, id int) *todo.Todo {
+	t, err := mw.next.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: id})
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+// publisher publishes the changes made to todos to a bus.
+type publisher struct {
+	bus    *Bus
+	shares todo.ShareService
+}
+
+// publish publishes a change to t, which was prev before the change if known.
+func (p publisher) publish(ctx context.Context, typ string, t, prev *todo.Todo) {
+	e := &todo.Event{Type: typ, OwnerID: t.OwnerID, TodoID: t.ID, ListID: t.ListID, Todo: t}
+	if prev != nil && prev.ListID != t.ListID {
+		e.FromListID = prev.ListID
+	}
+	e.PrincipalIDs = p.sharees(ctx, t.OwnerID, t.ID, e.ListID, e.FromListID)
+	p.bus.Publish(e)
+}
+
+// deleteEvent returns the event of deleting the todo with the given ID, which
+// is prev if known. It must be called before the delete, while the shares of
+// the todo still exist.
+func (p publisher) deleteEvent(ctx context.Context, id int, prev *todo.Todo) *todo.Event {
+	e := &todo.Event{Type: todo.EventTodoDeleted, OwnerID: todo.OwnerIDFromContext(ctx), TodoID: id}
+	if prev != nil {
+		e.OwnerID, e.ListID = prev.OwnerID, prev.ListID
+		e.PrincipalIDs = p.sharees(ctx, prev.OwnerID, id, prev.ListID)
+	}
+	return e
+}
+
+// sharees returns the principals the todo with the given ID, or one of the
+// given lists, is shared with. Shares that cannot be read only cost the
+// sharees the event, so errors are ignored.
+func (p publisher) sharees(ctx context.Context, ownerID string, todoID int, listIDs ...int) []string {
+	if p.shares == nil {
+		return nil
+	}
+
+	ctx = todo.NewContextWithOwnerID(ctx, ownerID)
+	requests := []todo.ListSharesRequest{{TodoID: todoID}}
+	for _, id := range listIDs {
+		if id != 0 {
+			requests = append(requests, todo.ListSharesRequest{ListID: id})
+		}
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, request := range requests {
+		shares, err := p.shares.ListShares(ctx, request)
+		if err != nil {
+			continue
+		}
+		for _, sh := range shares {
+			if !seen[sh.PrincipalID] {
+				seen[sh.PrincipalID] = true
+				ids = append(ids, sh.PrincipalID)
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,344 @@
+package eventmw_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"todo"
+	"todo/eventmw"
+	"todo/inmem"
+	"todo/todotest"
+)
+
+func TestTodoEventMiddleware(t *testing.T) {
+	alice := todotest.NewContextWithPrincipalID(context.Background(), "alice")
+	bob := todotest.NewContextWithPrincipalID(context.Background(), "bob")
+
+	t.Run("Changes", func(t *testing.T) {
+		bus, s, _ := newServices(t)
+		sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{})
+		other := mustSubscribe(t, bob, bus, todo.SubscribeRequest{})
+
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		value := "b"
+		if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		} else if err := s.DeleteTodo(alice, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Failed changes are not published.
+		if err := s.DeleteTodo(alice, todo.DeleteTodoRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		got := receive(t, sub, 3)
+		if e := got[0]; e.Seq != 1 || e.Type != todo.EventTodoCreated || e.OwnerID != "alice" || e.TodoID != a.ID || e.Todo.Value != "a" || e.CreatedAt.IsZero() {
+			t.Fatalf("unexpected event: %#v", e)
+		} else if e := got[1]; e.Seq != 2 || e.Type != todo.EventTodoUpdated || e.Todo.Value != "b" {
+			t.Fatalf("unexpected event: %#v", e)
+		} else if e := got[2]; e.Seq != 3 || e.Type != todo.EventTodoDeleted || e.TodoID != a.ID || e.Todo != nil {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+		assertNoEvent(t, sub)
+		assertNoEvent(t, other)
+	})
+
+	t.Run("ListFilter", func(t *testing.T) {
+		bus, s, lists := newServices(t)
+		l := todotest.MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "l"})
+		sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{ListID: l.ID})
+
+		todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		b := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b", ListID: l.ID})
+
+		// Moving a todo out of the list is delivered, later changes are not.
+		none := 0
+		if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: b.ID, ListID: &none}); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.UpdateTodo(alice, todo.UpdateTodoRequest{ID: b.ID, Value: "c"}); err != nil {
+			t.Fatal(err)
+		}
+
+		got := receive(t, sub, 2)
+		if e := got[0]; e.Type != todo.EventTodoCreated || e.TodoID != b.ID || e.ListID != l.ID {
+			t.Fatalf("unexpected event: %#v", e)
+		} else if e := got[1]; e.Type != todo.EventTodoUpdated || e.ListID != 0 || e.FromListID != l.ID {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+		assertNoEvent(t, sub)
+	})
+
+	t.Run("Sharees", func(t *testing.T) {
+		bus, s, lists := newServices(t)
+		carol := todotest.NewContextWithPrincipalID(context.Background(), "carol")
+		store := lists.(todo.ShareService)
+		l := todotest.MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "l"})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		todotest.MustCreateShare(t, alice, store, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+		todotest.MustCreateShare(t, alice, store, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "carol", Role: todo.RoleViewer})
+		sub := mustSubscribe(t, bob, bus, todo.SubscribeRequest{})
+		listSub := mustSubscribe(t, carol, bus, todo.SubscribeRequest{ListID: l.ID})
+
+		// Sharees receive changes to the todo, and members of its lists
+		// receive it moving in and out of the list.
+		value := "b"
+		if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, ListID: &l.ID}); err != nil {
+			t.Fatal(err)
+		}
+		none := 0
+		if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, ListID: &none}); err != nil {
+			t.Fatal(err)
+		} else if err := s.DeleteTodo(alice, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := receive(t, sub, 4); got[0].Type != todo.EventTodoUpdated || got[0].OwnerID != "alice" || got[0].Todo.Value != "b" {
+			t.Fatalf("unexpected event: %#v", got[0])
+		} else if got[3].Type != todo.EventTodoDeleted || got[3].TodoID != a.ID {
+			t.Fatalf("unexpected event: %#v", got[3])
+		}
+		if got := receive(t, listSub, 2); got[0].ListID != l.ID || got[1].FromListID != l.ID {
+			t.Fatalf("unexpected events: %#v", got)
+		}
+		assertNoEvent(t, sub)
+		assertNoEvent(t, listSub)
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		store := inmem.NewService()
+		bus := eventmw.NewBus()
+		s := eventmw.NewTodoEventMiddleware(bus, store)(&slowService{store})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{})
+
+		// Events of concurrent changes are numbered in the order of the
+		// versions they produced.
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				value := strconv.Itoa(i)
+				if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+					t.Error(err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		for i, e := range receive(t, sub, 20) {
+			if e.Todo.Version != i+2 {
+				t.Fatalf("%d: version=%d, want %d", e.Seq, e.Todo.Version, i+2)
+			}
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		bus, s, _ := newServices(t)
+		for _, v := range []string{"a", "b", "c"} {
+			todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: v})
+		}
+
+		after := 1
+		sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{After: &after})
+		if sub.Reset {
+			t.Fatal("unexpected reset")
+		} else if got := receive(t, sub, 2); got[0].Seq != 2 || got[1].Seq != 3 {
+			t.Fatalf("unexpected events: %#v", got)
+		}
+
+		// Resuming after the last event receives new events only.
+		after = 3
+		sub = mustSubscribe(t, alice, bus, todo.SubscribeRequest{After: &after})
+		todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "d"})
+		if sub.Reset {
+			t.Fatal("unexpected reset")
+		} else if got := receive(t, sub, 1); got[0].Seq != 4 {
+			t.Fatalf("unexpected events: %#v", got)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		bus, s, _ := newServices(t)
+		bus.BufferSize = 2
+		for _, v := range []string{"a", "b", "c", "d"} {
+			todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: v})
+		}
+
+		// Event 2 was dropped from the buffer.
+		after := 1
+		sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{After: &after})
+		if !sub.Reset {
+			t.Fatal("expected reset")
+		} else if got := receive(t, sub, 2); got[0].Seq != 3 || got[1].Seq != 4 {
+			t.Fatalf("unexpected events: %#v", got)
+		}
+
+		// Event 2 is the one before the oldest buffered event.
+		after = 2
+		if sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{After: &after}); sub.Reset {
+			t.Fatal("unexpected reset")
+		}
+
+		// Events published before a restart are unknown.
+		after = 10
+		if sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{After: &after}); !sub.Reset {
+			t.Fatal("expected reset")
+		}
+	})
+
+	t.Run("SlowSubscriber", func(t *testing.T) {
+		bus, s, _ := newServices(t)
+		bus.QueueSize = 1
+		sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{})
+		todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b"})
+
+		if e := <-sub.Events; e == nil || e.Seq != 1 {
+			t.Fatalf("unexpected event: %#v", e)
+		} else if e, ok := <-sub.Events; ok {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+	})
+
+	t.Run("Policies", func(t *testing.T) {
+		store := inmem.NewService()
+		bus := eventmw.NewBus()
+		s := eventmw.NewTodoEventMiddleware(bus, store)(store)
+		enforcer := eventmw.NewPolicyEnforcerEventMiddleware(bus, store)(store)
+
+		policies := []todo.Policy{{Action: todo.PolicyArchive, AfterDays: 1}, {Action: todo.PolicyPurge, AfterDays: 2}}
+		l := todotest.MustCreateList(t, alice, store, todo.CreateListRequest{Name: "l", Policies: policies})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", ListID: l.ID, Complete: true})
+		sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{ListID: l.ID})
+
+		now := time.Now()
+		if _, err := enforcer.EnforcePolicies(context.Background(), now.AddDate(0, 0, 1), 10); err != nil {
+			t.Fatal(err)
+		} else if _, err := enforcer.EnforcePolicies(context.Background(), now.AddDate(0, 0, 3), 10); err != nil {
+			t.Fatal(err)
+		}
+
+		got := receive(t, sub, 2)
+		if e := got[0]; e.Type != todo.EventTodoUpdated || e.OwnerID != "alice" || e.TodoID != a.ID || e.Todo.ArchivedAt == nil {
+			t.Fatalf("unexpected event: %#v", e)
+		} else if e := got[1]; e.Type != todo.EventTodoDeleted || e.OwnerID != "alice" || e.TodoID != a.ID || e.ListID != l.ID || e.Todo != nil {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+		assertNoEvent(t, sub)
+	})
+
+	t.Run("RestoreTodo", func(t *testing.T) {
+		store := inmem.NewService()
+		bus := eventmw.NewBus()
+		trash := eventmw.NewTrashEventMiddleware(bus, store)(store)
+		a := todotest.MustCreateTodo(t, alice, store, todo.CreateTodoRequest{Value: "a"})
+		if err := store.DeleteTodo(alice, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+		sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{})
+
+		if _, err := trash.RestoreTodo(alice, todo.RestoreTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if e := receive(t, sub, 1)[0]; e.Type != todo.EventTodoCreated || e.TodoID != a.ID || e.Todo == nil {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+		assertNoEvent(t, sub)
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		store := inmem.NewService()
+		bus := eventmw.NewBus()
+		tags := eventmw.NewTagEventMiddleware(bus, store, store)(store)
+		a := todotest.MustCreateTodo(t, alice, store, todo.CreateTodoRequest{Value: "a", Tags: []string{"work"}})
+		todotest.MustCreateTodo(t, alice, store, todo.CreateTodoRequest{Value: "b", Tags: []string{"home"}})
+		todotest.MustCreateTodo(t, alice, store, todo.CreateTodoRequest{Value: "c"})
+		sub := mustSubscribe(t, alice, bus, todo.SubscribeRequest{})
+
+		if _, err := tags.RenameTag(alice, todo.RenameTagRequest{Name: "#Work", NewName: "job"}); err != nil {
+			t.Fatal(err)
+		} else if e := receive(t, sub, 1)[0]; e.Type != todo.EventTodoUpdated || e.TodoID != a.ID || e.Todo.Tags[0] != "job" {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+
+		// Todos already carrying the tag merged into are left alone.
+		if _, err := tags.MergeTags(alice, todo.MergeTagsRequest{Names: []string{"job", "home"}, Into: "home"}); err != nil {
+			t.Fatal(err)
+		} else if e := receive(t, sub, 1)[0]; e.TodoID != a.ID || e.Todo.Tags[0] != "home" {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+		assertNoEvent(t, sub)
+	})
+
+	t.Run("ContextCanceled", func(t *testing.T) {
+		bus, _, _ := newServices(t)
+		ctx, cancel := context.WithCancel(alice)
+		sub := mustSubscribe(t, ctx, bus, todo.SubscribeRequest{})
+		cancel()
+		if e, ok := <-sub.Events; ok {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+	})
+}
+
+// slowService is a todo service returning patched todos the later the older
+// their version, so unserialized changes would be published out of order.
+type slowService struct {
+	todo.Service
+}
+
+func (s *slowService) PatchTodo(ctx context.Context, request todo.PatchTodoRequest) (*todo.Todo, error) {
+	t, err := s.Service.PatchTodo(ctx, request)
+	if err == nil {
+		time.Sleep(time.Duration(30-t.Version) * 100 * time.Microsecond)
+	}
+	return t, err
+}
+
+func newServices(tb testing.TB) (*eventmw.Bus, todo.Service, todo.ListService) {
+	tb.Helper()
+	store := inmem.NewService()
+	bus := eventmw.NewBus()
+	return bus, eventmw.NewTodoEventMiddleware(bus, store)(store), store
+}
+
+func mustSubscribe(tb testing.TB, ctx context.Context, s todo.EventService, request todo.SubscribeRequest) *todo.Subscription {
+	tb.Helper()
+	sub, err := s.Subscribe(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return sub
+}
+
+// receive returns the next n events of sub, which must already be queued.
+func receive(tb testing.TB, sub *todo.Subscription, n int) []*todo.Event {
+	tb.Helper()
+	var events []*todo.Event
+	for len(events) < n {
+		select {
+		case e, ok := <-sub.Events:
+			if !ok {
+				tb.Fatalf("subscription ended after %d events", len(events))
+			}
+			events = append(events, e)
+		default:
+			tb.Fatalf("received %d events, want %d", len(events), n)
+		}
+	}
+	return events
+}
+
+func assertNoEvent(tb testing.TB, sub *todo.Subscription) {
+	tb.Helper()
+	select {
+	case e := <-sub.Events:
+		tb.Fatalf("unexpected event: %#v", e)
+	default:
+	}
+}
@@ -0,0 +1,40 @@
+package eventmw
+
+import (
+	"context"
+	"time"
+	"todo"
+)
+
+// NewPolicyEnforcerEventMiddleware returns a middleware publishing an event to
+// bus for every todo archived or purged through it. Purged todos have lost
+// their own shares, so only the principals their list is shared with receive
+// their deletion.
+func NewPolicyEnforcerEventMiddleware(bus *Bus, shares todo.ShareService) todo.PolicyEnforcerMiddleware {
+	return func(next todo.PolicyEnforcer) todo.PolicyEnforcer {
+		return &policyEnforcerEventMiddleware{
+			publisher: publisher{bus: bus, shares: shares},
+			next:      next,
+		}
+	}
+}
+
+type policyEnforcerEventMiddleware struct {
+	publisher
+	next todo.PolicyEnforcer
+}
+
+// EnforcePolicies publishes the todos purged and archived so far if it fails
+// part way, in the order they were changed.
+func (mw policyEnforcerEventMiddleware) EnforcePolicies(ctx context.Context, now time.Time, limit int) (*todo.PolicyReport, error) {
+	report, err := mw.next.EnforcePolicies(ctx, now, limit)
+	if report != nil {
+		for _, t := range report.Purged {
+			mw.bus.Publish(mw.deleteEvent(ctx, t.ID, t))
+		}
+		for _, t := range report.Archived {
+			mw.publish(ctx, todo.EventTodoUpdated, t, nil)
+		}
+	}
+	return report, err
+}
@@ -0,0 +1,96 @@
+package eventmw
+
+import (
+	"context"
+	"sort"
+	"todo"
+)
+
+// NewTagEventMiddleware returns a middleware publishing an event to bus for
+// every todo changed by renaming or merging tags through it. The todos
+// carrying the tags are looked up in todos before and after the change.
+func NewTagEventMiddleware(bus *Bus, shares todo.ShareService, todos todo.Service) todo.TagMiddleware {
+	return func(next todo.TagService) todo.TagService {
+		return &tagEventMiddleware{
+			publisher: publisher{bus: bus, shares: shares},
+			next:      next,
+			todos:     todos,
+		}
+	}
+}
+
+type tagEventMiddleware struct {
+	publisher
+	next  todo.TagService
+	todos todo.Service
+}
+
+func (mw tagEventMiddleware) ListTags(ctx context.Context, request todo.ListTagsRequest) ([]*todo.Tag, error) {
+	return mw.next.ListTags(ctx, request)
+}
+
+func (mw tagEventMiddleware) RenameTag(ctx context.Context, request todo.RenameTagRequest) (*todo.Tag, error) {
+	var versions map[int]int
+	if name, err := todo.NormalizeTag(request.Name); err == nil {
+		versions = mw.tagged(ctx, name)
+	}
+	tag, err := mw.next.RenameTag(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.publishChanged(ctx, versions)
+	return tag, nil
+}
+
+func (mw tagEventMiddleware) MergeTags(ctx context.Context, request todo.MergeTagsRequest) (*todo.Tag, error) {
+	var versions map[int]int
+	if names, err := todo.NormalizeTags(request.Names); err == nil && len(names) > 0 {
+		versions = mw.tagged(ctx, names...)
+	}
+	tag, err := mw.next.MergeTags(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.publishChanged(ctx, versions)
+	return tag, nil
+}
+
+// tagged returns the versions of the caller's todos, archived or not, carrying
+// any of the given tags by ID.
+func (mw tagEventMiddleware) tagged(ctx context.Context, names ...string) map[int]int {
+	versions := make(map[int]int)
+	for _, archived := range []bool{false, true} {
+		request := todo.ListTodosRequest{AnyTags: names, Archived: archived, Limit: todo.MaxListLimit}
+		for {
+			resp, err := mw.todos.ListTodos(ctx, request)
+			if err != nil {
+				break
+			}
+			for _, t := range resp.Todos {
+				versions[t.ID] = t.Version
+			}
+			if request.Cursor = resp.NextCursor; request.Cursor == "" {
+				break
+			}
+		}
+	}
+	return versions
+}
+
+// publishChanged publishes the todos whose version changed since versions
+// were read, by ID.
+func (mw tagEventMiddleware) publishChanged(ctx context.Context, versions map[int]int) {
+	ids := make([]int, 0, len(versions))
+	for id := range versions {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		t, err := mw.todos.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: id})
+		if err != nil || t.Version == versions[id] {
+			continue
+		}
+		mw.publish(ctx, todo.EventTodoUpdated, t, nil)
+	}
+}
@@ -0,0 +1,36 @@
+package eventmw
+
+import (
+	"context"
+	"todo"
+)
+
+// NewTrashEventMiddleware returns a middleware publishing an event to bus for
+// every todo restored through it. Subtasks restored along with their parent do
+// not get events of their own.
+func NewTrashEventMiddleware(bus *Bus, shares todo.ShareService) todo.TrashMiddleware {
+	return func(next todo.TrashService) todo.TrashService {
+		return &trashEventMiddleware{
+			publisher: publisher{bus: bus, shares: shares},
+			next:      next,
+		}
+	}
+}
+
+type trashEventMiddleware struct {
+	publisher
+	next todo.TrashService
+}
+
+func (mw trashEventMiddleware) ListTrash(ctx context.Context, request todo.ListTrashRequest) ([]*todo.Todo, error) {
+	return mw.next.ListTrash(ctx, request)
+}
+
+func (mw trashEventMiddleware) RestoreTodo(ctx context.Context, request todo.RestoreTodoRequest) (*todo.Todo, error) {
+	t, err := mw.next.RestoreTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.publish(ctx, todo.EventTodoCreated, t, nil)
+	return t, nil
+}
@@ -1,12 +1,13 @@
 module todo
 
-go 1.15
+go 1.16
 
 require (
 	github.com/go-kit/kit v0.10.0
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.0
-	github.com/prometheus/client_golang v1.9.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/prometheus/client_golang v1.9.0
 	github.com/prometheus/common v0.18.0 // indirect
 	github.com/prometheus/procfs v0.6.0 // indirect
 	golang.org/x/sys v0.0.0-20210305230114-8fe3ee5dd75b // indirect
@@ -0,0 +1,36 @@
+package todo
+
+// TodoNode represents a todo and its subtasks in a tree of todos.
+type TodoNode struct {
+	*Todo
+	Children []*TodoNode `json:"children"`
+}
+
+// BuildTree arranges todos into trees by their parent. Todos whose parent is
+// not among todos become roots, so a filtered list still shows every todo.
+// The order of todos is kept among siblings.
+func BuildTree(todos []*Todo) []*TodoNode {
+	nodes := make(map[int]*TodoNode, len(todos))
+	for _, t := range todos {
+		nodes[t.ID] = &TodoNode{Todo: t, Children: make([]*TodoNode, 0)}
+	}
+
+	roots := make([]*TodoNode, 0)
+	for _, t := range todos {
+		if parent, ok := nodes[t.ParentID]; ok && t.ParentID != t.ID {
+			parent.Children = append(parent.Children, nodes[t.ID])
+		} else {
+			roots = append(roots, nodes[t.ID])
+		}
+	}
+	return roots
+}
+
+// CheckCompletable returns ECONFLICT if t is being completed while it still
+// has open subtasks. open is the number of incomplete children of t.
+func (t *Todo) CheckCompletable(prev *Todo, open int) error {
+	if t.Complete && (prev == nil || !prev.Complete) && open > 0 {
+		return Errorf(ECONFLICT, "Todo with ID '%d' has %d open subtasks.", t.ID, open)
+	}
+	return nil
+}
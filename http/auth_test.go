@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"todo/auth"
+)
+
+func TestServer_Authentication(t *testing.T) {
+	keys := auth.NewKeyStore("")
+	key, _, err := keys.Issue("alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := MustOpenTestServer(t, func(s *Server) {
+		s.Authenticator = &auth.Authenticator{Keys: keys}
+	})
+
+	if resp := mustDo(t, ts, "GET", "/api/todos", "", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	} else if resp.Header.Get("WWW-Authenticate") == "" {
+		t.Fatal("expected WWW-Authenticate header")
+	}
+
+	if resp := mustDo(t, ts, "GET", "/api/todos", "", map[string]string{"Authorization": "Bearer todo_bad_key"}); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	if resp := mustDo(t, ts, "GET", "/api/todos", "", map[string]string{"Authorization": "Bearer " + key}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	"net/http"
+	"todo"
+)
+
+// batchTodosResponse reports the result of every operation of a batch. Failed
+// operations carry the code & message of their error instead of a todo.
+type batchTodosResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+type batchResult struct {
+	Todo  *todo.Todo `json:"todo,omitempty"`
+	Code  string     `json:"code,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+func MakeBatchTodosEndpoint(s todo.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.BatchTodosRequest)
+		resp, err := s.BatchTodos(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make([]batchResult, 0, len(resp.Results))
+		for _, r := range resp.Results {
+			results = append(results, batchResult{
+				Todo:  r.Todo,
+				Code:  todo.ErrorCode(r.Err),
+				Error: todo.ErrorMessage(r.Err),
+			})
+		}
+		return &batchTodosResponse{Results: results}, nil
+	}
+}
+
+// decodeBatchTodosRequest reads a body such as
+// {"mode":"bestEffort","ops":[{"op":"create","create":{"value":"a"}}]}.
+func decodeBatchTodosRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.BatchTodosRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, todo.Errorf(todo.EINVALID, "Failed to encode JSON body.")
+	}
+
+	return req, nil
+}
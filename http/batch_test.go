@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_BatchTodos(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var a todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, nil, &a)
+	id := strconv.Itoa(a.ID)
+
+	// Failed operations report their error code in the best-effort mode.
+	var resp batchTodosResponse
+	if r := mustDoJSON(t, ts, "POST", "/api/todos:batch", `{"mode":"bestEffort","ops":[
+		{"op":"create","create":{"value":"b"}},
+		{"op":"patch","patch":{"id":`+id+`,"complete":true}},
+		{"op":"delete","delete":{"id":0}}
+	]}`, nil, &resp); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if len(resp.Results) != 3 {
+		t.Fatalf("unexpected results: %#v", resp.Results)
+	} else if r := resp.Results[1]; r.Todo == nil || !r.Todo.Complete || r.Code != "" {
+		t.Fatalf("unexpected result: %#v", r)
+	} else if r := resp.Results[2]; r.Todo != nil || r.Code != todo.ENOTFOUND || r.Error == "" {
+		t.Fatalf("unexpected result: %#v", r)
+	}
+
+	// An atomic batch fails as a whole.
+	if r := mustDo(t, ts, "POST", "/api/todos:batch", `{"ops":[
+		{"op":"delete","delete":{"id":`+id+`}},
+		{"op":"delete","delete":{"id":0}}
+	]}`, nil); r.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusNotFound)
+	} else if r := mustDo(t, ts, "GET", "/api/todos/"+id, "", nil); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	}
+
+	if r := mustDo(t, ts, "POST", "/api/todos:batch", `{"ops":[{"op":"delete"}]}`, nil); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"todo"
+)
+
+func (s *Server) configureDependencyHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakeDependencyServerEndpoints(s.DependencyService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	// Must be registered before "/api/todos/{id}" so it is not taken for an ID.
+	s.router.Handle(
+		"/api/todos/actionable",
+		httptransport.NewServer(
+			e.ListActionableTodosEndpoint,
+			decodeListActionableTodosRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	s.router.Handle(
+		"/api/todos/{id}/dependencies/{blockedById}",
+		httptransport.NewServer(
+			e.CreateDependencyEndpoint,
+			decodeCreateDependencyRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("PUT")
+
+	s.router.Handle(
+		"/api/todos/{id}/dependencies/{blockedById}",
+		httptransport.NewServer(
+			e.DeleteDependencyEndpoint,
+			decodeDeleteDependencyRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("DELETE")
+
+	s.router.Handle(
+		"/api/todos/{id}/dependencies",
+		httptransport.NewServer(
+			e.ListDependenciesEndpoint,
+			decodeListDependenciesRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	// Lists every dependency of the caller.
+	s.router.Handle(
+		"/api/dependencies",
+		httptransport.NewServer(
+			e.ListDependenciesEndpoint,
+			decodeListDependenciesRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+}
+
+type DependencyEndpoints struct {
+	CreateDependencyEndpoint    endpoint.Endpoint
+	DeleteDependencyEndpoint    endpoint.Endpoint
+	ListDependenciesEndpoint    endpoint.Endpoint
+	ListActionableTodosEndpoint endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e DependencyEndpoints) Wrap(mw endpoint.Middleware) DependencyEndpoints {
+	return DependencyEndpoints{
+		CreateDependencyEndpoint:    mw(e.CreateDependencyEndpoint),
+		DeleteDependencyEndpoint:    mw(e.DeleteDependencyEndpoint),
+		ListDependenciesEndpoint:    mw(e.ListDependenciesEndpoint),
+		ListActionableTodosEndpoint: mw(e.ListActionableTodosEndpoint),
+	}
+}
+
+// MakeDependencyServerEndpoints returns a DependencyEndpoints struct where each
+// endpoint invokes the corresponding method on the provided service.
+func MakeDependencyServerEndpoints(s todo.DependencyService) DependencyEndpoints {
+	return DependencyEndpoints{
+		CreateDependencyEndpoint:    MakeCreateDependencyEndpoint(s),
+		DeleteDependencyEndpoint:    MakeDeleteDependencyEndpoint(s),
+		ListDependenciesEndpoint:    MakeListDependenciesEndpoint(s),
+		ListActionableTodosEndpoint: MakeListActionableTodosEndpoint(s),
+	}
+}
+
+func MakeCreateDependencyEndpoint(s todo.DependencyService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.CreateDependencyRequest)
+		response, err = s.CreateDependency(ctx, req)
+		return
+	}
+}
+
+func MakeDeleteDependencyEndpoint(s todo.DependencyService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.DeleteDependencyRequest)
+		err = s.DeleteDependency(ctx, req)
+		return
+	}
+}
+
+func MakeListDependenciesEndpoint(s todo.DependencyService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.ListDependenciesRequest)
+		response, err = s.ListDependencies(ctx, req)
+		return
+	}
+}
+
+func MakeListActionableTodosEndpoint(s todo.DependencyService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.ListActionableTodosRequest)
+		response, err = s.ListActionableTodos(ctx, req)
+		return
+	}
+}
+
+// decodeCreateDependencyRequest marks the todo in the path as blocked by the
+// todo given as "blockedById". No body is needed.
+func decodeCreateDependencyRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.CreateDependencyRequest
+
+	if req.TodoID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	} else if req.BlockedByID, err = intVar(r, "blockedById"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func decodeDeleteDependencyRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.DeleteDependencyRequest
+
+	if req.TodoID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	} else if req.BlockedByID, err = intVar(r, "blockedById"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// decodeListDependenciesRequest lists the dependencies of the todo in the
+// path, if any.
+func decodeListDependenciesRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.ListDependenciesRequest
+
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if req.TodoID, err = intVar(r, "id"); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
+// decodeListActionableTodosRequest maps query string parameters onto a
+// ListActionableTodosRequest, e.g. "?listId=1&includeBlocked=true&limit=10".
+func decodeListActionableTodosRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.ListActionableTodosRequest
+	q := r.URL.Query()
+
+	if v := q.Get("listId"); v != "" {
+		if req.ListID, err = strconv.Atoi(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+	}
+
+	if v := q.Get("includeBlocked"); v != "" {
+		if req.IncludeBlocked, err = strconv.ParseBool(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type boolean.", v)
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		if req.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+	}
+
+	return req, nil
+}
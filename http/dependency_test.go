@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_Dependencies(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var a, b todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, nil, &a)
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b"}`, nil, &b)
+	path := "/api/todos/" + strconv.Itoa(a.ID)
+
+	var d todo.Dependency
+	if resp := mustDoJSON(t, ts, "PUT", path+"/dependencies/"+strconv.Itoa(b.ID), "", nil, &d); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if d.TodoID != a.ID || d.BlockedByID != b.ID {
+		t.Fatalf("unexpected dependency: %#v", d)
+	}
+	if resp := mustDo(t, ts, "PUT", "/api/todos/"+strconv.Itoa(b.ID)+"/dependencies/"+strconv.Itoa(a.ID), "", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	var deps []*todo.Dependency
+	if mustDoJSON(t, ts, "GET", "/api/dependencies", "", nil, &deps); len(deps) != 1 {
+		t.Fatalf("unexpected dependencies: %#v", deps)
+	}
+
+	var actionable []*todo.Todo
+	if resp := mustDoJSON(t, ts, "GET", "/api/todos/actionable", "", nil, &actionable); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if len(actionable) != 1 || actionable[0].ID != b.ID {
+		t.Fatalf("unexpected todos: %#v", actionable)
+	}
+	if mustDoJSON(t, ts, "GET", "/api/todos/actionable?includeBlocked=true", "", nil, &actionable); len(actionable) != 2 || !actionable[1].Blocked {
+		t.Fatalf("unexpected todos: %#v", actionable)
+	}
+
+	if resp := mustDo(t, ts, "POST", path+"/complete", "", nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusConflict)
+	} else if resp := mustDo(t, ts, "POST", path+"/complete?force=true", "", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if resp := mustDo(t, ts, "DELETE", path+"/dependencies/"+strconv.Itoa(b.ID), "", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if resp := mustDo(t, ts, "DELETE", path+"/dependencies/"+strconv.Itoa(b.ID), "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
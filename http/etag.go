@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"todo"
+)
+
+// EPRECONDITION is returned when an If-Match precondition does not hold. It is
+// a transport-level error: service-level version checks return ECONFLICT.
+const EPRECONDITION = "precondition_failed"
+
+type contextKey int
+
+const (
+	// ifMatchContextKey holds the If-Match request header.
+	ifMatchContextKey contextKey = iota
+
+	// ifNoneMatchContextKey holds the If-None-Match request header.
+	ifNoneMatchContextKey
+)
+
+// populateConditionalHeaders is a ServerBefore function which stores the
+// conditional request headers in the context.
+func populateConditionalHeaders(ctx context.Context, r *http.Request) context.Context {
+	ctx = context.WithValue(ctx, ifMatchContextKey, r.Header.Get("If-Match"))
+	ctx = context.WithValue(ctx, ifNoneMatchContextKey, r.Header.Get("If-None-Match"))
+	return ctx
+}
+
+// etag returns the entity tag of t, derived from its version.
+func etag(t *todo.Todo) string {
+	return strconv.Quote(strconv.Itoa(t.Version))
+}
+
+// matchETag returns true if the If-Match or If-None-Match header value matches
+// tag. Weak tags are compared by their opaque value.
+func matchETag(header, tag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, v := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(v), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch evaluates the If-Match header stored in ctx against the current
+// version of the todo. Returns the version the header matched, or zero if no
+// header was sent, so callers can pass it on to the service to guard against
+// concurrent changes between the check and the write.
+func checkIfMatch(ctx context.Context, s todo.Service, id int) (int, error) {
+	header, _ := ctx.Value(ifMatchContextKey).(string)
+	if header == "" {
+		return 0, nil
+	}
+
+	t, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: id})
+	if err != nil {
+		return 0, err
+	} else if !matchETag(header, etag(t)) {
+		return 0, todo.Errorf(EPRECONDITION, "Todo with ID '%d' does not match If-Match header.", id)
+	}
+	return t.Version, nil
+}
+
+// notModified returns true if the If-None-Match header stored in ctx matches t.
+func notModified(ctx context.Context, t *todo.Todo) bool {
+	header, _ := ctx.Value(ifNoneMatchContextKey).(string)
+	return header != "" && matchETag(header, etag(t))
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_ConditionalRequests(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var created todo.Todo
+	resp := mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, nil, &created)
+	path := "/api/todos/" + strconv.Itoa(created.ID)
+	if tag := resp.Header.Get("ETag"); tag != `"1"` {
+		t.Fatalf("ETag=%s, want %q", tag, `"1"`)
+	}
+
+	// Reads of an unchanged todo return 304.
+	if resp := mustDo(t, ts, "GET", path, "", map[string]string{"If-None-Match": `"1"`}); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusNotModified)
+	}
+
+	// Writes with a matching If-Match succeed and bump the version.
+	if resp := mustDo(t, ts, "PATCH", path, `{"complete":true}`, map[string]string{"If-Match": `"1"`}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if tag := resp.Header.Get("ETag"); tag != `"2"` {
+		t.Fatalf("ETag=%s, want %q", tag, `"2"`)
+	}
+
+	// Stale If-Match headers fail the precondition.
+	for _, method := range []string{"PUT", "PATCH", "DELETE"} {
+		if resp := mustDo(t, ts, method, path, `{"value":"b"}`, map[string]string{"If-Match": `"1"`}); resp.StatusCode != http.StatusPreconditionFailed {
+			t.Fatalf("%s: status=%d, want %d", method, resp.StatusCode, http.StatusPreconditionFailed)
+		}
+	}
+
+	// Stale versions in the body are rejected by the service.
+	if resp := mustDo(t, ts, "PUT", path, `{"value":"b","version":1}`, nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+
+	// A changed todo is returned in full.
+	if resp := mustDo(t, ts, "GET", path, "", map[string]string{"If-None-Match": `"1"`}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"strconv"
+	"time"
+	"todo"
+)
+
+// EventKeepAlive is the time after which a comment is sent on an idle event
+// stream so proxies do not close it.
+const EventKeepAlive = 30 * time.Second
+
+func (s *Server) configureEventHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakeEventServerEndpoints(s.EventService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	s.router.Handle(
+		"/api/events",
+		s.endOnShutdown(httptransport.NewServer(
+			e.SubscribeEndpoint,
+			decodeSubscribeRequest,
+			encodeEventStream,
+			options...,
+		)),
+	).Methods("GET")
+}
+
+// endOnShutdown cancels the context of the requests handled by h when the
+// server shuts down.
+func (s *Server) endOnShutdown(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-s.streams.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type EventEndpoints struct {
+	SubscribeEndpoint endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e EventEndpoints) Wrap(mw endpoint.Middleware) EventEndpoints {
+	return EventEndpoints{
+		SubscribeEndpoint: mw(e.SubscribeEndpoint),
+	}
+}
+
+// MakeEventServerEndpoints returns an EventEndpoints struct where each
+// endpoint invokes the corresponding method on the provided service.
+func MakeEventServerEndpoints(s todo.EventService) EventEndpoints {
+	return EventEndpoints{
+		SubscribeEndpoint: MakeSubscribeEndpoint(s),
+	}
+}
+
+func MakeSubscribeEndpoint(s todo.EventService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.SubscribeRequest)
+		response, err = s.Subscribe(ctx, req)
+		return
+	}
+}
+
+// decodeSubscribeRequest streams the events of every todo, or of the todos of
+// a single list with "?listId=1". A reconnecting client resumes after the
+// event given by the Last-Event-ID header.
+func decodeSubscribeRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.SubscribeRequest
+
+	if v := r.URL.Query().Get("listId"); v != "" {
+		if req.ListID, err = strconv.Atoi(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+	}
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		after, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+		req.After = &after
+	}
+
+	return req, nil
+}
+
+// encodeEventStream writes the events of a subscription as Server-Sent Events
+// until the subscription ends. Each event carries its sequence number as ID
+// and its type as event name. A reset subscription starts with a "reset"
+// event, telling the client to reload its todos.
+func encodeEventStream(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	sub := response.(*todo.Subscription)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming not supported by %T", w)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if sub.Reset {
+		if _, err := fmt.Fprint(w, "event: reset\ndata: {}\n\n"); err != nil {
+			return err
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(EventKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return err
+			}
+		case e, ok := <-sub.Events:
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			} else if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data); err != nil {
+				return err
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"todo"
+	"todo/eventmw"
+)
+
+func TestServer_Events(t *testing.T) {
+	ts := MustOpenTestServer(t, func(s *Server) {
+		bus := eventmw.NewBus()
+		s.TodoService = eventmw.NewTodoEventMiddleware(bus, s.ShareService)(s.TodoService)
+		s.EventService = bus
+	})
+
+	var l todo.List
+	mustDoJSON(t, ts, "POST", "/api/lists", `{"name":"l"}`, nil, &l)
+	mustDo(t, ts, "POST", "/api/todos", `{"value":"a"}`, nil)
+
+	stream := mustOpenEventStream(t, ts, "/api/events?listId="+strconv.Itoa(l.ID), nil)
+	if ct := stream.resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type=%q", ct)
+	}
+	var b todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b","listId":`+strconv.Itoa(l.ID)+`}`, nil, &b)
+	if e := stream.next(t); e.id != "2" || e.event != todo.EventTodoCreated || e.data.TodoID != b.ID || e.data.Todo.Value != "b" {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+
+	// Resume after the first event.
+	stream = mustOpenEventStream(t, ts, "/api/events", map[string]string{"Last-Event-ID": "1"})
+	if e := stream.next(t); e.id != "2" || e.data.TodoID != b.ID {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+
+	// Resuming after events the server does not know starts with a reset.
+	stream = mustOpenEventStream(t, ts, "/api/events", map[string]string{"Last-Event-ID": "100"})
+	if e := stream.next(t); e.event != "reset" {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+
+	if r := mustDo(t, ts, "GET", "/api/events?listId=x", "", nil); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	} else if r := mustDo(t, ts, "GET", "/api/events", "", map[string]string{"Last-Event-ID": "x"}); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestServer_Close(t *testing.T) {
+	var s *Server
+	ts := MustOpenTestServer(t, func(srv *Server) {
+		s = srv
+		s.EventService = eventmw.NewBus()
+	})
+
+	// Open streams end so the server shuts down in time.
+	stream := mustOpenEventStream(t, ts, "/api/events", nil)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	} else if stream.scanner.Scan() {
+		t.Fatalf("unexpected line: %q", stream.scanner.Text())
+	}
+}
+
+type eventStream struct {
+	resp    *http.Response
+	scanner *bufio.Scanner
+}
+
+type streamedEvent struct {
+	id, event string
+	data      todo.Event
+}
+
+// mustOpenEventStream opens an event stream which is closed when the test ends.
+func mustOpenEventStream(tb testing.TB, ts *httptest.Server, path string, header map[string]string) *eventStream {
+	tb.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	tb.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, "GET", ts.URL+path, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		tb.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	return &eventStream{resp: resp, scanner: bufio.NewScanner(resp.Body)}
+}
+
+// next reads the next event of the stream, skipping comments.
+func (s *eventStream) next(tb testing.TB) streamedEvent {
+	tb.Helper()
+
+	var e streamedEvent
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "" && e.event != "":
+			return e
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.data); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+	tb.Fatalf("stream ended: %v", s.scanner.Err())
+	return e
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_Hierarchy(t *testing.T) {
+	var svc todo.Service
+	ts := MustOpenTestServer(t, func(s *Server) { svc = s.TodoService })
+
+	var a, b, c todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, nil, &a)
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b","parentId":`+strconv.Itoa(a.ID)+`}`, nil, &b)
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"c","parentId":`+strconv.Itoa(b.ID)+`}`, nil, &c)
+	path := "/api/todos/" + strconv.Itoa(a.ID)
+
+	var children todo.ListTodosResponse
+	if resp := mustDoJSON(t, ts, "GET", path+"/children", "", nil, &children); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if len(children.Todos) != 1 || children.Todos[0].ID != b.ID {
+		t.Fatalf("unexpected todos: %#v", children.Todos)
+	}
+
+	var tree struct {
+		Todos      []*todo.TodoNode `json:"todos"`
+		TotalCount int              `json:"totalCount"`
+	}
+	mustDoJSON(t, ts, "GET", "/api/todos?view=tree", "", nil, &tree)
+	if len(tree.Todos) != 1 || len(tree.Todos[0].Children) != 1 || len(tree.Todos[0].Children[0].Children) != 1 {
+		t.Fatalf("unexpected tree: %#v", tree.Todos)
+	} else if tree.TotalCount != 3 {
+		t.Fatalf("totalCount=%d, want 3", tree.TotalCount)
+	}
+
+	// Trees are not paginated, so too many todos cannot be listed as one.
+	for i := 0; i < todo.MaxListLimit; i++ {
+		if _, err := svc.CreateTodo(context.Background(), todo.CreateTodoRequest{Value: "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if resp := mustDo(t, ts, "GET", "/api/todos?view=tree", "", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusBadRequest)
+	} else if resp := mustDo(t, ts, "GET", "/api/todos?view=tree&contains=a", "", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if resp := mustDo(t, ts, "GET", "/api/todos?view=graph", "", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if resp := mustDo(t, ts, "POST", path+"/complete", "", nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+
+	if resp := mustDo(t, ts, "DELETE", path+"?cascade=true", "", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if resp := mustDo(t, ts, "GET", "/api/todos/"+strconv.Itoa(c.ID), "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
@@ -3,7 +3,12 @@ package http
 import (
 	"context"
 	"encoding/json"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"todo"
 )
 
@@ -26,6 +31,11 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	//	LogError(r, err)
 	//}
 
+	// Tell the client how to authenticate.
+	if code == todo.EUNAUTHORIZED {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="todo"`)
+	}
+
 	// Print user message to response.
 	w.Header().Set("Content-type", "application/json")
 	w.WriteHeader(ErrorStatusCode(code))
@@ -50,6 +60,17 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 		encodeError(ctx, e.error(), w)
 		return nil
 	}
+
+	// Expose the version of a single todo as its entity tag. Reads can be
+	// answered without a body if the client already has this version.
+	if t, ok := response.(*todo.Todo); ok {
+		w.Header().Set("ETag", etag(t))
+		if method, _ := ctx.Value(httptransport.ContextKeyRequestMethod).(string); (method == http.MethodGet || method == http.MethodHead) && notModified(ctx, t) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	return json.NewEncoder(w).Encode(response)
 }
@@ -57,10 +78,12 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 // lookup of application error codes to HTTP status codes.
 var codes = map[string]int{
 	todo.ECONFLICT:       http.StatusConflict,
+	todo.EFORBIDDEN:      http.StatusForbidden,
 	todo.EINVALID:        http.StatusBadRequest,
 	todo.ENOTFOUND:       http.StatusNotFound,
 	todo.ENOTIMPLEMENTED: http.StatusNotImplemented,
 	todo.EUNAUTHORIZED:   http.StatusUnauthorized,
+	EPRECONDITION:        http.StatusPreconditionFailed,
 	todo.EINTERNAL:       http.StatusInternalServerError,
 }
 
@@ -70,4 +93,32 @@ func ErrorStatusCode(code string) int {
 		return v
 	}
 	return http.StatusInternalServerError
+}
+
+// intVar returns the integer value of the route variable name.
+func intVar(r *http.Request, name string) (int, error) {
+	v, ok := mux.Vars(r)[name]
+	if !ok {
+		return 0, todo.Errorf(todo.EINVALID, "Invalid value for parameter '%s'.", name)
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+	}
+	return i, nil
+}
+
+// listVar returns the comma separated values of the query parameter name,
+// which may be repeated, or nil if it is not set.
+func listVar(q url.Values, name string) []string {
+	var values []string
+	for _, param := range q[name] {
+		for _, v := range strings.Split(param, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
 }
\ No newline at end of file
@@ -0,0 +1,273 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+	"todo"
+	"todo/auth"
+)
+
+// DefaultIdempotencyTTL is the time responses are kept for retries when
+// IdempotencyStore.TTL is not set.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// DefaultIdempotencyMaxEntries is the number of responses kept when
+// IdempotencyStore.MaxEntries is not set.
+const DefaultIdempotencyMaxEntries = 10000
+
+// MaxIdempotencyKeyLength is the maximum length of an Idempotency-Key header.
+const MaxIdempotencyKeyLength = 255
+
+// Maximum sizes of the requests and responses kept for retries. Larger
+// requests are rejected, larger responses are served but not kept.
+const (
+	MaxIdempotentRequestSize  = 1 << 20
+	MaxIdempotentResponseSize = 1 << 20
+)
+
+// IdempotencyStore keeps the responses to POST requests carrying an
+// Idempotency-Key header in memory, keyed by caller and key, until they
+// expire.
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[idempotencyKey]*idempotentResponse
+	queue   []*idempotentResponse // stored responses in the order they expire
+
+	// Time responses are kept for once stored. Defaults to
+	// DefaultIdempotencyTTL.
+	TTL time.Duration
+
+	// Number of responses kept. Once reached, the oldest responses are
+	// dropped before they expire. Defaults to DefaultIdempotencyMaxEntries.
+	MaxEntries int
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+// NewIdempotencyStore returns a new instance of IdempotencyStore.
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{
+		entries:    make(map[idempotencyKey]*idempotentResponse),
+		TTL:        DefaultIdempotencyTTL,
+		MaxEntries: DefaultIdempotencyMaxEntries,
+		Now:        time.Now,
+	}
+}
+
+type idempotencyKey struct {
+	callerID string
+	key      string
+}
+
+// idempotentResponse is the response stored for a key. It is pending while
+// the first request is being served.
+type idempotentResponse struct {
+	key         idempotencyKey
+	fingerprint [sha256.Size]byte
+	expiresAt   time.Time
+	pending     bool
+
+	status int
+	header http.Header
+	body   []byte
+}
+
+// reserve returns the response stored for key. If there is none, the key is
+// reserved for a request with the given fingerprint and nil is returned.
+// Returns ECONFLICT if the key was used for a different request, its first
+// request is still being served, or the store is full of requests being
+// served.
+func (s *IdempotencyStore) reserve(key idempotencyKey, fingerprint [sha256.Size]byte) (*idempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire()
+	if e := s.entries[key]; e != nil && (e.pending || s.Now().Before(e.expiresAt)) {
+		if e.fingerprint != fingerprint {
+			return nil, todo.Errorf(todo.ECONFLICT, "Idempotency key was already used for a different request.")
+		} else if e.pending {
+			return nil, todo.Errorf(todo.ECONFLICT, "A request with the same idempotency key is still in progress.")
+		}
+		return e, nil
+	}
+
+	if s.evict(); len(s.entries) >= s.MaxEntries {
+		return nil, todo.Errorf(todo.ECONFLICT, "Too many requests with an idempotency key are in progress.")
+	}
+	s.entries[key] = &idempotentResponse{
+		key:         key,
+		fingerprint: fingerprint,
+		pending:     true,
+	}
+	return nil, nil
+}
+
+// complete stores the response to the request key was reserved for. It is
+// kept for s.TTL from now on.
+func (s *IdempotencyStore) complete(key idempotencyKey, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e := s.entries[key]; e != nil && e.pending {
+		e.pending = false
+		e.status, e.header, e.body = status, header, body
+		e.expiresAt = s.Now().Add(s.TTL)
+		s.queue = append(s.queue, e)
+	}
+}
+
+// release forgets key so the request it was reserved for can be retried.
+func (s *IdempotencyStore) release(key idempotencyKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// expire removes the responses whose TTL has passed. As every response is
+// kept for the same time, they expire in the order they were stored. Must be
+// called with s.mu held.
+func (s *IdempotencyStore) expire() {
+	now := s.Now()
+	for len(s.queue) > 0 && !now.Before(s.queue[0].expiresAt) {
+		s.pop()
+	}
+}
+
+// evict drops the oldest stored responses until fewer than s.MaxEntries are
+// kept. Responses still being served are not in the queue and are kept.
+// Must be called with s.mu held.
+func (s *IdempotencyStore) evict() {
+	for len(s.queue) > 0 && len(s.entries) >= s.MaxEntries {
+		s.pop()
+	}
+}
+
+// pop removes the response at the front of the queue. Every response leaves
+// the queue once, so expiring and evicting responses takes constant time per
+// response. Must be called with s.mu held.
+func (s *IdempotencyStore) pop() {
+	e := s.queue[0]
+	if s.entries[e.key] == e {
+		delete(s.entries, e.key)
+	}
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+}
+
+// NewIdempotencyMiddleware returns a transport middleware honouring the
+// Idempotency-Key header of POST requests. The first response to a key is
+// stored in store and replayed to retries by the same caller, marked by an
+// "Idempotent-Replayed: true" header. Reusing a key for a different method,
+// path or body fails with ECONFLICT. Server errors and responses larger than
+// MaxIdempotentResponseSize are not stored, so the request is served again
+// when retried. Requests failing authentication are passed on to be rejected
+// by the endpoint.
+//
+// Callers are told apart by the principal auth.NewHTTPMiddleware added to the
+// request context, which must wrap this middleware if authenticator is set,
+// or by their remote address if authenticator is nil. Anonymous callers behind
+// the same proxy therefore share their keys.
+func NewIdempotencyMiddleware(store *IdempotencyStore, authenticator *auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value := r.Header.Get("Idempotency-Key")
+			if r.Method != http.MethodPost || value == "" {
+				next.ServeHTTP(w, r)
+				return
+			} else if len(value) > MaxIdempotencyKeyLength {
+				encodeError(r.Context(), todo.Errorf(todo.EINVALID, "Idempotency key must be at most %d characters.", MaxIdempotencyKeyLength), w)
+				return
+			}
+
+			key := idempotencyKey{key: value}
+			if authenticator != nil {
+				principal := todo.PrincipalFromContext(r.Context())
+				if principal == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+				key.callerID = principal.ID
+			} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				key.callerID = host
+			} else {
+				key.callerID = r.RemoteAddr
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxIdempotentRequestSize))
+			if err != nil {
+				encodeError(r.Context(), todo.Errorf(todo.EINVALID, "Failed to read body of at most %d bytes.", MaxIdempotentRequestSize), w)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			h := sha256.New()
+			_, _ = h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+			_, _ = h.Write(body)
+			var fingerprint [sha256.Size]byte
+			copy(fingerprint[:], h.Sum(nil))
+
+			stored, err := store.reserve(key, fingerprint)
+			if err != nil {
+				encodeError(r.Context(), err, w)
+				return
+			} else if stored != nil {
+				for k, v := range stored.header {
+					w.Header()[k] = v
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.status)
+				_, _ = w.Write(stored.body)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				if rec.status == 0 || rec.status >= http.StatusInternalServerError || rec.truncated {
+					store.release(key)
+					return
+				}
+				store.complete(key, rec.status, rec.header, rec.body.Bytes())
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// responseRecorder passes a response on to the client while keeping a copy of
+// up to MaxIdempotentResponseSize bytes.
+type responseRecorder struct {
+	http.ResponseWriter
+	status    int
+	header    http.Header
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.header = w.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.body.Len()+len(b) > MaxIdempotentResponseSize {
+		w.truncated = true
+		w.body = bytes.Buffer{}
+	} else if !w.truncated {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todo"
+	"todo/auth"
+)
+
+func TestServer_Idempotency(t *testing.T) {
+	keys := auth.NewKeyStore("")
+	aliceKey, _, err := keys.Issue("alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobKey, _, err := keys.Issue("bob", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewIdempotencyStore()
+	store.TTL = time.Hour
+	store.Now = func() time.Time { return now }
+	ts := MustOpenTestServer(t, func(s *Server) {
+		s.Authenticator = &auth.Authenticator{Keys: keys}
+		s.Idempotency = store
+	})
+	alice := map[string]string{"Authorization": "Bearer " + aliceKey, "Idempotency-Key": "k1"}
+	bob := map[string]string{"Authorization": "Bearer " + bobKey, "Idempotency-Key": "k1"}
+
+	// Retries get the first response and create nothing.
+	var a, b todo.Todo
+	if r := mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, alice, &a); r.StatusCode != http.StatusOK || r.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatalf("status=%d, header=%v", r.StatusCode, r.Header)
+	}
+	if r := mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, alice, &b); r.StatusCode != http.StatusOK || r.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("status=%d, header=%v", r.StatusCode, r.Header)
+	} else if b.ID != a.ID || b.Version != a.Version {
+		t.Fatalf("unexpected todo: %#v, want %#v", b, a)
+	}
+	var list todo.ListTodosResponse
+	if mustDoJSON(t, ts, "GET", "/api/todos", "", alice, &list); list.TotalCount != 1 {
+		t.Fatalf("count=%d, want 1", list.TotalCount)
+	}
+
+	// Reusing a key for a different request is a conflict.
+	if r := mustDo(t, ts, "POST", "/api/todos", `{"value":"b"}`, alice); r.StatusCode != http.StatusConflict {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusConflict)
+	} else if r := mustDo(t, ts, "POST", "/api/todos:batch", `{"value":"a"}`, alice); r.StatusCode != http.StatusConflict {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusConflict)
+	}
+
+	// Keys are scoped to the principal.
+	if r := mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, bob, &b); r.StatusCode != http.StatusOK || r.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatalf("status=%d, header=%v", r.StatusCode, r.Header)
+	} else if b.ID == a.ID || b.OwnerID != "bob" {
+		t.Fatalf("unexpected todo: %#v", b)
+	}
+
+	// Errors other than server errors are replayed as well.
+	alice["Idempotency-Key"] = "k2"
+	if r := mustDo(t, ts, "POST", "/api/todos", `{"value":"a","listId":1}`, alice); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	} else if r := mustDo(t, ts, "POST", "/api/todos", `{"value":"a","listId":1}`, alice); r.StatusCode != http.StatusBadRequest || r.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("status=%d, header=%v", r.StatusCode, r.Header)
+	}
+
+	// Keys can be reused once they expire.
+	now = now.Add(time.Hour)
+	alice["Idempotency-Key"] = "k1"
+	if r := mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b"}`, alice, &b); r.StatusCode != http.StatusOK || r.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatalf("status=%d, header=%v", r.StatusCode, r.Header)
+	} else if b.Value != "b" {
+		t.Fatalf("unexpected todo: %#v", b)
+	}
+
+	alice["Idempotency-Key"] = strings.Repeat("k", MaxIdempotencyKeyLength+1)
+	if r := mustDo(t, ts, "POST", "/api/todos", `{"value":"a"}`, alice); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	// serve sends a POST request with the given key and body from addr and
+	// returns the response.
+	serve := func(h http.Handler, addr, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/todos", strings.NewReader(body))
+		r.RemoteAddr = addr
+		r.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// newHandler returns a handler counting its calls and answering with
+	// size bytes.
+	newHandler := func(store *IdempotencyStore, size int) (http.Handler, *int) {
+		var n int
+		return NewIdempotencyMiddleware(store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n++
+			_, _ = w.Write(bytes.Repeat([]byte("x"), size))
+		})), &n
+	}
+
+	t.Run("Anonymous", func(t *testing.T) {
+		h, n := newHandler(NewIdempotencyStore(), 1)
+		serve(h, "10.0.0.1:1000", "k1", "a")
+		if w := serve(h, "10.0.0.1:2000", "k1", "a"); w.Header().Get("Idempotent-Replayed") != "true" {
+			t.Fatalf("header=%v", w.Header())
+		} else if w := serve(h, "10.0.0.2:1000", "k1", "a"); w.Header().Get("Idempotent-Replayed") != "" {
+			t.Fatalf("header=%v", w.Header())
+		} else if *n != 2 {
+			t.Fatalf("calls=%d, want 2", *n)
+		}
+	})
+
+	t.Run("MaxRequestSize", func(t *testing.T) {
+		h, n := newHandler(NewIdempotencyStore(), 1)
+		if w := serve(h, "10.0.0.1:1000", "k1", strings.Repeat("x", MaxIdempotentRequestSize+1)); w.Code != http.StatusBadRequest {
+			t.Fatalf("status=%d, want %d", w.Code, http.StatusBadRequest)
+		} else if *n != 0 {
+			t.Fatalf("calls=%d, want 0", *n)
+		}
+	})
+
+	t.Run("MaxResponseSize", func(t *testing.T) {
+		h, n := newHandler(NewIdempotencyStore(), MaxIdempotentResponseSize+1)
+		if w := serve(h, "10.0.0.1:1000", "k1", "a"); w.Body.Len() != MaxIdempotentResponseSize+1 {
+			t.Fatalf("len=%d, want %d", w.Body.Len(), MaxIdempotentResponseSize+1)
+		} else if w := serve(h, "10.0.0.1:1000", "k1", "a"); w.Header().Get("Idempotent-Replayed") != "" {
+			t.Fatalf("header=%v", w.Header())
+		} else if *n != 2 {
+			t.Fatalf("calls=%d, want 2", *n)
+		}
+	})
+
+	t.Run("MaxEntries", func(t *testing.T) {
+		store := NewIdempotencyStore()
+		store.MaxEntries = 2
+		h, n := newHandler(store, 1)
+		for _, key := range []string{"k1", "k2", "k3"} {
+			serve(h, "10.0.0.1:1000", key, "a")
+		}
+
+		// The oldest response was dropped to make room.
+		if w := serve(h, "10.0.0.1:1000", "k3", "a"); w.Header().Get("Idempotent-Replayed") != "true" {
+			t.Fatalf("header=%v", w.Header())
+		} else if w := serve(h, "10.0.0.1:1000", "k1", "a"); w.Header().Get("Idempotent-Replayed") != "" {
+			t.Fatalf("header=%v", w.Header())
+		} else if *n != 4 {
+			t.Fatalf("calls=%d, want 4", *n)
+		} else if len(store.entries) != 2 {
+			t.Fatalf("len=%d, want 2", len(store.entries))
+		}
+
+		// Keys cannot be reserved while every kept request is in progress.
+		store = NewIdempotencyStore()
+		store.MaxEntries = 1
+		var inner *httptest.ResponseRecorder
+		var h2 http.Handler
+		h2 = NewIdempotencyMiddleware(store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Idempotency-Key") == "k1" {
+				inner = serve(h2, "10.0.0.1:1000", "k2", "a")
+			}
+		}))
+		serve(h2, "10.0.0.1:1000", "k1", "a")
+		if inner == nil || inner.Code != http.StatusConflict {
+			t.Fatalf("unexpected response: %#v", inner)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+	"todo"
+)
+
+// Media types accepted by PATCH requests.
+const (
+	mediaTypeMergePatch = "application/merge-patch+json" // RFC 7396
+	mediaTypeJSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+// patchTodoRequest wraps a todo.PatchTodoRequest with the "test" operations of
+// a JSON Patch document, which have to be checked against the current todo
+// before the patch is applied.
+type patchTodoRequest struct {
+	todo.PatchTodoRequest
+	Tests []jsonPatchTest
+}
+
+// jsonPatchTest is a "test" operation along with the operations before it,
+// as RFC 6902 tests a value against the document patched so far.
+type jsonPatchTest struct {
+	Op     jsonPatchOperation
+	Before todo.PatchTodoRequest
+}
+
+// jsonPatchOperation represents a single operation of an RFC 6902 document.
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func MakePatchTodoEndpoint(s todo.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(patchTodoRequest)
+		if version, err := checkIfMatch(ctx, s, req.ID); err != nil {
+			return nil, err
+		} else if version != 0 {
+			req.Version = version
+		}
+
+		// Evaluate "test" operations. If any fails, the whole patch fails.
+		// The patch is pinned to the tested version so it cannot be applied
+		// on top of a concurrent change.
+		if len(req.Tests) > 0 {
+			t, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: req.ID})
+			if err != nil {
+				return nil, err
+			} else if err := t.CheckVersion(req.Version); err != nil {
+				return nil, err
+			}
+			for _, test := range req.Tests {
+				doc := *t
+				test.Before.Apply(&doc)
+				if err := testTodoField(&doc, test.Op); err != nil {
+					return nil, err
+				}
+			}
+			req.Version = t.Version
+		}
+
+		response, err = s.PatchTodo(ctx, req.PatchTodoRequest)
+		return
+	}
+}
+
+// decodePatchTodoRequest decodes either a JSON Merge Patch or a JSON Patch
+// document depending on the request's Content-Type. Plain "application/json"
+// is treated as a merge patch.
+func decodePatchTodoRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req patchTodoRequest
+
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, todo.Errorf(todo.EINVALID, "Invalid value for parameter 'id'.")
+	}
+
+	req.ID, err = strconv.Atoi(id)
+	if err != nil {
+		return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", id)
+	}
+
+	// See decodeUpdateTodoRequest.
+	req.Scope = r.URL.Query().Get("scope")
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mediaTypeMergePatch, "application/json", "":
+		err = decodeMergePatch(r, &req)
+	case mediaTypeJSONPatch:
+		err = decodeJSONPatch(r, &req)
+	default:
+		return nil, todo.Errorf(todo.EINVALID, "Unsupported content type '%s'.", mediaType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// decodeMergePatch decodes an RFC 7396 merge patch. Members present in the
+// document are set on the todo. Null members remove optional fields and are
+// rejected for the others.
+func decodeMergePatch(r *http.Request, req *patchTodoRequest) error {
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		return todo.Errorf(todo.EINVALID, "Failed to decode merge patch document.")
+	}
+
+	for name, value := range doc {
+		if err := setPatchField(&req.PatchTodoRequest, "/"+name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeJSONPatch decodes an RFC 6902 JSON Patch. The "add" and "replace"
+// operations set a field, "copy" & "move" are only valid between identical
+// paths and "test" operations are checked by the endpoint against the todo
+// patched by the operations before them. Only optional fields can be removed.
+func decodeJSONPatch(r *http.Request, req *patchTodoRequest) error {
+	var ops []jsonPatchOperation
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		return todo.Errorf(todo.EINVALID, "Failed to decode JSON patch document.")
+	}
+
+	for _, op := range ops {
+		switch op.Op {
+		case "add", "replace":
+			if err := setPatchField(&req.PatchTodoRequest, op.Path, op.Value); err != nil {
+				return err
+			}
+		case "test":
+			if _, ok := patchFields[op.Path]; !ok {
+				return todo.Errorf(todo.EINVALID, "Invalid patch path '%s'.", op.Path)
+			}
+			req.Tests = append(req.Tests, jsonPatchTest{Op: op, Before: req.PatchTodoRequest})
+		case "copy", "move":
+			if op.From != op.Path {
+				return todo.Errorf(todo.EINVALID, "Operation '%s' from '%s' to '%s' is not supported.", op.Op, op.From, op.Path)
+			}
+		case "remove":
+			if err := setPatchField(&req.PatchTodoRequest, op.Path, nil); err != nil {
+				return err
+			}
+		default:
+			return todo.Errorf(todo.EINVALID, "Invalid patch operation '%s'.", op.Op)
+		}
+	}
+	return nil
+}
+
+// patchFields maps JSON pointers to the patchable field of a todo.
+var patchFields = map[string]func(req *todo.PatchTodoRequest) interface{}{
+	"/value":      func(req *todo.PatchTodoRequest) interface{} { return &req.Value },
+	"/complete":   func(req *todo.PatchTodoRequest) interface{} { return &req.Complete },
+	"/state":      func(req *todo.PatchTodoRequest) interface{} { return &req.State },
+	"/listId":     func(req *todo.PatchTodoRequest) interface{} { return &req.ListID },
+	"/parentId":   func(req *todo.PatchTodoRequest) interface{} { return &req.ParentID },
+	"/dueAt":      func(req *todo.PatchTodoRequest) interface{} { return &req.DueAt },
+	"/remindAt":   func(req *todo.PatchTodoRequest) interface{} { return &req.RemindAt },
+	"/timeZone":   func(req *todo.PatchTodoRequest) interface{} { return &req.TimeZone },
+	"/recurrence": func(req *todo.PatchTodoRequest) interface{} { return &req.Recurrence },
+	"/tags":       func(req *todo.PatchTodoRequest) interface{} { return &req.Tags },
+	"/priority":   func(req *todo.PatchTodoRequest) interface{} { return &req.Priority },
+	"/archivedAt": func(req *todo.PatchTodoRequest) interface{} { return &req.ArchivedAt },
+}
+
+// optionalFields lists the fields that can be removed, and the value that
+// removes them.
+var optionalFields = map[string]json.RawMessage{
+	"/parentId":   json.RawMessage(`0`),
+	"/dueAt":      json.RawMessage(`"0001-01-01T00:00:00Z"`),
+	"/remindAt":   json.RawMessage(`"0001-01-01T00:00:00Z"`),
+	"/timeZone":   json.RawMessage(`""`),
+	"/recurrence": json.RawMessage(`""`),
+	"/tags":       json.RawMessage(`[]`),
+	"/priority":   json.RawMessage(`0`),
+	"/archivedAt": json.RawMessage(`"0001-01-01T00:00:00Z"`),
+}
+
+// setPatchField sets the request field addressed by path to value. The field
+// is replaced rather than decoded into, so copies of req are left as they are.
+func setPatchField(req *todo.PatchTodoRequest, path string, value json.RawMessage) error {
+	field, ok := patchFields[path]
+	if !ok {
+		return todo.Errorf(todo.EINVALID, "Invalid patch path '%s'.", path)
+	} else if len(value) == 0 || bytes.Equal(value, []byte("null")) {
+		if value, ok = optionalFields[path]; !ok {
+			return todo.Errorf(todo.EINVALID, "Field '%s' cannot be removed.", path)
+		}
+	}
+
+	v := reflect.ValueOf(field(req)).Elem()
+	v.Set(reflect.Zero(v.Type()))
+	if err := json.Unmarshal(value, field(req)); err != nil {
+		return todo.Errorf(todo.EINVALID, "Invalid value for field '%s'.", path)
+	}
+	return nil
+}
+
+// testTodoField evaluates a JSON Patch "test" operation against t. Values are
+// compared the way todos store them, so dates are equal if they are the same
+// second and tags if they are the same set. Returns ECONFLICT if the field
+// does not have the expected value.
+func testTodoField(t *todo.Todo, op jsonPatchOperation) error {
+	var want todo.PatchTodoRequest
+	if err := setPatchField(&want, op.Path, op.Value); err != nil {
+		return err
+	} else if err := normalizePatchFields(&want); err != nil {
+		return err
+	}
+
+	var got todo.PatchTodoRequest
+	buf, _ := json.Marshal(t)
+	_ = json.Unmarshal(buf, &got)
+	if err := normalizePatchFields(&got); err != nil {
+		return err
+	}
+
+	field := patchFields[op.Path]
+	a, b := patchFieldValue(field(&got)), patchFieldValue(field(&want))
+	if at, ok := a.(time.Time); ok && at.Equal(b.(time.Time)) {
+		return nil
+	} else if !ok && reflect.DeepEqual(a, b) {
+		return nil
+	}
+	return todo.Errorf(todo.ECONFLICT, "Test of '%s' failed.", op.Path)
+}
+
+// normalizePatchFields normalizes the dates and tags set in req like backends
+// normalize those of todos. Removed dates become unset.
+func normalizePatchFields(req *todo.PatchTodoRequest) error {
+	for _, v := range []**time.Time{&req.DueAt, &req.RemindAt, &req.ArchivedAt} {
+		if *v != nil && (*v).IsZero() {
+			*v = nil
+		} else if *v != nil {
+			other := (*v).UTC().Truncate(time.Second)
+			*v = &other
+		}
+	}
+	if req.Tags != nil {
+		tags, err := todo.NormalizeTags(*req.Tags)
+		if err != nil {
+			return err
+		}
+		req.Tags = &tags
+	}
+	return nil
+}
+
+// patchFieldValue returns the value of a request field returned by
+// patchFields, or the zero value of the field if it is not set, as todos omit
+// fields with zero values.
+func patchFieldValue(field interface{}) interface{} {
+	v := reflect.ValueOf(field).Elem()
+	if v.IsNil() {
+		return reflect.Zero(v.Type().Elem()).Interface()
+	}
+	return v.Elem().Interface()
+}
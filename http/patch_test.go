@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"todo"
+)
+
+func TestDecodePatchTodoRequest(t *testing.T) {
+	value, complete, zero := "b", true, time.Time{}
+
+	for _, tt := range []struct {
+		name        string
+		contentType string
+		body        string
+		want        todo.PatchTodoRequest
+		tests       int
+		code        string
+	}{
+		{name: "MergePatch", contentType: mediaTypeMergePatch, body: `{"complete":true}`, want: todo.PatchTodoRequest{ID: 1, Complete: &complete}},
+		{name: "MergePatchNull", contentType: mediaTypeMergePatch, body: `{"value":null}`, code: todo.EINVALID},
+		{name: "MergePatchNullDate", contentType: mediaTypeMergePatch, body: `{"dueAt":null}`, want: todo.PatchTodoRequest{ID: 1, DueAt: &zero}},
+		{name: "MergePatchUnknown", contentType: mediaTypeMergePatch, body: `{"id":2}`, code: todo.EINVALID},
+		{name: "JSONPatch", contentType: mediaTypeJSONPatch, body: `[{"op":"test","path":"/value","value":"a"},{"op":"replace","path":"/value","value":"b"}]`, want: todo.PatchTodoRequest{ID: 1, Value: &value}, tests: 1},
+		{name: "JSONPatchRemove", contentType: mediaTypeJSONPatch, body: `[{"op":"remove","path":"/value"}]`, code: todo.EINVALID},
+		{name: "JSONPatchRemoveDate", contentType: mediaTypeJSONPatch, body: `[{"op":"remove","path":"/remindAt"}]`, want: todo.PatchTodoRequest{ID: 1, RemindAt: &zero}},
+		{name: "UnsupportedType", contentType: "text/plain", body: `x`, code: todo.EINVALID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/api/todos/1", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			r = mux.SetURLVars(r, map[string]string{"id": "1"})
+
+			req, err := decodePatchTodoRequest(context.Background(), r)
+			if tt.code != "" {
+				if todo.ErrorCode(err) != tt.code {
+					t.Fatalf("unexpected error: %#v", err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			got := req.(patchTodoRequest)
+			if !reflect.DeepEqual(got.PatchTodoRequest, tt.want) {
+				t.Fatalf("request=%#v, want %#v", got.PatchTodoRequest, tt.want)
+			} else if len(got.Tests) != tt.tests {
+				t.Fatalf("len(Tests)=%d, want %d", len(got.Tests), tt.tests)
+			}
+		})
+	}
+}
+
+func TestServer_JSONPatch(t *testing.T) {
+	ts := MustOpenTestServer(t)
+	header := map[string]string{"Content-Type": mediaTypeJSONPatch}
+
+	var a todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a","dueAt":"2030-01-01T12:00:00Z","tags":["work","home"]}`, nil, &a)
+	path := "/api/todos/" + strconv.Itoa(a.ID)
+
+	for _, tt := range []struct {
+		name string
+		body string
+		code int
+	}{
+		// Tests see the operations before them.
+		{"TestAfterReplace", `[{"op":"replace","path":"/value","value":"b"},{"op":"test","path":"/value","value":"b"}]`, http.StatusOK},
+		{"TestBeforeReplace", `[{"op":"test","path":"/value","value":"b"},{"op":"replace","path":"/value","value":"c"},{"op":"test","path":"/value","value":"b"}]`, http.StatusConflict},
+		{"TestFailed", `[{"op":"test","path":"/value","value":"a"}]`, http.StatusConflict},
+
+		// Values are compared as stored.
+		{"TestNormalized", `[{"op":"test","path":"/dueAt","value":"2030-01-01T13:00:00+01:00"},{"op":"test","path":"/tags","value":["home","#Work"]}]`, http.StatusOK},
+		{"TestRemoved", `[{"op":"remove","path":"/dueAt"},{"op":"test","path":"/dueAt","value":null},{"op":"test","path":"/parentId","value":0}]`, http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := mustDo(t, ts, "PATCH", path, tt.body, header); resp.StatusCode != tt.code {
+				t.Fatalf("status=%d, want %d", resp.StatusCode, tt.code)
+			}
+		})
+	}
+
+	var got todo.Todo
+	if mustDoJSON(t, ts, "GET", path, "", nil, &got); got.Value != "b" || got.DueAt != nil {
+		t.Fatalf("unexpected todo: %#v", got)
+	}
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"strconv"
+	"todo"
+)
+
+func (s *Server) configurePolicyHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakePolicyServerEndpoints(s.PolicyService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	s.router.Handle(
+		"/api/policies/report",
+		httptransport.NewServer(
+			e.ReportPoliciesEndpoint,
+			decodeReportPoliciesRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+}
+
+type PolicyEndpoints struct {
+	ReportPoliciesEndpoint endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e PolicyEndpoints) Wrap(mw endpoint.Middleware) PolicyEndpoints {
+	return PolicyEndpoints{
+		ReportPoliciesEndpoint: mw(e.ReportPoliciesEndpoint),
+	}
+}
+
+// MakePolicyServerEndpoints returns a PolicyEndpoints struct where each
+// endpoint invokes the corresponding method on the provided service.
+func MakePolicyServerEndpoints(s todo.PolicyService) PolicyEndpoints {
+	return PolicyEndpoints{
+		ReportPoliciesEndpoint: MakeReportPoliciesEndpoint(s),
+	}
+}
+
+func MakeReportPoliciesEndpoint(s todo.PolicyService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.ReportPoliciesRequest)
+		response, err = s.ReportPolicies(ctx, req)
+		return
+	}
+}
+
+// decodeReportPoliciesRequest reports on every list of the caller, or on a
+// single one with "?listId=1".
+func decodeReportPoliciesRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.ReportPoliciesRequest
+
+	if v := r.URL.Query().Get("listId"); v != "" {
+		if req.ListID, err = strconv.Atoi(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_Policies(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var l todo.List
+	if r := mustDoJSON(t, ts, "POST", "/api/lists", `{"name":"l","policies":[{"action":"archive","afterDays":0}]}`, nil, &l); r.StatusCode != http.StatusOK && r.StatusCode != http.StatusCreated {
+		t.Fatalf("status=%d", r.StatusCode)
+	} else if len(l.Policies) != 1 || l.Policies[0].Action != todo.PolicyArchive {
+		t.Fatalf("unexpected list: %#v", l)
+	}
+	if r := mustDo(t, ts, "POST", "/api/lists", `{"name":"l","policies":[{"action":"shred"}]}`, nil); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	}
+
+	var a, b todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a","complete":true,"listId":`+strconv.Itoa(l.ID)+`}`, nil, &a)
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b","listId":`+strconv.Itoa(l.ID)+`}`, nil, &b)
+
+	// The report is a dry run.
+	var report todo.PolicyReport
+	if r := mustDoJSON(t, ts, "GET", "/api/policies/report?listId="+strconv.Itoa(l.ID), "", nil, &report); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if len(report.Archived) != 1 || report.Archived[0].ID != a.ID || len(report.Purged) != 0 {
+		t.Fatalf("unexpected report: %#v", report)
+	}
+	var list todo.ListTodosResponse
+	if mustDoJSON(t, ts, "GET", "/api/todos", "", nil, &list); list.TotalCount != 2 {
+		t.Fatalf("count=%d, want 2", list.TotalCount)
+	}
+	if r := mustDo(t, ts, "GET", "/api/policies/report?listId=0x", "", nil); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	}
+
+	// Todos are archived by patching them and listed with "?archived=true".
+	path := "/api/todos/" + strconv.Itoa(b.ID)
+	if r := mustDo(t, ts, "PATCH", path, `{"archivedAt":"2021-01-01T12:00:00Z"}`, nil); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	}
+	if mustDoJSON(t, ts, "GET", "/api/todos?archived=true", "", nil, &list); len(list.Todos) != 1 || list.Todos[0].ID != b.ID {
+		t.Fatalf("unexpected todos: %#v", list.Todos)
+	}
+	mustDo(t, ts, "PATCH", path, `{"archivedAt":null}`, nil)
+	if mustDoJSON(t, ts, "GET", "/api/todos?archived=true", "", nil, &list); len(list.Todos) != 0 {
+		t.Fatalf("unexpected todos: %#v", list.Todos)
+	}
+}
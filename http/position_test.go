@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_MoveTodo(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var a, b todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a","priority":3}`, nil, &a)
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b"}`, nil, &b)
+	if a.Priority != todo.PriorityHigh || a.Position == "" || a.Position >= b.Position {
+		t.Fatalf("unexpected todos: %#v, %#v", a, b)
+	}
+
+	var moved todo.Todo
+	path := "/api/todos/" + strconv.Itoa(b.ID) + "/move"
+	if resp := mustDoJSON(t, ts, "POST", path, `{"beforeId":`+strconv.Itoa(a.ID)+`}`, nil, &moved); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if moved.Position >= a.Position || moved.Version != b.Version+1 {
+		t.Fatalf("unexpected todo: %#v", moved)
+	}
+
+	var resp struct{ Todos []*todo.Todo }
+	if mustDoJSON(t, ts, "GET", "/api/todos?sort=position", "", nil, &resp); len(resp.Todos) != 2 || resp.Todos[0].ID != b.ID {
+		t.Fatalf("unexpected todos: %#v", resp.Todos)
+	}
+
+	if r := mustDo(t, ts, "POST", path, `{}`, nil); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	} else if r := mustDo(t, ts, "POST", path, `{"afterId":`+strconv.Itoa(a.ID)+`}`, map[string]string{"If-Match": `"1"`}); r.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusPreconditionFailed)
+	}
+
+	if r := mustDo(t, ts, "PATCH", "/api/todos/"+strconv.Itoa(a.ID), `{"priority":null}`, map[string]string{"Content-Type": "application/merge-patch+json"}); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_Queries(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var a, b todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a","tags":["work"],"priority":1}`, nil, &a)
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b","tags":["work"],"priority":3}`, nil, &b)
+	mustDo(t, ts, "POST", "/api/todos", `{"value":"c","priority":3}`, nil)
+
+	var resp todo.ListTodosResponse
+	if r := mustDoJSON(t, ts, "GET", "/api/todos?query="+url.QueryEscape("tag:work ORDER BY priority DESC"), "", nil, &resp); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if len(resp.Todos) != 2 || resp.Todos[0].ID != b.ID || resp.Todos[1].ID != a.ID {
+		t.Fatalf("unexpected todos: %#v", resp.Todos)
+	}
+
+	// Invalid queries point at the offending token.
+	var e ErrorResponse
+	if r := mustDoJSON(t, ts, "GET", "/api/todos?query="+url.QueryEscape("tag:work AND AND"), "", nil, &e); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	} else if e.Error != "Unexpected 'AND' at offset 13." {
+		t.Fatalf("unexpected error: %q", e.Error)
+	}
+
+	// Smart lists are fetched like regular lists.
+	var l todo.List
+	if r := mustDoJSON(t, ts, "POST", "/api/lists", `{"name":"urgent","query":"priority:high tag:work"}`, nil, &l); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if l.Query != "priority:high tag:work" {
+		t.Fatalf("unexpected list: %#v", l)
+	}
+	resp = todo.ListTodosResponse{}
+	if mustDoJSON(t, ts, "GET", "/api/lists/"+strconv.Itoa(l.ID)+"/todos", "", nil, &resp); len(resp.Todos) != 1 || resp.Todos[0].ID != b.ID {
+		t.Fatalf("unexpected todos: %#v", resp.Todos)
+	}
+	if r := mustDo(t, ts, "POST", "/api/lists", `{"name":"x","query":"priority"}`, nil); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_Recurrence(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var a todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a","dueAt":"2021-03-01T09:00:00Z","recurrence":"FREQ=WEEKLY"}`, nil, &a)
+	path := "/api/todos/" + strconv.Itoa(a.ID)
+
+	// Moving this occurrence only creates the next one right away.
+	var got todo.Todo
+	if resp := mustDoJSON(t, ts, "PATCH", path+"?scope=this", `{"dueAt":"2021-03-02T09:00:00Z"}`, nil, &got); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if got.Recurrence != "" {
+		t.Fatalf("unexpected todo: %#v", got)
+	}
+
+	var list todo.ListTodosResponse
+	mustDoJSON(t, ts, "GET", "/api/todos?complete=false&seriesId="+strconv.Itoa(a.ID), "", nil, &list)
+	if len(list.Todos) != 2 {
+		t.Fatalf("unexpected todos: %#v", list.Todos)
+	}
+	next := list.Todos[1]
+
+	var completed todo.CompleteTodoResponse
+	if resp := mustDoJSON(t, ts, "POST", "/api/todos/"+strconv.Itoa(next.ID)+"/complete", "", nil, &completed); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if !completed.Todo.Complete || completed.Next == nil || completed.Next.Occurrence != 3 {
+		t.Fatalf("unexpected response: %#v", completed)
+	}
+
+	if resp := mustDo(t, ts, "PUT", path+"?scope=everything", `{"value":"a"}`, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
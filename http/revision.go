@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"todo"
+)
+
+func (s *Server) configureRevisionHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakeRevisionServerEndpoints(s.RevisionService, s.TodoService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	s.router.Handle(
+		"/api/todos/{id}/history",
+		httptransport.NewServer(
+			e.ListRevisionsEndpoint,
+			decodeListRevisionsRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	s.router.Handle(
+		"/api/todos/{id}/history/{revisionId}/restore",
+		httptransport.NewServer(
+			e.RestoreRevisionEndpoint,
+			decodeRestoreRevisionRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("POST")
+}
+
+type RevisionEndpoints struct {
+	ListRevisionsEndpoint   endpoint.Endpoint
+	RestoreRevisionEndpoint endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e RevisionEndpoints) Wrap(mw endpoint.Middleware) RevisionEndpoints {
+	return RevisionEndpoints{
+		ListRevisionsEndpoint:   mw(e.ListRevisionsEndpoint),
+		RestoreRevisionEndpoint: mw(e.RestoreRevisionEndpoint),
+	}
+}
+
+// MakeRevisionServerEndpoints returns a RevisionEndpoints struct where each
+// endpoint invokes the corresponding method on the provided service. todos is
+// used to evaluate If-Match headers.
+func MakeRevisionServerEndpoints(s todo.RevisionService, todos todo.Service) RevisionEndpoints {
+	return RevisionEndpoints{
+		ListRevisionsEndpoint:   MakeListRevisionsEndpoint(s),
+		RestoreRevisionEndpoint: MakeRestoreRevisionEndpoint(s, todos),
+	}
+}
+
+func MakeListRevisionsEndpoint(s todo.RevisionService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.ListRevisionsRequest)
+		response, err = s.ListRevisions(ctx, req)
+		return
+	}
+}
+
+func MakeRestoreRevisionEndpoint(s todo.RevisionService, todos todo.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.RestoreRevisionRequest)
+		if version, err := checkIfMatch(ctx, todos, req.TodoID); err != nil {
+			return nil, err
+		} else if version != 0 {
+			req.Version = version
+		}
+		response, err = s.RestoreRevision(ctx, req)
+		return
+	}
+}
+
+func decodeListRevisionsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.ListRevisionsRequest
+
+	if req.TodoID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func decodeRestoreRevisionRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.RestoreRevisionRequest
+
+	if req.TodoID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	} else if req.RevisionID, err = intVar(r, "revisionId"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_Revisions(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var a todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a","tags":["work"]}`, nil, &a)
+	path := "/api/todos/" + strconv.Itoa(a.ID)
+	mustDo(t, ts, "PATCH", path, `{"value":"b","tags":null}`, map[string]string{"Content-Type": "application/merge-patch+json"})
+
+	var revs []*todo.Revision
+	if r := mustDoJSON(t, ts, "GET", path+"/history", "", nil, &revs); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if len(revs) != 2 || revs[0].Todo.Value != "a" || revs[1].Todo.Value != "b" {
+		t.Fatalf("unexpected revisions: %#v", revs)
+	} else if c := revs[1].Changes; len(c) != 2 || c[0].Field != "value" || string(c[0].From) != `"a"` || c[1].Field != "tags" || string(c[1].To) != "null" {
+		t.Fatalf("unexpected changes: %#v", c)
+	}
+
+	// Restores honor If-Match like other changes.
+	restore := path + "/history/" + strconv.Itoa(revs[0].ID) + "/restore"
+	if r := mustDo(t, ts, "POST", restore, "", map[string]string{"If-Match": `"1"`}); r.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusPreconditionFailed)
+	}
+	var restored todo.Todo
+	if r := mustDoJSON(t, ts, "POST", restore, "", map[string]string{"If-Match": `"2"`}, &restored); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if restored.Value != "a" || len(restored.Tags) != 1 || r.Header.Get("ETag") != `"3"` {
+		t.Fatalf("unexpected todo: %#v", restored)
+	}
+	if mustDoJSON(t, ts, "GET", path+"/history", "", nil, &revs); len(revs) != 3 {
+		t.Fatalf("unexpected revisions: %#v", revs)
+	}
+
+	if r := mustDo(t, ts, "POST", path+"/history/1/restore", "", nil); r.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusNotFound)
+	} else if r := mustDo(t, ts, "GET", "/api/todos/0/history", "", nil); r.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"strconv"
+	"todo"
+)
+
+func (s *Server) configureSearchHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakeSearchServerEndpoints(s.SearchService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	// Registered before "/api/todos/{id}", which would match it too.
+	s.router.Handle(
+		"/api/todos/search",
+		httptransport.NewServer(
+			e.SearchTodosEndpoint,
+			decodeSearchTodosRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+}
+
+type SearchEndpoints struct {
+	SearchTodosEndpoint endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e SearchEndpoints) Wrap(mw endpoint.Middleware) SearchEndpoints {
+	return SearchEndpoints{
+		SearchTodosEndpoint: mw(e.SearchTodosEndpoint),
+	}
+}
+
+// MakeSearchServerEndpoints returns a SearchEndpoints struct where each
+// endpoint invokes the corresponding method on the provided service.
+func MakeSearchServerEndpoints(s todo.SearchService) SearchEndpoints {
+	return SearchEndpoints{
+		SearchTodosEndpoint: MakeSearchTodosEndpoint(s),
+	}
+}
+
+func MakeSearchTodosEndpoint(s todo.SearchService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.SearchTodosRequest)
+		response, err = s.SearchTodos(ctx, req)
+		return
+	}
+}
+
+// decodeSearchTodosRequest maps query string parameters onto a
+// SearchTodosRequest, e.g. "?q=%22quarterly+report%22+meet*&limit=10".
+func decodeSearchTodosRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.SearchTodosRequest
+	q := r.URL.Query()
+
+	req.Query = q.Get("q")
+
+	if v := q.Get("limit"); v != "" {
+		if req.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+	}
+
+	return req, nil
+}
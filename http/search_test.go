@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"todo"
+)
+
+func TestServer_SearchTodos(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var a, b todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"Buy running shoes"}`, nil, &a)
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"Run, run & run"}`, nil, &b)
+	mustDo(t, ts, "POST", "/api/todos", `{"value":"Water the plants"}`, nil)
+
+	var resp todo.SearchTodosResponse
+	if r := mustDoJSON(t, ts, "GET", "/api/todos/search?q=runs", "", nil, &resp); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if resp.TotalCount != 2 || len(resp.Results) != 2 || resp.Results[0].Todo.ID != b.ID || resp.Results[1].Todo.ID != a.ID {
+		t.Fatalf("unexpected response: %#v", resp)
+	} else if got, want := resp.Results[0].Snippet, "<mark>Run</mark>, <mark>run</mark> &amp; <mark>run</mark>"; got != want {
+		t.Fatalf("snippet=%q, want %q", got, want)
+	}
+
+	resp = todo.SearchTodosResponse{}
+	if mustDoJSON(t, ts, "GET", "/api/todos/search?q=%22running+shoes%22&limit=1", "", nil, &resp); len(resp.Results) != 1 || resp.Results[0].Todo.ID != a.ID {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+
+	for _, path := range []string{"/api/todos/search", "/api/todos/search?q=%22run", "/api/todos/search?q=run&limit=x"} {
+		if r := mustDo(t, ts, "GET", path, "", nil); r.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: status=%d, want %d", path, r.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
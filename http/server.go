@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"time"
 	"todo"
+	"todo/auth"
 )
 
 // ShutdownTimeout is the time given for outstanding requests to finish before shutdown.
@@ -19,6 +20,15 @@ type Server struct {
 	server *http.Server
 	router *mux.Router
 
+	// Router wrapped by the CORS, authentication and idempotency
+	// middlewares. It is built once the handlers are configured.
+	handler http.Handler
+
+	// Done when the server shuts down, ending the event streams which would
+	// otherwise keep it from shutting down.
+	streams       context.Context
+	cancelStreams context.CancelFunc
+
 	// Bind address & domain for the server's listener.
 	// If domain is specified, server is run on TLS using acme/autocert.
 	Addr   string
@@ -26,7 +36,51 @@ type Server struct {
 
 	Logger log.Logger
 
+	// Authenticates every API call if set. Otherwise the API is open to
+	// anonymous callers.
+	Authenticator *auth.Authenticator
+
 	TodoService todo.Service
+
+	// Manages who todos are shared with. Sharing is disabled if nil.
+	ShareService todo.ShareService
+
+	// Manages lists of todos. The list routes are disabled if nil.
+	ListService todo.ListService
+
+	// Manages which todos block which other todos. The dependency routes are
+	// disabled if nil.
+	DependencyService todo.DependencyService
+
+	// Manages the tags of all todos at once. The tag routes are disabled if
+	// nil.
+	TagService todo.TagService
+
+	// Exposes the workflow and the state history of todos. The workflow
+	// routes are disabled if nil.
+	WorkflowService todo.WorkflowService
+
+	// Finds todos by the words in their values. The search route is disabled
+	// if nil.
+	SearchService todo.SearchService
+
+	// Exposes the revision history of todos and restores todos to earlier
+	// revisions. The history routes are disabled if nil.
+	RevisionService todo.RevisionService
+
+	// Lists and restores deleted todos. The trash routes are disabled if nil.
+	TrashService todo.TrashService
+
+	// Reports what the retention policies of lists would do. The policy
+	// route is disabled if nil.
+	PolicyService todo.PolicyService
+
+	// Streams the changes made to todos. The event route is disabled if nil.
+	EventService todo.EventService
+
+	// Replays the responses to POST requests retried with the same
+	// Idempotency-Key header. The header is ignored if nil.
+	Idempotency *IdempotencyStore
 }
 
 func NewServer() *Server {
@@ -40,6 +94,10 @@ func NewServer() *Server {
 	// This includes changing route paths for JSON endpoints & overridding methods.
 	s.server.Handler = http.HandlerFunc(s.serveHTTP)
 
+	// Shutdown waits for active requests, so end the streams first.
+	s.streams, s.cancelStreams = context.WithCancel(context.Background())
+	s.server.RegisterOnShutdown(s.cancelStreams)
+
 	return s
 }
 
@@ -95,16 +153,32 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Delegate remaining HTTP handling to the gorilla router.
+	s.handler.ServeHTTP(w, r)
+}
+
+// configureMiddleware wraps the router by the middlewares applied to every
+// request.
+func (s *Server) configureMiddleware() {
 	// Allow CORS
-	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type", "Authorization"})
+	allowedHeaders := handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "If-Match", "If-None-Match", "Idempotency-Key", "Last-Event-ID"})
+	exposedHeaders := handlers.ExposedHeaders([]string{"ETag", "Idempotent-Replayed"})
 	allowedOrigins := handlers.AllowedOrigins([]string{"http://localhost:3000"})
-	allowedMethods := handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "OPTIONS", "DELETE"})
+	allowedMethods := handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "PATCH", "OPTIONS", "DELETE"})
 
-	// Delegate remaining HTTP handling to the gorilla router.
-	handlers.CORS(
+	var h http.Handler = s.router
+	if s.Idempotency != nil {
+		h = NewIdempotencyMiddleware(s.Idempotency, s.Authenticator)(h)
+	}
+	if s.Authenticator != nil {
+		h = auth.NewHTTPMiddleware(s.Authenticator)(h)
+	}
+
+	s.handler = handlers.CORS(
 		allowedOrigins,
 		allowedHeaders,
 		allowedMethods,
+		exposedHeaders,
 		handlers.AllowCredentials(),
-	)(s.router).ServeHTTP(w, r)
+	)(h)
 }
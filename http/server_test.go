@@ -0,0 +1,73 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/go-kit/kit/log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo"
+	"todo/inmem"
+	"todo/revmw"
+)
+
+// MustOpenTestServer returns a test server backed by in-memory services.
+// The server can be customized by opts before its routes are configured.
+func MustOpenTestServer(tb testing.TB, opts ...func(s *Server)) *httptest.Server {
+	tb.Helper()
+
+	s := NewServer()
+	s.Logger = log.NewNopLogger()
+	svc := inmem.NewService()
+	s.TodoService, s.ShareService, s.ListService, s.DependencyService, s.TagService = svc, svc, svc, svc, svc
+	s.WorkflowService = svc
+	s.SearchService = svc
+	s.TodoService = revmw.NewTodoRevisionMiddleware(svc, todo.RetentionPolicy{}, s.Logger)(svc)
+	s.RevisionService = revmw.NewRevisionService(s.TodoService, svc)
+	s.TrashService = svc
+	s.PolicyService = svc
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.configureHandlers()
+
+	ts := httptest.NewUnstartedServer(s.server.Handler)
+	ts.Config = s.server
+	ts.Start()
+	tb.Cleanup(ts.Close)
+	return ts
+}
+
+// mustDo sends a request to ts and returns the response with its body closed.
+func mustDo(tb testing.TB, ts *httptest.Server, method, path, body string, header map[string]string) *http.Response {
+	tb.Helper()
+	return mustDoJSON(tb, ts, method, path, body, header, nil)
+}
+
+// mustDoJSON sends a request to ts and decodes the JSON response body into v,
+// unless v is nil. Returns the response with its body closed.
+func mustDoJSON(tb testing.TB, ts *httptest.Server, method, path, body string, header map[string]string, v interface{}) *http.Response {
+	tb.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return resp
+}
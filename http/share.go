@@ -0,0 +1,208 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"net/http"
+	"todo"
+)
+
+func (s *Server) configureShareHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakeShareServerEndpoints(s.ShareService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	s.router.Handle(
+		"/api/todos/{id}/shares/{principalId}",
+		httptransport.NewServer(
+			e.CreateShareEndpoint,
+			decodeCreateShareRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("PUT")
+
+	s.router.Handle(
+		"/api/todos/{id}/shares/{principalId}",
+		httptransport.NewServer(
+			e.DeleteShareEndpoint,
+			decodeDeleteShareRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("DELETE")
+
+	s.router.Handle(
+		"/api/todos/{id}/shares",
+		httptransport.NewServer(
+			e.ListSharesEndpoint,
+			decodeListSharesRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	s.router.Handle(
+		"/api/lists/{id}/shares/{principalId}",
+		httptransport.NewServer(
+			e.CreateShareEndpoint,
+			decodeCreateListShareRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("PUT")
+
+	s.router.Handle(
+		"/api/lists/{id}/shares/{principalId}",
+		httptransport.NewServer(
+			e.DeleteShareEndpoint,
+			decodeDeleteListShareRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("DELETE")
+
+	s.router.Handle(
+		"/api/lists/{id}/shares",
+		httptransport.NewServer(
+			e.ListSharesEndpoint,
+			decodeListListSharesRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	// Lists the todos & lists shared with the caller.
+	s.router.Handle(
+		"/api/shares",
+		httptransport.NewServer(
+			e.ListSharesEndpoint,
+			decodeListSharesRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+}
+
+type ShareEndpoints struct {
+	CreateShareEndpoint endpoint.Endpoint
+	DeleteShareEndpoint endpoint.Endpoint
+	ListSharesEndpoint  endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e ShareEndpoints) Wrap(mw endpoint.Middleware) ShareEndpoints {
+	return ShareEndpoints{
+		CreateShareEndpoint: mw(e.CreateShareEndpoint),
+		DeleteShareEndpoint: mw(e.DeleteShareEndpoint),
+		ListSharesEndpoint:  mw(e.ListSharesEndpoint),
+	}
+}
+
+// MakeShareServerEndpoints returns a ShareEndpoints struct where each endpoint
+// invokes the corresponding method on the provided service.
+func MakeShareServerEndpoints(s todo.ShareService) ShareEndpoints {
+	return ShareEndpoints{
+		CreateShareEndpoint: MakeCreateShareEndpoint(s),
+		DeleteShareEndpoint: MakeDeleteShareEndpoint(s),
+		ListSharesEndpoint:  MakeListSharesEndpoint(s),
+	}
+}
+
+func MakeCreateShareEndpoint(s todo.ShareService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.CreateShareRequest)
+		response, err = s.CreateShare(ctx, req)
+		return
+	}
+}
+
+func MakeDeleteShareEndpoint(s todo.ShareService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.DeleteShareRequest)
+		err = s.DeleteShare(ctx, req)
+		return
+	}
+}
+
+func MakeListSharesEndpoint(s todo.ShareService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.ListSharesRequest)
+		response, err = s.ListShares(ctx, req)
+		return
+	}
+}
+
+// decodeCreateShareRequest reads the role from a body such as
+// {"role":"editor"}. The todo & principal are taken from the path.
+func decodeCreateShareRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.CreateShareRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, todo.Errorf(todo.EINVALID, "Failed to encode JSON body.")
+	}
+
+	if req.TodoID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+	req.PrincipalID = mux.Vars(r)["principalId"]
+
+	return req, nil
+}
+
+func decodeDeleteShareRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.DeleteShareRequest
+
+	if req.TodoID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+	req.PrincipalID = mux.Vars(r)["principalId"]
+
+	return req, nil
+}
+
+// decodeListSharesRequest lists the shares of the todo in the path, if any.
+func decodeListSharesRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.ListSharesRequest
+
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if req.TodoID, err = intVar(r, "id"); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
+// decodeCreateListShareRequest reads the role from a body such as
+// {"role":"editor"}. The list & principal are taken from the path.
+func decodeCreateListShareRequest(ctx context.Context, r *http.Request) (request interface{}, err error) {
+	if request, err = decodeCreateShareRequest(ctx, r); err != nil {
+		return nil, err
+	}
+	req := request.(todo.CreateShareRequest)
+	req.ListID, req.TodoID = req.TodoID, 0
+	return req, nil
+}
+
+func decodeDeleteListShareRequest(ctx context.Context, r *http.Request) (request interface{}, err error) {
+	if request, err = decodeDeleteShareRequest(ctx, r); err != nil {
+		return nil, err
+	}
+	req := request.(todo.DeleteShareRequest)
+	req.ListID, req.TodoID = req.TodoID, 0
+	return req, nil
+}
+
+func decodeListListSharesRequest(ctx context.Context, r *http.Request) (request interface{}, err error) {
+	if request, err = decodeListSharesRequest(ctx, r); err != nil {
+		return nil, err
+	}
+	req := request.(todo.ListSharesRequest)
+	req.ListID, req.TodoID = req.TodoID, 0
+	return req, nil
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+	"todo/auth"
+	"todo/authzmw"
+	"todo/inmem"
+)
+
+func TestServer_Shares(t *testing.T) {
+	keys := auth.NewKeyStore("")
+	aliceKey, _, err := keys.Issue("alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobKey, _, err := keys.Issue("bob", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := map[string]string{"Authorization": "Bearer " + aliceKey}
+	bob := map[string]string{"Authorization": "Bearer " + bobKey}
+
+	ts := MustOpenTestServer(t, func(s *Server) {
+		svc := inmem.NewService()
+		s.Authenticator = &auth.Authenticator{Keys: keys}
+		s.TodoService = authzmw.NewTodoAuthorizingMiddleware(svc)(svc)
+		s.ShareService = authzmw.NewShareAuthorizingMiddleware()(svc)
+		s.ListService = svc
+	})
+
+	var created todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, alice, &created)
+	path := "/api/todos/" + strconv.Itoa(created.ID)
+
+	if resp := mustDo(t, ts, "GET", path, "", bob); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	var sh todo.Share
+	if resp := mustDoJSON(t, ts, "PUT", path+"/shares/bob", `{"role":"viewer"}`, alice, &sh); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if sh.PrincipalID != "bob" || sh.Role != todo.RoleViewer {
+		t.Fatalf("unexpected share: %#v", sh)
+	}
+
+	var shares []*todo.Share
+	if mustDoJSON(t, ts, "GET", "/api/shares", "", bob, &shares); len(shares) != 1 || shares[0].TodoID != created.ID {
+		t.Fatalf("unexpected shares: %#v", shares)
+	}
+
+	if resp := mustDo(t, ts, "GET", path, "", bob); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if resp := mustDo(t, ts, "PATCH", path, `{"value":"b"}`, bob); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	// Sharing a list gives access to the todos in it.
+	var l todo.List
+	mustDoJSON(t, ts, "POST", "/api/lists", `{"name":"l"}`, alice, &l)
+	var b todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b","listId":`+strconv.Itoa(l.ID)+`}`, alice, &b)
+	listPath := "/api/lists/" + strconv.Itoa(l.ID)
+	if resp := mustDoJSON(t, ts, "PUT", listPath+"/shares/bob", `{"role":"editor"}`, alice, &sh); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if sh.ListID != l.ID || sh.Role != todo.RoleEditor {
+		t.Fatalf("unexpected share: %#v", sh)
+	}
+	if mustDoJSON(t, ts, "GET", listPath+"/shares", "", alice, &shares); len(shares) != 1 || shares[0].PrincipalID != "bob" {
+		t.Fatalf("unexpected shares: %#v", shares)
+	}
+	if resp := mustDo(t, ts, "PATCH", "/api/todos/"+strconv.Itoa(b.ID), `{"value":"c"}`, bob); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if resp := mustDo(t, ts, "DELETE", listPath+"/shares/bob", "", alice); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if resp := mustDo(t, ts, "GET", "/api/todos/"+strconv.Itoa(b.ID), "", bob); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	if resp := mustDo(t, ts, "DELETE", path+"/shares/bob", "", alice); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if resp := mustDo(t, ts, "GET", path, "", bob); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"net/http"
+	"todo"
+)
+
+func (s *Server) configureTagHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakeTagServerEndpoints(s.TagService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	s.router.Handle(
+		"/api/tags",
+		httptransport.NewServer(
+			e.ListTagsEndpoint,
+			decodeListTagsRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	s.router.Handle(
+		"/api/tags/merge",
+		httptransport.NewServer(
+			e.MergeTagsEndpoint,
+			decodeMergeTagsRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("POST")
+
+	s.router.Handle(
+		"/api/tags/{name}/rename",
+		httptransport.NewServer(
+			e.RenameTagEndpoint,
+			decodeRenameTagRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("POST")
+}
+
+type TagEndpoints struct {
+	ListTagsEndpoint  endpoint.Endpoint
+	RenameTagEndpoint endpoint.Endpoint
+	MergeTagsEndpoint endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e TagEndpoints) Wrap(mw endpoint.Middleware) TagEndpoints {
+	return TagEndpoints{
+		ListTagsEndpoint:  mw(e.ListTagsEndpoint),
+		RenameTagEndpoint: mw(e.RenameTagEndpoint),
+		MergeTagsEndpoint: mw(e.MergeTagsEndpoint),
+	}
+}
+
+// MakeTagServerEndpoints returns a TagEndpoints struct where each endpoint
+// invokes the corresponding method on the provided service.
+func MakeTagServerEndpoints(s todo.TagService) TagEndpoints {
+	return TagEndpoints{
+		ListTagsEndpoint:  MakeListTagsEndpoint(s),
+		RenameTagEndpoint: MakeRenameTagEndpoint(s),
+		MergeTagsEndpoint: MakeMergeTagsEndpoint(s),
+	}
+}
+
+func MakeListTagsEndpoint(s todo.TagService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.ListTagsRequest)
+		response, err = s.ListTags(ctx, req)
+		return
+	}
+}
+
+func MakeRenameTagEndpoint(s todo.TagService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.RenameTagRequest)
+		response, err = s.RenameTag(ctx, req)
+		return
+	}
+}
+
+func MakeMergeTagsEndpoint(s todo.TagService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.MergeTagsRequest)
+		response, err = s.MergeTags(ctx, req)
+		return
+	}
+}
+
+func decodeListTagsRequest(_ context.Context, _ *http.Request) (request interface{}, err error) {
+	return todo.ListTagsRequest{}, nil
+}
+
+// decodeRenameTagRequest reads the new name from a body such as
+// {"newName":"office"}. The tag to rename is taken from the path.
+func decodeRenameTagRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.RenameTagRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, todo.Errorf(todo.EINVALID, "Failed to encode JSON body.")
+	}
+	req.Name = mux.Vars(r)["name"]
+
+	return req, nil
+}
+
+// decodeMergeTagsRequest reads a body such as {"names":["job"],"into":"work"}.
+func decodeMergeTagsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.MergeTagsRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, todo.Errorf(todo.EINVALID, "Failed to encode JSON body.")
+	}
+
+	return req, nil
+}
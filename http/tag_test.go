@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"todo"
+)
+
+func TestServer_Tags(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var a todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a","tags":["#Job","urgent"]}`, nil, &a)
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b","tags":["office"]}`, nil, nil)
+	if !reflect.DeepEqual(a.Tags, []string{"job", "urgent"}) {
+		t.Fatalf("unexpected tags: %v", a.Tags)
+	}
+
+	var resp struct{ Todos []*todo.Todo }
+	if mustDoJSON(t, ts, "GET", "/api/todos?tags=job,urgent", "", nil, &resp); len(resp.Todos) != 1 || resp.Todos[0].ID != a.ID {
+		t.Fatalf("unexpected todos: %#v", resp.Todos)
+	} else if mustDoJSON(t, ts, "GET", "/api/todos?anyTags=job&anyTags=office", "", nil, &resp); len(resp.Todos) != 2 {
+		t.Fatalf("unexpected todos: %#v", resp.Todos)
+	} else if mustDoJSON(t, ts, "GET", "/api/todos?notTags=job", "", nil, &resp); len(resp.Todos) != 1 || resp.Todos[0].ID == a.ID {
+		t.Fatalf("unexpected todos: %#v", resp.Todos)
+	}
+
+	var tag todo.Tag
+	if r := mustDoJSON(t, ts, "POST", "/api/tags/job/rename", `{"newName":"work"}`, nil, &tag); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if tag != (todo.Tag{Name: "work", Count: 1}) {
+		t.Fatalf("unexpected tag: %#v", tag)
+	}
+	if r := mustDo(t, ts, "POST", "/api/tags/job/rename", `{"newName":"work"}`, nil); r.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusNotFound)
+	} else if r := mustDo(t, ts, "POST", "/api/tags/work/rename", `{"newName":"office"}`, nil); r.StatusCode != http.StatusConflict {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusConflict)
+	}
+
+	if r := mustDoJSON(t, ts, "POST", "/api/tags/merge", `{"names":["work","office"],"into":"work"}`, nil, &tag); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if tag != (todo.Tag{Name: "work", Count: 2}) {
+		t.Fatalf("unexpected tag: %#v", tag)
+	}
+
+	var tags []*todo.Tag
+	if mustDoJSON(t, ts, "GET", "/api/tags", "", nil, &tags); len(tags) != 2 || *tags[1] != (todo.Tag{Name: "work", Count: 2}) {
+		t.Fatalf("unexpected tags: %#v", tags)
+	}
+}
@@ -9,14 +9,57 @@ import (
 	"github.com/gorilla/mux"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"todo"
+	"todo/auth"
 )
 
 func (s *Server) configureHandlers() {
-	e := MakeServerEndpoints(s.TodoService)
+	var mw endpoint.Middleware
+	if s.Authenticator != nil {
+		mw = auth.NewEndpointMiddleware(s.Authenticator)
+	}
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorHandler(transport.NewLogErrorHandler(s.Logger)),
 		httptransport.ServerErrorEncoder(encodeError),
+		httptransport.ServerBefore(httptransport.PopulateRequestContext, populateConditionalHeaders),
+	}
+
+	if s.ShareService != nil {
+		s.configureShareHandlers(mw, options)
+	}
+	if s.ListService != nil {
+		s.configureListHandlers(mw, options)
+	}
+	if s.DependencyService != nil {
+		s.configureDependencyHandlers(mw, options)
+	}
+	if s.TagService != nil {
+		s.configureTagHandlers(mw, options)
+	}
+	if s.WorkflowService != nil {
+		s.configureWorkflowHandlers(mw, options)
+	}
+	if s.SearchService != nil {
+		s.configureSearchHandlers(mw, options)
+	}
+	if s.RevisionService != nil {
+		s.configureRevisionHandlers(mw, options)
+	}
+	if s.TrashService != nil {
+		s.configureTrashHandlers(mw, options)
+	}
+	if s.PolicyService != nil {
+		s.configurePolicyHandlers(mw, options)
+	}
+	if s.EventService != nil {
+		s.configureEventHandlers(mw, options)
+	}
+
+	e := MakeServerEndpoints(s.TodoService)
+	if mw != nil {
+		e = e.Wrap(mw)
 	}
 
 	s.router.Handle(
@@ -39,6 +82,16 @@ func (s *Server) configureHandlers() {
 		),
 	).Methods("PUT")
 
+	s.router.Handle(
+		"/api/todos/{id}",
+		httptransport.NewServer(
+			e.PatchTodoEndpoint,
+			decodePatchTodoRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("PATCH")
+
 	s.router.Handle(
 		"/api/todos/{id}",
 		httptransport.NewServer(
@@ -59,34 +112,109 @@ func (s *Server) configureHandlers() {
 		),
 	).Methods("GET")
 
+	s.router.Handle(
+		"/api/todos/{id}/complete",
+		httptransport.NewServer(
+			e.CompleteTodoEndpoint,
+			decodeCompleteTodoRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("POST")
+
+	s.router.Handle(
+		"/api/todos/{id}/move",
+		httptransport.NewServer(
+			e.MoveTodoEndpoint,
+			decodeMoveTodoRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("POST")
+
+	s.router.Handle(
+		"/api/todos:batch",
+		httptransport.NewServer(
+			e.BatchTodosEndpoint,
+			decodeBatchTodosRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("POST")
+
 	s.router.Handle(
 		"/api/todos",
 		httptransport.NewServer(
-			e.GetAllTodosEndpoint,
-			decodeGetAllTodosRequest,
+			e.ListTodosEndpoint,
+			decodeListTodosRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	s.router.Handle(
+		"/api/todos/{parentId}/children",
+		httptransport.NewServer(
+			e.ListTodosEndpoint,
+			decodeListTodosRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	s.router.Handle(
+		"/api/lists/{id}/todos",
+		httptransport.NewServer(
+			e.ListTodosEndpoint,
+			decodeListTodosRequest,
 			encodeResponse,
 			options...,
 		),
 	).Methods("GET")
+
+	s.configureMiddleware()
 }
 
 type TodoEndpoints struct {
-	CreateTodoEndpoint  endpoint.Endpoint
-	UpdateTodoEndpoint  endpoint.Endpoint
-	DeleteTodoEndpoint  endpoint.Endpoint
-	GetTodoByIDEndpoint endpoint.Endpoint
-	GetAllTodosEndpoint endpoint.Endpoint
+	CreateTodoEndpoint   endpoint.Endpoint
+	UpdateTodoEndpoint   endpoint.Endpoint
+	PatchTodoEndpoint    endpoint.Endpoint
+	DeleteTodoEndpoint   endpoint.Endpoint
+	GetTodoByIDEndpoint  endpoint.Endpoint
+	ListTodosEndpoint    endpoint.Endpoint
+	CompleteTodoEndpoint endpoint.Endpoint
+	MoveTodoEndpoint     endpoint.Endpoint
+	BatchTodosEndpoint   endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e TodoEndpoints) Wrap(mw endpoint.Middleware) TodoEndpoints {
+	return TodoEndpoints{
+		CreateTodoEndpoint:   mw(e.CreateTodoEndpoint),
+		UpdateTodoEndpoint:   mw(e.UpdateTodoEndpoint),
+		PatchTodoEndpoint:    mw(e.PatchTodoEndpoint),
+		DeleteTodoEndpoint:   mw(e.DeleteTodoEndpoint),
+		GetTodoByIDEndpoint:  mw(e.GetTodoByIDEndpoint),
+		ListTodosEndpoint:    mw(e.ListTodosEndpoint),
+		CompleteTodoEndpoint: mw(e.CompleteTodoEndpoint),
+		MoveTodoEndpoint:     mw(e.MoveTodoEndpoint),
+		BatchTodosEndpoint:   mw(e.BatchTodosEndpoint),
+	}
 }
 
 // MakeServerEndpoints returns an Endpoints struct where each endpoint invokes
 // the corresponding method on the provided service. Useful in a server.
 func MakeServerEndpoints(s todo.Service) TodoEndpoints {
 	return TodoEndpoints{
-		CreateTodoEndpoint:  MakeCreateTodoEndpoint(s),
-		UpdateTodoEndpoint:  MakeUpdateTodoEndpoint(s),
-		DeleteTodoEndpoint:  MakeDeleteTodoEndpoint(s),
-		GetTodoByIDEndpoint: MakeGetTodoByIDEndpoint(s),
-		GetAllTodosEndpoint: MakeGetAllTodosEndpoint(s),
+		CreateTodoEndpoint:   MakeCreateTodoEndpoint(s),
+		UpdateTodoEndpoint:   MakeUpdateTodoEndpoint(s),
+		PatchTodoEndpoint:    MakePatchTodoEndpoint(s),
+		DeleteTodoEndpoint:   MakeDeleteTodoEndpoint(s),
+		GetTodoByIDEndpoint:  MakeGetTodoByIDEndpoint(s),
+		ListTodosEndpoint:    MakeListTodosEndpoint(s),
+		CompleteTodoEndpoint: MakeCompleteTodoEndpoint(s),
+		MoveTodoEndpoint:     MakeMoveTodoEndpoint(s),
+		BatchTodosEndpoint:   MakeBatchTodosEndpoint(s),
 	}
 }
 
@@ -101,6 +229,11 @@ func MakeCreateTodoEndpoint(s todo.Service) endpoint.Endpoint {
 func MakeUpdateTodoEndpoint(s todo.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(todo.UpdateTodoRequest)
+		if version, err := checkIfMatch(ctx, s, req.ID); err != nil {
+			return nil, err
+		} else if version != 0 {
+			req.Version = version
+		}
 		response, err = s.UpdateTodo(ctx, req)
 		return
 	}
@@ -109,6 +242,11 @@ func MakeUpdateTodoEndpoint(s todo.Service) endpoint.Endpoint {
 func MakeDeleteTodoEndpoint(s todo.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(todo.DeleteTodoRequest)
+		if version, err := checkIfMatch(ctx, s, req.ID); err != nil {
+			return nil, err
+		} else if version != 0 {
+			req.Version = version
+		}
 		err = s.DeleteTodo(ctx, req)
 		return
 	}
@@ -122,9 +260,70 @@ func MakeGetTodoByIDEndpoint(s todo.Service) endpoint.Endpoint {
 	}
 }
 
-func MakeGetAllTodosEndpoint(s todo.Service) endpoint.Endpoint {
-	return func(ctx context.Context, _ interface{}) (response interface{}, err error) {
-		response, err = s.GetAllTodos(ctx)
+func MakeListTodosEndpoint(s todo.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(listTodosRequest)
+		if req.Tree {
+			return listTodoTree(ctx, s, req.ListTodosRequest)
+		}
+		response, err = s.ListTodos(ctx, req.ListTodosRequest)
+		return
+	}
+}
+
+// listTodosRequest wraps a todo.ListTodosRequest with the view the todos are
+// returned in.
+type listTodosRequest struct {
+	todo.ListTodosRequest
+
+	// If set, all matching todos are returned as trees of subtasks instead
+	// of a single page. At most todo.MaxListLimit todos can be listed so.
+	Tree bool
+}
+
+// todoTreeResponse represents all todos matching a filter arranged as trees.
+type todoTreeResponse struct {
+	Todos      []*todo.TodoNode `json:"todos"`
+	TotalCount int              `json:"totalCount"`
+}
+
+// listTodoTree fetches the todos matching request in a single page and
+// arranges them by their parent. Todos whose parent does not match become
+// roots. Returns EINVALID if more than todo.MaxListLimit todos match.
+func listTodoTree(ctx context.Context, s todo.Service, request todo.ListTodosRequest) (*todoTreeResponse, error) {
+	request.Limit, request.Cursor = todo.MaxListLimit, ""
+
+	resp, err := s.ListTodos(ctx, request)
+	if err != nil {
+		return nil, err
+	} else if resp.NextCursor != "" {
+		return nil, todo.Errorf(todo.EINVALID, "More than %d todos match; narrow the filter to list them as a tree.", todo.MaxListLimit)
+	}
+	return &todoTreeResponse{Todos: todo.BuildTree(resp.Todos), TotalCount: resp.TotalCount}, nil
+}
+
+func MakeCompleteTodoEndpoint(s todo.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.CompleteTodoRequest)
+		if version, err := checkIfMatch(ctx, s, req.ID); err != nil {
+			return nil, err
+		} else if version != 0 {
+			req.Version = version
+		}
+		response, err = s.CompleteTodo(ctx, req)
+		return
+	}
+}
+
+func MakeMoveTodoEndpoint(s todo.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.MoveTodoRequest)
+		if version, err := checkIfMatch(ctx, s, req.ID); err != nil {
+			return nil, err
+		} else if version != 0 {
+			req.Version = version
+		}
+		response, err = s.MoveTodo(ctx, req)
 		return
 	}
 }
@@ -157,6 +356,12 @@ func decodeUpdateTodoRequest(_ context.Context, r *http.Request) (request interf
 		return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", id)
 	}
 
+	// Changes to recurring todos apply to all future occurrences unless
+	// "?scope=this" is given.
+	if v := r.URL.Query().Get("scope"); v != "" {
+		req.Scope = v
+	}
+
 	return req, nil
 }
 
@@ -174,6 +379,14 @@ func decodeDeleteTodoRequest(_ context.Context, r *http.Request) (request interf
 		return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", id)
 	}
 
+	// Subtasks are moved up to the todo's parent unless "?cascade=true" is
+	// given.
+	if v := r.URL.Query().Get("cascade"); v != "" {
+		if req.Cascade, err = strconv.ParseBool(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type boolean.", v)
+		}
+	}
+
 	return req, nil
 }
 
@@ -194,6 +407,132 @@ func decodeGetTodoByIDRequest(_ context.Context, r *http.Request) (request inter
 	return req, nil
 }
 
-func decodeGetAllTodosRequest(_ context.Context, _ *http.Request) (request interface{}, err error) {
-	return nil, nil
+// decodeCompleteTodoRequest also completes blocked todos if the query string
+// contains "force=true".
+func decodeCompleteTodoRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.CompleteTodoRequest
+	if req.ID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+
+	if v := r.URL.Query().Get("force"); v != "" {
+		if req.Force, err = strconv.ParseBool(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type boolean.", v)
+		}
+	}
+
+	return req, nil
+}
+
+// decodeMoveTodoRequest reads the anchors from a body such as {"afterId":1},
+// {"beforeId":2} or both.
+func decodeMoveTodoRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.MoveTodoRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, todo.Errorf(todo.EINVALID, "Failed to encode JSON body.")
+	}
+	if req.ID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// decodeListTodosRequest maps query string parameters onto a ListTodosRequest,
+// e.g. "?complete=false&sort=-id&limit=50&cursor=...". A leading "-" on the
+// sort field sorts in descending order. Todos are limited to the list in the
+// path, if any, or to the list given by "listId", and likewise to the
+// subtasks of the parent in the path or given by "parentId". Todos must carry
+// all "tags", any of "anyTags" and none of "notTags", each a comma separated
+// list such as "work,#errand", and match "query", see todo.ParseQuery.
+// "?view=tree" returns all matching todos arranged by their parent, if there
+// are at most todo.MaxListLimit of them.
+func decodeListTodosRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req listTodosRequest
+	q := r.URL.Query()
+
+	if _, ok := mux.Vars(r)["id"]; ok {
+		if req.ListID, err = intVar(r, "id"); err != nil {
+			return nil, err
+		}
+	} else if v := q.Get("listId"); v != "" {
+		if req.ListID, err = strconv.Atoi(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+	}
+
+	if v := q.Get("complete"); v != "" {
+		complete, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type boolean.", v)
+		}
+		req.Complete = &complete
+	}
+
+	req.State = q.Get("state")
+	req.Contains = q.Get("contains")
+
+	if v := q.Get("dueBefore"); v != "" {
+		dueBefore, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type time.", v)
+		}
+		req.DueBefore = &dueBefore
+	}
+
+	if v := q.Get("overdue"); v != "" {
+		if req.Overdue, err = strconv.ParseBool(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type boolean.", v)
+		}
+	}
+
+	if v := q.Get("seriesId"); v != "" {
+		if req.SeriesID, err = strconv.Atoi(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+	}
+
+	if v := q.Get("archived"); v != "" {
+		if req.Archived, err = strconv.ParseBool(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type boolean.", v)
+		}
+	}
+
+	req.Tags, req.AnyTags, req.NotTags = listVar(q, "tags"), listVar(q, "anyTags"), listVar(q, "notTags")
+	req.Query = q.Get("query")
+
+	if _, ok := mux.Vars(r)["parentId"]; ok {
+		if req.ParentID, err = intVar(r, "parentId"); err != nil {
+			return nil, err
+		}
+	} else if v := q.Get("parentId"); v != "" {
+		if req.ParentID, err = strconv.Atoi(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+	}
+
+	switch v := q.Get("view"); v {
+	case "", "page":
+	case "tree":
+		req.Tree = true
+	default:
+		return nil, todo.Errorf(todo.EINVALID, "Invalid view '%s'.", v)
+	}
+
+	if v := q.Get("sort"); strings.HasPrefix(v, "-") {
+		req.SortBy, req.SortDirection = strings.TrimPrefix(v, "-"), todo.SortDesc
+	} else if v != "" {
+		req.SortBy, req.SortDirection = v, todo.SortAsc
+	}
+
+	if v := q.Get("limit"); v != "" {
+		if req.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type integer.", v)
+		}
+	}
+
+	req.Cursor = q.Get("cursor")
+
+	return req, nil
 }
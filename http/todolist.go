@@ -0,0 +1,195 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"strconv"
+	"todo"
+)
+
+func (s *Server) configureListHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakeListServerEndpoints(s.ListService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	s.router.Handle(
+		"/api/lists",
+		httptransport.NewServer(
+			e.CreateListEndpoint,
+			decodeCreateListRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("POST")
+
+	s.router.Handle(
+		"/api/lists/{id}",
+		httptransport.NewServer(
+			e.UpdateListEndpoint,
+			decodeUpdateListRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("PUT")
+
+	s.router.Handle(
+		"/api/lists/{id}",
+		httptransport.NewServer(
+			e.DeleteListEndpoint,
+			decodeDeleteListRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("DELETE")
+
+	s.router.Handle(
+		"/api/lists/{id}",
+		httptransport.NewServer(
+			e.GetListByIDEndpoint,
+			decodeGetListByIDRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	s.router.Handle(
+		"/api/lists",
+		httptransport.NewServer(
+			e.ListListsEndpoint,
+			decodeListListsRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+}
+
+type ListEndpoints struct {
+	CreateListEndpoint  endpoint.Endpoint
+	UpdateListEndpoint  endpoint.Endpoint
+	DeleteListEndpoint  endpoint.Endpoint
+	GetListByIDEndpoint endpoint.Endpoint
+	ListListsEndpoint   endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e ListEndpoints) Wrap(mw endpoint.Middleware) ListEndpoints {
+	return ListEndpoints{
+		CreateListEndpoint:  mw(e.CreateListEndpoint),
+		UpdateListEndpoint:  mw(e.UpdateListEndpoint),
+		DeleteListEndpoint:  mw(e.DeleteListEndpoint),
+		GetListByIDEndpoint: mw(e.GetListByIDEndpoint),
+		ListListsEndpoint:   mw(e.ListListsEndpoint),
+	}
+}
+
+// MakeListServerEndpoints returns a ListEndpoints struct where each endpoint
+// invokes the corresponding method on the provided service.
+func MakeListServerEndpoints(s todo.ListService) ListEndpoints {
+	return ListEndpoints{
+		CreateListEndpoint:  MakeCreateListEndpoint(s),
+		UpdateListEndpoint:  MakeUpdateListEndpoint(s),
+		DeleteListEndpoint:  MakeDeleteListEndpoint(s),
+		GetListByIDEndpoint: MakeGetListByIDEndpoint(s),
+		ListListsEndpoint:   MakeListListsEndpoint(s),
+	}
+}
+
+func MakeCreateListEndpoint(s todo.ListService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.CreateListRequest)
+		response, err = s.CreateList(ctx, req)
+		return
+	}
+}
+
+func MakeUpdateListEndpoint(s todo.ListService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.UpdateListRequest)
+		response, err = s.UpdateList(ctx, req)
+		return
+	}
+}
+
+func MakeDeleteListEndpoint(s todo.ListService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.DeleteListRequest)
+		err = s.DeleteList(ctx, req)
+		return
+	}
+}
+
+func MakeGetListByIDEndpoint(s todo.ListService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.GetListByIDRequest)
+		response, err = s.GetListByID(ctx, req)
+		return
+	}
+}
+
+func MakeListListsEndpoint(s todo.ListService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.ListListsRequest)
+		response, err = s.ListLists(ctx, req)
+		return
+	}
+}
+
+func decodeCreateListRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.CreateListRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, todo.Errorf(todo.EINVALID, "Failed to encode JSON body.")
+	}
+
+	return req, nil
+}
+
+func decodeUpdateListRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.UpdateListRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, todo.Errorf(todo.EINVALID, "Failed to encode JSON body.")
+	}
+
+	if req.ID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// decodeDeleteListRequest also deletes the todos in the list if the query
+// string contains "cascade=true".
+func decodeDeleteListRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.DeleteListRequest
+
+	if req.ID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+
+	if v := r.URL.Query().Get("cascade"); v != "" {
+		if req.Cascade, err = strconv.ParseBool(v); err != nil {
+			return nil, todo.Errorf(todo.EINVALID, "Failed to convert '%s' to type boolean.", v)
+		}
+	}
+
+	return req, nil
+}
+
+func decodeGetListByIDRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.GetListByIDRequest
+
+	if req.ID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func decodeListListsRequest(_ context.Context, _ *http.Request) (request interface{}, err error) {
+	return todo.ListListsRequest{}, nil
+}
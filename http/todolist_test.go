@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_Lists(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var l todo.List
+	if resp := mustDoJSON(t, ts, "POST", "/api/lists", `{"name":"work"}`, nil, &l); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	path := "/api/lists/" + strconv.Itoa(l.ID)
+
+	var created todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a","listId":`+strconv.Itoa(l.ID)+`}`, nil, &created)
+	mustDo(t, ts, "POST", "/api/todos", `{"value":"b"}`, nil)
+
+	var resp todo.ListTodosResponse
+	if mustDoJSON(t, ts, "GET", path+"/todos", "", nil, &resp); len(resp.Todos) != 1 || resp.Todos[0].ID != created.ID {
+		t.Fatalf("unexpected todos: %#v", resp.Todos)
+	}
+
+	if resp := mustDo(t, ts, "DELETE", path, "", nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusConflict)
+	} else if resp := mustDo(t, ts, "DELETE", path+"?cascade=true", "", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if resp := mustDo(t, ts, "GET", path+"/todos", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
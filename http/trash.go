@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"todo"
+)
+
+func (s *Server) configureTrashHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakeTrashServerEndpoints(s.TrashService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	s.router.Handle(
+		"/api/trash",
+		httptransport.NewServer(
+			e.ListTrashEndpoint,
+			decodeListTrashRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	s.router.Handle(
+		"/api/todos/{id}/restore",
+		httptransport.NewServer(
+			e.RestoreTodoEndpoint,
+			decodeRestoreTodoRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("POST")
+}
+
+type TrashEndpoints struct {
+	ListTrashEndpoint   endpoint.Endpoint
+	RestoreTodoEndpoint endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e TrashEndpoints) Wrap(mw endpoint.Middleware) TrashEndpoints {
+	return TrashEndpoints{
+		ListTrashEndpoint:   mw(e.ListTrashEndpoint),
+		RestoreTodoEndpoint: mw(e.RestoreTodoEndpoint),
+	}
+}
+
+// MakeTrashServerEndpoints returns a TrashEndpoints struct where each endpoint
+// invokes the corresponding method on the provided service.
+func MakeTrashServerEndpoints(s todo.TrashService) TrashEndpoints {
+	return TrashEndpoints{
+		ListTrashEndpoint:   MakeListTrashEndpoint(s),
+		RestoreTodoEndpoint: MakeRestoreTodoEndpoint(s),
+	}
+}
+
+func MakeListTrashEndpoint(s todo.TrashService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.ListTrashRequest)
+		response, err = s.ListTrash(ctx, req)
+		return
+	}
+}
+
+func MakeRestoreTodoEndpoint(s todo.TrashService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.RestoreTodoRequest)
+		response, err = s.RestoreTodo(ctx, req)
+		return
+	}
+}
+
+func decodeListTrashRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	return todo.ListTrashRequest{}, nil
+}
+
+func decodeRestoreTodoRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.RestoreTodoRequest
+
+	if req.ID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_Trash(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var a, b todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, nil, &a)
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"b","parentId":`+strconv.Itoa(a.ID)+`}`, nil, &b)
+	path := "/api/todos/" + strconv.Itoa(a.ID)
+	mustDo(t, ts, "DELETE", path+"?cascade=true", "", nil)
+
+	var trash []*todo.Todo
+	if r := mustDoJSON(t, ts, "GET", "/api/trash", "", nil, &trash); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if len(trash) != 2 || trash[0].DeletedAt == nil {
+		t.Fatalf("unexpected todos: %#v", trash)
+	} else if r := mustDo(t, ts, "GET", path, "", nil); r.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusNotFound)
+	}
+
+	// Restoring a todo also restores the subtasks deleted with it.
+	var restored todo.Todo
+	if r := mustDoJSON(t, ts, "POST", path+"/restore", "", nil, &restored); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if restored.ID != a.ID || restored.DeletedAt != nil {
+		t.Fatalf("unexpected todo: %#v", restored)
+	} else if r := mustDo(t, ts, "GET", "/api/todos/"+strconv.Itoa(b.ID), "", nil); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	}
+	if mustDoJSON(t, ts, "GET", "/api/trash", "", nil, &trash); len(trash) != 0 {
+		t.Fatalf("unexpected todos: %#v", trash)
+	}
+
+	if r := mustDo(t, ts, "POST", path+"/restore", "", nil); r.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusNotFound)
+	}
+}
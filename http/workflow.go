@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"net/http"
+	"todo"
+)
+
+func (s *Server) configureWorkflowHandlers(mw endpoint.Middleware, options []httptransport.ServerOption) {
+	e := MakeWorkflowServerEndpoints(s.WorkflowService)
+	if mw != nil {
+		e = e.Wrap(mw)
+	}
+
+	s.router.Handle(
+		"/api/workflow",
+		httptransport.NewServer(
+			e.GetWorkflowEndpoint,
+			decodeGetWorkflowRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+
+	s.router.Handle(
+		"/api/todos/{id}/transitions",
+		httptransport.NewServer(
+			e.ListTransitionsEndpoint,
+			decodeListTransitionsRequest,
+			encodeResponse,
+			options...,
+		),
+	).Methods("GET")
+}
+
+type WorkflowEndpoints struct {
+	GetWorkflowEndpoint     endpoint.Endpoint
+	ListTransitionsEndpoint endpoint.Endpoint
+}
+
+// Wrap returns a copy of e with every endpoint wrapped by mw.
+func (e WorkflowEndpoints) Wrap(mw endpoint.Middleware) WorkflowEndpoints {
+	return WorkflowEndpoints{
+		GetWorkflowEndpoint:     mw(e.GetWorkflowEndpoint),
+		ListTransitionsEndpoint: mw(e.ListTransitionsEndpoint),
+	}
+}
+
+// MakeWorkflowServerEndpoints returns a WorkflowEndpoints struct where each
+// endpoint invokes the corresponding method on the provided service.
+func MakeWorkflowServerEndpoints(s todo.WorkflowService) WorkflowEndpoints {
+	return WorkflowEndpoints{
+		GetWorkflowEndpoint:     MakeGetWorkflowEndpoint(s),
+		ListTransitionsEndpoint: MakeListTransitionsEndpoint(s),
+	}
+}
+
+func MakeGetWorkflowEndpoint(s todo.WorkflowService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.GetWorkflowRequest)
+		response, err = s.GetWorkflow(ctx, req)
+		return
+	}
+}
+
+func MakeListTransitionsEndpoint(s todo.WorkflowService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(todo.ListTransitionsRequest)
+		response, err = s.ListTransitions(ctx, req)
+		return
+	}
+}
+
+func decodeGetWorkflowRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	return todo.GetWorkflowRequest{}, nil
+}
+
+func decodeListTransitionsRequest(_ context.Context, r *http.Request) (request interface{}, err error) {
+	var req todo.ListTransitionsRequest
+
+	if req.TodoID, err = intVar(r, "id"); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
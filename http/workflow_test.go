@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"todo"
+)
+
+func TestServer_Workflow(t *testing.T) {
+	ts := MustOpenTestServer(t)
+
+	var w todo.Workflow
+	if resp := mustDoJSON(t, ts, "GET", "/api/workflow", "", nil, &w); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if len(w.States) != len(todo.DefaultWorkflow.States) {
+		t.Fatalf("unexpected workflow: %#v", w)
+	}
+
+	var a todo.Todo
+	mustDoJSON(t, ts, "POST", "/api/todos", `{"value":"a"}`, nil, &a)
+	if a.State != todo.StateBacklog {
+		t.Fatalf("unexpected state: %q", a.State)
+	}
+
+	path := "/api/todos/" + strconv.Itoa(a.ID)
+	var patched todo.Todo
+	if resp := mustDoJSON(t, ts, "PATCH", path, `{"state":"in-progress"}`, map[string]string{"Content-Type": "application/merge-patch+json"}, &patched); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", resp.StatusCode, http.StatusOK)
+	} else if patched.State != todo.StateInProgress || patched.StateChangedAt == nil {
+		t.Fatalf("unexpected todo: %#v", patched)
+	}
+	if r := mustDo(t, ts, "PATCH", path, `{"state":"unknown"}`, map[string]string{"Content-Type": "application/merge-patch+json"}); r.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusBadRequest)
+	}
+
+	var resp struct{ Todos []*todo.Todo }
+	if mustDoJSON(t, ts, "GET", "/api/todos?state=in-progress", "", nil, &resp); len(resp.Todos) != 1 || resp.Todos[0].ID != a.ID {
+		t.Fatalf("unexpected todos: %#v", resp.Todos)
+	}
+
+	var trans []*todo.Transition
+	if r := mustDoJSON(t, ts, "GET", path+"/transitions", "", nil, &trans); r.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusOK)
+	} else if len(trans) != 2 || trans[1].From != todo.StateBacklog || trans[1].To != todo.StateInProgress {
+		t.Fatalf("unexpected transitions: %#v", trans)
+	}
+	if r := mustDo(t, ts, "GET", "/api/todos/0/transitions", "", nil); r.StatusCode != http.StatusNotFound {
+		t.Fatalf("status=%d, want %d", r.StatusCode, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,37 @@
+package todo
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+)
+
+// ID layout. IDs are positive integers no larger than 2^53 so they survive
+// JSON number handling in JavaScript clients.
+const (
+	// Number of random low bits of an ID.
+	idRandomBits = 12
+
+	// Start of the millisecond timestamp stored in the high bits of an ID.
+	idEpoch = 1577836800000 // 2020-01-01T00:00:00Z
+)
+
+// NextID returns a new opaque ID greater than last. IDs are made of the
+// creation time in milliseconds followed by random bits, so they are ordered
+// by creation but reveal neither how many todos exist nor which IDs belong to
+// other owners. An ID is never reused as long as callers pass the largest ID
+// they have ever issued as last.
+func NextID(last int, now time.Time) int {
+	id := int(now.UnixNano()/int64(time.Millisecond)-idEpoch)<<idRandomBits | randomIDBits()
+	if id <= last {
+		id = last + 1 + randomIDBits()
+	}
+	return id
+}
+
+// randomIDBits returns a random number in [0, 2^idRandomBits).
+func randomIDBits() int {
+	var b [2]byte
+	_, _ = rand.Read(b[:])
+	return int(binary.BigEndian.Uint16(b[:])) & (1<<idRandomBits - 1)
+}
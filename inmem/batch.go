@@ -0,0 +1,69 @@
+package inmem
+
+import (
+	"context"
+	"todo"
+)
+
+// BatchTodos applies the operations of the batch while holding the lock, so no
+// other change is interleaved with them. An atomic batch is logged as a single
+// record once every operation succeeded. If an operation fails, the records
+// applied so far are reverted, see rollback.
+func (s *Service) BatchTodos(ctx context.Context, request todo.BatchTodosRequest) (*todo.BatchTodosResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &todo.BatchTodosResponse{Results: make([]*todo.BatchResult, 0, len(request.Ops))}
+	if request.Mode == todo.BatchBestEffort {
+		for _, op := range request.Ops {
+			t, err := s.applyOp(ctx, op)
+			resp.Results = append(resp.Results, &todo.BatchResult{Todo: t, Err: err})
+		}
+		return resp, nil
+	}
+
+	s.staged = make([]*record, 0, len(request.Ops))
+	defer func() { s.staged, s.undo = nil, nil }()
+
+	for i, op := range request.Ops {
+		t, err := s.applyOp(ctx, op)
+		if err != nil {
+			s.rollback()
+			return nil, todo.BatchOpError(i, err)
+		}
+		resp.Results = append(resp.Results, &todo.BatchResult{Todo: t})
+	}
+
+	rec := &record{Op: opBatch, Records: s.staged}
+	if s.wal != nil {
+		if err := s.wal.append(rec); err != nil {
+			s.rollback()
+			return nil, err
+		}
+		if s.SnapshotThreshold > 0 && s.wal.n >= s.SnapshotThreshold {
+			_ = s.wal.compact(s.snapshot())
+		}
+	}
+	return resp, nil
+}
+
+// applyOp applies a single operation of a batch and returns the todo it
+// created or changed, if any. Must be called with s.mu held.
+func (s *Service) applyOp(ctx context.Context, op todo.BatchOp) (*todo.Todo, error) {
+	switch op.Op {
+	case todo.BatchCreate:
+		return s.create(ctx, *op.Create)
+	case todo.BatchUpdate:
+		return s.update(ctx, *op.Update)
+	case todo.BatchPatch:
+		return s.patch(ctx, *op.Patch)
+	default:
+		return nil, s.delete(ctx, *op.Delete)
+	}
+}
@@ -0,0 +1,198 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"todo"
+)
+
+func (s *Service) CreateDependency(ctx context.Context, request todo.CreateDependencyRequest) (*todo.Dependency, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.lookup(ctx, request.TodoID); err != nil {
+		return nil, err
+	} else if _, err := s.lookup(ctx, request.BlockedByID); err != nil {
+		return nil, todo.Errorf(todo.EINVALID, "Blocking todo with ID '%d' does not exist.", request.BlockedByID)
+	}
+	if i := s.indexOfDependency(request.TodoID, request.BlockedByID); i != -1 {
+		return copyDependency(s.deps[i]), nil
+	}
+
+	// The new dependency closes a cycle if the blocking todo already waits
+	// on the blocked todo, directly or through other todos.
+	if s.dependsOn(request.BlockedByID, request.TodoID) {
+		return nil, todo.Errorf(todo.EINVALID, "Todo with ID '%d' cannot be blocked by todo with ID '%d' as it would create a cycle.", request.TodoID, request.BlockedByID)
+	}
+
+	d := &todo.Dependency{
+		TodoID:      request.TodoID,
+		BlockedByID: request.BlockedByID,
+		CreatedAt:   s.Now().UTC(),
+	}
+	if err := s.commit(&record{Op: opPutDependency, Dependency: d}); err != nil {
+		return nil, err
+	}
+
+	return copyDependency(d), nil
+}
+
+func (s *Service) DeleteDependency(ctx context.Context, request todo.DeleteDependencyRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.lookup(ctx, request.TodoID); err != nil {
+		return err
+	} else if s.indexOfDependency(request.TodoID, request.BlockedByID) == -1 {
+		return todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' is not blocked by todo with ID '%d'.", request.TodoID, request.BlockedByID)
+	}
+
+	return s.commit(&record{Op: opDeleteDependency, Dependency: &todo.Dependency{
+		TodoID:      request.TodoID,
+		BlockedByID: request.BlockedByID,
+	}})
+}
+
+func (s *Service) ListDependencies(ctx context.Context, request todo.ListDependenciesRequest) ([]*todo.Dependency, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Both todos of a dependency have the same owner, so checking the
+	// blocked todo is enough.
+	match := func(d *todo.Dependency) bool {
+		_, err := s.lookup(ctx, d.TodoID)
+		return err == nil
+	}
+	if request.TodoID != 0 {
+		if _, err := s.lookup(ctx, request.TodoID); err != nil {
+			return nil, err
+		}
+		match = func(d *todo.Dependency) bool { return d.TodoID == request.TodoID || d.BlockedByID == request.TodoID }
+	}
+
+	deps := make([]*todo.Dependency, 0)
+	for _, d := range s.deps {
+		if match(d) {
+			deps = append(deps, copyDependency(d))
+		}
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].TodoID != deps[j].TodoID {
+			return deps[i].TodoID < deps[j].TodoID
+		}
+		return deps[i].BlockedByID < deps[j].BlockedByID
+	})
+	return deps, nil
+}
+
+func (s *Service) ListActionableTodos(ctx context.Context, request todo.ListActionableTodosRequest) ([]*todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Normalize(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if request.ListID != 0 {
+		if _, err := s.lookupList(ctx, request.ListID); err != nil {
+			return nil, err
+		}
+	}
+
+	ownerID := todo.OwnerIDFromContext(ctx)
+	todos := make([]*todo.Todo, 0)
+	for _, t := range s.todos {
+		if t.OwnerID == ownerID {
+			todos = append(todos, copyTodo(t))
+		}
+	}
+	return todo.ActionableTodos(todos, s.deps, request), nil
+}
+
+// view returns a copy of t with its computed fields set.
+// Must be called with s.mu held.
+func (s *Service) view(t *todo.Todo) *todo.Todo {
+	other := copyTodo(t)
+	other.Blocked = s.blocked(t.ID)
+	return other
+}
+
+// blocked returns true if the todo with the given ID is blocked by an open
+// todo. Must be called with s.mu held.
+func (s *Service) blocked(id int) bool {
+	for _, d := range s.deps {
+		if d.TodoID != id {
+			continue
+		} else if i, err := s.indexOf(d.BlockedByID); err == nil && !s.todos[i].Complete {
+			return true
+		}
+	}
+	return false
+}
+
+// dependsOn returns true if the todo with the given ID is blocked by the todo
+// with the ID other, directly or transitively. Must be called with s.mu held.
+func (s *Service) dependsOn(id, other int) bool {
+	seen := map[int]bool{id: true}
+	for queue := []int{id}; len(queue) > 0; queue = queue[1:] {
+		for _, d := range s.deps {
+			if d.TodoID != queue[0] || seen[d.BlockedByID] {
+				continue
+			} else if d.BlockedByID == other {
+				return true
+			}
+			seen[d.BlockedByID] = true
+			queue = append(queue, d.BlockedByID)
+		}
+	}
+	return false
+}
+
+// indexOfDependency returns the position of the dependency between two todos
+// in s.deps, or -1 if there is none. Must be called with s.mu held.
+func (s *Service) indexOfDependency(todoID, blockedByID int) int {
+	for i, d := range s.deps {
+		if d.TodoID == todoID && d.BlockedByID == blockedByID {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeDependencies removes every dependency fn returns true for.
+// Must be called with s.mu held.
+func (s *Service) removeDependencies(fn func(d *todo.Dependency) bool) {
+	if s.staged != nil {
+		prev := append([]*todo.Dependency(nil), s.deps...)
+		s.onUndo(func() { s.deps = prev })
+	}
+	deps := s.deps[:0]
+	for _, d := range s.deps {
+		if !fn(d) {
+			deps = append(deps, d)
+		}
+	}
+	s.deps = deps
+}
+
+// copyDependency returns a copy of d so callers never share memory with the store.
+func copyDependency(d *todo.Dependency) *todo.Dependency {
+	other := *d
+	return &other
+}
@@ -0,0 +1,47 @@
+package inmem
+
+import (
+	"context"
+	"todo"
+)
+
+// checkParentID returns EINVALID if the parent of t is not one of the caller's
+// todos or is t itself or one of its subtasks. Must be called with s.mu held.
+func (s *Service) checkParentID(ctx context.Context, t *todo.Todo) error {
+	for id := t.ParentID; id != 0; {
+		if id == t.ID {
+			return todo.Errorf(todo.EINVALID, "Todo with ID '%d' cannot be a subtask of itself.", t.ID)
+		}
+
+		i, err := s.lookup(ctx, id)
+		if err != nil {
+			return todo.Errorf(todo.EINVALID, "Parent todo with ID '%d' does not exist.", t.ParentID)
+		}
+		id = s.todos[i].ParentID
+	}
+	return nil
+}
+
+// childrenOf returns the direct subtasks of the todo with the given ID.
+// Must be called with s.mu held.
+func (s *Service) childrenOf(id int) []*todo.Todo {
+	var children []*todo.Todo
+	for _, t := range s.todos {
+		if t.ParentID == id {
+			children = append(children, t)
+		}
+	}
+	return children
+}
+
+// openChildren returns the number of incomplete subtasks of the todo with the
+// given ID. Must be called with s.mu held.
+func (s *Service) openChildren(id int) int {
+	var n int
+	for _, t := range s.childrenOf(id) {
+		if !t.Complete {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.PolicyService = (*Service)(nil)
+var _ todo.PolicyEnforcer = (*Service)(nil)
+
+func (s *Service) ReportPolicies(ctx context.Context, request todo.ReportPoliciesRequest) (*todo.PolicyReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if request.ListID != 0 {
+		if _, err := s.lookupList(ctx, request.ListID); err != nil {
+			return nil, err
+		}
+	}
+
+	ownerID := todo.OwnerIDFromContext(ctx)
+	archive, purge := s.policyTodos(s.Now(), -1, func(l *todo.List) bool {
+		return l.OwnerID == ownerID && (request.ListID == 0 || l.ID == request.ListID)
+	})
+
+	report := &todo.PolicyReport{Archived: make([]*todo.Todo, 0), Purged: make([]*todo.Todo, 0)}
+	for _, t := range archive {
+		report.Archived = append(report.Archived, s.view(t))
+	}
+	for _, t := range purge {
+		report.Purged = append(report.Purged, s.view(t))
+	}
+	return report, nil
+}
+
+// EnforcePolicies archives and purges the todos the policies of their list
+// apply to. Every change is logged before it is returned. If an error occurs,
+// the todos changed so far are returned with it.
+func (s *Service) EnforcePolicies(ctx context.Context, now time.Time, limit int) (*todo.PolicyReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archive, purge := s.policyTodos(now, limit, func(*todo.List) bool { return true })
+
+	report := &todo.PolicyReport{Archived: make([]*todo.Todo, 0), Purged: make([]*todo.Todo, 0)}
+	for _, t := range purge {
+		v := s.view(t)
+		if err := s.commit(&record{Op: opDelete, ID: t.ID, Trash: s.trashTodo(t.ID, false)}); err != nil {
+			return report, err
+		}
+		report.Purged = append(report.Purged, v)
+	}
+
+	archivedAt := now.UTC().Truncate(time.Second)
+	for _, t := range archive {
+		t = copyTodo(t)
+		t.ArchivedAt = &archivedAt
+		t.Version++
+		if err := s.commit(&record{Op: opPut, Todo: t}); err != nil {
+			return report, err
+		}
+		report.Archived = append(report.Archived, s.view(t))
+	}
+	return report, nil
+}
+
+// policyTodos returns the todos to archive and to purge at now under the
+// policies of the lists matched by fn, ordered by ID. Todos to purge are found
+// first, and no more than limit todos are returned in total unless limit is
+// negative. Must be called with s.mu held.
+func (s *Service) policyTodos(now time.Time, limit int, fn func(l *todo.List) bool) (archive, purge []*todo.Todo) {
+	for _, action := range []string{todo.PolicyPurge, todo.PolicyArchive} {
+		policies := make(map[int]todo.Policy)
+		for _, l := range s.lists {
+			for _, p := range l.Policies {
+				if p.Action == action && fn(l) {
+					policies[l.ID] = p
+				}
+			}
+		}
+
+		var todos []*todo.Todo
+		for _, t := range s.todos {
+			if p, ok := policies[t.ListID]; ok && p.Match(t, now) {
+				todos = append(todos, t)
+			}
+		}
+		sort.Slice(todos, func(i, j int) bool { return todos[i].ID < todos[j].ID })
+		if n := limit - len(archive) - len(purge); limit >= 0 && len(todos) > n {
+			todos = todos[:n]
+		}
+
+		if action == todo.PolicyPurge {
+			purge = todos
+		} else {
+			archive = todos
+		}
+	}
+	return archive, purge
+}
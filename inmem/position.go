@@ -0,0 +1,103 @@
+package inmem
+
+import (
+	"context"
+	"todo"
+)
+
+func (s *Service) MoveTodo(ctx context.Context, request todo.MoveTodoRequest) (*todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.lookup(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	} else if err := s.todos[i].CheckVersion(request.Version); err != nil {
+		return nil, err
+	}
+
+	after, err := s.anchor(ctx, request.AfterID)
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.anchor(ctx, request.BeforeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// With a single anchor, the todo goes between the anchor and its
+	// neighbour on the other side.
+	t := copyTodo(s.todos[i])
+	if before == nil {
+		before = s.neighbour(after, t.ID, false)
+	} else if after == nil {
+		after = s.neighbour(before, t.ID, true)
+	}
+	if err := t.PlaceBetween(after, before); err != nil {
+		return nil, err
+	}
+	t.Version++
+
+	if err := s.commit(&record{Op: opPut, Todo: t}); err != nil {
+		return nil, err
+	}
+	return s.view(s.todos[i]), nil
+}
+
+// anchor returns the caller's todo a todo is moved next to, or nil if id is
+// zero. Returns EINVALID if the todo does not exist. Must be called with s.mu
+// held.
+func (s *Service) anchor(ctx context.Context, id int) (*todo.Todo, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	i, err := s.lookup(ctx, id)
+	if todo.ErrorCode(err) == todo.ENOTFOUND {
+		return nil, todo.Errorf(todo.EINVALID, "Todo with ID '%d' does not exist.", id)
+	} else if err != nil {
+		return nil, err
+	}
+	return s.todos[i], nil
+}
+
+// neighbour returns the todo of the same owner ordered right after t, or right
+// before it if previous is set. The todo with ID skip is ignored. Returns nil
+// if there is none. Must be called with s.mu held.
+func (s *Service) neighbour(t *todo.Todo, skip int, previous bool) *todo.Todo {
+	var other *todo.Todo
+	for _, u := range s.todos {
+		if u.OwnerID != t.OwnerID || u.ID == skip {
+			continue
+		}
+		if previous && u.Position < t.Position && (other == nil || u.Position > other.Position) {
+			other = u
+		} else if !previous && u.Position > t.Position && (other == nil || u.Position < other.Position) {
+			other = u
+		}
+	}
+	return other
+}
+
+// placeLast positions t after all other todos of its owner, including the
+// given todos which are not committed yet. Must be called with s.mu held.
+func (s *Service) placeLast(t *todo.Todo, pending ...*todo.Todo) error {
+	last := s.last[t.OwnerID]
+	for _, other := range pending {
+		if other.Position > last {
+			last = other.Position
+		}
+	}
+
+	p, err := todo.PositionBetween(last, "")
+	if err != nil {
+		return err
+	}
+	t.Position = p
+	return nil
+}
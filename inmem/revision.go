@@ -0,0 +1,126 @@
+package inmem
+
+import (
+	"context"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.RevisionStore = (*Service)(nil)
+
+func (s *Service) CreateRevision(ctx context.Context, r *todo.Revision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.ID = todo.NextID(s.nextRevID-1, s.Now())
+	r.CreatedAt = s.Now().UTC().Truncate(time.Second)
+	return s.commit(&record{Op: opPutRevision, Revision: r})
+}
+
+func (s *Service) FindRevisions(ctx context.Context, todoID int) ([]*todo.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Revisions are appended as they are created, so they are already in
+	// order.
+	revs := make([]*todo.Revision, 0)
+	for _, r := range s.revs {
+		if r.TodoID == todoID {
+			revs = append(revs, copyRevision(r))
+		}
+	}
+	return revs, nil
+}
+
+func (s *Service) PruneRevisions(ctx context.Context, todoID int, policy todo.RetentionPolicy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The revisions to delete are logged rather than the policy, so the log
+	// replays the same way at any time.
+	var n int
+	for _, r := range s.revs {
+		if r.TodoID == todoID {
+			n++
+		}
+	}
+	cutoff := s.Now().UTC().Truncate(time.Second).Add(-policy.MaxAge)
+	var ids []int
+	for _, r := range s.revs {
+		if policy.MaxRevisions > 0 && r.TodoID == todoID && n > policy.MaxRevisions {
+			ids = append(ids, r.ID)
+			n--
+		} else if policy.MaxAge > 0 && r.TodoID == todoID && r.CreatedAt.Before(cutoff) {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.commit(&record{Op: opDeleteRevisions, IDs: ids})
+}
+
+func (s *Service) ExpireRevisions(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Revisions are in order of creation, so the oldest come first.
+	var ids []int
+	for _, r := range s.revs {
+		if len(ids) == limit {
+			break
+		} else if r.CreatedAt.Before(createdBefore) {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return len(ids), s.commit(&record{Op: opDeleteRevisions, IDs: ids})
+}
+
+// removeRevisions removes the revisions with the given IDs.
+// Must be called with s.mu held.
+func (s *Service) removeRevisions(ids []int) {
+	remove := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	revs := s.revs[:0]
+	for _, r := range s.revs {
+		if !remove[r.ID] {
+			revs = append(revs, r)
+		}
+	}
+	s.revs = revs
+}
+
+// copyRevision returns a copy of r so callers never share memory with the
+// store.
+func copyRevision(r *todo.Revision) *todo.Revision {
+	other := *r
+	other.Todo = copyTodo(r.Todo)
+	other.Changes = make([]*todo.Change, len(r.Changes))
+	for i, c := range r.Changes {
+		change := *c
+		other.Changes[i] = &change
+	}
+	return &other
+}
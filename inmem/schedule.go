@@ -0,0 +1,46 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"time"
+	"todo"
+)
+
+// ClaimDueReminders marks due reminders as sent and returns them, oldest first.
+// The claim is logged before it is returned so reminders are not sent again
+// after a restart.
+func (s *Service) ClaimDueReminders(ctx context.Context, now time.Time, limit int) ([]*todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*todo.Todo
+	for _, t := range s.todos {
+		if !t.Complete && t.ArchivedAt == nil && t.RemindAt != nil && t.RemindedAt == nil && !t.RemindAt.After(now) {
+			due = append(due, t)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].RemindAt.Before(*due[j].RemindAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*todo.Todo, 0, len(due))
+	remindedAt := now.UTC().Truncate(time.Second)
+	for _, t := range due {
+		// The claim changes the representation of the todo, so its
+		// version is bumped and stale updates cannot clear the claim.
+		t = copyTodo(t)
+		t.RemindedAt = &remindedAt
+		t.Version++
+		if err := s.commit(&record{Op: opPut, Todo: t}); err != nil {
+			return claimed, err
+		}
+		claimed = append(claimed, s.view(t))
+	}
+	return claimed, nil
+}
@@ -0,0 +1,148 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"todo"
+)
+
+func (s *Service) SearchTodos(ctx context.Context, request todo.SearchTodosRequest) (*todo.SearchTodosResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Normalize(); err != nil {
+		return nil, err
+	}
+	query, err := todo.ParseSearchQuery(request.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Like the SQLite backend, todos are scored against the words of all
+	// todos rather than only those of the caller.
+	hits := make([]map[int]int, len(query.Terms))
+	for i, term := range query.Terms {
+		hits[i] = s.words.hits(term)
+	}
+	n := len(s.todos)
+	avg := float64(s.words.length) / float64(n)
+
+	ownerID := todo.OwnerIDFromContext(ctx)
+	resp := &todo.SearchTodosResponse{Results: make([]*todo.SearchResult, 0)}
+matches:
+	for id := range hits[0] {
+		t := s.todos[s.offsets[id]]
+		if t.OwnerID != ownerID {
+			continue
+		}
+
+		var score float64
+		for i := range query.Terms {
+			tf := hits[i][id]
+			if tf == 0 {
+				continue matches
+			}
+			score += todo.BM25(tf, len(hits[i]), n, float64(s.words.lengths[id]), avg)
+		}
+		resp.Results = append(resp.Results, &todo.SearchResult{Todo: t, Score: score})
+	}
+
+	resp.TotalCount = len(resp.Results)
+	todo.SortSearchResults(resp.Results)
+	if len(resp.Results) > request.Limit {
+		resp.Results = resp.Results[:request.Limit]
+	}
+	for _, r := range resp.Results {
+		r.Snippet = query.Snippet(r.Todo.Value)
+		r.Todo = s.view(r.Todo)
+	}
+	return resp, nil
+}
+
+// searchIndex is an inverted index of the words of todos.
+type searchIndex struct {
+	// Positions of each stem in the todos containing it, by todo ID.
+	terms map[string]map[int][]int
+
+	// Number of words of each todo by ID, and of all todos.
+	lengths map[int]int
+	length  int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		terms:   make(map[string]map[int][]int),
+		lengths: make(map[int]int),
+	}
+}
+
+// add adds the words of t to the index.
+func (x *searchIndex) add(t *todo.Todo) {
+	tokens := todo.Tokenize(t.Value)
+	for i, tok := range tokens {
+		if x.terms[tok.Term] == nil {
+			x.terms[tok.Term] = make(map[int][]int)
+		}
+		x.terms[tok.Term][t.ID] = append(x.terms[tok.Term][t.ID], i)
+	}
+	x.lengths[t.ID] = len(tokens)
+	x.length += len(tokens)
+}
+
+// remove removes the words of t from the index.
+func (x *searchIndex) remove(t *todo.Todo) {
+	for _, tok := range todo.Tokenize(t.Value) {
+		if delete(x.terms[tok.Term], t.ID); len(x.terms[tok.Term]) == 0 {
+			delete(x.terms, tok.Term)
+		}
+	}
+	x.length -= x.lengths[t.ID]
+	delete(x.lengths, t.ID)
+}
+
+// hits returns how often term occurs in each todo containing it, by todo ID.
+func (x *searchIndex) hits(term todo.SearchTerm) map[int]int {
+	stems := term.Stems()
+	hits := make(map[int]int)
+	switch {
+	case term.Prefix:
+		for stem, positions := range x.terms {
+			if strings.HasPrefix(stem, stems[0]) {
+				for id, p := range positions {
+					hits[id] += len(p)
+				}
+			}
+		}
+	case len(stems) == 1:
+		for id, p := range x.terms[stems[0]] {
+			hits[id] = len(p)
+		}
+	default:
+		// A phrase occurs wherever its first word is followed by the
+		// others.
+		for id, positions := range x.terms[stems[0]] {
+			for _, p := range positions {
+				if x.follows(id, p, stems[1:]) {
+					hits[id]++
+				}
+			}
+		}
+	}
+	return hits
+}
+
+// follows returns true if the stems occur in the todo with the given ID right
+// after position p.
+func (x *searchIndex) follows(id, p int, stems []string) bool {
+	for k, stem := range stems {
+		positions := x.terms[stem][id]
+		i := sort.SearchInts(positions, p+k+1)
+		if i == len(positions) || positions[i] != p+k+1 {
+			return false
+		}
+	}
+	return true
+}
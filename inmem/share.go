@@ -0,0 +1,184 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"todo"
+)
+
+func (s *Service) CreateShare(ctx context.Context, request todo.CreateShareRequest) (*todo.Share, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Validate(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh := &todo.Share{
+		TodoID:      request.TodoID,
+		ListID:      request.ListID,
+		PrincipalID: request.PrincipalID,
+		Role:        request.Role,
+		CreatedAt:   s.Now().UTC(),
+	}
+	if request.ListID != 0 {
+		i, err := s.lookupList(ctx, request.ListID)
+		if err != nil {
+			return nil, err
+		} else if s.lists[i].Query != "" {
+			return nil, todo.Errorf(todo.EINVALID, "List with ID '%d' is a smart list and cannot be shared.", request.ListID)
+		}
+		sh.OwnerID = s.lists[i].OwnerID
+	} else {
+		i, err := s.lookup(ctx, request.TodoID)
+		if err != nil {
+			return nil, err
+		}
+		sh.OwnerID = s.todos[i].OwnerID
+	}
+	if err := s.commit(&record{Op: opPutShare, Share: sh}); err != nil {
+		return nil, err
+	}
+
+	return copyShare(sh), nil
+}
+
+func (s *Service) DeleteShare(ctx context.Context, request todo.DeleteShareRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if request.ListID != 0 {
+		if _, err := s.lookupList(ctx, request.ListID); err != nil {
+			return err
+		} else if s.indexOfShare(0, request.ListID, request.PrincipalID) == -1 {
+			return todo.Errorf(todo.ENOTFOUND, "List with ID '%d' is not shared with '%s'.", request.ListID, request.PrincipalID)
+		}
+	} else if _, err := s.lookup(ctx, request.TodoID); err != nil {
+		return err
+	} else if s.indexOfShare(request.TodoID, 0, request.PrincipalID) == -1 {
+		return todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' is not shared with '%s'.", request.TodoID, request.PrincipalID)
+	}
+
+	return s.commit(&record{Op: opDeleteShare, Share: &todo.Share{TodoID: request.TodoID, ListID: request.ListID, PrincipalID: request.PrincipalID}})
+}
+
+func (s *Service) GetShare(ctx context.Context, request todo.GetShareRequest) (*todo.Share, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	principalID := todo.PrincipalIDFromContext(ctx)
+	if request.ListID != 0 {
+		i := s.indexOfShare(0, request.ListID, principalID)
+		if i == -1 {
+			return nil, todo.Errorf(todo.ENOTFOUND, "List with ID '%d' could not be found.", request.ListID)
+		}
+		return copyShare(s.shares[i]), nil
+	}
+
+	// The todo may be shared directly and through its list.
+	var best *todo.Share
+	if i := s.indexOfShare(request.TodoID, 0, principalID); i != -1 {
+		best = s.shares[i]
+	}
+	if j, err := s.indexOf(request.TodoID); err == nil && s.todos[j].ListID != 0 {
+		if i := s.indexOfShare(0, s.todos[j].ListID, principalID); i != -1 && (best == nil || !todo.RoleIncludes(best.Role, s.shares[i].Role)) {
+			best = s.shares[i]
+		}
+	}
+	if best == nil {
+		return nil, todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' could not be found.", request.TodoID)
+	}
+	return copyShare(best), nil
+}
+
+func (s *Service) ListShares(ctx context.Context, request todo.ListSharesRequest) ([]*todo.Share, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	match := func(sh *todo.Share) bool { return sh.PrincipalID == todo.PrincipalIDFromContext(ctx) }
+	if request.ListID != 0 {
+		if _, err := s.lookupList(ctx, request.ListID); err != nil {
+			return nil, err
+		}
+		match = func(sh *todo.Share) bool { return sh.ListID == request.ListID }
+	} else if request.TodoID != 0 {
+		if _, err := s.lookup(ctx, request.TodoID); err != nil {
+			return nil, err
+		}
+		match = func(sh *todo.Share) bool { return sh.TodoID == request.TodoID }
+	}
+
+	shares := make([]*todo.Share, 0)
+	for _, sh := range s.shares {
+		if match(sh) {
+			shares = append(shares, copyShare(sh))
+		}
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].TodoID != shares[j].TodoID {
+			return shares[i].TodoID < shares[j].TodoID
+		} else if shares[i].ListID != shares[j].ListID {
+			return shares[i].ListID < shares[j].ListID
+		}
+		return shares[i].PrincipalID < shares[j].PrincipalID
+	})
+	return shares, nil
+}
+
+// indexOfShare returns the position of the share of a todo, or of a list if
+// todoID is zero, with a principal in s.shares, or -1 if there is none.
+// Must be called with s.mu held.
+func (s *Service) indexOfShare(todoID, listID int, principalID string) int {
+	for i, sh := range s.shares {
+		if sh.TodoID == todoID && sh.ListID == listID && sh.PrincipalID == principalID {
+			return i
+		}
+	}
+	return -1
+}
+
+// listShared returns true if the list with the given ID is shared with anyone.
+// Must be called with s.mu held.
+func (s *Service) listShared(listID int) bool {
+	for _, sh := range s.shares {
+		if sh.TodoID == 0 && sh.ListID == listID {
+			return true
+		}
+	}
+	return false
+}
+
+// removeShares removes all shares matching fn. Must be called with s.mu held.
+func (s *Service) removeShares(fn func(sh *todo.Share) bool) {
+	if s.staged != nil {
+		prev := append([]*todo.Share(nil), s.shares...)
+		s.onUndo(func() { s.shares = prev })
+	}
+	shares := s.shares[:0]
+	for _, sh := range s.shares {
+		if !fn(sh) {
+			shares = append(shares, sh)
+		}
+	}
+	s.shares = shares
+}
+
+// copyShare returns a copy of sh so callers never share memory with the store.
+func copyShare(sh *todo.Share) *todo.Share {
+	other := *sh
+	return &other
+}
@@ -0,0 +1,160 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"todo"
+)
+
+// tagKey identifies a tag of an owner in the tag index.
+type tagKey struct {
+	OwnerID string
+	Tag     string
+}
+
+func (s *Service) ListTags(ctx context.Context, request todo.ListTagsRequest) ([]*todo.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ownerID := todo.OwnerIDFromContext(ctx)
+	tags := make([]*todo.Tag, 0)
+	for k, ids := range s.tagged {
+		if k.OwnerID == ownerID {
+			tags = append(tags, &todo.Tag{Name: k.Tag, Count: len(ids)})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (s *Service) RenameTag(ctx context.Context, request todo.RenameTagRequest) (*todo.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ownerID := todo.OwnerIDFromContext(ctx)
+	if len(s.tagged[tagKey{ownerID, request.Name}]) == 0 {
+		return nil, todo.Errorf(todo.ENOTFOUND, "Tag '%s' could not be found.", request.Name)
+	} else if len(s.tagged[tagKey{ownerID, request.NewName}]) > 0 {
+		return nil, todo.Errorf(todo.ECONFLICT, "Tag '%s' already exists.", request.NewName)
+	}
+
+	return s.replaceTags(ownerID, []string{request.Name}, request.NewName)
+}
+
+func (s *Service) MergeTags(ctx context.Context, request todo.MergeTagsRequest) (*todo.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ownerID := todo.OwnerIDFromContext(ctx)
+	var found bool
+	for _, name := range request.Names {
+		found = found || len(s.tagged[tagKey{ownerID, name}]) > 0
+	}
+	if !found {
+		return nil, todo.Errorf(todo.ENOTFOUND, "None of the tags could be found.")
+	}
+
+	return s.replaceTags(ownerID, request.Names, request.Into)
+}
+
+// replaceTags replaces names with into on every todo of the owner and commits
+// all changed todos in a single record. Must be called with s.mu held.
+func (s *Service) replaceTags(ownerID string, names []string, into string) (*todo.Tag, error) {
+	ids := make(map[int]struct{})
+	for _, name := range names {
+		for id := range s.tagged[tagKey{ownerID, name}] {
+			ids[id] = struct{}{}
+		}
+	}
+
+	rec := &record{Op: opPut}
+	for _, t := range s.todosByID(ids) {
+		t = copyTodo(t)
+		if t.ReplaceTags(names, into) {
+			t.Version++
+			rec.Todos = append(rec.Todos, t)
+		}
+	}
+	if len(rec.Todos) > 0 {
+		if err := s.commit(rec); err != nil {
+			return nil, err
+		}
+	}
+
+	return &todo.Tag{Name: into, Count: len(s.tagged[tagKey{ownerID, into}])}, nil
+}
+
+// candidates returns the todos of the owner that may match the tag filters of
+// request, using the tag index where possible. The other filters still have
+// to be checked. Must be called with s.mu held.
+func (s *Service) candidates(ownerID string, request todo.ListTodosRequest) []*todo.Todo {
+	switch {
+	case len(request.Tags) > 0:
+		// Every match carries all tags, so the least used one narrows the
+		// todos down the most.
+		ids := s.tagged[tagKey{ownerID, request.Tags[0]}]
+		for _, tag := range request.Tags[1:] {
+			if other := s.tagged[tagKey{ownerID, tag}]; len(other) < len(ids) {
+				ids = other
+			}
+		}
+		return s.todosByID(ids)
+	case len(request.AnyTags) > 0:
+		ids := make(map[int]struct{})
+		for _, tag := range request.AnyTags {
+			for id := range s.tagged[tagKey{ownerID, tag}] {
+				ids[id] = struct{}{}
+			}
+		}
+		return s.todosByID(ids)
+	}
+	return s.todos
+}
+
+// todosByID returns the todos with the given IDs, ordered by ID.
+// Must be called with s.mu held.
+func (s *Service) todosByID(ids map[int]struct{}) []*todo.Todo {
+	todos := make([]*todo.Todo, 0, len(ids))
+	for id := range ids {
+		todos = append(todos, s.todos[s.offsets[id]])
+	}
+	sort.Slice(todos, func(i, j int) bool { return todos[i].ID < todos[j].ID })
+	return todos
+}
+
+// tag adds t to the tag index. Must be called with s.mu held.
+func (s *Service) tag(t *todo.Todo) {
+	for _, tag := range t.Tags {
+		k := tagKey{t.OwnerID, tag}
+		if s.tagged[k] == nil {
+			s.tagged[k] = make(map[int]struct{})
+		}
+		s.tagged[k][t.ID] = struct{}{}
+	}
+}
+
+// untag removes t from the tag index. Must be called with s.mu held.
+func (s *Service) untag(t *todo.Todo) {
+	for _, tag := range t.Tags {
+		k := tagKey{t.OwnerID, tag}
+		if delete(s.tagged[k], t.ID); len(s.tagged[k]) == 0 {
+			delete(s.tagged, k)
+		}
+	}
+}
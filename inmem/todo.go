@@ -2,80 +2,755 @@ package inmem
 
 import (
 	"context"
+	"os"
+	"sort"
 	"sync"
+	"time"
 	"todo"
 )
 
 type Service struct {
-	nextID int
-	mu     sync.Mutex
-	todos  []*todo.Todo
+	nextID     int
+	nextListID int
+	nextRevID  int
+	mu         sync.Mutex
+	todos      []*todo.Todo
+	shares     []*todo.Share
+	lists      []*todo.List
+	deps       []*todo.Dependency
+	trans      []*todo.Transition
+	revs       []*todo.Revision
+	trash      []*trashed
+	wal        *wal
+
+	// Records of the atomic batch being applied, see BatchTodos. They are
+	// applied as they are committed but only logged once the batch succeeds.
+	// Applying them adds the functions reverting them to undo, which are run
+	// in reverse if the batch fails.
+	staged []*record
+	undo   []func()
+
+	// Indexes over s.todos: the offset of every todo by ID, the IDs of the
+	// todos carrying each tag of an owner, the largest position of the todos
+	// of each owner, and the words of every todo.
+	offsets map[int]int
+	tagged  map[tagKey]map[int]struct{}
+	last    map[string]string
+	words   *searchIndex
+
+	// Directory where the write-ahead log and snapshots are stored. If empty,
+	// todos are only kept in memory and are lost when the process exits.
+	Dir string
+
+	// Number of log records after which the log is compacted into a snapshot.
+	// Defaults to DefaultSnapshotThreshold.
+	SnapshotThreshold int
+
+	// If set, todos can be completed while some of their subtasks are
+	// still open. Otherwise completing them fails with ECONFLICT.
+	AllowOpenChildren bool
+
+	// Workflow todos follow. Defaults to todo.DefaultWorkflow.
+	Workflow *todo.Workflow
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
 }
 
-func NewService() todo.Service {
+func NewService() *Service {
 	return &Service{
-		nextID: 1,
-		todos: make([]*todo.Todo, 0),
+		nextID:            1,
+		nextListID:        1,
+		nextRevID:         1,
+		todos:             make([]*todo.Todo, 0),
+		shares:            make([]*todo.Share, 0),
+		lists:             make([]*todo.List, 0),
+		deps:              make([]*todo.Dependency, 0),
+		trans:             make([]*todo.Transition, 0),
+		revs:              make([]*todo.Revision, 0),
+		trash:             make([]*trashed, 0),
+		offsets:           make(map[int]int),
+		tagged:            make(map[tagKey]map[int]struct{}),
+		last:              make(map[string]string),
+		words:             newSearchIndex(),
+		SnapshotThreshold: DefaultSnapshotThreshold,
+		Now:               time.Now,
 	}
 }
 
-func (s *Service) CreateTodo(ctx context.Context, request todo.CreateTodoRequest) (*todo.Todo, error) {
+// Open restores the state stored in Dir by loading the latest snapshot and
+// replaying the log on top of it. Does nothing if Dir is not set.
+func (s *Service) Open() error {
+	if s.Dir == "" {
+		return nil
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	t := &todo.Todo{
-		ID:       s.nextID,
-		Value:    request.Value,
-		Complete: false,
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	snap, err := readSnapshot(s.Dir)
+	if err != nil {
+		return err
 	}
-	s.todos = append(s.todos, t)
-	s.nextID++
+	s.load(snap)
 
-	return t, nil
+	if s.wal, err = openWAL(s.Dir, snap.Seq, s.apply); err != nil {
+		return err
+	}
+	return nil
+}
+
+// load replaces the in-memory state with a copy of snap.
+// Must be called with s.mu held.
+func (s *Service) load(snap *snapshot) {
+	s.todos = make([]*todo.Todo, 0, len(snap.Todos))
+	s.offsets = make(map[int]int, len(snap.Todos))
+	s.tagged = make(map[tagKey]map[int]struct{})
+	s.last = make(map[string]string)
+	s.words = newSearchIndex()
+	s.nextID = snap.NextID
+	s.nextListID = snap.NextListID
+	s.lists = make([]*todo.List, 0, len(snap.Lists))
+	for _, l := range snap.Lists {
+		s.apply(&record{Op: opPutList, List: l})
+	}
+	for _, t := range snap.Todos {
+		s.apply(&record{Op: opPut, Todo: t})
+	}
+	s.shares = make([]*todo.Share, 0, len(snap.Shares))
+	for _, sh := range snap.Shares {
+		s.apply(&record{Op: opPutShare, Share: sh})
+	}
+	s.deps = make([]*todo.Dependency, 0, len(snap.Dependencies))
+	for _, d := range snap.Dependencies {
+		s.apply(&record{Op: opPutDependency, Dependency: d})
+	}
+	s.trans = make([]*todo.Transition, 0, len(snap.Transitions))
+	for _, tr := range snap.Transitions {
+		s.trans = append(s.trans, copyTransition(tr))
+	}
+	s.nextRevID = snap.NextRevID
+	s.revs = make([]*todo.Revision, 0, len(snap.Revisions))
+	for _, r := range snap.Revisions {
+		s.apply(&record{Op: opPutRevision, Revision: r})
+	}
+	s.trash = make([]*trashed, 0, len(snap.Trash))
+	for _, e := range snap.Trash {
+		s.trash = append(s.trash, copyTrashed(e))
+	}
+}
+
+// Close compacts the log into a snapshot and closes it.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.wal == nil {
+		return nil
+	}
+	if err := s.wal.compact(s.snapshot()); err != nil {
+		_ = s.wal.Close()
+		return err
+	}
+	err := s.wal.Close()
+	s.wal = nil
+	return err
+}
+
+func (s *Service) CreateTodo(ctx context.Context, request todo.CreateTodoRequest) (*todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(ctx, request)
 }
 
 func (s *Service) UpdateTodo(ctx context.Context, request todo.UpdateTodoRequest) (*todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(ctx, request)
+}
+
+func (s *Service) PatchTodo(ctx context.Context, request todo.PatchTodoRequest) (*todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	t, err := s.getTodoByID(ctx, request.ID)
+	return s.patch(ctx, request)
+}
+
+// create creates the todo described by request. Must be called with s.mu held.
+func (s *Service) create(ctx context.Context, request todo.CreateTodoRequest) (*todo.Todo, error) {
+	t := &todo.Todo{
+		ID:         todo.NextID(s.nextID-1, s.Now()),
+		OwnerID:    todo.OwnerIDFromContext(ctx),
+		ListID:     request.ListID,
+		Value:      request.Value,
+		Complete:   request.Complete,
+		DueAt:      request.DueAt,
+		RemindAt:   request.RemindAt,
+		TimeZone:   request.TimeZone,
+		ParentID:   request.ParentID,
+		Recurrence: request.Recurrence,
+		State:      request.State,
+		Tags:       request.Tags,
+		Priority:   request.Priority,
+		Version:    1,
+	}
+	if _, err := s.save(ctx, t, nil, "", false); err != nil {
+		return nil, err
+	}
+	return copyTodo(t), nil
+}
+
+// update replaces the todo described by request. Must be called with s.mu held.
+func (s *Service) update(ctx context.Context, request todo.UpdateTodoRequest) (*todo.Todo, error) {
+	i, err := s.lookup(ctx, request.ID)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.todos[i].CheckVersion(request.Version); err != nil {
+		return nil, err
+	}
+	t := copyTodo(s.todos[i])
+	t.ListID = request.ListID
+	t.ParentID = request.ParentID
 	t.Value = request.Value
 	t.Complete = request.Complete
+	t.State = request.State
+	t.DueAt, t.RemindAt, t.TimeZone = request.DueAt, request.RemindAt, request.TimeZone
+	t.Recurrence = request.Recurrence
+	t.Tags = request.Tags
+	t.Priority = request.Priority
+	t.Version++
+
+	if _, err := s.save(ctx, t, s.todos[i], request.Scope, false); err != nil {
+		return nil, err
+	}
+	return copyTodo(t), nil
+}
+
+// patch applies the partial update described by request.
+// Must be called with s.mu held.
+func (s *Service) patch(ctx context.Context, request todo.PatchTodoRequest) (*todo.Todo, error) {
+	i, err := s.lookup(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.todos[i].CheckVersion(request.Version); err != nil {
+		return nil, err
+	}
+	t := copyTodo(s.todos[i])
+	request.Apply(t)
+	t.Version++
+
+	if _, err := s.save(ctx, t, s.todos[i], request.Scope, false); err != nil {
+		return nil, err
+	}
+	return copyTodo(t), nil
+}
+
+func (s *Service) CompleteTodo(ctx context.Context, request todo.CompleteTodoRequest) (*todo.CompleteTodoResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.lookup(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.todos[i].CheckVersion(request.Version); err != nil {
+		return nil, err
+	}
+	t := copyTodo(s.todos[i])
+	t.Complete = true
+	t.Version++
+
+	next, err := s.save(ctx, t, s.todos[i], "", request.Force)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &todo.CompleteTodoResponse{Todo: copyTodo(t)}
+	if next != nil {
+		resp.Next = copyTodo(next)
+	}
+	return resp, nil
+}
+
+// save validates & commits t, which changed from prev or is new if prev is
+// nil. If the change continues a recurring series, the next occurrence is
+// committed in the same record and returned. Blocked todos are only completed
+// if force is set. Must be called with s.mu held.
+func (s *Service) save(ctx context.Context, t, prev *todo.Todo, scope string, force bool) (*todo.Todo, error) {
+	t.Blocked = s.blocked(t.ID)
+
+	if err := t.Validate(); err != nil {
+		return nil, err
+	} else if err := s.checkListID(ctx, t.ListID); err != nil {
+		return nil, err
+	} else if err := s.checkParentID(ctx, t); err != nil {
+		return nil, err
+	}
+	rec := &record{Op: opPut, Todo: t}
+	if err := s.transition(rec, t, prev); err != nil {
+		return nil, err
+	}
+	if !s.AllowOpenChildren {
+		if err := t.CheckCompletable(prev, s.openChildren(t.ID)); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.CheckUnblocked(prev, force); err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		t.Reschedule(prev)
+	} else if err := s.placeLast(t); err != nil {
+		return nil, err
+	}
+
+	next, err := t.Recur(prev, scope)
+	if err != nil {
+		return nil, err
+	} else if next != nil {
+		last := s.nextID - 1
+		if t.ID > last {
+			last = t.ID
+		}
+		next.ID = todo.NextID(last, s.Now())
+		if err := s.placeLast(next, t); err != nil {
+			return nil, err
+		} else if err := s.transition(rec, next, nil, t); err != nil {
+			return nil, err
+		}
+	}
 
-	return t, nil
+	rec.Next = next
+	if err := s.commit(rec); err != nil {
+		return nil, err
+	}
+	return next, nil
 }
 
 func (s *Service) DeleteTodo(ctx context.Context, request todo.DeleteTodoRequest) error {
-	panic("implement me")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delete(ctx, request)
+}
+
+// delete moves the todo described by request to the trash.
+// Must be called with s.mu held.
+func (s *Service) delete(ctx context.Context, request todo.DeleteTodoRequest) error {
+	i, err := s.lookup(ctx, request.ID)
+	if err != nil {
+		return err
+	} else if err := s.todos[i].CheckVersion(request.Version); err != nil {
+		return err
+	}
+
+	return s.commit(&record{Op: opDelete, ID: request.ID, Cascade: request.Cascade, Trash: s.trashTodo(request.ID, request.Cascade)})
 }
 
 func (s *Service) GetTodoByID(ctx context.Context, request todo.GetTodoByIDRequest) (*todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	t, err := s.getTodoByID(ctx, request.ID)
+	i, err := s.lookup(ctx, request.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return t, nil
+	return s.view(s.todos[i]), nil
 }
 
-func (s *Service) GetAllTodos(ctx context.Context) ([]*todo.Todo, error) {
+func (s *Service) ListTodos(ctx context.Context, request todo.ListTodosRequest) (*todo.ListTodosResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.todos, nil
+	if request.ListID != 0 {
+		i, err := s.lookupList(ctx, request.ListID)
+		if err != nil {
+			return nil, err
+		}
+		request.UseList(s.lists[i])
+	}
+	if err := request.Normalize(); err != nil {
+		return nil, err
+	}
+	if request.ParentID != 0 {
+		if _, err := s.lookup(ctx, request.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	ownerID, now := todo.OwnerIDFromContext(ctx), s.Now()
+	matches := make([]*todo.Todo, 0)
+	for _, t := range s.candidates(ownerID, request) {
+		if t.OwnerID != ownerID {
+			continue
+		}
+
+		// Queries may filter on whether todos are blocked, which is only
+		// known once they are viewed.
+		v := t
+		if request.Where() != nil {
+			v = s.view(t)
+		}
+		if request.Match(v, now) {
+			matches = append(matches, t)
+		}
+	}
+	sortTodos(matches, request.SortBy, request.SortDirection)
+
+	resp := &todo.ListTodosResponse{
+		Todos:      make([]*todo.Todo, 0),
+		TotalCount: len(matches),
+	}
+
+	// Skip everything up to and including the todo the cursor points at.
+	if request.Cursor != "" {
+		c, err := todo.DecodeCursor(request.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		i := sort.Search(len(matches), func(i int) bool { return c.After(matches[i]) })
+		matches = matches[i:]
+	}
+
+	if len(matches) > request.Limit {
+		matches = matches[:request.Limit]
+		resp.NextCursor = todo.NewCursor(matches[len(matches)-1], request.SortBy, request.SortDirection).Encode()
+	}
+	for _, t := range matches {
+		resp.Todos = append(resp.Todos, s.view(t))
+	}
+
+	return resp, nil
+}
+
+// commit durably writes rec to the log, if enabled, and then applies it to the
+// in-memory state. The state is left untouched if the write fails. Records of
+// an atomic batch are only logged once the whole batch succeeds, see
+// BatchTodos. Must be called with s.mu held.
+func (s *Service) commit(rec *record) error {
+	if s.staged != nil {
+		s.staged = append(s.staged, rec)
+		s.apply(rec)
+		return nil
+	}
+
+	if s.wal != nil {
+		if err := s.wal.append(rec); err != nil {
+			return err
+		}
+	}
+	s.apply(rec)
+
+	if s.wal != nil && s.SnapshotThreshold > 0 && s.wal.n >= s.SnapshotThreshold {
+		// The record is already durable so a failed compaction only means
+		// the log keeps growing until the next attempt.
+		_ = s.wal.compact(s.snapshot())
+	}
+	return nil
+}
+
+// apply applies rec to the in-memory state. It is used both for new mutations
+// and when replaying the log. Must be called with s.mu held.
+func (s *Service) apply(rec *record) {
+	switch rec.Op {
+	case opPut:
+		for _, t := range append([]*todo.Todo{rec.Todo, rec.Next}, rec.Todos...) {
+			if t == nil {
+				continue
+			}
+			t := copyTodo(t)
+			t.Blocked = false
+			// Records written before todos had states get the state
+			// matching Complete.
+			if t.State == "" {
+				_, _ = s.workflow().Transition(t, nil, time.Time{})
+				t.StateChangedAt = nil
+			}
+			last, nextID := s.last[t.OwnerID], s.nextID
+			if i, err := s.indexOf(t.ID); err == nil {
+				// Records written before todos had positions keep the
+				// position the todo was given when it was created.
+				if t.Position == "" {
+					t.Position = s.todos[i].Position
+				}
+				prev := s.todos[i]
+				s.untag(prev)
+				s.words.remove(prev)
+				s.todos[i] = t
+				s.onUndo(func() { s.todos[i] = prev; s.tag(prev); s.words.add(prev) })
+			} else {
+				if t.Position == "" {
+					_ = s.placeLast(t)
+				}
+				s.offsets[t.ID] = len(s.todos)
+				s.todos = append(s.todos, t)
+				s.onUndo(func() { s.todos = s.todos[:len(s.todos)-1]; delete(s.offsets, t.ID) })
+			}
+			s.tag(t)
+			s.words.add(t)
+			if t.Position > s.last[t.OwnerID] {
+				s.last[t.OwnerID] = t.Position
+			}
+			// IDs must never be reused, even if the todo was deleted later on.
+			if t.ID >= s.nextID {
+				s.nextID = t.ID + 1
+			}
+			s.onUndo(func() {
+				s.untag(t)
+				s.words.remove(t)
+				if s.nextID = nextID; last == "" {
+					delete(s.last, t.OwnerID)
+				} else {
+					s.last[t.OwnerID] = last
+				}
+			})
+		}
+		n := len(s.trans)
+		for _, tr := range rec.Transitions {
+			s.trans = append(s.trans, copyTransition(tr))
+		}
+		s.onUndo(func() { s.trans = s.trans[:n] })
+	case opDelete:
+		// Records written before todos had a trash carry no entries and
+		// delete todos permanently.
+		n := len(s.trash)
+		for _, e := range rec.Trash {
+			s.trash = append(s.trash, copyTrashed(e))
+		}
+		s.onUndo(func() { s.trash = s.trash[:n] })
+
+		i, err := s.indexOf(rec.ID)
+		if err != nil {
+			break
+		}
+		prev := s.todos[i]
+		s.untag(prev)
+		s.words.remove(prev)
+		s.todos = append(s.todos[:i], s.todos[i+1:]...)
+		delete(s.offsets, rec.ID)
+		for j := i; j < len(s.todos); j++ {
+			s.offsets[s.todos[j].ID] = j
+		}
+		s.onUndo(func() {
+			s.todos = append(s.todos[:i], append([]*todo.Todo{prev}, s.todos[i:]...)...)
+			for j := i; j < len(s.todos); j++ {
+				s.offsets[s.todos[j].ID] = j
+			}
+			s.tag(prev)
+			s.words.add(prev)
+		})
+		s.removeShares(func(sh *todo.Share) bool { return sh.TodoID == rec.ID })
+		s.removeDependencies(func(d *todo.Dependency) bool { return d.TodoID == rec.ID || d.BlockedByID == rec.ID })
+		s.removeTransitions(rec.ID)
+
+		// Subtasks are either deleted as well or moved up to the parent.
+		for _, child := range s.childrenOf(rec.ID) {
+			if rec.Cascade {
+				s.apply(&record{Op: opDelete, ID: child.ID, Cascade: true})
+			} else {
+				child, parentID := child, child.ParentID
+				child.ParentID = prev.ParentID
+				child.Version++
+				s.onUndo(func() { child.ParentID = parentID; child.Version-- })
+			}
+		}
+	case opPutShare:
+		sh := copyShare(rec.Share)
+		s.removeShares(func(other *todo.Share) bool {
+			return other.TodoID == sh.TodoID && other.ListID == sh.ListID && other.PrincipalID == sh.PrincipalID
+		})
+		s.shares = append(s.shares, sh)
+	case opDeleteShare:
+		s.removeShares(func(sh *todo.Share) bool {
+			return sh.TodoID == rec.Share.TodoID && sh.ListID == rec.Share.ListID && sh.PrincipalID == rec.Share.PrincipalID
+		})
+	case opPutDependency:
+		d := copyDependency(rec.Dependency)
+		s.removeDependencies(func(other *todo.Dependency) bool {
+			return other.TodoID == d.TodoID && other.BlockedByID == d.BlockedByID
+		})
+		s.deps = append(s.deps, d)
+	case opDeleteDependency:
+		s.removeDependencies(func(d *todo.Dependency) bool {
+			return d.TodoID == rec.Dependency.TodoID && d.BlockedByID == rec.Dependency.BlockedByID
+		})
+	case opPutList:
+		l := copyList(rec.List)
+		if i, err := s.indexOfList(l.ID); err == nil {
+			s.lists[i] = l
+		} else {
+			s.lists = append(s.lists, l)
+		}
+		if l.ID >= s.nextListID {
+			s.nextListID = l.ID + 1
+		}
+	case opDeleteList:
+		if i, err := s.indexOfList(rec.ID); err == nil {
+			s.lists = append(s.lists[:i], s.lists[i+1:]...)
+		}
+		s.removeShares(func(sh *todo.Share) bool { return sh.ListID == rec.ID })
+		if rec.Cascade {
+			for _, t := range s.todosInList(rec.ID) {
+				s.apply(&record{Op: opDelete, ID: t.ID})
+			}
+		}
+	case opPutRevision:
+		s.revs = append(s.revs, copyRevision(rec.Revision))
+		if rec.Revision.ID >= s.nextRevID {
+			s.nextRevID = rec.Revision.ID + 1
+		}
+	case opDeleteRevisions:
+		s.removeRevisions(rec.IDs)
+	case opRestore:
+		for _, e := range rec.Trash {
+			s.removeTrash(e.Todo.ID)
+			s.apply(&record{Op: opPut, Todo: e.Todo, Transitions: e.Transitions})
+			for _, sh := range e.Shares {
+				s.apply(&record{Op: opPutShare, Share: sh})
+			}
+		}
+		// Dependencies are restored once both todos are.
+		for _, e := range rec.Trash {
+			for _, d := range e.Dependencies {
+				s.apply(&record{Op: opPutDependency, Dependency: d})
+			}
+		}
+	case opPurge:
+		s.removeTrash(rec.IDs...)
+	case opBatch:
+		for _, r := range rec.Records {
+			s.apply(r)
+		}
+	}
 }
 
-func (s *Service) getTodoByID(_ context.Context, id int) (*todo.Todo, error) {
-	for i := range s.todos {
-		if s.todos[i].ID == id {
-			return s.todos[i], nil
+// onUndo adds fn to the functions reverting the atomic batch being applied, if
+// any. Must be called with s.mu held.
+func (s *Service) onUndo(fn func()) {
+	if s.staged != nil {
+		s.undo = append(s.undo, fn)
+	}
+}
+
+// rollback reverts the records applied by the atomic batch being applied,
+// newest first. Must be called with s.mu held.
+func (s *Service) rollback() {
+	for i := len(s.undo) - 1; i >= 0; i-- {
+		s.undo[i]()
+	}
+	s.undo = nil
+}
+
+// snapshot returns the current state. Must be called with s.mu held.
+func (s *Service) snapshot() *snapshot {
+	return &snapshot{
+		NextID:       s.nextID,
+		NextListID:   s.nextListID,
+		NextRevID:    s.nextRevID,
+		Todos:        s.todos,
+		Shares:       s.shares,
+		Lists:        s.lists,
+		Dependencies: s.deps,
+		Transitions:  s.trans,
+		Revisions:    s.revs,
+		Trash:        s.trash,
+	}
+}
+
+// lookup returns the position of the todo with the given ID in s.todos if it
+// is owned by the caller. Todos of other owners are reported as not found so
+// their existence is not revealed. Must be called with s.mu held.
+func (s *Service) lookup(ctx context.Context, id int) (int, error) {
+	i, err := s.indexOf(id)
+	if err != nil {
+		return -1, err
+	} else if s.todos[i].OwnerID != todo.OwnerIDFromContext(ctx) {
+		return -1, todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' could not be found.", id)
+	}
+	return i, nil
+}
+
+// indexOf returns the offset of the todo with the given ID in s.todos.
+// Must be called with s.mu held.
+func (s *Service) indexOf(id int) (int, error) {
+	if i, ok := s.offsets[id]; ok {
+		return i, nil
+	}
+	return -1, todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' could not be found.", id)
+}
+
+// sortTodos sorts todos by the given field, breaking ties by ID.
+func sortTodos(todos []*todo.Todo, sortBy, sortDirection string) {
+	sort.SliceStable(todos, func(i, j int) bool {
+		cmp := todo.CompareSortKeys(todos[i].SortKey(sortBy), todos[j].SortKey(sortBy))
+		if cmp == 0 {
+			cmp = todo.CompareSortKeys(todos[i].ID, todos[j].ID)
 		}
+		if sortDirection == todo.SortDesc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// copyTodo returns a copy of t so callers never share memory with the store.
+func copyTodo(t *todo.Todo) *todo.Todo {
+	other := *t
+	other.DueAt = copyTime(t.DueAt)
+	other.RemindAt = copyTime(t.RemindAt)
+	other.RemindedAt = copyTime(t.RemindedAt)
+	other.StateChangedAt = copyTime(t.StateChangedAt)
+	other.DeletedAt = copyTime(t.DeletedAt)
+	other.ArchivedAt = copyTime(t.ArchivedAt)
+	if t.Tags != nil {
+		other.Tags = append([]string(nil), t.Tags...)
+	}
+	return &other
+}
+
+// copyTime returns a copy of an optional time.
+func copyTime(v *time.Time) *time.Time {
+	if v == nil {
+		return nil
 	}
-	return nil, todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' could not be found.", id)
+	other := *v
+	return &other
 }
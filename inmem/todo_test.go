@@ -0,0 +1,138 @@
+package inmem_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo"
+	"todo/inmem"
+	"todo/todotest"
+)
+
+func TestService(t *testing.T) {
+	todotest.TestService(t, func(t *testing.T) todo.Service {
+		return inmem.NewService()
+	})
+}
+
+func TestService_Durable(t *testing.T) {
+	todotest.TestService(t, func(t *testing.T) todo.Service {
+		return MustOpenService(t, t.TempDir())
+	})
+}
+
+func TestService_Shares(t *testing.T) {
+	todotest.TestShareService(t, func(t *testing.T) (todo.Service, todo.ShareService) {
+		s := MustOpenService(t, t.TempDir())
+		return s, s
+	})
+}
+
+func TestService_ListShares(t *testing.T) {
+	todotest.TestListShares(t, func(t *testing.T) (todo.Service, todo.ListService, todo.ShareService) {
+		s := MustOpenService(t, t.TempDir())
+		return s, s, s
+	})
+}
+
+func TestService_Lists(t *testing.T) {
+	todotest.TestListService(t, func(t *testing.T) (todo.Service, todo.ListService) {
+		s := MustOpenService(t, t.TempDir())
+		return s, s
+	})
+}
+
+func TestService_Reminders(t *testing.T) {
+	todotest.TestReminderService(t, func(t *testing.T) (todo.Service, todo.ReminderService) {
+		s := MustOpenService(t, t.TempDir())
+		return s, s
+	})
+}
+
+func TestService_Dependencies(t *testing.T) {
+	todotest.TestDependencyService(t, func(t *testing.T) (todo.Service, todo.DependencyService) {
+		s := MustOpenService(t, t.TempDir())
+		return s, s
+	})
+}
+
+func TestService_Tags(t *testing.T) {
+	todotest.TestTagService(t, func(t *testing.T) (todo.Service, todo.TagService) {
+		s := MustOpenService(t, t.TempDir())
+		return s, s
+	})
+}
+
+func TestService_Workflow(t *testing.T) {
+	todotest.TestWorkflowService(t, func(t *testing.T) (todo.Service, todo.ListService, todo.WorkflowService) {
+		s := MustOpenService(t, t.TempDir())
+		return s, s, s
+	})
+}
+
+func TestService_Search(t *testing.T) {
+	todotest.TestSearchService(t, func(t *testing.T) (todo.Service, todo.SearchService) {
+		s := MustOpenService(t, t.TempDir())
+		return s, s
+	})
+}
+
+func TestService_Queries(t *testing.T) {
+	todotest.TestQueries(t, func(t *testing.T) (todo.Service, todo.ListService, todo.DependencyService) {
+		s := MustOpenService(t, t.TempDir())
+		return s, s, s
+	})
+}
+
+func TestService_Revisions(t *testing.T) {
+	todotest.TestRevisionStore(t, func(t *testing.T, now func() time.Time) todo.RevisionStore {
+		s := MustOpenService(t, t.TempDir())
+		s.Now = now
+		return s
+	})
+}
+
+func TestService_Trash(t *testing.T) {
+	todotest.TestTrashService(t, func(t *testing.T) todotest.TrashServices {
+		s := MustOpenService(t, t.TempDir())
+		return todotest.TrashServices{Todos: s, Lists: s, Shares: s, Dependencies: s, Trash: s, Purge: s}
+	})
+}
+
+func TestService_Policies(t *testing.T) {
+	todotest.TestPolicyService(t, func(t *testing.T, now func() time.Time) todotest.PolicyServices {
+		s := MustOpenService(t, t.TempDir())
+		s.Now = now
+		return todotest.PolicyServices{Todos: s, Lists: s, Trash: s, Policies: s, Enforcer: s}
+	})
+}
+
+func TestService_AllowOpenChildren(t *testing.T) {
+	s := inmem.NewService()
+	s.AllowOpenChildren = true
+	ctx := context.Background()
+
+	parent := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "parent"})
+	todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "child", ParentID: parent.ID})
+	if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: parent.ID}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// MustOpenService returns a new, open service storing its data in dir. The
+// service is closed automatically when the test finishes.
+func MustOpenService(tb testing.TB, dir string) *inmem.Service {
+	tb.Helper()
+
+	s := inmem.NewService()
+	s.Dir = dir
+	if err := s.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			tb.Fatal(err)
+		}
+	})
+	return s
+}
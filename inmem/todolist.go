@@ -0,0 +1,198 @@
+package inmem
+
+import (
+	"context"
+	"todo"
+)
+
+func (s *Service) CreateList(ctx context.Context, request todo.CreateListRequest) (*todo.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Validate(); err != nil {
+		return nil, err
+	} else if err := s.workflow().ValidateLimits(request.WIPLimits); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := &todo.List{
+		ID:        todo.NextID(s.nextListID-1, s.Now()),
+		OwnerID:   todo.OwnerIDFromContext(ctx),
+		Name:      request.Name,
+		WIPLimits: copyLimits(request.WIPLimits),
+		Query:     request.Query,
+		Policies:  copyPolicies(request.Policies),
+		CreatedAt: s.Now().UTC(),
+	}
+	if err := s.commit(&record{Op: opPutList, List: l}); err != nil {
+		return nil, err
+	}
+
+	return copyList(l), nil
+}
+
+func (s *Service) UpdateList(ctx context.Context, request todo.UpdateListRequest) (*todo.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	} else if err := request.Validate(); err != nil {
+		return nil, err
+	} else if err := s.workflow().ValidateLimits(request.WIPLimits); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.lookupList(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(s.todosInList(request.ID)); n > 0 && request.Query != "" {
+		return nil, todo.Errorf(todo.ECONFLICT, "List with ID '%d' contains %d todos and cannot become a smart list.", request.ID, n)
+	} else if request.Query != "" && s.listShared(request.ID) {
+		return nil, todo.Errorf(todo.ECONFLICT, "List with ID '%d' is shared and cannot become a smart list.", request.ID)
+	}
+	l := copyList(s.lists[i])
+	l.Name = request.Name
+	l.WIPLimits = copyLimits(request.WIPLimits)
+	l.Query = request.Query
+	l.Policies = copyPolicies(request.Policies)
+
+	if err := s.commit(&record{Op: opPutList, List: l}); err != nil {
+		return nil, err
+	}
+
+	return copyList(l), nil
+}
+
+func (s *Service) DeleteList(ctx context.Context, request todo.DeleteListRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.lookupList(ctx, request.ID); err != nil {
+		return err
+	} else if n := len(s.todosInList(request.ID)); n > 0 && !request.Cascade {
+		return todo.Errorf(todo.ECONFLICT, "List with ID '%d' still contains %d todos.", request.ID, n)
+	}
+
+	return s.commit(&record{Op: opDeleteList, ID: request.ID, Cascade: request.Cascade})
+}
+
+func (s *Service) GetListByID(ctx context.Context, request todo.GetListByIDRequest) (*todo.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.lookupList(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyList(s.lists[i]), nil
+}
+
+func (s *Service) ListLists(ctx context.Context, request todo.ListListsRequest) ([]*todo.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Lists are appended as they are created and IDs increase over time, so
+	// they are already ordered by ID.
+	ownerID := todo.OwnerIDFromContext(ctx)
+	lists := make([]*todo.List, 0)
+	for _, l := range s.lists {
+		if l.OwnerID == ownerID {
+			lists = append(lists, copyList(l))
+		}
+	}
+	return lists, nil
+}
+
+// lookupList returns the position of the list with the given ID in s.lists if
+// it is owned by the caller. Must be called with s.mu held.
+func (s *Service) lookupList(ctx context.Context, id int) (int, error) {
+	i, err := s.indexOfList(id)
+	if err != nil {
+		return -1, err
+	} else if s.lists[i].OwnerID != todo.OwnerIDFromContext(ctx) {
+		return -1, todo.Errorf(todo.ENOTFOUND, "List with ID '%d' could not be found.", id)
+	}
+	return i, nil
+}
+
+// indexOfList returns the position of the list with the given ID in s.lists.
+// Must be called with s.mu held.
+func (s *Service) indexOfList(id int) (int, error) {
+	for i := range s.lists {
+		if s.lists[i].ID == id {
+			return i, nil
+		}
+	}
+	return -1, todo.Errorf(todo.ENOTFOUND, "List with ID '%d' could not be found.", id)
+}
+
+// checkListID returns EINVALID if a todo cannot be put in the list with the
+// given ID because the caller has no such list or it is a smart list. Zero
+// means no list. Must be called with s.mu held.
+func (s *Service) checkListID(ctx context.Context, id int) error {
+	if id == 0 {
+		return nil
+	} else if i, err := s.lookupList(ctx, id); err != nil {
+		return todo.Errorf(todo.EINVALID, "List with ID '%d' does not exist.", id)
+	} else if s.lists[i].Query != "" {
+		return todo.Errorf(todo.EINVALID, "Todos cannot be added to smart list with ID '%d'.", id)
+	}
+	return nil
+}
+
+// todosInList returns the todos in the list with the given ID.
+// Must be called with s.mu held.
+func (s *Service) todosInList(id int) []*todo.Todo {
+	var todos []*todo.Todo
+	for _, t := range s.todos {
+		if t.ListID == id {
+			todos = append(todos, t)
+		}
+	}
+	return todos
+}
+
+// copyList returns a copy of l so callers never share memory with the store.
+func copyList(l *todo.List) *todo.List {
+	other := *l
+	other.WIPLimits = copyLimits(l.WIPLimits)
+	other.Policies = copyPolicies(l.Policies)
+	return &other
+}
+
+// copyPolicies returns a copy of the policies of a list.
+func copyPolicies(policies []todo.Policy) []todo.Policy {
+	if len(policies) == 0 {
+		return nil
+	}
+	return append([]todo.Policy(nil), policies...)
+}
+
+// copyLimits returns a copy of the WIP limits of a list.
+func copyLimits(limits map[string]int) map[string]int {
+	if len(limits) == 0 {
+		return nil
+	}
+	other := make(map[string]int, len(limits))
+	for state, n := range limits {
+		other[state] = n
+	}
+	return other
+}
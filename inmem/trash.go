@@ -0,0 +1,223 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.TrashService = (*Service)(nil)
+var _ todo.PurgeService = (*Service)(nil)
+
+// trashed is a deleted todo along with the data deleted with it.
+type trashed struct {
+	Todo *todo.Todo `json:"todo"`
+
+	// ID of the todo whose deletion deleted this one. It is the ID of the
+	// todo itself unless it was deleted as a subtask.
+	DeletedWith int `json:"deletedWith"`
+
+	Shares       []*todo.Share      `json:"shares"`
+	Dependencies []*todo.Dependency `json:"dependencies"`
+	Transitions  []*todo.Transition `json:"transitions"`
+}
+
+func (s *Service) ListTrash(ctx context.Context, request todo.ListTrashRequest) ([]*todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todos := make([]*todo.Todo, 0)
+	for _, e := range s.trash {
+		if e.Todo.OwnerID == todo.OwnerIDFromContext(ctx) {
+			todos = append(todos, copyTodo(e.Todo))
+		}
+	}
+	sort.SliceStable(todos, func(i, j int) bool {
+		if !todos[i].DeletedAt.Equal(*todos[j].DeletedAt) {
+			return todos[i].DeletedAt.After(*todos[j].DeletedAt)
+		}
+		return todos[i].ID > todos[j].ID
+	})
+	return todos, nil
+}
+
+func (s *Service) RestoreTodo(ctx context.Context, request todo.RestoreTodoRequest) (*todo.Todo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOfTrash(request.ID)
+	if i == -1 || s.trash[i].Todo.OwnerID != todo.OwnerIDFromContext(ctx) {
+		return nil, todo.Errorf(todo.ENOTFOUND, "Deleted todo with ID '%d' could not be found.", request.ID)
+	}
+
+	// Subtasks are trashed after their parent, so a single pass finds every
+	// subtask deleted along with the todo.
+	restored := map[int]bool{request.ID: true}
+	var batch []*trashed
+	for _, e := range s.trash {
+		if e.Todo.ID == request.ID || (e.DeletedWith == s.trash[i].DeletedWith && restored[e.Todo.ParentID]) {
+			restored[e.Todo.ID] = true
+			batch = append(batch, e)
+		}
+	}
+
+	rec := &record{Op: opRestore}
+	var pending []*todo.Todo
+	for _, e := range batch {
+		t := copyTodo(e.Todo)
+		t.DeletedAt = nil
+		t.Version++
+		if _, err := s.indexOf(t.ParentID); err != nil && !restored[t.ParentID] {
+			t.ParentID = 0
+		}
+		if err := s.checkListID(ctx, t.ListID); err != nil {
+			t.ListID = 0
+		} else if t.ListID != 0 {
+			if err := s.checkLimit(t, pending...); err != nil {
+				return nil, err
+			}
+		}
+		pending = append(pending, t)
+
+		other := &trashed{Todo: t, DeletedWith: e.DeletedWith, Shares: e.Shares, Transitions: e.Transitions}
+		for _, d := range e.Dependencies {
+			if s.restorable(d.TodoID, restored) && s.restorable(d.BlockedByID, restored) && !s.dependsOn(d.BlockedByID, d.TodoID) {
+				other.Dependencies = append(other.Dependencies, d)
+			}
+		}
+		rec.Trash = append(rec.Trash, other)
+	}
+	if err := s.commit(rec); err != nil {
+		return nil, err
+	}
+
+	return s.view(pending[0]), nil
+}
+
+func (s *Service) PurgeTrash(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []int
+	for _, e := range s.trash {
+		if len(ids) < limit && e.Todo.DeletedAt.Before(deletedBefore) {
+			ids = append(ids, e.Todo.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := s.commit(&record{Op: opPurge, IDs: ids}); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// trashTodo returns the trash entries of the todo with the given ID and, if
+// cascade is set, of its subtasks, parents first. Must be called with s.mu
+// held.
+func (s *Service) trashTodo(id int, cascade bool) []*trashed {
+	now := s.Now().UTC().Truncate(time.Second)
+
+	var entries []*trashed
+	queue := []int{id}
+	for len(queue) > 0 {
+		i, err := s.indexOf(queue[0])
+		queue = queue[1:]
+		if err != nil {
+			continue
+		}
+		t := copyTodo(s.todos[i])
+		t.Blocked = false
+		t.DeletedAt = &now
+
+		e := &trashed{Todo: t, DeletedWith: id}
+		for _, sh := range s.shares {
+			if sh.TodoID == t.ID {
+				e.Shares = append(e.Shares, copyShare(sh))
+			}
+		}
+		for _, d := range s.deps {
+			if d.TodoID == t.ID || d.BlockedByID == t.ID {
+				e.Dependencies = append(e.Dependencies, copyDependency(d))
+			}
+		}
+		for _, tr := range s.trans {
+			if tr.TodoID == t.ID {
+				e.Transitions = append(e.Transitions, copyTransition(tr))
+			}
+		}
+		entries = append(entries, e)
+
+		if cascade {
+			for _, child := range s.childrenOf(t.ID) {
+				queue = append(queue, child.ID)
+			}
+		}
+	}
+	return entries
+}
+
+// restorable returns true if the todo with the given ID exists or is about to
+// be restored. Must be called with s.mu held.
+func (s *Service) restorable(id int, restored map[int]bool) bool {
+	_, err := s.indexOf(id)
+	return err == nil || restored[id]
+}
+
+// indexOfTrash returns the offset of the deleted todo with the given ID in
+// s.trash, or -1 if there is none. Must be called with s.mu held.
+func (s *Service) indexOfTrash(id int) int {
+	for i, e := range s.trash {
+		if e.Todo.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeTrash removes the deleted todos with the given IDs from the trash.
+// Must be called with s.mu held.
+func (s *Service) removeTrash(ids ...int) {
+	remove := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	trash := s.trash[:0]
+	for _, e := range s.trash {
+		if !remove[e.Todo.ID] {
+			trash = append(trash, e)
+		}
+	}
+	s.trash = trash
+}
+
+// copyTrashed returns a copy of e so callers never share memory with the
+// store.
+func copyTrashed(e *trashed) *trashed {
+	other := &trashed{Todo: copyTodo(e.Todo), DeletedWith: e.DeletedWith}
+	for _, sh := range e.Shares {
+		other.Shares = append(other.Shares, copyShare(sh))
+	}
+	for _, d := range e.Dependencies {
+		other.Dependencies = append(other.Dependencies, copyDependency(d))
+	}
+	for _, tr := range e.Transitions {
+		other.Transitions = append(other.Transitions, copyTransition(tr))
+	}
+	return other
+}
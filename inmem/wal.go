@@ -0,0 +1,318 @@
+package inmem
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"todo"
+)
+
+const (
+	// Name of the append-only log file within the data directory.
+	walFilename = "wal.log"
+
+	// Name of the most recent snapshot within the data directory.
+	snapshotFilename = "snapshot.json"
+
+	// Size of the header preceding every log record: payload length followed
+	// by the CRC-32 checksum of the payload, both big endian uint32.
+	recordHeaderSize = 8
+)
+
+// DefaultSnapshotThreshold is the number of log records after which the log is
+// compacted into a snapshot.
+const DefaultSnapshotThreshold = 1000
+
+// Log record operations.
+const (
+	opPut         = "put"
+	opDelete      = "delete"
+	opPutShare    = "put_share"
+	opDeleteShare = "delete_share"
+	opPutList     = "put_list"
+	opDeleteList  = "delete_list"
+
+	opPutDependency    = "put_dependency"
+	opDeleteDependency = "delete_dependency"
+
+	opPutRevision     = "put_revision"
+	opDeleteRevisions = "delete_revisions"
+
+	opRestore = "restore"
+	opPurge   = "purge"
+
+	opBatch = "batch"
+)
+
+// record represents a single mutation stored in the write-ahead log.
+type record struct {
+	// Sequence number of the record. Records already covered by a snapshot
+	// are skipped on replay.
+	Seq uint64 `json:"seq"`
+
+	Op         string           `json:"op"`
+	Todo       *todo.Todo       `json:"todo,omitempty"`
+	Share      *todo.Share      `json:"share,omitempty"`
+	List       *todo.List       `json:"list,omitempty"`
+	ID         int              `json:"id,omitempty"`
+	Dependency *todo.Dependency `json:"dependency,omitempty"`
+	Revision   *todo.Revision   `json:"revision,omitempty"`
+
+	// IDs of the revisions deleted when pruning them, or of the todos purged
+	// from the trash.
+	IDs []int `json:"ids,omitempty"`
+
+	// Deleted todos moved to the trash or restored from it.
+	Trash []*trashed `json:"trash,omitempty"`
+
+	// Next occurrence of a recurring todo created by the same change, so a
+	// completed occurrence is never logged without its successor.
+	Next *todo.Todo `json:"next,omitempty"`
+
+	// Further todos changed by the same operation, e.g. when renaming a tag.
+	Todos []*todo.Todo `json:"todos,omitempty"`
+
+	// State transitions made by the operation.
+	Transitions []*todo.Transition `json:"transitions,omitempty"`
+
+	// Set when deleting a list also deletes the todos in it, or deleting a
+	// todo also deletes its subtasks.
+	Cascade bool `json:"cascade,omitempty"`
+
+	// Records of an atomic batch, applied together so a batch is never
+	// replayed in part.
+	Records []*record `json:"records,omitempty"`
+}
+
+// snapshot represents the full state of the service at a given sequence number.
+type snapshot struct {
+	Seq          uint64             `json:"seq"`
+	NextID       int                `json:"nextId"`
+	NextListID   int                `json:"nextListId"`
+	NextRevID    int                `json:"nextRevisionId"`
+	Todos        []*todo.Todo       `json:"todos"`
+	Shares       []*todo.Share      `json:"shares"`
+	Lists        []*todo.List       `json:"lists"`
+	Dependencies []*todo.Dependency `json:"dependencies"`
+	Transitions  []*todo.Transition `json:"transitions"`
+	Revisions    []*todo.Revision   `json:"revisions"`
+	Trash        []*trashed         `json:"trash"`
+}
+
+// wal is an append-only, fsynced log of records.
+type wal struct {
+	f    *os.File
+	dir  string
+	seq  uint64 // sequence number of the last record written
+	size int64  // size of the log file
+	n    int    // number of records written since the last snapshot
+}
+
+// openWAL opens the log in dir, calling fn for every record found after the
+// snapshot sequence number seq. A torn record at the end of the log, left
+// behind by a crash mid-write, is truncated. Corruption anywhere else is
+// reported as an error.
+func openWAL(dir string, seq uint64, fn func(rec *record)) (*wal, error) {
+	f, err := os.OpenFile(filepath.Join(dir, walFilename), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &wal{f: f, dir: dir, seq: seq}
+	if err := w.replay(fn); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// replay reads every record from the start of the log.
+func (w *wal) replay(fn func(rec *record)) error {
+	fi, err := w.f.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size()
+
+	r := bufio.NewReader(io.NewSectionReader(w.f, 0, size))
+	var offset int64
+	for offset < size {
+		payload, err := readRecord(r, size-offset)
+		if errors.Is(err, io.ErrUnexpectedEOF) || (errors.Is(err, errChecksum) && offset+recordHeaderSize+int64(len(payload)) == size) {
+			// The last record was only partially written. Drop it so new
+			// records are appended after the last complete one.
+			if err := w.f.Truncate(offset); err != nil {
+				return err
+			} else if err := w.f.Sync(); err != nil {
+				return err
+			}
+			size = offset
+			break
+		} else if err != nil {
+			return fmt.Errorf("wal: record at offset %d: %w", offset, err)
+		}
+
+		var rec record
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return fmt.Errorf("wal: record at offset %d: %w", offset, err)
+		}
+		offset += recordHeaderSize + int64(len(payload))
+
+		// Skip records already contained in the snapshot.
+		if rec.Seq <= w.seq {
+			continue
+		}
+		fn(&rec)
+		w.seq = rec.Seq
+		w.n++
+	}
+	w.size = size
+
+	return nil
+}
+
+// errChecksum is returned when a record's payload does not match its checksum.
+var errChecksum = errors.New("checksum mismatch")
+
+// readRecord reads the next record payload from r, which has remaining bytes
+// left. Returns io.ErrUnexpectedEOF if the record is incomplete.
+func readRecord(r io.Reader, remaining int64) ([]byte, error) {
+	var hdr [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+
+	n := int64(binary.BigEndian.Uint32(hdr[0:4]))
+	if n > remaining-recordHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(hdr[4:8]) {
+		return payload, errChecksum
+	}
+	return payload, nil
+}
+
+// append assigns the next sequence number to rec and durably writes it to
+// the log. The record is not considered written unless append returns nil.
+func (w *wal) append(rec *record) error {
+	rec.Seq = w.seq + 1
+
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[recordHeaderSize:], payload)
+
+	if _, err := w.f.Write(buf); err != nil {
+		w.rollback()
+		return err
+	} else if err := w.f.Sync(); err != nil {
+		w.rollback()
+		return err
+	}
+
+	w.seq = rec.Seq
+	w.size += int64(len(buf))
+	w.n++
+	return nil
+}
+
+// rollback removes a partially written record so it cannot be replayed.
+func (w *wal) rollback() {
+	_ = w.f.Truncate(w.size)
+}
+
+// compact writes snap as the new snapshot and empties the log. The snapshot is
+// written to a temporary file and renamed into place so a crash never leaves a
+// partial snapshot behind. If a crash happens before the log is truncated, the
+// records are skipped on replay as they are covered by the snapshot.
+func (w *wal) compact(snap *snapshot) error {
+	snap.Seq = w.seq
+
+	buf, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err := writeFileSync(filepath.Join(w.dir, snapshotFilename), buf); err != nil {
+		return err
+	}
+
+	if err := w.f.Truncate(0); err != nil {
+		return err
+	} else if err := w.f.Sync(); err != nil {
+		return err
+	}
+	w.size, w.n = 0, 0
+
+	return nil
+}
+
+// Close closes the log file.
+func (w *wal) Close() error {
+	return w.f.Close()
+}
+
+// readSnapshot reads the snapshot in dir. Returns an empty snapshot if none exists.
+func readSnapshot(dir string) (*snapshot, error) {
+	buf, err := os.ReadFile(filepath.Join(dir, snapshotFilename))
+	if os.IsNotExist(err) {
+		return &snapshot{NextID: 1, NextListID: 1}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(buf, &snap); err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// writeFileSync atomically replaces filename with data.
+func writeFileSync(filename string, data []byte) error {
+	tmp := filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	} else if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, filename); err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename itself is durable.
+	d, err := os.Open(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
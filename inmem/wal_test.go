@@ -0,0 +1,413 @@
+package inmem_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"todo"
+	"todo/inmem"
+	"todo/todotest"
+)
+
+func TestService_Recovery(t *testing.T) {
+	t.Run("ReplayLog", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: a.ID, Value: "a2", Complete: true}); err != nil {
+			t.Fatal(err)
+		} else if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		}
+		want := mustGetAllTodos(t, s)
+
+		// Simulate a crash by reopening without closing, so only the log is
+		// available to restore from.
+		s = openService(t, dir, 0)
+		if got := mustGetAllTodos(t, s); !reflect.DeepEqual(got, want) {
+			t.Fatalf("todos=%#v, want %#v", got, want)
+		}
+
+		// The ID of the deleted todo must not be handed out again.
+		if c := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"}); c.ID <= b.ID {
+			t.Fatalf("ID=%d, want greater than %d", c.ID, b.ID)
+		}
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 3)
+		var last *todo.Todo
+		for i := 0; i < 10; i++ {
+			last = todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x"})
+		}
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: last.ID}); err != nil {
+			t.Fatal(err)
+		}
+		want := mustGetAllTodos(t, s)
+
+		if _, err := os.Stat(filepath.Join(dir, "snapshot.json")); err != nil {
+			t.Fatal(err)
+		}
+
+		s = openService(t, dir, 3)
+		if got := mustGetAllTodos(t, s); !reflect.DeepEqual(got, want) {
+			t.Fatalf("todos=%#v, want %#v", got, want)
+		}
+		if next := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "y"}); next.ID <= last.ID {
+			t.Fatalf("ID=%d, want greater than %d", next.ID, last.ID)
+		}
+	})
+
+	t.Run("TornRecord", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		want := mustGetAllTodos(t, s)
+
+		// Append half a record as if the process died in the middle of a write.
+		f, err := os.OpenFile(filepath.Join(dir, "wal.log"), os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := f.Write([]byte{0, 0, 0, 42, 1, 2}); err != nil {
+			t.Fatal(err)
+		} else if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		s = openService(t, dir, 0)
+		if got := mustGetAllTodos(t, s); !reflect.DeepEqual(got, want) {
+			t.Fatalf("todos=%#v, want %#v", got, want)
+		}
+
+		// New records must be readable after the truncated one.
+		b := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		s = openService(t, dir, 0)
+		if _, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Lists", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateList(t, ctx, s, todo.CreateListRequest{Name: "a"})
+		b := todotest.MustCreateList(t, ctx, s, todo.CreateListRequest{Name: "b"})
+		todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x", ListID: a.ID})
+		want := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "y", ListID: b.ID})
+		if err := s.DeleteList(ctx, todo.DeleteListRequest{ID: a.ID, Cascade: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Replaying a cascading delete removes the list's todos again.
+		s = openService(t, dir, 0)
+		if got := mustGetAllTodos(t, s); !reflect.DeepEqual(got, []*todo.Todo{want}) {
+			t.Fatalf("todos=%#v, want %#v", got, []*todo.Todo{want})
+		} else if lists, err := s.ListLists(ctx, todo.ListListsRequest{}); err != nil {
+			t.Fatal(err)
+		} else if len(lists) != 1 || lists[0].ID != b.ID {
+			t.Fatalf("unexpected lists: %#v", lists)
+		}
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+		now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		s := openService(t, dir, 0)
+		s.Now = func() time.Time { return now }
+		l := todotest.MustCreateList(t, ctx, s, todo.CreateListRequest{Name: "l", Policies: []todo.Policy{
+			{Action: todo.PolicyArchive, AfterDays: 1},
+			{Action: todo.PolicyPurge, AfterDays: 1},
+		}})
+		a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", ListID: l.ID, Complete: true})
+		b := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", ListID: l.ID, Complete: true})
+		if _, err := s.EnforcePolicies(ctx, now.AddDate(0, 0, 1), 1); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.EnforcePolicies(ctx, now.AddDate(0, 0, 2), 10); err != nil {
+			t.Fatal(err)
+		}
+
+		// Policies, archived todos & purged todos survive a restart.
+		s = openService(t, dir, 0)
+		if got, err := s.GetListByID(ctx, todo.GetListByIDRequest{ID: l.ID}); err != nil {
+			t.Fatal(err)
+		} else if len(got.Policies) != 2 {
+			t.Fatalf("unexpected policies: %v", got.Policies)
+		}
+		if got := todotest.MustListTodos(t, ctx, s, todo.ListTodosRequest{Archived: true}).Todos; len(got) != 1 || got[0].ID != b.ID || got[0].ArchivedAt == nil {
+			t.Fatalf("unexpected todos: %#v", got)
+		} else if trash, err := s.ListTrash(ctx, todo.ListTrashRequest{}); err != nil {
+			t.Fatal(err)
+		} else if len(trash) != 1 || trash[0].ID != a.ID || trash[0].ArchivedAt == nil {
+			t.Fatalf("unexpected trash: %#v", trash)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		resp, err := s.BatchTodos(ctx, todo.BatchTodosRequest{Ops: []todo.BatchOp{
+			{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "b"}},
+			{Op: todo.BatchDelete, Delete: &todo.DeleteTodoRequest{ID: a.ID}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := resp.Results[0].Todo
+
+		// A failed atomic batch is not logged.
+		if _, err := s.BatchTodos(ctx, todo.BatchTodosRequest{Ops: []todo.BatchOp{
+			{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "c"}},
+			{Op: todo.BatchDelete, Delete: &todo.DeleteTodoRequest{ID: a.ID}},
+		}}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		s = openService(t, dir, 0)
+		if got := mustGetAllTodos(t, s); len(got) != 1 || got[0].ID != b.ID {
+			t.Fatalf("unexpected todos: %#v", got)
+		} else if trash, err := s.ListTrash(ctx, todo.ListTrashRequest{}); err != nil {
+			t.Fatal(err)
+		} else if len(trash) != 1 || trash[0].ID != a.ID {
+			t.Fatalf("unexpected trash: %#v", trash)
+		}
+
+		// IDs of the todos created by the batch are not reused.
+		if c := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"}); c.ID <= b.ID {
+			t.Fatalf("ID=%d, want more than %d", c.ID, b.ID)
+		}
+	})
+
+	t.Run("Shares", func(t *testing.T) {
+		dir := t.TempDir()
+		alice := todotest.NewContextWithPrincipalID(context.Background(), "alice")
+		bob := todotest.NewContextWithPrincipalID(context.Background(), "bob")
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		want := todotest.MustCreateShare(t, alice, s, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+
+		// Shares are restored from the log first, then from the snapshot
+		// taken when the next todo is created.
+		for _, threshold := range []int{1, 0} {
+			s = openService(t, dir, threshold)
+			if got, err := s.GetShare(bob, todo.GetShareRequest{TodoID: a.ID}); err != nil {
+				t.Fatal(err)
+			} else if !got.CreatedAt.Equal(want.CreatedAt) || got.Role != want.Role {
+				t.Fatalf("share=%#v, want %#v", got, want)
+			}
+			todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "x"})
+		}
+	})
+
+	t.Run("Dependencies", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		todotest.MustCreateDependency(t, ctx, s, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: b.ID})
+
+		// Dependencies are restored from the log first, then from the
+		// snapshot taken when the next todo is created.
+		for _, threshold := range []int{1, 0} {
+			s = openService(t, dir, threshold)
+			if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+				t.Fatal(err)
+			} else if !got.Blocked {
+				t.Fatal("expected todo to be blocked")
+			}
+			todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x"})
+		}
+	})
+
+	t.Run("Positions", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		if _, err := s.MoveTodo(ctx, todo.MoveTodoRequest{ID: b.ID, BeforeID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The order is restored from the log first, then from the snapshot
+		// taken when the next todo is created, which goes last.
+		for _, threshold := range []int{1, 0} {
+			s = openService(t, dir, threshold)
+			c := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+			resp := todotest.MustListTodos(t, ctx, s, todo.ListTodosRequest{SortBy: todo.SortByPosition})
+			if got := resp.Todos; got[0].ID != b.ID || got[1].ID != a.ID || got[len(got)-1].ID != c.ID {
+				t.Fatalf("unexpected order: %#v", got)
+			}
+		}
+	})
+
+	t.Run("Transitions", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		state := todo.StateInProgress
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, State: &state}); err != nil {
+			t.Fatal(err)
+		}
+
+		// States & transitions are restored from the log first, then from
+		// the snapshot taken when the next todo is created.
+		for _, threshold := range []int{1, 0} {
+			s = openService(t, dir, threshold)
+			if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+				t.Fatal(err)
+			} else if got.State != todo.StateInProgress {
+				t.Fatalf("unexpected state: %q", got.State)
+			} else if trans := todotest.MustListTransitions(t, ctx, s, todo.ListTransitionsRequest{TodoID: a.ID}); len(trans) != 2 || trans[1].To != todo.StateInProgress {
+				t.Fatalf("unexpected transitions: %#v", trans)
+			}
+			todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x"})
+		}
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", Tags: []string{"job"}})
+		if _, err := s.RenameTag(ctx, todo.RenameTagRequest{Name: "job", NewName: "work"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The tag index is rebuilt from the log first, then from the snapshot
+		// taken when the next todo is created.
+		for _, threshold := range []int{1, 0} {
+			s = openService(t, dir, threshold)
+			if got := todotest.MustListTodos(t, ctx, s, todo.ListTodosRequest{Tags: []string{"work"}}).Todos; len(got) != 1 || got[0].ID != a.ID {
+				t.Fatalf("unexpected todos: %#v", got)
+			} else if got[0].Version != a.Version+1 {
+				t.Fatalf("unexpected version: %d", got[0].Version)
+			} else if tags := todotest.MustListTags(t, ctx, s); len(tags) != 1 || tags[0].Name != "work" {
+				t.Fatalf("unexpected tags: %#v", tags)
+			}
+			todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x"})
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Call the plumber"})
+		if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: a.ID, Value: "Call the electrician"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The search index is rebuilt from the log first, then from the
+		// snapshot taken when the next todo is created.
+		for _, threshold := range []int{1, 0} {
+			s = openService(t, dir, threshold)
+			if got := todotest.MustSearchTodos(t, ctx, s, todo.SearchTodosRequest{Query: "electricians"}); len(got.Results) != 1 || got.Results[0].Todo.ID != a.ID {
+				t.Fatalf("unexpected results: %#v", got.Results)
+			} else if got := todotest.MustSearchTodos(t, ctx, s, todo.SearchTodosRequest{Query: "plumber"}); len(got.Results) != 0 {
+				t.Fatalf("unexpected results: %#v", got.Results)
+			}
+			todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x"})
+		}
+	})
+
+	t.Run("Revisions", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		var last *todo.Revision
+		for _, v := range []string{"a", "b", "c"} {
+			last = &todo.Revision{TodoID: 1, Todo: &todo.Todo{ID: 1, Value: v}}
+			if err := s.CreateRevision(ctx, last); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.PruneRevisions(ctx, 1, todo.RetentionPolicy{MaxRevisions: 2}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Pruned revisions stay deleted, and their IDs are not handed out
+		// again.
+		c := last
+		for _, threshold := range []int{1, 0} {
+			s = openService(t, dir, threshold)
+			if revs, err := s.FindRevisions(ctx, 1); err != nil {
+				t.Fatal(err)
+			} else if len(revs) != 2 || revs[0].Todo.Value != "b" || revs[1].ID != c.ID {
+				t.Fatalf("unexpected revisions: %#v", revs)
+			}
+			r := &todo.Revision{TodoID: 2, Todo: &todo.Todo{ID: 2, Value: "x"}}
+			if err := s.CreateRevision(ctx, r); err != nil {
+				t.Fatal(err)
+			} else if r.ID <= last.ID {
+				t.Fatalf("ID=%d, want greater than %d", r.ID, last.ID)
+			}
+			last = r
+			todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x"})
+		}
+	})
+
+	t.Run("Trash", func(t *testing.T) {
+		dir, ctx := t.TempDir(), context.Background()
+
+		s := openService(t, dir, 0)
+		a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		for _, id := range []int{a.ID, b.ID} {
+			if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.RestoreTodo(ctx, todo.RestoreTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The trash is rebuilt from the log first, then from the snapshot
+		// taken when the next todo is created.
+		for _, threshold := range []int{1, 0} {
+			s = openService(t, dir, threshold)
+			if got, err := s.ListTrash(ctx, todo.ListTrashRequest{}); err != nil {
+				t.Fatal(err)
+			} else if len(got) != 1 || got[0].ID != b.ID || got[0].DeletedAt == nil {
+				t.Fatalf("unexpected todos: %#v", got)
+			} else if _, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+				t.Fatal(err)
+			}
+			todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x"})
+		}
+	})
+}
+
+// openService opens a service in dir without closing it at the end of the
+// test, so a later open of the same directory behaves like a restart after a
+// crash.
+func openService(tb testing.TB, dir string, snapshotThreshold int) *inmem.Service {
+	tb.Helper()
+
+	s := inmem.NewService()
+	s.Dir = dir
+	s.SnapshotThreshold = snapshotThreshold
+	if err := s.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	return s
+}
+
+func mustGetAllTodos(tb testing.TB, s todo.Service) []*todo.Todo {
+	tb.Helper()
+	return todotest.MustListTodos(tb, context.Background(), s, todo.ListTodosRequest{Limit: todo.MaxListLimit}).Todos
+}
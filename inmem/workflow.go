@@ -0,0 +1,128 @@
+package inmem
+
+import (
+	"context"
+	"todo"
+)
+
+func (s *Service) GetWorkflow(ctx context.Context, request todo.GetWorkflowRequest) (*todo.Workflow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return copyWorkflow(s.workflow()), nil
+}
+
+func (s *Service) ListTransitions(ctx context.Context, request todo.ListTransitionsRequest) ([]*todo.Transition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.lookup(ctx, request.TodoID); err != nil {
+		return nil, err
+	}
+
+	// Transitions are appended as they are made, so they are already
+	// ordered oldest first.
+	trans := make([]*todo.Transition, 0)
+	for _, tr := range s.trans {
+		if tr.TodoID == request.TodoID {
+			trans = append(trans, copyTransition(tr))
+		}
+	}
+	return trans, nil
+}
+
+// workflow returns the workflow todos follow.
+func (s *Service) workflow() *todo.Workflow {
+	if s.Workflow == nil {
+		return todo.DefaultWorkflow
+	}
+	return s.Workflow
+}
+
+// transition moves t, which changed from prev or is new if prev is nil, to
+// its new state and adds the transition to rec. Returns ECONFLICT if the list
+// of t has no room left in that state. The given todos are part of the same
+// change but not committed yet. Must be called with s.mu held.
+func (s *Service) transition(rec *record, t, prev *todo.Todo, pending ...*todo.Todo) error {
+	tr, err := s.workflow().Transition(t, prev, s.Now())
+	if err != nil {
+		return err
+	} else if t.EntersList(prev) {
+		if err := s.checkLimit(t, pending...); err != nil {
+			return err
+		}
+	}
+	if tr != nil {
+		rec.Transitions = append(rec.Transitions, tr)
+	}
+	return nil
+}
+
+// checkLimit returns ECONFLICT if t cannot enter its state in its list because
+// the list is at its limit for that state. Must be called with s.mu held.
+func (s *Service) checkLimit(t *todo.Todo, pending ...*todo.Todo) error {
+	i, err := s.indexOfList(t.ListID)
+	if err != nil {
+		return err
+	} else if len(s.lists[i].WIPLimits) == 0 {
+		return nil
+	}
+
+	changed := map[int]*todo.Todo{t.ID: t}
+	for _, other := range pending {
+		changed[other.ID] = other
+	}
+	var n int
+	for _, other := range s.todos {
+		if _, ok := changed[other.ID]; !ok && other.ListID == t.ListID && other.State == t.State {
+			n++
+		}
+	}
+	for _, other := range pending {
+		if other.ListID == t.ListID && other.State == t.State {
+			n++
+		}
+	}
+	return s.lists[i].CheckLimit(t.State, n)
+}
+
+// removeTransitions removes the transitions of the todo with the given ID.
+// Must be called with s.mu held.
+func (s *Service) removeTransitions(id int) {
+	if s.staged != nil {
+		prev := append([]*todo.Transition(nil), s.trans...)
+		s.onUndo(func() { s.trans = prev })
+	}
+	trans := s.trans[:0]
+	for _, tr := range s.trans {
+		if tr.TodoID != id {
+			trans = append(trans, tr)
+		}
+	}
+	s.trans = trans
+}
+
+// copyTransition returns a copy of tr so callers never share memory with the store.
+func copyTransition(tr *todo.Transition) *todo.Transition {
+	other := *tr
+	return &other
+}
+
+// copyWorkflow returns a copy of w so callers cannot change it.
+func copyWorkflow(w *todo.Workflow) *todo.Workflow {
+	other := &todo.Workflow{
+		States: append([]string(nil), w.States...),
+		Closed: append([]string(nil), w.Closed...),
+	}
+	if w.Transitions != nil {
+		other.Transitions = make(map[string][]string, len(w.Transitions))
+		for from, states := range w.Transitions {
+			other.Transitions[from] = append([]string(nil), states...)
+		}
+	}
+	return other
+}
@@ -0,0 +1,83 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewDependencyInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.DependencyMiddleware {
+	return func(next todo.DependencyService) todo.DependencyService {
+		return dependencyInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type dependencyInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.DependencyService
+}
+
+func (mw dependencyInstrumentingMiddleware) CreateDependency(ctx context.Context, request todo.CreateDependencyRequest) (d *todo.Dependency, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "CreateDependency", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	d, err = mw.service.CreateDependency(ctx, request)
+	return
+}
+
+func (mw dependencyInstrumentingMiddleware) DeleteDependency(ctx context.Context, request todo.DeleteDependencyRequest) (err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "DeleteDependency", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	err = mw.service.DeleteDependency(ctx, request)
+	return
+}
+
+func (mw dependencyInstrumentingMiddleware) ListDependencies(ctx context.Context, request todo.ListDependenciesRequest) (deps []*todo.Dependency, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ListDependencies", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	deps, err = mw.service.ListDependencies(ctx, request)
+	return
+}
+
+func (mw dependencyInstrumentingMiddleware) ListActionableTodos(ctx context.Context, request todo.ListActionableTodosRequest) (todos []*todo.Todo, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ListActionableTodos", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	todos, err = mw.service.ListActionableTodos(ctx, request)
+	return
+}
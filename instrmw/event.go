@@ -0,0 +1,46 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewEventInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.EventMiddleware {
+	return func(next todo.EventService) todo.EventService {
+		return eventInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type eventInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.EventService
+}
+
+// Subscribe is instrumented until the subscription starts, not for as long as
+// it lasts.
+func (mw eventInstrumentingMiddleware) Subscribe(ctx context.Context, request todo.SubscribeRequest) (sub *todo.Subscription, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "Subscribe", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	sub, err = mw.service.Subscribe(ctx, request)
+	return
+}
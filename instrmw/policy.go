@@ -0,0 +1,44 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewPolicyInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.PolicyMiddleware {
+	return func(next todo.PolicyService) todo.PolicyService {
+		return policyInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type policyInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.PolicyService
+}
+
+func (mw policyInstrumentingMiddleware) ReportPolicies(ctx context.Context, request todo.ReportPoliciesRequest) (report *todo.PolicyReport, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ReportPolicies", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	report, err = mw.service.ReportPolicies(ctx, request)
+	return
+}
@@ -0,0 +1,57 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewRevisionInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.RevisionMiddleware {
+	return func(next todo.RevisionService) todo.RevisionService {
+		return revisionInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type revisionInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.RevisionService
+}
+
+func (mw revisionInstrumentingMiddleware) ListRevisions(ctx context.Context, request todo.ListRevisionsRequest) (revs []*todo.Revision, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ListRevisions", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	revs, err = mw.service.ListRevisions(ctx, request)
+	return
+}
+
+func (mw revisionInstrumentingMiddleware) RestoreRevision(ctx context.Context, request todo.RestoreRevisionRequest) (t *todo.Todo, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "RestoreRevision", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	t, err = mw.service.RestoreRevision(ctx, request)
+	return
+}
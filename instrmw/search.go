@@ -0,0 +1,44 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewSearchInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.SearchMiddleware {
+	return func(next todo.SearchService) todo.SearchService {
+		return searchInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type searchInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.SearchService
+}
+
+func (mw searchInstrumentingMiddleware) SearchTodos(ctx context.Context, request todo.SearchTodosRequest) (resp *todo.SearchTodosResponse, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "SearchTodos", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	resp, err = mw.service.SearchTodos(ctx, request)
+	return
+}
@@ -0,0 +1,83 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewShareInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.ShareMiddleware {
+	return func(next todo.ShareService) todo.ShareService {
+		return shareInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type shareInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.ShareService
+}
+
+func (mw shareInstrumentingMiddleware) CreateShare(ctx context.Context, request todo.CreateShareRequest) (sh *todo.Share, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "CreateShare", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	sh, err = mw.service.CreateShare(ctx, request)
+	return
+}
+
+func (mw shareInstrumentingMiddleware) DeleteShare(ctx context.Context, request todo.DeleteShareRequest) (err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "DeleteShare", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	err = mw.service.DeleteShare(ctx, request)
+	return
+}
+
+func (mw shareInstrumentingMiddleware) GetShare(ctx context.Context, request todo.GetShareRequest) (sh *todo.Share, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "GetShare", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	sh, err = mw.service.GetShare(ctx, request)
+	return
+}
+
+func (mw shareInstrumentingMiddleware) ListShares(ctx context.Context, request todo.ListSharesRequest) (shares []*todo.Share, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ListShares", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	shares, err = mw.service.ListShares(ctx, request)
+	return
+}
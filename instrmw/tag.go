@@ -0,0 +1,70 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewTagInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.TagMiddleware {
+	return func(next todo.TagService) todo.TagService {
+		return tagInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type tagInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.TagService
+}
+
+func (mw tagInstrumentingMiddleware) ListTags(ctx context.Context, request todo.ListTagsRequest) (tags []*todo.Tag, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ListTags", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	tags, err = mw.service.ListTags(ctx, request)
+	return
+}
+
+func (mw tagInstrumentingMiddleware) RenameTag(ctx context.Context, request todo.RenameTagRequest) (tag *todo.Tag, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "RenameTag", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	tag, err = mw.service.RenameTag(ctx, request)
+	return
+}
+
+func (mw tagInstrumentingMiddleware) MergeTags(ctx context.Context, request todo.MergeTagsRequest) (tag *todo.Tag, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "MergeTags", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	tag, err = mw.service.MergeTags(ctx, request)
+	return
+}
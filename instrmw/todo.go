@@ -56,6 +56,19 @@ func (mw todoInstrumentingMiddleware) UpdateTodo(ctx context.Context, request to
 	return
 }
 
+func (mw todoInstrumentingMiddleware) PatchTodo(ctx context.Context, request todo.PatchTodoRequest) (t *todo.Todo, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "PatchTodo", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	t, err = mw.service.PatchTodo(ctx, request)
+	return
+}
+
 func (mw todoInstrumentingMiddleware) DeleteTodo(ctx context.Context, request todo.DeleteTodoRequest) (err error) {
 	defer func(begin time.Time) {
 		lvs := []string{"method", "DeleteTodo", "error", fmt.Sprint(err != nil)}
@@ -82,15 +95,70 @@ func (mw todoInstrumentingMiddleware) GetTodoByID(ctx context.Context, request t
 	return
 }
 
-func (mw todoInstrumentingMiddleware) GetAllTodos(ctx context.Context) (t []*todo.Todo, err error) {
+func (mw todoInstrumentingMiddleware) ListTodos(ctx context.Context, request todo.ListTodosRequest) (resp *todo.ListTodosResponse, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ListTodos", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	resp, err = mw.service.ListTodos(ctx, request)
+	return
+}
+
+func (mw todoInstrumentingMiddleware) CompleteTodo(ctx context.Context, request todo.CompleteTodoRequest) (resp *todo.CompleteTodoResponse, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "CompleteTodo", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	resp, err = mw.service.CompleteTodo(ctx, request)
+	return
+}
+
+func (mw todoInstrumentingMiddleware) MoveTodo(ctx context.Context, request todo.MoveTodoRequest) (t *todo.Todo, err error) {
 	defer func(begin time.Time) {
-		lvs := []string{"method", "GetAllTodos", "error", fmt.Sprint(err != nil)}
+		lvs := []string{"method", "MoveTodo", "error", fmt.Sprint(err != nil)}
 		mw.requestCount.With(lvs...).Add(1)
 		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
 		if err != nil {
 			mw.errorCount.With(lvs...).Add(1)
 		}
 	}(time.Now())
-	t, err = mw.service.GetAllTodos(ctx)
+	t, err = mw.service.MoveTodo(ctx, request)
+	return
+}
+
+// BatchTodos is timed as a whole, as the backend applies every operation in a
+// single call. Operations are only counted, as "BatchTodos.create" and so on,
+// and count as errors if they failed on their own or were rolled back along
+// with an atomic batch.
+func (mw todoInstrumentingMiddleware) BatchTodos(ctx context.Context, request todo.BatchTodosRequest) (resp *todo.BatchTodosResponse, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "BatchTodos", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+
+		for i, op := range request.Ops {
+			failed := err != nil
+			if resp != nil && i < len(resp.Results) {
+				failed = resp.Results[i].Err != nil
+			}
+			lvs := []string{"method", "BatchTodos." + op.Op, "error", fmt.Sprint(failed)}
+			mw.requestCount.With(lvs...).Add(1)
+			if failed {
+				mw.errorCount.With(lvs...).Add(1)
+			}
+		}
+	}(time.Now())
+	resp, err = mw.service.BatchTodos(ctx, request)
 	return
 }
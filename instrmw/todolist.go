@@ -0,0 +1,96 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewListInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.ListMiddleware {
+	return func(next todo.ListService) todo.ListService {
+		return listInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type listInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.ListService
+}
+
+func (mw listInstrumentingMiddleware) CreateList(ctx context.Context, request todo.CreateListRequest) (l *todo.List, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "CreateList", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	l, err = mw.service.CreateList(ctx, request)
+	return
+}
+
+func (mw listInstrumentingMiddleware) UpdateList(ctx context.Context, request todo.UpdateListRequest) (l *todo.List, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "UpdateList", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	l, err = mw.service.UpdateList(ctx, request)
+	return
+}
+
+func (mw listInstrumentingMiddleware) DeleteList(ctx context.Context, request todo.DeleteListRequest) (err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "DeleteList", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	err = mw.service.DeleteList(ctx, request)
+	return
+}
+
+func (mw listInstrumentingMiddleware) GetListByID(ctx context.Context, request todo.GetListByIDRequest) (l *todo.List, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "GetListByID", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	l, err = mw.service.GetListByID(ctx, request)
+	return
+}
+
+func (mw listInstrumentingMiddleware) ListLists(ctx context.Context, request todo.ListListsRequest) (lists []*todo.List, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ListLists", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	lists, err = mw.service.ListLists(ctx, request)
+	return
+}
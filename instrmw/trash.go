@@ -0,0 +1,57 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewTrashInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.TrashMiddleware {
+	return func(next todo.TrashService) todo.TrashService {
+		return trashInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type trashInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.TrashService
+}
+
+func (mw trashInstrumentingMiddleware) ListTrash(ctx context.Context, request todo.ListTrashRequest) (todos []*todo.Todo, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ListTrash", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	todos, err = mw.service.ListTrash(ctx, request)
+	return
+}
+
+func (mw trashInstrumentingMiddleware) RestoreTodo(ctx context.Context, request todo.RestoreTodoRequest) (t *todo.Todo, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "RestoreTodo", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	t, err = mw.service.RestoreTodo(ctx, request)
+	return
+}
@@ -0,0 +1,57 @@
+package instrmw
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kit/kit/metrics"
+	"time"
+	"todo"
+)
+
+func NewWorkflowInstrumentingMiddleware(
+	requestCount metrics.Counter,
+	errorCount metrics.Counter,
+	requestDuration metrics.Histogram,
+) todo.WorkflowMiddleware {
+	return func(next todo.WorkflowService) todo.WorkflowService {
+		return workflowInstrumentingMiddleware{
+			requestCount:    requestCount,
+			errorCount:      errorCount,
+			requestDuration: requestDuration,
+			service:         next,
+		}
+	}
+}
+
+type workflowInstrumentingMiddleware struct {
+	requestCount    metrics.Counter
+	errorCount      metrics.Counter
+	requestDuration metrics.Histogram
+	service         todo.WorkflowService
+}
+
+func (mw workflowInstrumentingMiddleware) GetWorkflow(ctx context.Context, request todo.GetWorkflowRequest) (w *todo.Workflow, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "GetWorkflow", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	w, err = mw.service.GetWorkflow(ctx, request)
+	return
+}
+
+func (mw workflowInstrumentingMiddleware) ListTransitions(ctx context.Context, request todo.ListTransitionsRequest) (trans []*todo.Transition, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "ListTransitions", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestDuration.With(lvs...).Observe(time.Since(begin).Seconds())
+		if err != nil {
+			mw.errorCount.With(lvs...).Add(1)
+		}
+	}(time.Now())
+	trans, err = mw.service.ListTransitions(ctx, request)
+	return
+}
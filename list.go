@@ -0,0 +1,275 @@
+package todo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Fields todos can be sorted by.
+const (
+	SortByID       = "id"
+	SortByValue    = "value"
+	SortByComplete = "complete"
+	SortByPriority = "priority"
+	SortByPosition = "position"
+)
+
+// Sort directions.
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// Page size limits for ListTodos.
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// Normalize fills in defaults and validates the request. Backends call it
+// before listing so every implementation interprets a request the same way.
+func (r *ListTodosRequest) Normalize() error {
+	q, err := ParseQuery(r.Query)
+	if err != nil {
+		return err
+	} else if q.SortBy != "" {
+		r.SortBy, r.SortDirection = q.SortBy, q.SortDirection
+	}
+	r.where = q.Where
+
+	// The order of a smart list only applies if the request has none.
+	if r.listQuery != "" {
+		q, err := ParseQuery(r.listQuery)
+		if err != nil {
+			return err
+		} else if r.SortBy == "" && q.SortBy != "" {
+			r.SortBy, r.SortDirection = q.SortBy, q.SortDirection
+		}
+		r.where = and(r.where, q.Where)
+	}
+
+	if r.SortBy == "" {
+		r.SortBy = SortByID
+	}
+	switch r.SortBy {
+	case SortByID, SortByValue, SortByComplete, SortByPriority, SortByPosition:
+	default:
+		return Errorf(EINVALID, "Invalid sort field '%s'.", r.SortBy)
+	}
+
+	if r.SortDirection == "" {
+		r.SortDirection = SortAsc
+	}
+	if r.SortDirection != SortAsc && r.SortDirection != SortDesc {
+		return Errorf(EINVALID, "Invalid sort direction '%s'.", r.SortDirection)
+	}
+
+	r.DueBefore = normalizeTime(r.DueBefore)
+
+	for _, tags := range []*[]string{&r.Tags, &r.AnyTags, &r.NotTags} {
+		if *tags, err = NormalizeTags(*tags); err != nil {
+			return err
+		}
+	}
+
+	if r.Limit == 0 {
+		r.Limit = DefaultListLimit
+	} else if r.Limit < 0 || r.Limit > MaxListLimit {
+		return Errorf(EINVALID, "Limit must be between 1 and %d.", MaxListLimit)
+	}
+
+	if r.Cursor != "" {
+		c, err := DecodeCursor(r.Cursor)
+		if err != nil {
+			return err
+		} else if c.SortBy != r.SortBy || c.SortDirection != r.SortDirection {
+			return Errorf(EINVALID, "Cursor does not match the requested sort order.")
+		}
+	}
+	return nil
+}
+
+// Match returns true if t passes the request's filters at time now.
+func (r *ListTodosRequest) Match(t *Todo, now time.Time) bool {
+	if r.ListID != 0 && t.ListID != r.ListID {
+		return false
+	}
+	if r.Complete != nil && t.Complete != *r.Complete {
+		return false
+	}
+	if r.State != "" && t.State != r.State {
+		return false
+	}
+	if r.Contains != "" && !strings.Contains(t.Value, r.Contains) {
+		return false
+	}
+	if r.DueBefore != nil && (t.DueAt == nil || !t.DueAt.Before(*r.DueBefore)) {
+		return false
+	}
+	if r.Overdue && !t.Overdue(now) {
+		return false
+	}
+	if r.SeriesID != 0 && t.SeriesID != r.SeriesID {
+		return false
+	}
+	if r.ParentID != 0 && t.ParentID != r.ParentID {
+		return false
+	}
+	if (t.ArchivedAt != nil) != r.Archived {
+		return false
+	}
+	for _, tag := range r.Tags {
+		if !t.HasTag(tag) {
+			return false
+		}
+	}
+	if len(r.AnyTags) > 0 && !t.hasAnyTag(r.AnyTags) {
+		return false
+	}
+	if t.hasAnyTag(r.NotTags) {
+		return false
+	}
+	if r.where != nil && !r.where.Match(t, now) {
+		return false
+	}
+	return true
+}
+
+// UseList restricts the request to the todos of l, the list with ID ListID.
+// The todos of a smart list are those matching its query rather than those
+// in the list. Must be called before Normalize.
+func (r *ListTodosRequest) UseList(l *List) {
+	if l.Query != "" {
+		r.ListID, r.listQuery = 0, l.Query
+	}
+}
+
+// Where returns the condition of the queries of the request, or nil if it has
+// none. Only set once the request is normalized.
+func (r *ListTodosRequest) Where() Expr {
+	return r.where
+}
+
+// Cursor represents a position within a sorted list of todos. It holds the
+// sort key and ID of the last todo on a page so the next page starts right
+// after it, even if todos are inserted or deleted in between.
+type Cursor struct {
+	SortBy        string      `json:"s"`
+	SortDirection string      `json:"d"`
+	Key           interface{} `json:"k"`
+	ID            int         `json:"id"`
+}
+
+// NewCursor returns a cursor positioned at t.
+func NewCursor(t *Todo, sortBy, sortDirection string) Cursor {
+	return Cursor{
+		SortBy:        sortBy,
+		SortDirection: sortDirection,
+		Key:           t.SortKey(sortBy),
+		ID:            t.ID,
+	}
+}
+
+// Encode returns the opaque string representation of the cursor.
+func (c Cursor) Encode() string {
+	buf, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// After returns true if t is ordered after the cursor position.
+func (c Cursor) After(t *Todo) bool {
+	cmp := CompareSortKeys(t.SortKey(c.SortBy), c.Key)
+	if cmp == 0 {
+		cmp = CompareSortKeys(t.ID, c.ID)
+	}
+	if c.SortDirection == SortDesc {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+// DecodeCursor parses a cursor returned by Cursor.Encode.
+// Returns EINVALID if s is not a valid cursor.
+func DecodeCursor(s string) (*Cursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, Errorf(EINVALID, "Invalid cursor.")
+	}
+
+	var raw struct {
+		Cursor
+		Key json.RawMessage `json:"k"`
+	}
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return nil, Errorf(EINVALID, "Invalid cursor.")
+	}
+	c := raw.Cursor
+
+	// Decode the key into the type SortKey() returns for the field.
+	var key interface{}
+	switch c.SortBy {
+	case SortByID:
+	case SortByValue, SortByPosition:
+		var v string
+		err = json.Unmarshal(raw.Key, &v)
+		key = v
+	case SortByComplete:
+		var v bool
+		err = json.Unmarshal(raw.Key, &v)
+		key = v
+	case SortByPriority:
+		var v int
+		err = json.Unmarshal(raw.Key, &v)
+		key = v
+	default:
+		return nil, Errorf(EINVALID, "Invalid cursor.")
+	}
+	if err != nil {
+		return nil, Errorf(EINVALID, "Invalid cursor.")
+	}
+	c.Key = key
+
+	return &c, nil
+}
+
+// SortKey returns the value of the field todos are sorted by. The ID is used
+// to break ties, so SortByID has no separate key.
+func (t *Todo) SortKey(sortBy string) interface{} {
+	switch sortBy {
+	case SortByValue:
+		return t.Value
+	case SortByComplete:
+		return t.Complete
+	case SortByPriority:
+		return t.Priority
+	case SortByPosition:
+		return t.Position
+	}
+	return nil
+}
+
+// CompareSortKeys returns -1, 0 or 1 depending on whether a is ordered before,
+// the same as or after b. Both keys must be of the same type.
+func CompareSortKeys(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		b := b.(int)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	case string:
+		return strings.Compare(a, b.(string))
+	case bool:
+		b := b.(bool)
+		if !a && b {
+			return -1
+		} else if a && !b {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,78 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewDependencyLoggingMiddleware(logger log.Logger) todo.DependencyMiddleware {
+	return func(next todo.DependencyService) todo.DependencyService {
+		return &dependencyLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type dependencyLoggingMiddleware struct {
+	next   todo.DependencyService
+	logger log.Logger
+}
+
+func (mw dependencyLoggingMiddleware) CreateDependency(ctx context.Context, request todo.CreateDependencyRequest) (d *todo.Dependency, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "CreateDependency",
+			"todoId", request.TodoID,
+			"blockedById", request.BlockedByID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.CreateDependency(ctx, request)
+}
+
+func (mw dependencyLoggingMiddleware) DeleteDependency(ctx context.Context, request todo.DeleteDependencyRequest) (err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "DeleteDependency",
+			"todoId", request.TodoID,
+			"blockedById", request.BlockedByID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.DeleteDependency(ctx, request)
+}
+
+func (mw dependencyLoggingMiddleware) ListDependencies(ctx context.Context, request todo.ListDependenciesRequest) (deps []*todo.Dependency, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ListDependencies",
+			"todoId", request.TodoID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ListDependencies(ctx, request)
+}
+
+func (mw dependencyLoggingMiddleware) ListActionableTodos(ctx context.Context, request todo.ListActionableTodosRequest) (todos []*todo.Todo, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ListActionableTodos",
+			"listId", request.ListID,
+			"includeBlocked", request.IncludeBlocked,
+			"limit", request.Limit,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ListActionableTodos(ctx, request)
+}
@@ -0,0 +1,36 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewEventLoggingMiddleware(logger log.Logger) todo.EventMiddleware {
+	return func(next todo.EventService) todo.EventService {
+		return &eventLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type eventLoggingMiddleware struct {
+	next   todo.EventService
+	logger log.Logger
+}
+
+func (mw eventLoggingMiddleware) Subscribe(ctx context.Context, request todo.SubscribeRequest) (sub *todo.Subscription, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "Subscribe",
+			"listId", request.ListID,
+			"after", optionalInt(request.After),
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.Subscribe(ctx, request)
+}
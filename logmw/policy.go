@@ -0,0 +1,35 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewPolicyLoggingMiddleware(logger log.Logger) todo.PolicyMiddleware {
+	return func(next todo.PolicyService) todo.PolicyService {
+		return &policyLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type policyLoggingMiddleware struct {
+	next   todo.PolicyService
+	logger log.Logger
+}
+
+func (mw policyLoggingMiddleware) ReportPolicies(ctx context.Context, request todo.ReportPoliciesRequest) (report *todo.PolicyReport, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ReportPolicies",
+			"listId", request.ListID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ReportPolicies(ctx, request)
+}
@@ -0,0 +1,50 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewRevisionLoggingMiddleware(logger log.Logger) todo.RevisionMiddleware {
+	return func(next todo.RevisionService) todo.RevisionService {
+		return &revisionLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type revisionLoggingMiddleware struct {
+	next   todo.RevisionService
+	logger log.Logger
+}
+
+func (mw revisionLoggingMiddleware) ListRevisions(ctx context.Context, request todo.ListRevisionsRequest) (revs []*todo.Revision, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ListRevisions",
+			"todoId", request.TodoID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ListRevisions(ctx, request)
+}
+
+func (mw revisionLoggingMiddleware) RestoreRevision(ctx context.Context, request todo.RestoreRevisionRequest) (t *todo.Todo, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "RestoreRevision",
+			"todoId", request.TodoID,
+			"revisionId", request.RevisionID,
+			"version", request.Version,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.RestoreRevision(ctx, request)
+}
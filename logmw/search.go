@@ -0,0 +1,36 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewSearchLoggingMiddleware(logger log.Logger) todo.SearchMiddleware {
+	return func(next todo.SearchService) todo.SearchService {
+		return &searchLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type searchLoggingMiddleware struct {
+	next   todo.SearchService
+	logger log.Logger
+}
+
+func (mw searchLoggingMiddleware) SearchTodos(ctx context.Context, request todo.SearchTodosRequest) (resp *todo.SearchTodosResponse, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "SearchTodos",
+			"query", request.Query,
+			"limit", request.Limit,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.SearchTodos(ctx, request)
+}
@@ -0,0 +1,81 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewShareLoggingMiddleware(logger log.Logger) todo.ShareMiddleware {
+	return func(next todo.ShareService) todo.ShareService {
+		return &shareLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type shareLoggingMiddleware struct {
+	next   todo.ShareService
+	logger log.Logger
+}
+
+func (mw shareLoggingMiddleware) CreateShare(ctx context.Context, request todo.CreateShareRequest) (sh *todo.Share, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "CreateShare",
+			"todoId", request.TodoID,
+			"listId", request.ListID,
+			"principalId", request.PrincipalID,
+			"role", request.Role,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.CreateShare(ctx, request)
+}
+
+func (mw shareLoggingMiddleware) DeleteShare(ctx context.Context, request todo.DeleteShareRequest) (err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "DeleteShare",
+			"todoId", request.TodoID,
+			"listId", request.ListID,
+			"principalId", request.PrincipalID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.DeleteShare(ctx, request)
+}
+
+func (mw shareLoggingMiddleware) GetShare(ctx context.Context, request todo.GetShareRequest) (sh *todo.Share, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "GetShare",
+			"todoId", request.TodoID,
+			"listId", request.ListID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.GetShare(ctx, request)
+}
+
+func (mw shareLoggingMiddleware) ListShares(ctx context.Context, request todo.ListSharesRequest) (shares []*todo.Share, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ListShares",
+			"todoId", request.TodoID,
+			"listId", request.ListID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ListShares(ctx, request)
+}
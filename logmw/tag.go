@@ -0,0 +1,62 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewTagLoggingMiddleware(logger log.Logger) todo.TagMiddleware {
+	return func(next todo.TagService) todo.TagService {
+		return &tagLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type tagLoggingMiddleware struct {
+	next   todo.TagService
+	logger log.Logger
+}
+
+func (mw tagLoggingMiddleware) ListTags(ctx context.Context, request todo.ListTagsRequest) (tags []*todo.Tag, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ListTags",
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ListTags(ctx, request)
+}
+
+func (mw tagLoggingMiddleware) RenameTag(ctx context.Context, request todo.RenameTagRequest) (tag *todo.Tag, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "RenameTag",
+			"name", request.Name,
+			"newName", request.NewName,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.RenameTag(ctx, request)
+}
+
+func (mw tagLoggingMiddleware) MergeTags(ctx context.Context, request todo.MergeTagsRequest) (tag *todo.Tag, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "MergeTags",
+			"names", request.Names,
+			"into", request.Into,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.MergeTags(ctx, request)
+}
@@ -25,8 +25,17 @@ func (mw todoLoggingMiddleware) CreateTodo(ctx context.Context, request todo.Cre
 	defer func(begin time.Time) {
 		_ = mw.logger.Log(
 			"method", "CreateTodo",
+			"listId", request.ListID,
+			"parentId", request.ParentID,
 			"value", request.Value,
 			"complete", request.Complete,
+			"state", request.State,
+			"dueAt", optionalTime(request.DueAt),
+			"remindAt", optionalTime(request.RemindAt),
+			"timeZone", request.TimeZone,
+			"recurrence", request.Recurrence,
+			"tags", request.Tags,
+			"priority", request.Priority,
 			"took", time.Since(begin),
 			"err", err,
 		)
@@ -40,8 +49,19 @@ func (mw todoLoggingMiddleware) UpdateTodo(ctx context.Context, request todo.Upd
 		_ = mw.logger.Log(
 			"method", "UpdateTodo",
 			"id", request.ID,
+			"listId", request.ListID,
+			"parentId", request.ParentID,
 			"value", request.Value,
 			"complete", request.Complete,
+			"state", request.State,
+			"dueAt", optionalTime(request.DueAt),
+			"remindAt", optionalTime(request.RemindAt),
+			"timeZone", request.TimeZone,
+			"recurrence", request.Recurrence,
+			"tags", request.Tags,
+			"priority", request.Priority,
+			"scope", request.Scope,
+			"version", request.Version,
 			"took", time.Since(begin),
 			"err", err,
 		)
@@ -50,11 +70,39 @@ func (mw todoLoggingMiddleware) UpdateTodo(ctx context.Context, request todo.Upd
 	return mw.next.UpdateTodo(ctx, request)
 }
 
+func (mw todoLoggingMiddleware) PatchTodo(ctx context.Context, request todo.PatchTodoRequest) (t *todo.Todo, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "PatchTodo",
+			"id", request.ID,
+			"listId", optionalInt(request.ListID),
+			"parentId", optionalInt(request.ParentID),
+			"value", optionalString(request.Value),
+			"complete", optionalBool(request.Complete),
+			"state", optionalString(request.State),
+			"dueAt", optionalTime(request.DueAt),
+			"remindAt", optionalTime(request.RemindAt),
+			"timeZone", optionalString(request.TimeZone),
+			"recurrence", optionalString(request.Recurrence),
+			"tags", optionalStrings(request.Tags),
+			"priority", optionalInt(request.Priority),
+			"scope", request.Scope,
+			"version", request.Version,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.PatchTodo(ctx, request)
+}
+
 func (mw todoLoggingMiddleware) DeleteTodo(ctx context.Context, request todo.DeleteTodoRequest) (err error) {
 	defer func(begin time.Time) {
 		_ = mw.logger.Log(
 			"method", "DeleteTodo",
 			"id", request.ID,
+			"cascade", request.Cascade,
+			"version", request.Version,
 			"took", time.Since(begin),
 			"err", err,
 		)
@@ -76,14 +124,124 @@ func (mw todoLoggingMiddleware) GetTodoByID(ctx context.Context, request todo.Ge
 	return mw.next.GetTodoByID(ctx, request)
 }
 
-func (mw todoLoggingMiddleware) GetAllTodos(ctx context.Context) (t []*todo.Todo, err error) {
+func (mw todoLoggingMiddleware) ListTodos(ctx context.Context, request todo.ListTodosRequest) (resp *todo.ListTodosResponse, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ListTodos",
+			"listId", request.ListID,
+			"complete", optionalBool(request.Complete),
+			"state", request.State,
+			"contains", request.Contains,
+			"dueBefore", optionalTime(request.DueBefore),
+			"overdue", request.Overdue,
+			"seriesId", request.SeriesID,
+			"parentId", request.ParentID,
+			"tags", request.Tags,
+			"anyTags", request.AnyTags,
+			"notTags", request.NotTags,
+			"query", request.Query,
+			"sortBy", request.SortBy,
+			"sortDirection", request.SortDirection,
+			"limit", request.Limit,
+			"cursor", request.Cursor,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ListTodos(ctx, request)
+}
+
+func (mw todoLoggingMiddleware) CompleteTodo(ctx context.Context, request todo.CompleteTodoRequest) (resp *todo.CompleteTodoResponse, err error) {
 	defer func(begin time.Time) {
 		_ = mw.logger.Log(
-			"method", "GetAllTodos",
+			"method", "CompleteTodo",
+			"id", request.ID,
+			"force", request.Force,
+			"version", request.Version,
 			"took", time.Since(begin),
 			"err", err,
 		)
 	}(time.Now())
 
-	return mw.next.GetAllTodos(ctx)
+	return mw.next.CompleteTodo(ctx, request)
+}
+
+func (mw todoLoggingMiddleware) MoveTodo(ctx context.Context, request todo.MoveTodoRequest) (t *todo.Todo, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "MoveTodo",
+			"id", request.ID,
+			"beforeId", request.BeforeID,
+			"afterId", request.AfterID,
+			"version", request.Version,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.MoveTodo(ctx, request)
+}
+
+func (mw todoLoggingMiddleware) BatchTodos(ctx context.Context, request todo.BatchTodosRequest) (resp *todo.BatchTodosResponse, err error) {
+	defer func(begin time.Time) {
+		var failed int
+		if resp != nil {
+			for _, r := range resp.Results {
+				if r.Err != nil {
+					failed++
+				}
+			}
+		}
+		_ = mw.logger.Log(
+			"method", "BatchTodos",
+			"mode", request.Mode,
+			"ops", len(request.Ops),
+			"failed", failed,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.BatchTodos(ctx, request)
+}
+
+// optionalBool dereferences optional boolean request fields so they are logged by value.
+func optionalBool(v *bool) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// optionalString dereferences optional string request fields.
+func optionalString(v *string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// optionalStrings dereferences optional string slice request fields.
+func optionalStrings(v *[]string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// optionalInt dereferences optional integer request fields.
+func optionalInt(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// optionalTime dereferences optional time request fields.
+func optionalTime(v *time.Time) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
 }
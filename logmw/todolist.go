@@ -0,0 +1,92 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewListLoggingMiddleware(logger log.Logger) todo.ListMiddleware {
+	return func(next todo.ListService) todo.ListService {
+		return &listLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type listLoggingMiddleware struct {
+	next   todo.ListService
+	logger log.Logger
+}
+
+func (mw listLoggingMiddleware) CreateList(ctx context.Context, request todo.CreateListRequest) (l *todo.List, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "CreateList",
+			"name", request.Name,
+			"wipLimits", request.WIPLimits,
+			"query", request.Query,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.CreateList(ctx, request)
+}
+
+func (mw listLoggingMiddleware) UpdateList(ctx context.Context, request todo.UpdateListRequest) (l *todo.List, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "UpdateList",
+			"id", request.ID,
+			"name", request.Name,
+			"wipLimits", request.WIPLimits,
+			"query", request.Query,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.UpdateList(ctx, request)
+}
+
+func (mw listLoggingMiddleware) DeleteList(ctx context.Context, request todo.DeleteListRequest) (err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "DeleteList",
+			"id", request.ID,
+			"cascade", request.Cascade,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.DeleteList(ctx, request)
+}
+
+func (mw listLoggingMiddleware) GetListByID(ctx context.Context, request todo.GetListByIDRequest) (l *todo.List, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "GetListByID",
+			"id", request.ID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.GetListByID(ctx, request)
+}
+
+func (mw listLoggingMiddleware) ListLists(ctx context.Context, request todo.ListListsRequest) (lists []*todo.List, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ListLists",
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ListLists(ctx, request)
+}
@@ -0,0 +1,47 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewTrashLoggingMiddleware(logger log.Logger) todo.TrashMiddleware {
+	return func(next todo.TrashService) todo.TrashService {
+		return &trashLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type trashLoggingMiddleware struct {
+	next   todo.TrashService
+	logger log.Logger
+}
+
+func (mw trashLoggingMiddleware) ListTrash(ctx context.Context, request todo.ListTrashRequest) (todos []*todo.Todo, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ListTrash",
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ListTrash(ctx, request)
+}
+
+func (mw trashLoggingMiddleware) RestoreTodo(ctx context.Context, request todo.RestoreTodoRequest) (t *todo.Todo, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "RestoreTodo",
+			"id", request.ID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.RestoreTodo(ctx, request)
+}
@@ -0,0 +1,47 @@
+package logmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+func NewWorkflowLoggingMiddleware(logger log.Logger) todo.WorkflowMiddleware {
+	return func(next todo.WorkflowService) todo.WorkflowService {
+		return &workflowLoggingMiddleware{
+			next:   next,
+			logger: logger,
+		}
+	}
+}
+
+type workflowLoggingMiddleware struct {
+	next   todo.WorkflowService
+	logger log.Logger
+}
+
+func (mw workflowLoggingMiddleware) GetWorkflow(ctx context.Context, request todo.GetWorkflowRequest) (w *todo.Workflow, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "GetWorkflow",
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.GetWorkflow(ctx, request)
+}
+
+func (mw workflowLoggingMiddleware) ListTransitions(ctx context.Context, request todo.ListTransitionsRequest) (trans []*todo.Transition, err error) {
+	defer func(begin time.Time) {
+		_ = mw.logger.Log(
+			"method", "ListTransitions",
+			"todoId", request.TodoID,
+			"took", time.Since(begin),
+			"err", err,
+		)
+	}(time.Now())
+
+	return mw.next.ListTransitions(ctx, request)
+}
@@ -0,0 +1,101 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// PolicyService reports what the retention policies of the caller's lists
+// would do, so policies can be tried out before they take effect.
+type PolicyService interface {
+	ReportPolicies(ctx context.Context, request ReportPoliciesRequest) (*PolicyReport, error)
+}
+
+// PolicyMiddleware describes a service middleware for the PolicyService.
+type PolicyMiddleware func(service PolicyService) PolicyService
+
+// PolicyEnforcer applies the retention policies of the lists of all owners. It
+// is used by background jobs and not exposed to callers of the API.
+type PolicyEnforcer interface {
+	// EnforcePolicies archives or purges up to limit todos the policies of
+	// their list apply to at now and returns them. Purges are applied first,
+	// so a todo is never archived and purged by the same call.
+	EnforcePolicies(ctx context.Context, now time.Time, limit int) (*PolicyReport, error)
+}
+
+// PolicyEnforcerMiddleware describes a middleware for the PolicyEnforcer.
+type PolicyEnforcerMiddleware func(enforcer PolicyEnforcer) PolicyEnforcer
+
+// Policy actions.
+const (
+	// PolicyArchive archives the completed todos of a list.
+	PolicyArchive = "archive"
+
+	// PolicyPurge deletes the archived todos of a list. Purged todos are
+	// moved to the trash like any deleted todo, so they can still be
+	// restored until the trash is purged in turn.
+	PolicyPurge = "purge"
+)
+
+// Policy is a retention policy of a list, e.g. "archive completed todos after
+// 7 days" or "purge archived todos after 365 days".
+type Policy struct {
+	Action string `json:"action"`
+
+	// Number of days after which the action applies: since a todo was
+	// completed for PolicyArchive, since it was archived for PolicyPurge.
+	AfterDays int `json:"afterDays"`
+}
+
+// ValidatePolicies returns EINVALID if one of policies has an unknown action or
+// a negative number of days, or if an action is used twice.
+func ValidatePolicies(policies []Policy) error {
+	seen := make(map[string]bool, len(policies))
+	for _, p := range policies {
+		switch p.Action {
+		case PolicyArchive, PolicyPurge:
+		default:
+			return Errorf(EINVALID, "Invalid policy action '%s'.", p.Action)
+		}
+		if p.AfterDays < 0 {
+			return Errorf(EINVALID, "Policy '%s' cannot apply after a negative number of days.", p.Action)
+		} else if seen[p.Action] {
+			return Errorf(EINVALID, "Policy '%s' is set more than once.", p.Action)
+		}
+		seen[p.Action] = true
+	}
+	return nil
+}
+
+// Cutoff returns the time the todos p applies to at now must have been
+// completed or archived by.
+func (p Policy) Cutoff(now time.Time) time.Time {
+	return now.UTC().Truncate(time.Second).AddDate(0, 0, -p.AfterDays)
+}
+
+// Match returns true if p applies to t at now. Todos completed before the
+// time of completion was tracked count as completed long ago.
+func (p Policy) Match(t *Todo, now time.Time) bool {
+	cutoff := p.Cutoff(now)
+	switch p.Action {
+	case PolicyArchive:
+		return t.ArchivedAt == nil && t.Complete && (t.StateChangedAt == nil || !t.StateChangedAt.After(cutoff))
+	case PolicyPurge:
+		return t.ArchivedAt != nil && !t.ArchivedAt.After(cutoff)
+	}
+	return false
+}
+
+// ReportPoliciesRequest reports the todos the policies of the caller's lists
+// apply to right now, without changing anything.
+type ReportPoliciesRequest struct {
+	// Optional list to report on. Defaults to all lists of the caller.
+	ListID int `json:"listId"`
+}
+
+// PolicyReport lists the todos archived or purged by policies, or that would
+// be in a report, ordered by ID.
+type PolicyReport struct {
+	Archived []*Todo `json:"archived"`
+	Purged   []*Todo `json:"purged"`
+}
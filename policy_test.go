@@ -0,0 +1,34 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+	"todo"
+)
+
+func TestPolicy_Match(t *testing.T) {
+	now := time.Date(2021, 1, 8, 12, 0, 0, 0, time.UTC)
+	week := now.AddDate(0, 0, -7)
+	later := week.Add(time.Second)
+
+	archive := todo.Policy{Action: todo.PolicyArchive, AfterDays: 7}
+	purge := todo.Policy{Action: todo.PolicyPurge, AfterDays: 7}
+	for _, tt := range []struct {
+		p    todo.Policy
+		t    *todo.Todo
+		want bool
+	}{
+		{archive, &todo.Todo{Complete: true, StateChangedAt: &week}, true},
+		{archive, &todo.Todo{Complete: true, StateChangedAt: &later}, false},
+		{archive, &todo.Todo{Complete: true}, true},
+		{archive, &todo.Todo{StateChangedAt: &week}, false},
+		{archive, &todo.Todo{Complete: true, StateChangedAt: &week, ArchivedAt: &week}, false},
+		{purge, &todo.Todo{ArchivedAt: &week}, true},
+		{purge, &todo.Todo{ArchivedAt: &later}, false},
+		{purge, &todo.Todo{Complete: true, StateChangedAt: &week}, false},
+	} {
+		if got := tt.p.Match(tt.t, now); got != tt.want {
+			t.Errorf("%s %#v: got %v, want %v", tt.p.Action, tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package todo
+
+import (
+	"strings"
+)
+
+// Priorities of a todo, from none to high.
+const (
+	PriorityNone   = 0
+	PriorityLow    = 1
+	PriorityMedium = 2
+	PriorityHigh   = 3
+)
+
+// MoveTodoRequest moves a todo in the manual order of its owner's todos.
+// The todo is placed right after AfterID, right before BeforeID, or between
+// the two if both are set. Only the moved todo changes.
+type MoveTodoRequest struct {
+	ID       int `json:"id"`
+	BeforeID int `json:"beforeId"`
+	AfterID  int `json:"afterId"`
+
+	// Expected current version of the todo. See UpdateTodoRequest.Version.
+	Version int `json:"version"`
+}
+
+// Validate returns EINVALID if the request is malformed.
+func (r *MoveTodoRequest) Validate() error {
+	if r.BeforeID == 0 && r.AfterID == 0 {
+		return Errorf(EINVALID, "Todo to move before or after required.")
+	} else if r.BeforeID == r.ID || r.AfterID == r.ID {
+		return Errorf(EINVALID, "Todo with ID '%d' cannot be moved relative to itself.", r.ID)
+	} else if r.BeforeID == r.AfterID {
+		return Errorf(EINVALID, "Todo cannot be moved before and after the same todo.")
+	}
+	return nil
+}
+
+// Positions are fractional indexes: strings that sort in the manual order of
+// todos and between any two of which another one can be generated, so moving
+// a todo never changes the position of other todos.
+//
+// A position consists of an integer part and an optional fraction. The first
+// character of the integer part encodes its length, so appending todos one by
+// one only makes positions grow logarithmically. The fraction never ends in
+// the smallest digit, which guarantees there is always room before it.
+const (
+	positionDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// Integer part that cannot be decremented any further.
+	smallestPositionInteger = "A00000000000000000000000000"
+)
+
+// PositionBetween returns a position ordered after a and before b. An empty a
+// stands for the start and an empty b for the end of the order. Returns
+// EINVALID unless a is ordered before b.
+func PositionBetween(a, b string) (string, error) {
+	if a != "" && b != "" && a >= b {
+		return "", Errorf(EINVALID, "Position '%s' is not before position '%s'.", a, b)
+	}
+
+	switch {
+	case a == "" && b == "":
+		return "a0", nil
+	case a == "":
+		ib, err := positionInteger(b)
+		if err != nil {
+			return "", err
+		}
+		if fb := b[len(ib):]; ib == smallestPositionInteger {
+			return ib + positionMidpoint("", fb), nil
+		} else if fb != "" {
+			return ib, nil
+		}
+		if i, ok := decrementPositionInteger(ib); ok {
+			return i, nil
+		}
+		return "", Errorf(EINTERNAL, "Cannot move before position '%s'.", b)
+	case b == "":
+		ia, err := positionInteger(a)
+		if err != nil {
+			return "", err
+		}
+		if i, ok := incrementPositionInteger(ia); ok {
+			return i, nil
+		}
+		return ia + positionMidpoint(a[len(ia):], ""), nil
+	}
+
+	ia, err := positionInteger(a)
+	if err != nil {
+		return "", err
+	}
+	ib, err := positionInteger(b)
+	if err != nil {
+		return "", err
+	}
+	if ia == ib {
+		return ia + positionMidpoint(a[len(ia):], b[len(ib):]), nil
+	}
+	if i, ok := incrementPositionInteger(ia); !ok {
+		return "", Errorf(EINTERNAL, "Cannot move after position '%s'.", a)
+	} else if i < b {
+		return i, nil
+	}
+	return ia + positionMidpoint(a[len(ia):], ""), nil
+}
+
+// positionInteger returns the integer part of a position.
+func positionInteger(p string) (string, error) {
+	n := 0
+	switch h := p[0]; {
+	case h >= 'a' && h <= 'z':
+		n = int(h-'a') + 2
+	case h >= 'A' && h <= 'Z':
+		n = int('Z'-h) + 2
+	}
+	if n == 0 || n > len(p) {
+		return "", Errorf(EINVALID, "Invalid position '%s'.", p)
+	}
+	return p[:n], nil
+}
+
+// positionMidpoint returns a fraction between the fractions a and b. An empty
+// b stands for the end of the fraction range.
+func positionMidpoint(a, b string) string {
+	if b != "" {
+		// Keep the common prefix, padding a with zero digits.
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			if n > len(a) {
+				return b[:n] + positionMidpoint("", b[n:])
+			}
+			return b[:n] + positionMidpoint(a[n:], b[n:])
+		}
+	}
+
+	da, db := 0, len(positionDigits)
+	if a != "" {
+		da = strings.IndexByte(positionDigits, a[0])
+	}
+	if b != "" {
+		db = strings.IndexByte(positionDigits, b[0])
+	}
+	if db-da > 1 {
+		return string(positionDigits[(da+db+1)/2])
+	} else if len(b) > 1 {
+		// The first digits are consecutive but b continues, so its first
+		// digit alone is in between.
+		return b[:1]
+	}
+	if a == "" {
+		return positionDigits[da:da+1] + positionMidpoint("", "")
+	}
+	return positionDigits[da:da+1] + positionMidpoint(a[1:], "")
+}
+
+// digitAt returns the digit of fraction s at i, or the zero digit past its end.
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return positionDigits[0]
+}
+
+// incrementPositionInteger returns the integer part following i. Returns false
+// if i is the largest integer part.
+func incrementPositionInteger(i string) (string, bool) {
+	head, digits := i[0], []byte(i[1:])
+	for j := len(digits) - 1; j >= 0; j-- {
+		if d := strings.IndexByte(positionDigits, digits[j]) + 1; d < len(positionDigits) {
+			digits[j] = positionDigits[d]
+			return string(head) + string(digits), true
+		}
+		digits[j] = positionDigits[0]
+	}
+
+	// All digits overflowed, continue with the next integer length.
+	switch head {
+	case 'Z':
+		return "a0", true
+	case 'z':
+		return "", false
+	}
+	head++
+	if head > 'a' {
+		digits = append(digits, positionDigits[0])
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), true
+}
+
+// decrementPositionInteger returns the integer part preceding i. Returns false
+// if i is the smallest integer part.
+func decrementPositionInteger(i string) (string, bool) {
+	last := positionDigits[len(positionDigits)-1]
+	head, digits := i[0], []byte(i[1:])
+	for j := len(digits) - 1; j >= 0; j-- {
+		if d := strings.IndexByte(positionDigits, digits[j]) - 1; d >= 0 {
+			digits[j] = positionDigits[d]
+			return string(head) + string(digits), true
+		}
+		digits[j] = last
+	}
+
+	// All digits underflowed, continue with the previous integer length.
+	switch head {
+	case 'a':
+		return "Z" + string(last), true
+	case 'A':
+		return "", false
+	}
+	head--
+	if head < 'Z' {
+		digits = append(digits, last)
+	} else {
+		digits = digits[:len(digits)-1]
+	}
+	return string(head) + string(digits), true
+}
+
+// PlaceBetween sets the position of t between the positions of after and
+// before, either of which may be nil for the start or end of the order.
+func (t *Todo) PlaceBetween(after, before *Todo) error {
+	var a, b string
+	if after != nil {
+		a = after.Position
+	}
+	if before != nil {
+		b = before.Position
+	}
+	if after != nil && before != nil && a >= b {
+		return Errorf(EINVALID, "Todo with ID '%d' is not ordered before todo with ID '%d'.", after.ID, before.ID)
+	}
+
+	p, err := PositionBetween(a, b)
+	if err != nil {
+		return err
+	}
+	t.Position = p
+	return nil
+}
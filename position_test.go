@@ -0,0 +1,74 @@
+package todo_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"todo"
+)
+
+func TestPositionBetween(t *testing.T) {
+	t.Run("Append", func(t *testing.T) {
+		var last string
+		for i := 0; i < 5000; i++ {
+			p, err := todo.PositionBetween(last, "")
+			if err != nil {
+				t.Fatal(err)
+			} else if p <= last {
+				t.Fatalf("%d: %q is not after %q", i, p, last)
+			} else if len(p) > 4 {
+				t.Fatalf("%d: position %q is too long", i, p)
+			}
+			last = p
+		}
+	})
+
+	t.Run("Prepend", func(t *testing.T) {
+		first, _ := todo.PositionBetween("", "")
+		for i := 0; i < 5000; i++ {
+			p, err := todo.PositionBetween("", first)
+			if err != nil {
+				t.Fatal(err)
+			} else if p >= first {
+				t.Fatalf("%d: %q is not before %q", i, p, first)
+			} else if len(p) > 4 {
+				t.Fatalf("%d: position %q is too long", i, p)
+			}
+			first = p
+		}
+	})
+
+	t.Run("Insert", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(1))
+		var positions []string
+		for i := 0; i < 2000; i++ {
+			j := rnd.Intn(len(positions) + 1)
+			var a, b string
+			if j > 0 {
+				a = positions[j-1]
+			}
+			if j < len(positions) {
+				b = positions[j]
+			}
+
+			p, err := todo.PositionBetween(a, b)
+			if err != nil {
+				t.Fatal(err)
+			} else if p <= a || (b != "" && p >= b) {
+				t.Fatalf("%d: %q is not between %q and %q", i, p, a, b)
+			}
+			positions = append(positions[:j], append([]string{p}, positions[j:]...)...)
+		}
+		if !sort.StringsAreSorted(positions) {
+			t.Fatal("positions are not sorted")
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		for _, tt := range [][2]string{{"a1", "a0"}, {"a0", "a0"}, {"!", ""}, {"b1", "b2"}} {
+			if _, err := todo.PositionBetween(tt[0], tt[1]); todo.ErrorCode(err) != todo.EINVALID {
+				t.Errorf("%q, %q: unexpected error: %#v", tt[0], tt[1], err)
+			}
+		}
+	})
+}
@@ -0,0 +1,514 @@
+package todo
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields of todos a query can filter on.
+const (
+	FieldTag      = "tag"
+	FieldState    = "state"
+	FieldText     = "text"
+	FieldList     = "list"
+	FieldParent   = "parent"
+	FieldPriority = "priority"
+	FieldDue      = "due"
+	FieldComplete = "complete"
+	FieldBlocked  = "blocked"
+	FieldOverdue  = "overdue"
+)
+
+// Operators of a query.
+const (
+	OpAnd = "AND"
+	OpOr  = "OR"
+
+	OpEq = "="
+	OpNe = "!="
+	OpLt = "<"
+	OpLe = "<="
+	OpGt = ">"
+	OpGe = ">="
+)
+
+// Query is a parsed query, see ParseQuery.
+type Query struct {
+	// Condition todos must satisfy. Nil if the query matches every todo.
+	Where Expr
+
+	// Sort order given by the ORDER BY clause. Empty if there is none.
+	SortBy        string
+	SortDirection string
+}
+
+// Expr is a node of the condition of a query: a *BinaryExpr, a *NotExpr or a
+// *Condition.
+type Expr interface {
+	// Match returns true if t satisfies the expression at time now.
+	Match(t *Todo, now time.Time) bool
+}
+
+// BinaryExpr combines two expressions with OpAnd or OpOr.
+type BinaryExpr struct {
+	Op       string
+	LHS, RHS Expr
+}
+
+func (e *BinaryExpr) Match(t *Todo, now time.Time) bool {
+	if e.Op == OpOr {
+		return e.LHS.Match(t, now) || e.RHS.Match(t, now)
+	}
+	return e.LHS.Match(t, now) && e.RHS.Match(t, now)
+}
+
+// NotExpr negates an expression.
+type NotExpr struct {
+	Expr Expr
+}
+
+func (e *NotExpr) Match(t *Todo, now time.Time) bool {
+	return !e.Expr.Match(t, now)
+}
+
+// Condition compares a field of todos with a value, e.g. "priority>=2".
+type Condition struct {
+	// One of the Field constants.
+	Field string
+
+	// One of the comparison operators. ":" is parsed as OpEq.
+	Op string
+
+	// Value of the type of the field: a string for tags, states & text, an
+	// int for lists, parents & priorities and a bool for the other flags.
+	// Dates are a time.Time, a time.Duration relative to the time the query
+	// runs at, or nil for todos without a date. Text is in lower case.
+	Value interface{}
+
+	// Byte offset of the field in the query.
+	Offset int
+}
+
+// Time returns the date a FieldDue condition compares with at time now, or nil
+// if it matches todos without a due date.
+func (c *Condition) Time(now time.Time) *time.Time {
+	switch v := c.Value.(type) {
+	case time.Time:
+		return &v
+	case time.Duration:
+		return normalizeTime(timePtr(now.Add(v)))
+	}
+	return nil
+}
+
+func (c *Condition) Match(t *Todo, now time.Time) bool {
+	switch c.Field {
+	case FieldTag:
+		return t.HasTag(c.Value.(string)) == (c.Op == OpEq)
+	case FieldState:
+		return (t.State == c.Value.(string)) == (c.Op == OpEq)
+	case FieldText:
+		return strings.Contains(lowerASCII(t.Value), c.Value.(string)) == (c.Op == OpEq)
+	case FieldList:
+		return compareInts(t.ListID, c.Op, c.Value.(int))
+	case FieldParent:
+		return compareInts(t.ParentID, c.Op, c.Value.(int))
+	case FieldPriority:
+		return compareInts(t.Priority, c.Op, c.Value.(int))
+	case FieldComplete:
+		return (t.Complete == c.Value.(bool)) == (c.Op == OpEq)
+	case FieldBlocked:
+		return (t.Blocked == c.Value.(bool)) == (c.Op == OpEq)
+	case FieldOverdue:
+		return (t.Overdue(now) == c.Value.(bool)) == (c.Op == OpEq)
+	case FieldDue:
+		// Like in SQL, todos without a due date never compare with a date.
+		v := c.Time(now)
+		if v == nil {
+			return (t.DueAt == nil) == (c.Op == OpEq)
+		} else if t.DueAt == nil {
+			return false
+		}
+		return compareInts(int(t.DueAt.Unix()-v.Unix()), c.Op, 0)
+	}
+	return false
+}
+
+// compareInts returns true if a op b holds.
+func compareInts(a int, op string, b int) bool {
+	switch op {
+	case OpEq:
+		return a == b
+	case OpNe:
+		return a != b
+	case OpLt:
+		return a < b
+	case OpLe:
+		return a <= b
+	case OpGt:
+		return a > b
+	case OpGe:
+		return a >= b
+	}
+	return false
+}
+
+func timePtr(v time.Time) *time.Time {
+	return &v
+}
+
+// and returns an expression matching todos that match both a and b, either of
+// which may be nil.
+func and(a, b Expr) Expr {
+	if a == nil {
+		return b
+	} else if b == nil {
+		return a
+	}
+	return &BinaryExpr{Op: OpAnd, LHS: a, RHS: b}
+}
+
+// ParseQuery parses a query such as
+//
+//	tag:work AND due<7d AND NOT state:done ORDER BY priority DESC
+//
+// Conditions compare a field with a value using ":" or "=", "!=", "<", "<=",
+// ">" or ">=" and are combined with NOT, AND & OR, in order of precedence, and
+// parentheses. Conditions next to each other must all match. Keywords are
+// case insensitive and values containing spaces, parentheses or operators
+// other than ":" are quoted, e.g. text:"call back".
+//
+// Fields are:
+//
+//	tag       a tag of the todo
+//	state     the workflow state
+//	text      a part of the value, regardless of case
+//	list      the ID of the list, or "none"
+//	parent    the ID of the parent, or "none"
+//	priority  0 to 3, or none, low, medium & high
+//	due       a date such as 2021-03-01, an RFC 3339 time, "now", a time
+//	          relative to now such as 7d, -2w or 12h, or "none"
+//	complete  true or false, likewise blocked & overdue
+//
+// An empty query matches every todo. The optional ORDER BY clause sorts by
+// one of the fields of ListTodosRequest.SortBy, in ascending order unless
+// followed by DESC. Returns EINVALID with the offset of the offending part of
+// the query if the query is malformed.
+func ParseQuery(s string) (*Query, error) {
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+
+	q := &Query{}
+	if tok := p.peek(); tok.kind != tokenEOF && !tok.is("ORDER") {
+		if q.Where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek().is("ORDER") {
+		p.next()
+		if tok := p.next(); !tok.is("BY") {
+			return nil, tok.unexpected()
+		}
+		tok := p.next()
+		if tok.kind != tokenWord {
+			return nil, tok.unexpected()
+		}
+		switch q.SortBy = strings.ToLower(tok.text); q.SortBy {
+		case SortByID, SortByValue, SortByComplete, SortByPriority, SortByPosition:
+		default:
+			return nil, Errorf(EINVALID, "Invalid sort field '%s' at offset %d.", tok.text, tok.offset)
+		}
+		q.SortDirection = SortAsc
+		if tok := p.peek(); tok.is("ASC") {
+			p.next()
+		} else if tok.is("DESC") {
+			p.next()
+			q.SortDirection = SortDesc
+		}
+	}
+
+	if tok := p.next(); tok.kind != tokenEOF {
+		return nil, tok.unexpected()
+	}
+	return q, nil
+}
+
+// Kinds of query tokens.
+const (
+	tokenEOF = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+// queryToken is a token of a query along with its byte offset in the query.
+type queryToken struct {
+	kind   int
+	text   string
+	offset int
+}
+
+// is returns true if the token is the given keyword, regardless of case.
+func (t queryToken) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// unexpected returns the error reported when the token is not allowed where
+// it occurs.
+func (t queryToken) unexpected() error {
+	switch t.kind {
+	case tokenEOF:
+		return Errorf(EINVALID, "Unexpected end of query at offset %d.", t.offset)
+	case tokenString:
+		return Errorf(EINVALID, "Unexpected \"%s\" at offset %d.", t.text, t.offset)
+	}
+	return Errorf(EINVALID, "Unexpected '%s' at offset %d.", t.text, t.offset)
+}
+
+// lexQuery splits a query into tokens, ending with a tokenEOF.
+func lexQuery(s string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: tokenLParen, text: "(", offset: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: tokenRParen, text: ")", offset: i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, Errorf(EINVALID, "Unterminated string at offset %d.", i)
+			}
+			tokens = append(tokens, queryToken{kind: tokenString, text: s[i+1 : i+1+end], offset: i})
+			i += end + 2
+		case c == ':' || c == '=' || c == '!' || c == '<' || c == '>':
+			op := s[i : i+1]
+			if i+1 < len(s) && s[i+1] == '=' && c != ':' && c != '=' {
+				op = s[i : i+2]
+			} else if c == '!' {
+				return nil, Errorf(EINVALID, "Unexpected '!' at offset %d.", i)
+			}
+			tokens = append(tokens, queryToken{kind: tokenOp, text: op, offset: i})
+			i += len(op)
+		default:
+			// Values may contain ':', e.g. RFC 3339 times.
+			stop := " \t\n\r():=!<>\""
+			if n := len(tokens); n > 0 && tokens[n-1].kind == tokenOp {
+				stop = " \t\n\r()=!<>\""
+			}
+			end := strings.IndexAny(s[i:], stop)
+			if end < 0 {
+				end = len(s) - i
+			}
+			tokens = append(tokens, queryToken{kind: tokenWord, text: s[i : i+end], offset: i})
+			i += end
+		}
+	}
+	return append(tokens, queryToken{kind: tokenEOF, offset: len(s)}), nil
+}
+
+// queryParser is a recursive descent parser of query tokens.
+type queryParser struct {
+	tokens []queryToken
+	i      int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.i]
+}
+
+// next returns the next token and moves past it, except at the end.
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (Expr, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is(OpOr) {
+		p.next()
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: OpOr, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *queryParser) parseAnd() (Expr, error) {
+	lhs, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		// AND may be left out between conditions.
+		if tok := p.peek(); tok.is(OpAnd) {
+			p.next()
+		} else if tok.kind != tokenLParen && (tok.kind != tokenWord || tok.is(OpOr) || tok.is("ORDER")) {
+			return lhs, nil
+		}
+		rhs, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: OpAnd, LHS: lhs, RHS: rhs}
+	}
+}
+
+func (p *queryParser) parseNot() (Expr, error) {
+	if !p.peek().is("NOT") {
+		return p.parsePrimary()
+	}
+	p.next()
+	e, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &NotExpr{Expr: e}, nil
+}
+
+func (p *queryParser) parsePrimary() (Expr, error) {
+	switch tok := p.next(); tok.kind {
+	case tokenLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		} else if tok := p.next(); tok.kind != tokenRParen {
+			return nil, tok.unexpected()
+		}
+		return e, nil
+	case tokenWord:
+		if tok.is(OpAnd) || tok.is(OpOr) || tok.is("ORDER") {
+			return nil, tok.unexpected()
+		}
+		return p.parseCondition(tok)
+	default:
+		return nil, tok.unexpected()
+	}
+}
+
+// parseCondition parses the operator & value following the field tok.
+func (p *queryParser) parseCondition(tok queryToken) (Expr, error) {
+	c := &Condition{Field: strings.ToLower(tok.text), Offset: tok.offset}
+	switch c.Field {
+	case FieldTag, FieldState, FieldText, FieldList, FieldParent, FieldPriority, FieldDue, FieldComplete, FieldBlocked, FieldOverdue:
+	default:
+		return nil, Errorf(EINVALID, "Unknown field '%s' at offset %d.", tok.text, tok.offset)
+	}
+
+	op := p.next()
+	if op.kind != tokenOp {
+		return nil, op.unexpected()
+	}
+	if c.Op = op.text; c.Op == ":" {
+		c.Op = OpEq
+	}
+	switch c.Field {
+	case FieldPriority, FieldDue:
+	default:
+		if c.Op != OpEq && c.Op != OpNe {
+			return nil, Errorf(EINVALID, "Operator '%s' cannot be used with field '%s' at offset %d.", op.text, c.Field, op.offset)
+		}
+	}
+
+	v := p.next()
+	if v.kind != tokenWord && v.kind != tokenString {
+		return nil, v.unexpected()
+	}
+	var ok bool
+	if c.Value, ok = parseConditionValue(c.Field, v.text); !ok {
+		return nil, Errorf(EINVALID, "Invalid value '%s' for field '%s' at offset %d.", v.text, c.Field, v.offset)
+	} else if c.Value == nil && c.Op != OpEq && c.Op != OpNe {
+		return nil, Errorf(EINVALID, "Operator '%s' cannot be used with 'none' at offset %d.", op.text, op.offset)
+	}
+	return c, nil
+}
+
+// priorityNames maps the names of priorities to their value.
+var priorityNames = map[string]int{
+	"none":   PriorityNone,
+	"low":    PriorityLow,
+	"medium": PriorityMedium,
+	"high":   PriorityHigh,
+}
+
+// parseConditionValue returns the value of a condition on field, see
+// Condition.Value, or false if s is not a valid value.
+func parseConditionValue(field, s string) (interface{}, bool) {
+	switch field {
+	case FieldTag:
+		tag, err := NormalizeTag(s)
+		return tag, err == nil
+	case FieldState:
+		return s, s != ""
+	case FieldText:
+		return lowerASCII(s), s != ""
+	case FieldList, FieldParent:
+		if strings.EqualFold(s, "none") {
+			return 0, true
+		}
+		id, err := strconv.Atoi(s)
+		return id, err == nil && id > 0
+	case FieldPriority:
+		if v, ok := priorityNames[strings.ToLower(s)]; ok {
+			return v, true
+		}
+		v, err := strconv.Atoi(s)
+		return v, err == nil && v >= PriorityNone && v <= PriorityHigh
+	case FieldComplete, FieldBlocked, FieldOverdue:
+		v, err := strconv.ParseBool(s)
+		return v, err == nil
+	case FieldDue:
+		return parseQueryTime(s)
+	}
+	return nil, false
+}
+
+// parseQueryTime parses the value of a FieldDue condition.
+func parseQueryTime(s string) (interface{}, bool) {
+	switch strings.ToLower(s) {
+	case "none":
+		return nil, true
+	case "now":
+		return time.Duration(0), true
+	}
+
+	if v, err := time.Parse("2006-01-02", s); err == nil {
+		return v, true
+	} else if v, err := time.Parse(time.RFC3339, s); err == nil {
+		return *normalizeTime(&v), true
+	}
+
+	// Relative times are a number of hours, days or weeks.
+	units := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(s) < 2 {
+		return nil, false
+	}
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return nil, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < -100000 || n > 100000 {
+		return nil, false
+	}
+	return time.Duration(n) * unit, true
+}
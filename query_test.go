@@ -0,0 +1,108 @@
+package todo_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"todo"
+)
+
+func TestParseQuery(t *testing.T) {
+	q, err := todo.ParseQuery(`tag:Work and (priority>=high OR NOT text:"Call back") due<7d order by priority desc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &todo.Query{
+		Where: &todo.BinaryExpr{
+			Op: todo.OpAnd,
+			LHS: &todo.BinaryExpr{
+				Op:  todo.OpAnd,
+				LHS: &todo.Condition{Field: todo.FieldTag, Op: todo.OpEq, Value: "work", Offset: 0},
+				RHS: &todo.BinaryExpr{
+					Op:  todo.OpOr,
+					LHS: &todo.Condition{Field: todo.FieldPriority, Op: todo.OpGe, Value: todo.PriorityHigh, Offset: 14},
+					RHS: &todo.NotExpr{Expr: &todo.Condition{Field: todo.FieldText, Op: todo.OpEq, Value: "call back", Offset: 36}},
+				},
+			},
+			RHS: &todo.Condition{Field: todo.FieldDue, Op: todo.OpLt, Value: 7 * 24 * time.Hour, Offset: 54},
+		},
+		SortBy:        todo.SortByPriority,
+		SortDirection: todo.SortDesc,
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Fatalf("ParseQuery()=%#v, want %#v", q, want)
+	}
+
+	if q, err := todo.ParseQuery("  "); err != nil {
+		t.Fatal(err)
+	} else if q.Where != nil || q.SortBy != "" {
+		t.Fatalf("unexpected query: %#v", q)
+	}
+}
+
+func TestParseQuery_ErrInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		msg   string
+	}{
+		{"tag:work AND", "Unexpected end of query at offset 12."},
+		{"tag:work AND )", "Unexpected ')' at offset 13."},
+		{"tag:work OR or tag:x", "Unexpected 'or' at offset 12."},
+		{"(tag:work", "Unexpected end of query at offset 9."},
+		{"tag:work state", "Unexpected end of query at offset 14."},
+		{"tag work", "Unexpected 'work' at offset 4."},
+		{"tag:work \"x\"", "Unexpected \"x\" at offset 9."},
+		{"color:red", "Unknown field 'color' at offset 0."},
+		{"tag<work", "Operator '<' cannot be used with field 'tag' at offset 3."},
+		{"priority:urgent", "Invalid value 'urgent' for field 'priority' at offset 9."},
+		{"due<soon", "Invalid value 'soon' for field 'due' at offset 4."},
+		{"due<none", "Operator '<' cannot be used with 'none' at offset 3."},
+		{"list:0", "Invalid value '0' for field 'list' at offset 5."},
+		{"text:\"call", "Unterminated string at offset 5."},
+		{"tag!work", "Unexpected '!' at offset 3."},
+		{"tag:a ORDER priority", "Unexpected 'priority' at offset 12."},
+		{"ORDER BY due", "Invalid sort field 'due' at offset 9."},
+		{"ORDER BY id DESC tag:a", "Unexpected 'tag' at offset 17."},
+	} {
+		if _, err := todo.ParseQuery(tt.query); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("%q: unexpected error: %#v", tt.query, err)
+		} else if msg := todo.ErrorMessage(err); msg != tt.msg {
+			t.Fatalf("%q: message=%q, want %q", tt.query, msg, tt.msg)
+		}
+	}
+}
+
+func TestQuery_Match(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	due := now.Add(36 * time.Hour)
+	a := &todo.Todo{Value: "Write the Report", Tags: []string{"work"}, State: todo.StateInProgress, DueAt: &due, Priority: todo.PriorityMedium}
+	b := &todo.Todo{Value: "Buy milk", Complete: true, State: todo.StateDone}
+
+	for _, tt := range []struct {
+		query string
+		a, b  bool
+	}{
+		{"tag:work", true, false},
+		{"tag!=work", false, true},
+		{"text:report", true, false},
+		{"due<2d", true, false},
+		{"due>=1d due<=2021-03-03", true, false},
+		{"due:\"2021-03-03T00:00:00Z\"", true, false},
+		{"due<2021-03-03T00:00:01Z due>2021-03-02T23:59:59+00:00", true, false},
+		{"NOT due>1w", true, true},
+		{"due:none", false, true},
+		{"overdue:false", true, true},
+		{"priority>low OR complete:true", true, true},
+		{"state:in-progress", true, false},
+		{"NOT (tag:work OR state:done)", false, false},
+	} {
+		q, err := todo.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%q: %s", tt.query, err)
+		} else if got := q.Where.Match(a, now); got != tt.a {
+			t.Fatalf("%q: Match(a)=%v, want %v", tt.query, got, tt.a)
+		} else if got := q.Where.Match(b, now); got != tt.b {
+			t.Fatalf("%q: Match(b)=%v, want %v", tt.query, got, tt.b)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies.
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// Scopes of a change to a recurring todo.
+const (
+	// ScopeThis only changes the occurrence itself. It is split from the
+	// series, which continues unchanged with the next occurrence.
+	ScopeThis = "this"
+
+	// ScopeFuture changes the occurrence and every occurrence after it.
+	// This is the default.
+	ScopeFuture = "future"
+)
+
+// maxPeriods is the number of periods searched for the next occurrence before
+// a rule is considered to have no more occurrences, e.g. for February 30th.
+const maxPeriods = 5000
+
+// Rule represents an RFC 5545 recurrence rule such as
+// "FREQ=MONTHLY;BYDAY=-1FR" (every last Friday of the month).
+//
+// The FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY & BYMONTH parts are
+// supported and weeks start on Monday.
+type Rule struct {
+	Freq     string
+	Interval int
+
+	// Number of occurrences of the series and the time of the last one.
+	// Zero if the series does not end.
+	Count int
+	Until *time.Time
+
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+// WeekdayNum represents a BYDAY entry such as "MO" or "-1FR". N selects the
+// nth occurrence of the weekday within the month or year, counting from the
+// end if negative. Zero matches every occurrence.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// weekdays maps RFC 5545 weekday names to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRule parses an RFC 5545 RRULE value, with or without the "RRULE:"
+// prefix. Returns EINVALID if the rule is malformed or unsupported.
+func ParseRule(s string) (*Rule, error) {
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:"), ";") {
+		name, value := part, ""
+		if i := strings.Index(part, "="); i != -1 {
+			name, value = part[:i], part[i+1:]
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			r.Interval, err = parsePositive(value)
+		case "COUNT":
+			r.Count, err = parsePositive(value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseByMonthDay(value)
+		case "BYMONTH":
+			r.ByMonth, err = parseByMonth(value)
+		default:
+			return nil, Errorf(EINVALID, "Unsupported recurrence rule part '%s'.", name)
+		}
+		if err != nil {
+			return nil, Errorf(EINVALID, "Invalid recurrence rule part '%s'.", part)
+		}
+	}
+
+	switch r.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	case "":
+		return nil, Errorf(EINVALID, "Recurrence rule must have a frequency.")
+	default:
+		return nil, Errorf(EINVALID, "Unsupported recurrence frequency '%s'.", r.Freq)
+	}
+
+	if r.Count != 0 && r.Until != nil {
+		return nil, Errorf(EINVALID, "Recurrence rule cannot have both COUNT and UNTIL.")
+	} else if r.Freq == FreqWeekly && len(r.ByMonthDay) > 0 {
+		return nil, Errorf(EINVALID, "BYMONTHDAY cannot be used with FREQ=WEEKLY.")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != FreqMonthly && r.Freq != FreqYearly {
+			return nil, Errorf(EINVALID, "Numbered BYDAY can only be used with FREQ=MONTHLY or FREQ=YEARLY.")
+		}
+	}
+
+	return r, nil
+}
+
+func parsePositive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err == nil && n < 1 {
+		err = fmt.Errorf("not positive")
+	}
+	return n, err
+}
+
+// parseUntil parses a UTC date-time or a date, which ends the series at the
+// end of that day in UTC.
+func parseUntil(s string) (*time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("20060102", s)
+	if err != nil {
+		return nil, err
+	}
+	t = t.Add(24*time.Hour - time.Second)
+	return &t, nil
+}
+
+func parseByDay(s string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, v := range strings.Split(strings.ToUpper(s), ",") {
+		if len(v) < 2 {
+			return nil, fmt.Errorf("invalid weekday")
+		}
+		wd, ok := weekdays[v[len(v)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday")
+		}
+
+		var n int
+		if prefix := v[:len(v)-2]; prefix != "" {
+			var err error
+			if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid weekday number")
+			}
+		}
+		days = append(days, WeekdayNum{N: n, Weekday: wd})
+	}
+	return days, nil
+}
+
+func parseByMonthDay(s string) ([]int, error) {
+	var days []int
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil || n == 0 || n < -31 || n > 31 {
+			return nil, fmt.Errorf("invalid month day")
+		}
+		days = append(days, n)
+	}
+	return days, nil
+}
+
+func parseByMonth(s string) ([]time.Month, error) {
+	var months []time.Month
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 12 {
+			return nil, fmt.Errorf("invalid month")
+		}
+		months = append(months, time.Month(n))
+	}
+	return months, nil
+}
+
+// String returns the rule in its canonical RRULE form.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count != 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		var a []string
+		for _, wd := range r.ByDay {
+			s := strings.ToUpper(wd.Weekday.String()[:2])
+			if wd.N != 0 {
+				s = strconv.Itoa(wd.N) + s
+			}
+			a = append(a, s)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(a, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		var a []string
+		for _, n := range r.ByMonthDay {
+			a = append(a, strconv.Itoa(n))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(a, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		var a []string
+		for _, m := range r.ByMonth {
+			a = append(a, strconv.Itoa(int(m)))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(a, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence after prev, which must be an occurrence of
+// the rule. Occurrences keep the time of day of prev in its location, so a
+// daily todo due at 9:00 stays due at 9:00 across daylight saving changes.
+// Returns false if there is no occurrence after prev before UNTIL. COUNT is
+// not taken into account as the rule does not know how many occurrences came
+// before prev.
+func (r *Rule) Next(prev time.Time) (time.Time, bool) {
+	hour, min, sec := prev.Clock()
+
+	// Dates are computed in UTC, where every day has 24 hours, and only
+	// converted to prev's location at the end.
+	day := time.Date(prev.Year(), prev.Month(), prev.Day(), 0, 0, 0, 0, time.UTC)
+	start := r.periodStart(day)
+	for i := 0; i < maxPeriods; i++ {
+		for _, d := range r.expand(r.advance(start, i*r.Interval), day) {
+			t := time.Date(d.Year(), d.Month(), d.Day(), hour, min, sec, 0, prev.Location())
+			if !t.After(prev) {
+				continue
+			} else if r.Until != nil && t.After(*r.Until) {
+				return time.Time{}, false
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// periodStart returns the first day of the period containing day.
+func (r *Rule) periodStart(day time.Time) time.Time {
+	switch r.Freq {
+	case FreqWeekly:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case FreqMonthly:
+		return day.AddDate(0, 0, 1-day.Day())
+	case FreqYearly:
+		return time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// advance returns the first day of the nth period after the one starting at start.
+func (r *Rule) advance(start time.Time, n int) time.Time {
+	switch r.Freq {
+	case FreqWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case FreqMonthly:
+		return start.AddDate(0, n, 0)
+	case FreqYearly:
+		return start.AddDate(n, 0, 0)
+	}
+	return start.AddDate(0, 0, n)
+}
+
+// expand returns the days of the period starting at start that match the
+// rule, in order. Parts that are not set default to the day of the series
+// occurrence day, e.g. a weekly rule without BYDAY repeats on its weekday.
+func (r *Rule) expand(start, day time.Time) []time.Time {
+	var days []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		last := lastDayOfMonth(start)
+		if r.matchMonth(start) && r.matchMonthDay(start, last) && r.matchWeekday(start, start, start) {
+			days = append(days, start)
+		}
+
+	case FreqWeekly:
+		for d := start; d.Before(start.AddDate(0, 0, 7)); d = d.AddDate(0, 0, 1) {
+			if !r.matchMonth(d) {
+				continue
+			} else if len(r.ByDay) == 0 && d.Weekday() == day.Weekday() {
+				days = append(days, d)
+			} else if len(r.ByDay) > 0 && r.matchWeekday(d, d, d) {
+				days = append(days, d)
+			}
+		}
+
+	case FreqMonthly:
+		if r.matchMonth(start) {
+			days = r.expandMonth(start, day)
+		}
+
+	case FreqYearly:
+		// Numbered weekdays count within the year unless months are given.
+		if len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) > 0 {
+			last := start.AddDate(1, 0, -1)
+			for d := start; !d.After(last); d = d.AddDate(0, 0, 1) {
+				if r.matchWeekday(d, start, last) {
+					days = append(days, d)
+				}
+			}
+			break
+		}
+
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{day.Month()}
+		}
+		months = append([]time.Month(nil), months...)
+		sort.Slice(months, func(i, j int) bool { return months[i] < months[j] })
+		for _, m := range months {
+			days = append(days, r.expandMonth(time.Date(start.Year(), m, 1, 0, 0, 0, 0, time.UTC), day)...)
+		}
+	}
+	return days
+}
+
+// expandMonth returns the days of the month starting at first that match the
+// BYMONTHDAY & BYDAY parts, or the day of the month of day if neither is set.
+func (r *Rule) expandMonth(first, day time.Time) []time.Time {
+	last := lastDayOfMonth(first)
+
+	var days []time.Time
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			if d.Day() == day.Day() {
+				days = append(days, d)
+			}
+		} else if r.matchMonthDay(d, last) && r.matchWeekday(d, first, last) {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// matchMonth returns true if d is in one of the BYMONTH months, if set.
+func (r *Rule) matchMonth(d time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if d.Month() == m {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMonthDay returns true if d is one of the BYMONTHDAY days of its month,
+// which ends on last, if set.
+func (r *Rule) matchMonthDay(d, last time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	for _, n := range r.ByMonthDay {
+		if (n > 0 && d.Day() == n) || (n < 0 && d.Day() == last.Day()+n+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekday returns true if d matches one of the BYDAY weekdays, if set.
+// Numbered weekdays are counted within the period from first to last.
+func (r *Rule) matchWeekday(d, first, last time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if d.Weekday() != wd.Weekday {
+			continue
+		}
+		switch {
+		case wd.N == 0:
+			return true
+		case wd.N > 0 && daysBetween(first, d)/7+1 == wd.N:
+			return true
+		case wd.N < 0 && daysBetween(d, last)/7+1 == -wd.N:
+			return true
+		}
+	}
+	return false
+}
+
+// lastDayOfMonth returns the last day of the month of d.
+func lastDayOfMonth(d time.Time) time.Time {
+	return time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween returns the number of days from a to b, both dates in UTC.
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a) / (24 * time.Hour))
+}
+
+// Recur applies the recurrence of t after it changed from prev, which is nil
+// if t is new, and returns the next occurrence to create, if any. t must
+// already have an ID and be validated.
+//
+// Completing a recurring todo hands its rule on to the next occurrence. A
+// change with ScopeThis splits t from the series as an exception and the
+// series continues unchanged with the next occurrence.
+func (t *Todo) Recur(prev *Todo, scope string) (*Todo, error) {
+	switch scope {
+	case "", ScopeThis, ScopeFuture:
+	default:
+		return nil, Errorf(EINVALID, "Invalid scope '%s'.", scope)
+	}
+
+	// The first recurring todo starts the series.
+	if t.Recurrence != "" && t.SeriesID == 0 {
+		t.SeriesID, t.Occurrence = t.ID, 1
+	}
+
+	if scope == ScopeThis && prev != nil && prev.Recurrence != "" {
+		if t.Recurrence != prev.Recurrence {
+			return nil, Errorf(EINVALID, "The recurrence of todo with ID '%d' can only be changed for all future occurrences.", t.ID)
+		}
+		t.Recurrence = ""
+		return prev.nextOccurrence(), nil
+	}
+
+	if t.Recurrence != "" && t.Complete && (prev == nil || !prev.Complete) {
+		next := t.nextOccurrence()
+		t.Recurrence = ""
+		return next, nil
+	}
+	return nil, nil
+}
+
+// nextOccurrence returns the occurrence following t in its series, or nil if
+// the series ends with t. t must be validated.
+func (t *Todo) nextOccurrence() *Todo {
+	rule, err := ParseRule(t.Recurrence)
+	if err != nil || (rule.Count != 0 && t.Occurrence >= rule.Count) {
+		return nil
+	}
+
+	loc := time.UTC
+	if t.TimeZone != "" {
+		loc, _ = time.LoadLocation(t.TimeZone)
+	}
+	dueAt, ok := rule.Next(t.DueAt.In(loc))
+	if !ok {
+		return nil
+	}
+	dueAt = dueAt.UTC()
+
+	next := &Todo{
+		OwnerID:    t.OwnerID,
+		ListID:     t.ListID,
+		ParentID:   t.ParentID,
+		Value:      t.Value,
+		DueAt:      &dueAt,
+		TimeZone:   t.TimeZone,
+		Recurrence: t.Recurrence,
+		SeriesID:   t.SeriesID,
+		Occurrence: t.Occurrence + 1,
+		Tags:       t.Tags,
+		Priority:   t.Priority,
+		Version:    1,
+	}
+	// Reminders keep their distance to the due date.
+	if t.RemindAt != nil {
+		remindAt := dueAt.Add(t.RemindAt.Sub(*t.DueAt))
+		next.RemindAt = &remindAt
+	}
+	return next
+}
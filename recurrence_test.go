@@ -0,0 +1,80 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+	"todo"
+)
+
+func TestRule_Next(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 9, 0, 0, 0, time.UTC) }
+
+	for _, tt := range []struct {
+		rule string
+		prev time.Time
+		want []time.Time // following occurrences, in order
+		end  bool        // true if there are no more occurrences
+	}{
+		{"FREQ=DAILY;INTERVAL=3", date(2021, 2, 27), []time.Time{date(2021, 3, 2), date(2021, 3, 5)}, false},
+		{"FREQ=WEEKLY", date(2021, 3, 3), []time.Time{date(2021, 3, 10), date(2021, 3, 17)}, false},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", date(2021, 3, 1), []time.Time{date(2021, 3, 5), date(2021, 3, 15), date(2021, 3, 19)}, false},
+		{"FREQ=MONTHLY", date(2021, 1, 31), []time.Time{date(2021, 3, 31), date(2021, 5, 31)}, false},
+		{"FREQ=MONTHLY;BYDAY=-1FR", date(2021, 3, 26), []time.Time{date(2021, 4, 30), date(2021, 5, 28)}, false},
+		{"FREQ=MONTHLY;BYDAY=2TU,4TU", date(2021, 3, 9), []time.Time{date(2021, 3, 23), date(2021, 4, 13)}, false},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1", date(2021, 2, 1), []time.Time{date(2021, 2, 28), date(2021, 3, 1)}, false},
+		{"FREQ=YEARLY", date(2020, 2, 29), []time.Time{date(2024, 2, 29)}, false},
+		{"FREQ=YEARLY;BYMONTH=3,9;BYDAY=1SU", date(2021, 3, 7), []time.Time{date(2021, 9, 5), date(2022, 3, 6)}, false},
+		{"FREQ=YEARLY;BYDAY=-1MO", date(2021, 12, 27), []time.Time{date(2022, 12, 26)}, false},
+		{"FREQ=DAILY;BYDAY=SA,SU;BYMONTH=12", date(2021, 12, 26), []time.Time{date(2022, 12, 3), date(2022, 12, 4)}, false},
+		{"FREQ=DAILY;UNTIL=20210302", date(2021, 3, 1), []time.Time{date(2021, 3, 2)}, true},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", date(2021, 1, 1), nil, true},
+	} {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := todo.ParseRule(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prev := tt.prev
+			for _, want := range tt.want {
+				got, ok := r.Next(prev)
+				if !ok || !got.Equal(want) {
+					t.Fatalf("Next(%v)=%v, %v, want %v", prev, got, ok, want)
+				}
+				prev = got
+			}
+			if got, ok := r.Next(prev); tt.end && ok {
+				t.Fatalf("Next(%v)=%v, want none", prev, got)
+			}
+		})
+	}
+}
+
+func TestRule_String(t *testing.T) {
+	r, err := todo.ParseRule("RRULE:freq=monthly;bymonth=1,6;byday=-1fr;interval=1;until=20211231")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := r.String(), "FREQ=MONTHLY;UNTIL=20211231T235959Z;BYDAY=-1FR;BYMONTH=1,6"; got != want {
+		t.Fatalf("String()=%q, want %q", got, want)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=SECONDLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;BYDAY=XX",
+		"FREQ=DAILY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;WKST=SU",
+	} {
+		if _, err := todo.ParseRule(s); todo.ErrorCode(err) != todo.EINVALID {
+			t.Errorf("ParseRule(%q): unexpected error: %#v", s, err)
+		}
+	}
+}
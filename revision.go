@@ -0,0 +1,149 @@
+package todo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// RevisionService gives access to the revision history of todos, see
+// revmw.NewTodoRevisionMiddleware.
+type RevisionService interface {
+	ListRevisions(ctx context.Context, request ListRevisionsRequest) ([]*Revision, error)
+	RestoreRevision(ctx context.Context, request RestoreRevisionRequest) (*Todo, error)
+}
+
+// RevisionMiddleware describes a service middleware for the RevisionService.
+type RevisionMiddleware func(service RevisionService) RevisionService
+
+// RevisionStore stores the revisions of todos. It does not check whether the
+// caller may access a todo, which is left to the RevisionService. Revisions
+// are kept when their todo is deleted until they are pruned.
+type RevisionStore interface {
+	// CreateRevision stores r and assigns its ID & CreatedAt.
+	CreateRevision(ctx context.Context, r *Revision) error
+
+	// FindRevisions returns the revisions of the todo with the given ID,
+	// oldest first.
+	FindRevisions(ctx context.Context, todoID int) ([]*Revision, error)
+
+	// PruneRevisions deletes the revisions of the todo with the given ID
+	// beyond the newest policy.MaxRevisions or older than policy.MaxAge.
+	PruneRevisions(ctx context.Context, todoID int, policy RetentionPolicy) error
+
+	// ExpireRevisions deletes up to limit revisions of any todo created
+	// before createdBefore, oldest first, and returns the number of revisions
+	// deleted. It is used by background jobs, see scheduler.RevisionPruner.
+	ExpireRevisions(ctx context.Context, createdBefore time.Time, limit int) (int, error)
+}
+
+// RetentionPolicy limits the revisions kept of each todo. Zero fields do not
+// limit anything.
+type RetentionPolicy struct {
+	// Number of revisions kept per todo, newest first.
+	MaxRevisions int
+
+	// Age after which revisions are deleted.
+	MaxAge time.Duration
+}
+
+// Revision is the state of a todo after a change made to it.
+type Revision struct {
+	// Opaque ID of the revision, see NextID.
+	ID     int `json:"id"`
+	TodoID int `json:"todoId"`
+
+	// Principal who made the change, empty if anonymous.
+	AuthorID  string    `json:"authorId"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Fields changed by the change, see DiffTodos.
+	Changes []*Change `json:"changes"`
+
+	// Todo after the change.
+	Todo *Todo `json:"todo"`
+}
+
+// Change is the change of a field of a todo. Values are JSON encoded like the
+// field of Todo of the same name, and null if not set.
+type Change struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// ListRevisionsRequest lists the revisions of a todo, oldest first.
+type ListRevisionsRequest struct {
+	TodoID int `json:"todoId"`
+}
+
+// RestoreRevisionRequest updates a todo to its state in one of its revisions.
+// The restore is a change of its own and becomes a new revision.
+type RestoreRevisionRequest struct {
+	TodoID     int `json:"todoId"`
+	RevisionID int `json:"revisionId"`
+
+	// Expected current version of the todo. See UpdateTodoRequest.Version.
+	Version int `json:"version"`
+}
+
+// DiffTodos returns the fields a user can change that differ between prev and
+// t, by JSON name and in the order of Todo. Every field set on t differs if
+// prev is nil, except the position new todos are placed at by backends.
+func DiffTodos(prev, t *Todo) []*Change {
+	if prev == nil {
+		prev = &Todo{}
+	}
+
+	changes := make([]*Change, 0)
+	add := func(field string, changed bool, from, to interface{}) {
+		if changed {
+			changes = append(changes, &Change{Field: field, From: marshalChange(from), To: marshalChange(to)})
+		}
+	}
+	add("listId", prev.ListID != t.ListID, prev.ListID, t.ListID)
+	add("parentId", prev.ParentID != t.ParentID, prev.ParentID, t.ParentID)
+	add("value", prev.Value != t.Value, prev.Value, t.Value)
+	add("state", prev.State != t.State, prev.State, t.State)
+	add("complete", prev.Complete != t.Complete, prev.Complete, t.Complete)
+	add("dueAt", !equalTime(prev.DueAt, t.DueAt), prev.DueAt, t.DueAt)
+	add("remindAt", !equalTime(prev.RemindAt, t.RemindAt), prev.RemindAt, t.RemindAt)
+	add("timeZone", prev.TimeZone != t.TimeZone, prev.TimeZone, t.TimeZone)
+	add("recurrence", prev.Recurrence != t.Recurrence, prev.Recurrence, t.Recurrence)
+	add("tags", !equalTags(prev.Tags, t.Tags), prev.Tags, t.Tags)
+	add("priority", prev.Priority != t.Priority, prev.Priority, t.Priority)
+	add("position", prev.ID != 0 && prev.Position != t.Position, prev.Position, t.Position)
+	add("archivedAt", !equalTime(prev.ArchivedAt, t.ArchivedAt), prev.ArchivedAt, t.ArchivedAt)
+	add("deletedAt", !equalTime(prev.DeletedAt, t.DeletedAt), prev.DeletedAt, t.DeletedAt)
+	return changes
+}
+
+// marshalChange returns the JSON encoding of a changed value, or null for an
+// unset date or tags.
+func marshalChange(v interface{}) json.RawMessage {
+	switch v := v.(type) {
+	case *time.Time:
+		if v == nil {
+			return json.RawMessage("null")
+		}
+	case []string:
+		if len(v) == 0 {
+			return json.RawMessage("null")
+		}
+	}
+	buf, _ := json.Marshal(v)
+	return buf
+}
+
+// equalTags returns true if a and b are the same normalized tags.
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
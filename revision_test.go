@@ -0,0 +1,38 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+	"todo"
+)
+
+func TestDiffTodos(t *testing.T) {
+	dueAt := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	prev := &todo.Todo{ID: 1, Value: "a", Tags: []string{"work"}, DueAt: &dueAt, Version: 1}
+	next := &todo.Todo{ID: 1, Value: "b", Priority: todo.PriorityHigh, Version: 2}
+
+	for _, tt := range []struct {
+		prev, t *todo.Todo
+		want    []string
+	}{
+		{prev, next, []string{`value:"a"->"b"`, `dueAt:"2030-01-01T12:00:00Z"->null`, `tags:["work"]->null`, `priority:0->3`}},
+		{nil, prev, []string{`value:""->"a"`, `dueAt:null->"2030-01-01T12:00:00Z"`, `tags:null->["work"]`}},
+		{prev, prev, nil},
+		{next, &todo.Todo{ID: 1, Value: "b", Priority: todo.PriorityHigh, ArchivedAt: &dueAt, Version: 3}, []string{`archivedAt:null->"2030-01-01T12:00:00Z"`}},
+		{next, &todo.Todo{ID: 1, Value: "b", Priority: todo.PriorityHigh, Position: "b", DeletedAt: &dueAt, Version: 2}, []string{`position:""->"b"`, `deletedAt:null->"2030-01-01T12:00:00Z"`}},
+		{nil, &todo.Todo{ID: 1, Value: "a", Position: "a"}, []string{`value:""->"a"`}},
+	} {
+		var got []string
+		for _, c := range todo.DiffTodos(tt.prev, tt.t) {
+			got = append(got, c.Field+":"+string(c.From)+"->"+string(c.To))
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("changes=%v, want %v", got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("changes=%v, want %v", got, tt.want)
+			}
+		}
+	}
+}
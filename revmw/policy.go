@@ -0,0 +1,39 @@
+package revmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"time"
+	"todo"
+)
+
+// NewPolicyEnforcerRevisionMiddleware returns a middleware storing a revision
+// of every todo archived through it in store. Revisions are pruned according
+// to policy, and revisions that cannot be stored are logged to logger.
+func NewPolicyEnforcerRevisionMiddleware(store todo.RevisionStore, policy todo.RetentionPolicy, logger log.Logger) todo.PolicyEnforcerMiddleware {
+	return func(next todo.PolicyEnforcer) todo.PolicyEnforcer {
+		return &policyEnforcerRevisionMiddleware{
+			recorder: recorder{store: store, policy: policy, logger: logger},
+			next:     next,
+		}
+	}
+}
+
+type policyEnforcerRevisionMiddleware struct {
+	recorder
+	next todo.PolicyEnforcer
+}
+
+// EnforcePolicies records the todos archived so far if it fails part way.
+func (mw policyEnforcerRevisionMiddleware) EnforcePolicies(ctx context.Context, now time.Time, limit int) (*todo.PolicyReport, error) {
+	report, err := mw.next.EnforcePolicies(ctx, now, limit)
+	if report != nil {
+		for _, t := range report.Archived {
+			// Archiving changes nothing else, so the todo before is known.
+			prev := *t
+			prev.ArchivedAt = nil
+			mw.record(ctx, t, &prev)
+		}
+	}
+	return report, err
+}
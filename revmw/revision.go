@@ -0,0 +1,60 @@
+package revmw
+
+import (
+	"context"
+	"todo"
+)
+
+// NewRevisionService returns a service listing the revisions in store of the
+// todos the caller can read from todos, and restoring them through todos.
+// Restores are only recorded if todos is wrapped by the todo middleware.
+func NewRevisionService(todos todo.Service, store todo.RevisionStore) todo.RevisionService {
+	return &revisionService{
+		todos: todos,
+		store: store,
+	}
+}
+
+type revisionService struct {
+	todos todo.Service
+	store todo.RevisionStore
+}
+
+func (s *revisionService) ListRevisions(ctx context.Context, request todo.ListRevisionsRequest) ([]*todo.Revision, error) {
+	if _, err := s.todos.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: request.TodoID}); err != nil {
+		return nil, err
+	}
+	return s.store.FindRevisions(ctx, request.TodoID)
+}
+
+func (s *revisionService) RestoreRevision(ctx context.Context, request todo.RestoreRevisionRequest) (*todo.Todo, error) {
+	revs, err := s.ListRevisions(ctx, todo.ListRevisionsRequest{TodoID: request.TodoID})
+	if err != nil {
+		return nil, err
+	}
+	var old *todo.Todo
+	for _, r := range revs {
+		if r.ID == request.RevisionID {
+			old = r.Todo
+		}
+	}
+	if old == nil {
+		return nil, todo.Errorf(todo.ENOTFOUND, "Revision with ID '%d' of todo with ID '%d' could not be found.", request.RevisionID, request.TodoID)
+	}
+
+	return s.todos.UpdateTodo(ctx, todo.UpdateTodoRequest{
+		ID:         request.TodoID,
+		Value:      old.Value,
+		Complete:   old.Complete,
+		State:      old.State,
+		ListID:     old.ListID,
+		ParentID:   old.ParentID,
+		DueAt:      old.DueAt,
+		RemindAt:   old.RemindAt,
+		TimeZone:   old.TimeZone,
+		Recurrence: old.Recurrence,
+		Tags:       old.Tags,
+		Priority:   old.Priority,
+		Version:    request.Version,
+	})
+}
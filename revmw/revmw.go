@@ -0,0 +1,242 @@
+// Package revmw keeps the revision history of todos for any backend.
+//
+// The todo middleware stores a revision in a todo.RevisionStore after every
+// change made through it, along with the fields that changed since the todo
+// was last read. Deleting a todo is recorded as a change of its deletedAt
+// field; subtasks deleted along with it do not get revisions of their own. Changes made through other services, such as renaming a
+// tag, do not get revisions of their own and only show up in the difference
+// of the next revision. Changes to a todo made through the middleware are
+// serialized, so every revision is compared with the one before it. A change
+// whose revision cannot be stored is not undone: the error is logged and the
+// change succeeds without a revision.
+//
+// The trash middleware records restored todos against the todo as it was in
+// the trash.
+//
+// The policy enforcer middleware records the todos archived by the retention
+// policies of lists, without an author. Todos purged by a policy keep their
+// revisions like any deleted todo.
+//
+// The revision service lists revisions and restores them through the todo
+// service, so a restore is recorded like any change.
+package revmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"sort"
+	"sync"
+	"time"
+	"todo"
+)
+
+// lockStripes is the number of locks changes to todos are serialized with.
+const lockStripes = 64
+
+// NewTodoRevisionMiddleware returns a middleware storing a revision of every
+// todo created or changed through it in store. Revisions of a todo are pruned
+// according to policy whenever it changes; revisions of todos that no longer
+// change expire through scheduler.RevisionPruner. Revisions that cannot be
+// stored are logged to logger.
+func NewTodoRevisionMiddleware(store todo.RevisionStore, policy todo.RetentionPolicy, logger log.Logger) todo.Middleware {
+	return func(next todo.Service) todo.Service {
+		return &todoRevisionMiddleware{
+			recorder: recorder{store: store, policy: policy, logger: logger},
+			next:     next,
+			locks:    new([lockStripes]sync.Mutex),
+		}
+	}
+}
+
+type todoRevisionMiddleware struct {
+	recorder
+	next  todo.Service
+	locks *[lockStripes]sync.Mutex
+}
+
+func (mw todoRevisionMiddleware) CreateTodo(ctx context.Context, request todo.CreateTodoRequest) (*todo.Todo, error) {
+	t, err := mw.next.CreateTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.record(ctx, t, nil)
+	return t, nil
+}
+
+func (mw todoRevisionMiddleware) UpdateTodo(ctx context.Context, request todo.UpdateTodoRequest) (*todo.Todo, error) {
+	defer mw.lock(request.ID)()
+	prev := mw.find(ctx, request.ID)
+	t, err := mw.next.UpdateTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.record(ctx, t, prev)
+	return t, nil
+}
+
+func (mw todoRevisionMiddleware) PatchTodo(ctx context.Context, request todo.PatchTodoRequest) (*todo.Todo, error) {
+	defer mw.lock(request.ID)()
+	prev := mw.find(ctx, request.ID)
+	t, err := mw.next.PatchTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.record(ctx, t, prev)
+	return t, nil
+}
+
+// DeleteTodo records the deletion and keeps the revisions of the todo until
+// they are pruned.
+func (mw todoRevisionMiddleware) DeleteTodo(ctx context.Context, request todo.DeleteTodoRequest) error {
+	defer mw.lock(request.ID)()
+	prev := mw.find(ctx, request.ID)
+	if err := mw.next.DeleteTodo(ctx, request); err != nil {
+		return err
+	}
+	if prev != nil {
+		mw.recordDelete(ctx, prev)
+	}
+	return nil
+}
+
+func (mw todoRevisionMiddleware) GetTodoByID(ctx context.Context, request todo.GetTodoByIDRequest) (*todo.Todo, error) {
+	return mw.next.GetTodoByID(ctx, request)
+}
+
+func (mw todoRevisionMiddleware) ListTodos(ctx context.Context, request todo.ListTodosRequest) (*todo.ListTodosResponse, error) {
+	return mw.next.ListTodos(ctx, request)
+}
+
+func (mw todoRevisionMiddleware) CompleteTodo(ctx context.Context, request todo.CompleteTodoRequest) (*todo.CompleteTodoResponse, error) {
+	defer mw.lock(request.ID)()
+	prev := mw.find(ctx, request.ID)
+	resp, err := mw.next.CompleteTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.record(ctx, resp.Todo, prev)
+	if resp.Next != nil {
+		mw.record(ctx, resp.Next, nil)
+	}
+	return resp, nil
+}
+
+func (mw todoRevisionMiddleware) MoveTodo(ctx context.Context, request todo.MoveTodoRequest) (*todo.Todo, error) {
+	defer mw.lock(request.ID)()
+	prev := mw.find(ctx, request.ID)
+	t, err := mw.next.MoveTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.record(ctx, t, prev)
+	return t, nil
+}
+
+// BatchTodos records a revision of every todo created, changed or deleted by
+// the batch. A todo changed several times gets a revision for each change.
+func (mw todoRevisionMiddleware) BatchTodos(ctx context.Context, request todo.BatchTodosRequest) (*todo.BatchTodosResponse, error) {
+	var ids []int
+	for _, op := range request.Ops {
+		if id := op.TodoID(); id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	defer mw.lock(ids...)()
+
+	prevs := make(map[int]*todo.Todo)
+	for _, op := range request.Ops {
+		if id := op.TodoID(); id != 0 && prevs[id] == nil {
+			prevs[id] = mw.find(ctx, id)
+		}
+	}
+
+	resp, err := mw.next.BatchTodos(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range resp.Results {
+		switch {
+		case r.Todo != nil:
+			mw.record(ctx, r.Todo, prevs[r.Todo.ID])
+			prevs[r.Todo.ID] = r.Todo
+		case r.Err == nil && i < len(request.Ops) && request.Ops[i].Op == todo.BatchDelete:
+			if prev := prevs[request.Ops[i].Delete.ID]; prev != nil {
+				mw.recordDelete(ctx, prev)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// lock serializes the changes made through the middleware to the todos with
+// the given IDs, so the todo read before a change is the one it applies to.
+// Todos share a fixed number of locks, which are taken in order so batches
+// cannot deadlock. Returns the function releasing the locks.
+func (mw todoRevisionMiddleware) lock(ids ...int) func() {
+	seen := make(map[int]bool, len(ids))
+	var stripes []int
+	for _, id := range ids {
+		if i := int(uint(id) % lockStripes); !seen[i] {
+			seen[i] = true
+			stripes = append(stripes, i)
+		}
+	}
+	sort.Ints(stripes)
+
+	for _, i := range stripes {
+		mw.locks[i].Lock()
+	}
+	return func() {
+		for _, i := range stripes {
+			mw.locks[i].Unlock()
+		}
+	}
+}
+
+// find returns the todo with the given ID before it is changed, or nil if it
+// cannot be read, in which case the change fails as well.
+func (mw todoRevisionMiddleware) find(ctx context.Context, id int) *todo.Todo {
+	t, err := mw.next.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: id})
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+// recordDelete stores a revision of prev deleted at about the current time.
+// Backends do not return deleted todos, so the time may differ slightly from
+// the one the todo has in the trash.
+func (r recorder) recordDelete(ctx context.Context, prev *todo.Todo) {
+	t := *prev
+	deletedAt := time.Now().UTC().Truncate(time.Second)
+	t.DeletedAt = &deletedAt
+	r.record(ctx, &t, prev)
+}
+
+// recorder stores the revisions of changed todos.
+type recorder struct {
+	store  todo.RevisionStore
+	policy todo.RetentionPolicy
+	logger log.Logger
+}
+
+// record stores a revision of t, which changed from prev or is new if prev is
+// nil, and prunes the revisions of t. Changes to fields without revisions are
+// not recorded. As the change is already made, errors are only logged.
+func (r recorder) record(ctx context.Context, t, prev *todo.Todo) {
+	changes := todo.DiffTodos(prev, t)
+	if prev != nil && len(changes) == 0 {
+		return
+	}
+
+	if err := r.store.CreateRevision(ctx, &todo.Revision{
+		TodoID:   t.ID,
+		AuthorID: todo.PrincipalIDFromContext(ctx),
+		Changes:  changes,
+		Todo:     t,
+	}); err != nil {
+		_ = r.logger.Log("method", "CreateRevision", "todoId", t.ID, "err", err)
+	} else if err := r.store.PruneRevisions(ctx, t.ID, r.policy); err != nil {
+		_ = r.logger.Log("method", "PruneRevisions", "todoId", t.ID, "err", err)
+	}
+}
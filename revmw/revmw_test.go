@@ -0,0 +1,301 @@
+package revmw_test
+
+import (
+	"context"
+	"errors"
+	"github.com/go-kit/kit/log"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"todo"
+	"todo/authzmw"
+	"todo/inmem"
+	"todo/revmw"
+	"todo/todotest"
+)
+
+func TestTodoRevisionMiddleware(t *testing.T) {
+	alice := todotest.NewContextWithPrincipalID(context.Background(), "alice")
+
+	t.Run("Changes", func(t *testing.T) {
+		s, revs := newServices(t, todo.RetentionPolicy{})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		value := "b"
+		if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Changes that leave the todo as it was are not recorded.
+		if _, err := s.UpdateTodo(alice, todo.UpdateTodoRequest{ID: a.ID, Value: "b"}); err != nil {
+			t.Fatal(err)
+		}
+
+		got := mustListRevisions(t, alice, revs, a.ID)
+		if len(got) != 2 {
+			t.Fatalf("unexpected revisions: %#v", got)
+		} else if r := got[0]; r.AuthorID != "alice" || r.Todo.Value != "a" || len(r.Changes) != 2 || r.Changes[0].Field != "value" || r.Changes[1].Field != "state" {
+			t.Fatalf("unexpected revision: %#v", r)
+		} else if r := got[1]; r.Todo.Value != "b" || len(r.Changes) != 1 || string(r.Changes[0].From) != `"a"` || string(r.Changes[0].To) != `"b"` {
+			t.Fatalf("unexpected revision: %#v", r)
+		}
+	})
+
+	t.Run("CompleteTodo", func(t *testing.T) {
+		s, revs := newServices(t, todo.RetentionPolicy{})
+		dueAt := time.Now().Add(24 * time.Hour)
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", DueAt: &dueAt, Recurrence: "FREQ=DAILY"})
+
+		resp, err := s.CompleteTodo(alice, todo.CompleteTodoRequest{ID: a.ID})
+		if err != nil {
+			t.Fatal(err)
+		} else if resp.Next == nil {
+			t.Fatal("expected next occurrence")
+		}
+		if got := mustListRevisions(t, alice, revs, a.ID); len(got) != 2 || !got[1].Todo.Complete {
+			t.Fatalf("unexpected revisions: %#v", got)
+		} else if got := mustListRevisions(t, alice, revs, resp.Next.ID); len(got) != 1 {
+			t.Fatalf("unexpected revisions: %#v", got)
+		}
+	})
+
+	t.Run("BatchTodos", func(t *testing.T) {
+		s, revs := newServices(t, todo.RetentionPolicy{})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		b, c := "b", "c"
+
+		resp, err := s.BatchTodos(alice, todo.BatchTodosRequest{Ops: []todo.BatchOp{
+			{Op: todo.BatchPatch, Patch: &todo.PatchTodoRequest{ID: a.ID, Value: &b}},
+			{Op: todo.BatchPatch, Patch: &todo.PatchTodoRequest{ID: a.ID, Value: &c}},
+			{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "d"}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Every change is recorded against the change before it.
+		if got := mustListRevisions(t, alice, revs, a.ID); len(got) != 3 {
+			t.Fatalf("unexpected revisions: %#v", got)
+		} else if r := got[2]; r.Todo.Value != "c" || len(r.Changes) != 1 || string(r.Changes[0].From) != `"b"` {
+			t.Fatalf("unexpected revision: %#v", r)
+		} else if got := mustListRevisions(t, alice, revs, resp.Results[2].Todo.ID); len(got) != 1 {
+			t.Fatalf("unexpected revisions: %#v", got)
+		}
+	})
+
+	t.Run("MoveTodo", func(t *testing.T) {
+		s, revs := newServices(t, todo.RetentionPolicy{})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		b := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b"})
+
+		moved, err := s.MoveTodo(alice, todo.MoveTodoRequest{ID: b.ID, BeforeID: a.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mustListRevisions(t, alice, revs, b.ID); len(got) != 2 {
+			t.Fatalf("unexpected revisions: %#v", got)
+		} else if r := got[1]; r.AuthorID != "alice" || len(r.Changes) != 1 || r.Changes[0].Field != "position" || string(r.Changes[0].To) != strconv.Quote(moved.Position) {
+			t.Fatalf("unexpected revision: %#v", r)
+		}
+	})
+
+	t.Run("DeleteAndRestore", func(t *testing.T) {
+		store := inmem.NewService()
+		s := revmw.NewTodoRevisionMiddleware(store, todo.RetentionPolicy{}, log.NewNopLogger())(store)
+		trash := revmw.NewTrashRevisionMiddleware(store, todo.RetentionPolicy{}, log.NewNopLogger())(store)
+		revs := revmw.NewRevisionService(s, store)
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		b := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b"})
+
+		if err := s.DeleteTodo(alice, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if _, err := trash.RestoreTodo(alice, todo.RestoreTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The deletion and the restore are changes of the deletedAt field.
+		got := mustListRevisions(t, alice, revs, a.ID)
+		if len(got) != 3 {
+			t.Fatalf("unexpected revisions: %#v", got)
+		} else if r := got[1]; r.AuthorID != "alice" || r.Todo.DeletedAt == nil || len(r.Changes) != 1 || r.Changes[0].Field != "deletedAt" || string(r.Changes[0].From) != "null" {
+			t.Fatalf("unexpected revision: %#v", r)
+		} else if r := got[2]; r.AuthorID != "alice" || r.Todo.DeletedAt != nil || len(r.Changes) != 1 || r.Changes[0].Field != "deletedAt" || string(r.Changes[0].To) != "null" {
+			t.Fatalf("unexpected revision: %#v", r)
+		}
+
+		// Deletes in batches are recorded too.
+		if _, err := s.BatchTodos(alice, todo.BatchTodosRequest{Ops: []todo.BatchOp{
+			{Op: todo.BatchDelete, Delete: &todo.DeleteTodoRequest{ID: b.ID}},
+		}}); err != nil {
+			t.Fatal(err)
+		} else if _, err := trash.RestoreTodo(alice, todo.RestoreTodoRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		} else if got := mustListRevisions(t, alice, revs, b.ID); len(got) != 3 || got[1].Todo.DeletedAt == nil {
+			t.Fatalf("unexpected revisions: %#v", got)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		store := inmem.NewService()
+		s := revmw.NewTodoRevisionMiddleware(store, todo.RetentionPolicy{}, log.NewNopLogger())(&slowService{store})
+		revs := revmw.NewRevisionService(s, store)
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "0"})
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 20; i++ {
+			wg.Add(1)
+			go func(value string) {
+				defer wg.Done()
+				if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+					t.Error(err)
+				}
+			}(strconv.Itoa(i))
+		}
+		wg.Wait()
+
+		// Every revision changes the value of the revision before it.
+		got := mustListRevisions(t, alice, revs, a.ID)
+		if len(got) != 21 {
+			t.Fatalf("len=%d, want 21", len(got))
+		}
+		for i := 1; i < len(got); i++ {
+			if c := got[i].Changes; len(c) != 1 || string(c[0].From) != strconv.Quote(got[i-1].Todo.Value) {
+				t.Fatalf("revision %d: unexpected changes: %s", i, c[0].From)
+			}
+		}
+	})
+
+	t.Run("ErrStore", func(t *testing.T) {
+		store := inmem.NewService()
+		s := revmw.NewTodoRevisionMiddleware(&failingStore{store}, todo.RetentionPolicy{}, log.NewNopLogger())(store)
+
+		// The change is made even though its revision is lost.
+		a, err := s.CreateTodo(alice, todo.CreateTodoRequest{Value: "a"})
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := s.UpdateTodo(alice, todo.UpdateTodoRequest{ID: a.ID, Value: "b"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Policies", func(t *testing.T) {
+		store := inmem.NewService()
+		s := revmw.NewTodoRevisionMiddleware(store, todo.RetentionPolicy{}, log.NewNopLogger())(store)
+		enforcer := revmw.NewPolicyEnforcerRevisionMiddleware(store, todo.RetentionPolicy{}, log.NewNopLogger())(store)
+		revs := revmw.NewRevisionService(s, store)
+
+		l := todotest.MustCreateList(t, alice, store, todo.CreateListRequest{Name: "l", Policies: []todo.Policy{{Action: todo.PolicyArchive, AfterDays: 1}}})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", ListID: l.ID, Complete: true})
+		if _, err := enforcer.EnforcePolicies(context.Background(), time.Now().AddDate(0, 0, 1), 10); err != nil {
+			t.Fatal(err)
+		}
+
+		got := mustListRevisions(t, alice, revs, a.ID)
+		if len(got) != 2 {
+			t.Fatalf("unexpected revisions: %#v", got)
+		} else if r := got[1]; r.AuthorID != "" || r.Todo.ArchivedAt == nil || len(r.Changes) != 1 || r.Changes[0].Field != "archivedAt" || string(r.Changes[0].From) != "null" {
+			t.Fatalf("unexpected revision: %#v", r)
+		}
+	})
+
+	t.Run("Retention", func(t *testing.T) {
+		s, revs := newServices(t, todo.RetentionPolicy{MaxRevisions: 2})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		for _, v := range []string{"b", "c"} {
+			if _, err := s.UpdateTodo(alice, todo.UpdateTodoRequest{ID: a.ID, Value: v}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := mustListRevisions(t, alice, revs, a.ID); len(got) != 2 || got[0].Todo.Value != "b" || got[1].Todo.Value != "c" {
+			t.Fatalf("unexpected revisions: %#v", got)
+		}
+	})
+}
+
+func TestRevisionService(t *testing.T) {
+	alice := todotest.NewContextWithPrincipalID(context.Background(), "alice")
+	bob := todotest.NewContextWithPrincipalID(context.Background(), "bob")
+
+	t.Run("RestoreRevision", func(t *testing.T) {
+		s, revs := newServices(t, todo.RetentionPolicy{})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", Tags: []string{"work"}, Priority: todo.PriorityHigh})
+		b, err := s.UpdateTodo(alice, todo.UpdateTodoRequest{ID: a.ID, Value: "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := mustListRevisions(t, alice, revs, a.ID)[0]
+
+		// Restores are guarded by the version like other changes.
+		if _, err := revs.RestoreRevision(alice, todo.RestoreRevisionRequest{TodoID: a.ID, RevisionID: first.ID, Version: a.Version}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+		got, err := revs.RestoreRevision(alice, todo.RestoreRevisionRequest{TodoID: a.ID, RevisionID: first.ID, Version: b.Version})
+		if err != nil {
+			t.Fatal(err)
+		} else if got.Value != "a" || len(got.Tags) != 1 || got.Priority != todo.PriorityHigh || got.Version != b.Version+1 {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		// The restore is recorded as a change of its own.
+		if got := mustListRevisions(t, alice, revs, a.ID); len(got) != 3 || got[2].Todo.Value != "a" {
+			t.Fatalf("unexpected revisions: %#v", got)
+		}
+
+		if _, err := revs.RestoreRevision(alice, todo.RestoreRevisionRequest{TodoID: a.ID, RevisionID: first.ID + 1000}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("Access", func(t *testing.T) {
+		s, revs := newServices(t, todo.RetentionPolicy{})
+		a := todotest.MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+
+		// Only callers who can read a todo can read its history.
+		if _, err := revs.ListRevisions(bob, todo.ListRevisionsRequest{TodoID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+		if err := s.DeleteTodo(alice, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if _, err := revs.ListRevisions(alice, todo.ListRevisionsRequest{TodoID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
+
+// slowService is a todo service taking a while to patch todos.
+type slowService struct {
+	todo.Service
+}
+
+func (s *slowService) PatchTodo(ctx context.Context, request todo.PatchTodoRequest) (*todo.Todo, error) {
+	time.Sleep(time.Millisecond)
+	return s.Service.PatchTodo(ctx, request)
+}
+
+// failingStore is a revision store failing to store revisions.
+type failingStore struct {
+	todo.RevisionStore
+}
+
+func (s *failingStore) CreateRevision(ctx context.Context, r *todo.Revision) error {
+	return errors.New("disk full")
+}
+
+// newServices returns a todo service recording revisions according to policy,
+// authorized like in production, and a revision service for it.
+func newServices(tb testing.TB, policy todo.RetentionPolicy) (todo.Service, todo.RevisionService) {
+	tb.Helper()
+	store := inmem.NewService()
+	s := authzmw.NewTodoAuthorizingMiddleware(store)(store)
+	s = revmw.NewTodoRevisionMiddleware(store, policy, log.NewNopLogger())(s)
+	return s, revmw.NewRevisionService(s, store)
+}
+
+func mustListRevisions(tb testing.TB, ctx context.Context, s todo.RevisionService, todoID int) []*todo.Revision {
+	tb.Helper()
+	revs, err := s.ListRevisions(ctx, todo.ListRevisionsRequest{TodoID: todoID})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return revs
+}
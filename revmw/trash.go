@@ -0,0 +1,56 @@
+package revmw
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"todo"
+)
+
+// NewTrashRevisionMiddleware returns a middleware storing a revision of every
+// todo restored through it in store. Revisions are pruned according to policy,
+// and revisions that cannot be stored are logged to logger. Subtasks restored
+// along with a todo do not get revisions of their own.
+func NewTrashRevisionMiddleware(store todo.RevisionStore, policy todo.RetentionPolicy, logger log.Logger) todo.TrashMiddleware {
+	return func(next todo.TrashService) todo.TrashService {
+		return &trashRevisionMiddleware{
+			recorder: recorder{store: store, policy: policy, logger: logger},
+			next:     next,
+		}
+	}
+}
+
+type trashRevisionMiddleware struct {
+	recorder
+	next todo.TrashService
+}
+
+func (mw trashRevisionMiddleware) ListTrash(ctx context.Context, request todo.ListTrashRequest) ([]*todo.Todo, error) {
+	return mw.next.ListTrash(ctx, request)
+}
+
+// RestoreTodo records the restored todo against the todo as it was in the
+// trash, so the revision shows its list or parent if they were dropped.
+func (mw trashRevisionMiddleware) RestoreTodo(ctx context.Context, request todo.RestoreTodoRequest) (*todo.Todo, error) {
+	prev := mw.find(ctx, request.ID)
+	t, err := mw.next.RestoreTodo(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	mw.record(ctx, t, prev)
+	return t, nil
+}
+
+// find returns the todo with the given ID in the trash of the caller, or nil
+// if it cannot be found, in which case the restore fails as well.
+func (mw trashRevisionMiddleware) find(ctx context.Context, id int) *todo.Todo {
+	todos, err := mw.next.ListTrash(ctx, todo.ListTrashRequest{})
+	if err != nil {
+		return nil
+	}
+	for _, t := range todos {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
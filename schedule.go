@@ -0,0 +1,87 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// ReminderService finds reminders that are due across all owners. It is used
+// by background jobs and not exposed to callers of the API.
+type ReminderService interface {
+	// ClaimDueReminders marks up to limit incomplete todos whose reminder is
+	// due at now as reminded and returns them. Archived todos and todos in
+	// the trash are skipped, so their reminders are only sent once they are
+	// unarchived or restored. A reminder is only ever
+	// claimed once, even by concurrent callers, until RemindAt is changed.
+	// Claiming a reminder bumps the version of its todo.
+	// If an error occurs, the reminders claimed so far are returned with it.
+	ClaimDueReminders(ctx context.Context, now time.Time, limit int) ([]*Todo, error)
+}
+
+// Notifier delivers the reminder of a todo to its owner.
+type Notifier interface {
+	Notify(ctx context.Context, t *Todo) error
+}
+
+// Validate normalizes the dates of t to UTC at second precision, its
+// recurrence rule to its canonical form and its tags. Returns EINVALID if its
+// time zone is unknown, its rule or one of its tags is invalid or its priority
+// is out of range. Zero dates are removed.
+func (t *Todo) Validate() (err error) {
+	t.DueAt, t.RemindAt = normalizeTime(t.DueAt), normalizeTime(t.RemindAt)
+	t.ArchivedAt = normalizeTime(t.ArchivedAt)
+
+	if t.Tags, err = NormalizeTags(t.Tags); err != nil {
+		return err
+	} else if t.Priority < PriorityNone || t.Priority > PriorityHigh {
+		return Errorf(EINVALID, "Priority must be between %d and %d.", PriorityNone, PriorityHigh)
+	}
+
+	if t.TimeZone != "" {
+		if _, err := time.LoadLocation(t.TimeZone); err != nil {
+			return Errorf(EINVALID, "Invalid time zone '%s'.", t.TimeZone)
+		}
+	}
+
+	if t.Recurrence != "" {
+		rule, err := ParseRule(t.Recurrence)
+		if err != nil {
+			return err
+		} else if t.DueAt == nil {
+			return Errorf(EINVALID, "A recurring todo must have a due date.")
+		}
+		t.Recurrence = rule.String()
+	}
+	return nil
+}
+
+// Reschedule clears RemindedAt if the reminder of t changed since prev, so the
+// new reminder fires.
+func (t *Todo) Reschedule(prev *Todo) {
+	if !equalTime(t.RemindAt, prev.RemindAt) {
+		t.RemindedAt = nil
+	}
+}
+
+// Overdue returns true if t is incomplete and was due before now.
+func (t *Todo) Overdue(now time.Time) bool {
+	return !t.Complete && t.DueAt != nil && t.DueAt.Before(now)
+}
+
+// normalizeTime returns v in UTC truncated to seconds, or nil if v is nil or
+// zero. Backends store dates this way so they compare & sort as strings.
+func normalizeTime(v *time.Time) *time.Time {
+	if v == nil || v.IsZero() {
+		return nil
+	}
+	other := v.UTC().Truncate(time.Second)
+	return &other
+}
+
+// equalTime returns true if a and b are both nil or the same instant.
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"sync"
+	"time"
+	"todo"
+)
+
+// DefaultEnforceInterval is used when Enforcer.Interval is not set.
+const DefaultEnforceInterval = time.Hour
+
+// Enforcer periodically applies the retention policies of lists, archiving
+// completed todos and purging archived ones.
+type Enforcer struct {
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
+
+	Policies todo.PolicyEnforcer
+	Logger   log.Logger
+
+	// Time between two runs. Defaults to DefaultEnforceInterval.
+	Interval time.Duration
+
+	// Maximum number of todos changed at once. Defaults to DefaultBatchSize.
+	BatchSize int
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+// NewEnforcer returns a new instance of Enforcer.
+func NewEnforcer(policies todo.PolicyEnforcer) *Enforcer {
+	e := &Enforcer{
+		Policies:  policies,
+		Logger:    log.NewNopLogger(),
+		Interval:  DefaultEnforceInterval,
+		BatchSize: DefaultBatchSize,
+		Now:       time.Now,
+	}
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	return e
+}
+
+// Open starts running the enforcer in the background. Policies are applied
+// right away.
+func (e *Enforcer) Open() error {
+	e.wg.Add(1)
+	go func() { defer e.wg.Done(); e.run() }()
+	return nil
+}
+
+// Close stops the enforcer and waits for the current run to finish.
+func (e *Enforcer) Close() error {
+	e.cancel()
+	e.wg.Wait()
+	return nil
+}
+
+func (e *Enforcer) run() {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Tick(e.ctx); err != nil && e.ctx.Err() == nil {
+			_ = e.Logger.Log("method", "EnforcePolicies", "err", err)
+		}
+
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick applies the policies of all lists. It keeps applying them in batches
+// until no todo is left to change, so a backlog is cleared in a single run.
+func (e *Enforcer) Tick(ctx context.Context) error {
+	now := e.Now()
+	for {
+		report, err := e.Policies.EnforcePolicies(ctx, now, e.BatchSize)
+		var n int
+		if report != nil {
+			n = len(report.Archived) + len(report.Purged)
+			if n > 0 {
+				_ = e.Logger.Log("method", "EnforcePolicies", "archived", len(report.Archived), "purged", len(report.Purged))
+			}
+		}
+		if err != nil {
+			return err
+		} else if n < e.BatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/go-kit/kit/log"
+	"net/http"
+	"time"
+	"todo"
+)
+
+// Ensure notifiers implement interface.
+var (
+	_ todo.Notifier = (*LogNotifier)(nil)
+	_ todo.Notifier = (*WebhookNotifier)(nil)
+)
+
+// LogNotifier delivers reminders by writing them to a log.
+type LogNotifier struct {
+	Logger log.Logger
+}
+
+// NewLogNotifier returns a new instance of LogNotifier.
+func NewLogNotifier(logger log.Logger) *LogNotifier {
+	return &LogNotifier{Logger: logger}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, t *todo.Todo) error {
+	return n.Logger.Log(
+		"event", "reminder",
+		"id", t.ID,
+		"ownerId", t.OwnerID,
+		"value", t.Value,
+		"dueAt", t.DueAt,
+		"remindAt", t.RemindAt,
+	)
+}
+
+// SignatureHeader is the header WebhookNotifier signs requests in. It holds the
+// hex encoded HMAC-SHA256 of the request body, keyed by the webhook secret.
+const SignatureHeader = "X-Todo-Signature"
+
+// WebhookNotifier delivers reminders by POSTing them as JSON to a URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+
+	// Key used to sign request bodies. Requests are not signed if empty.
+	Secret []byte
+}
+
+// NewWebhookNotifier returns a new instance of WebhookNotifier.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// webhookEvent is the body of a webhook request.
+type webhookEvent struct {
+	Event string     `json:"event"`
+	Todo  *todo.Todo `json:"todo"`
+}
+
+// Notify POSTs the reminder to the webhook URL. Any response other than 2xx is
+// reported as an error.
+func (n *WebhookNotifier) Notify(ctx context.Context, t *todo.Todo) error {
+	body, err := json.Marshal(webhookEvent{Event: "reminder", Todo: t})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.Secret) > 0 {
+		mac := hmac.New(sha256.New, n.Secret)
+		_, _ = mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: unexpected status %s", resp.Status)
+	}
+	return nil
+}
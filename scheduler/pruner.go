@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"sync"
+	"time"
+	"todo"
+)
+
+// DefaultPruneInterval is used when RevisionPruner.Interval is not set.
+const DefaultPruneInterval = time.Hour
+
+// RevisionPruner periodically deletes the revisions of all todos once they are
+// older than the maximum age. Revisions beyond the maximum number per todo are
+// deleted as changes are recorded, see revmw.NewTodoRevisionMiddleware.
+type RevisionPruner struct {
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
+
+	Revisions todo.RevisionStore
+	Logger    log.Logger
+
+	// Age after which revisions are deleted.
+	MaxAge time.Duration
+
+	// Time between two runs. Defaults to DefaultPruneInterval.
+	Interval time.Duration
+
+	// Maximum number of revisions deleted at once. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+// NewRevisionPruner returns a new instance of RevisionPruner.
+func NewRevisionPruner(revisions todo.RevisionStore, maxAge time.Duration) *RevisionPruner {
+	p := &RevisionPruner{
+		Revisions: revisions,
+		Logger:    log.NewNopLogger(),
+		MaxAge:    maxAge,
+		Interval:  DefaultPruneInterval,
+		BatchSize: DefaultBatchSize,
+		Now:       time.Now,
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return p
+}
+
+// Open starts running the pruner in the background. Expired revisions are
+// deleted right away.
+func (p *RevisionPruner) Open() error {
+	p.wg.Add(1)
+	go func() { defer p.wg.Done(); p.run() }()
+	return nil
+}
+
+// Close stops the pruner and waits for the current run to finish.
+func (p *RevisionPruner) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+func (p *RevisionPruner) run() {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Tick(p.ctx); err != nil && p.ctx.Err() == nil {
+			_ = p.Logger.Log("method", "ExpireRevisions", "err", err)
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick deletes every revision older than the maximum age. It keeps deleting
+// batches until none are left, so a backlog is cleared in a single run.
+func (p *RevisionPruner) Tick(ctx context.Context) error {
+	createdBefore := p.Now().Add(-p.MaxAge)
+	for {
+		n, err := p.Revisions.ExpireRevisions(ctx, createdBefore, p.BatchSize)
+		if n > 0 {
+			_ = p.Logger.Log("method", "ExpireRevisions", "expired", n)
+		}
+		if err != nil {
+			return err
+		} else if n < p.BatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"sync"
+	"time"
+	"todo"
+)
+
+// DefaultPurgeInterval is used when Purger.Interval is not set.
+const DefaultPurgeInterval = time.Hour
+
+// DefaultTrashRetention is the time deleted todos are kept in the trash unless
+// configured otherwise.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// Purger periodically deletes todos from the trash permanently once they have
+// been deleted for longer than the retention window.
+type Purger struct {
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
+
+	Trash  todo.PurgeService
+	Logger log.Logger
+
+	// Time deleted todos are kept in the trash.
+	Retention time.Duration
+
+	// Time between two runs. Defaults to DefaultPurgeInterval.
+	Interval time.Duration
+
+	// Maximum number of todos deleted at once. Defaults to DefaultBatchSize.
+	BatchSize int
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+// NewPurger returns a new instance of Purger.
+func NewPurger(trash todo.PurgeService, retention time.Duration) *Purger {
+	p := &Purger{
+		Trash:     trash,
+		Logger:    log.NewNopLogger(),
+		Retention: retention,
+		Interval:  DefaultPurgeInterval,
+		BatchSize: DefaultBatchSize,
+		Now:       time.Now,
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return p
+}
+
+// Open starts running the purger in the background. Expired todos are purged
+// right away.
+func (p *Purger) Open() error {
+	p.wg.Add(1)
+	go func() { defer p.wg.Done(); p.run() }()
+	return nil
+}
+
+// Close stops the purger and waits for the current run to finish.
+func (p *Purger) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+func (p *Purger) run() {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Tick(p.ctx); err != nil && p.ctx.Err() == nil {
+			_ = p.Logger.Log("method", "PurgeTrash", "err", err)
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick purges every todo deleted before the retention window. It keeps
+// purging batches until none are left, so a backlog is cleared in a single
+// run.
+func (p *Purger) Tick(ctx context.Context) error {
+	deletedBefore := p.Now().Add(-p.Retention)
+	for {
+		n, err := p.Trash.PurgeTrash(ctx, deletedBefore, p.BatchSize)
+		if n > 0 {
+			_ = p.Logger.Log("method", "PurgeTrash", "purged", n)
+		}
+		if err != nil {
+			return err
+		} else if n < p.BatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package scheduler runs background jobs such as sending reminders.
+package scheduler
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"sync"
+	"time"
+	"todo"
+)
+
+// Defaults used when the corresponding Scheduler field is not set.
+const (
+	DefaultInterval  = 30 * time.Second
+	DefaultBatchSize = 100
+)
+
+// Scheduler periodically claims due reminders and delivers them through a
+// notifier.
+//
+// Reminders are claimed before they are delivered, so a reminder is never
+// delivered twice, even across restarts. A reminder that cannot be delivered
+// is logged and not retried.
+type Scheduler struct {
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
+
+	Reminders todo.ReminderService
+	Notifier  todo.Notifier
+	Logger    log.Logger
+
+	// Time between two runs. Defaults to DefaultInterval.
+	Interval time.Duration
+
+	// Maximum number of reminders claimed at once. Defaults to DefaultBatchSize.
+	BatchSize int
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+// NewScheduler returns a new instance of Scheduler.
+func NewScheduler(reminders todo.ReminderService, notifier todo.Notifier) *Scheduler {
+	s := &Scheduler{
+		Reminders: reminders,
+		Notifier:  notifier,
+		Logger:    log.NewNopLogger(),
+		Interval:  DefaultInterval,
+		BatchSize: DefaultBatchSize,
+		Now:       time.Now,
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Open starts running the scheduler in the background. Due reminders are
+// sent right away.
+func (s *Scheduler) Open() error {
+	s.wg.Add(1)
+	go func() { defer s.wg.Done(); s.run() }()
+	return nil
+}
+
+// Close stops the scheduler and waits for the current run to finish.
+func (s *Scheduler) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Scheduler) run() {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Tick(s.ctx); err != nil && s.ctx.Err() == nil {
+			_ = s.Logger.Log("method", "ClaimDueReminders", "err", err)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick delivers every reminder due at the current time. It keeps claiming
+// batches until none are left, so a backlog is cleared in a single run.
+func (s *Scheduler) Tick(ctx context.Context) error {
+	now := s.Now()
+	for {
+		todos, err := s.Reminders.ClaimDueReminders(ctx, now, s.BatchSize)
+		for _, t := range todos {
+			s.notify(ctx, t)
+		}
+		if err != nil {
+			return err
+		} else if len(todos) < s.BatchSize {
+			return nil
+		}
+	}
+}
+
+// notify delivers the reminder of t and logs the outcome.
+func (s *Scheduler) notify(ctx context.Context, t *todo.Todo) {
+	err := s.Notifier.Notify(ctx, t)
+	_ = s.Logger.Log(
+		"method", "Notify",
+		"id", t.ID,
+		"ownerId", t.OwnerID,
+		"remindAt", t.RemindAt,
+		"err", err,
+	)
+}
@@ -0,0 +1,285 @@
+package scheduler_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"todo"
+	"todo/inmem"
+	"todo/scheduler"
+	"todo/todotest"
+)
+
+func TestScheduler_Tick(t *testing.T) {
+	dir, ctx := t.TempDir(), context.Background()
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	remindAt := now.Add(time.Hour)
+
+	s := mustOpenService(t, dir)
+	a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", RemindAt: &remindAt})
+	b := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", RemindAt: &remindAt})
+
+	var n notifier
+	sched := scheduler.NewScheduler(s, &n)
+	sched.BatchSize = 1
+	sched.Now = func() time.Time { return now }
+
+	// Nothing is due yet.
+	if err := sched.Tick(ctx); err != nil {
+		t.Fatal(err)
+	} else if got := n.ids(); len(got) != 0 {
+		t.Fatalf("notified=%v, want none", got)
+	}
+
+	// Both reminders are sent once the clock reaches them, even though only
+	// one is claimed at a time.
+	now = remindAt
+	if err := sched.Tick(ctx); err != nil {
+		t.Fatal(err)
+	} else if got := n.ids(); len(got) != 2 || got[0] != a.ID || got[1] != b.ID {
+		t.Fatalf("notified=%v, want [%d %d]", got, a.ID, b.ID)
+	}
+
+	// Reminders are not sent again after a restart.
+	s = mustOpenService(t, dir)
+	sched.Reminders = s
+	now = now.Add(time.Hour)
+	if err := sched.Tick(ctx); err != nil {
+		t.Fatal(err)
+	} else if got := n.ids(); len(got) != 2 {
+		t.Fatalf("notified=%v, want 2 todos", got)
+	}
+}
+
+func TestScheduler_Open(t *testing.T) {
+	ctx := context.Background()
+	s := inmem.NewService()
+	todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", RemindAt: &time.Time{}})
+	remindAt := time.Now().Add(-time.Minute)
+	a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", RemindAt: &remindAt})
+
+	var n notifier
+	sched := scheduler.NewScheduler(s, &n)
+	if err := sched.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sched.Close()
+
+	// Due reminders are sent as soon as the scheduler starts.
+	for deadline := time.Now().Add(5 * time.Second); len(n.ids()) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for reminder")
+		}
+	}
+	if got := n.ids(); len(got) != 1 || got[0] != a.ID {
+		t.Fatalf("notified=%v, want [%d]", got, a.ID)
+	}
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(scheduler.SignatureHeader)
+	}))
+	defer srv.Close()
+
+	n := scheduler.NewWebhookNotifier(srv.URL)
+	n.Secret = []byte("secret")
+	if err := n.Notify(context.Background(), &todo.Todo{ID: 1, Value: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var event struct {
+		Event string     `json:"event"`
+		Todo  *todo.Todo `json:"todo"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatal(err)
+	} else if event.Event != "reminder" || event.Todo == nil || event.Todo.ID != 1 {
+		t.Fatalf("unexpected event: %s", body)
+	}
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	_, _ = mac.Write(body)
+	if want := hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Fatalf("signature=%q, want %q", signature, want)
+	}
+
+	// Responses other than 2xx are reported as errors.
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	if err := n.Notify(context.Background(), &todo.Todo{ID: 1}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPurger_Tick(t *testing.T) {
+	dir, ctx := t.TempDir(), context.Background()
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	s := mustOpenService(t, dir)
+	s.Now = func() time.Time { return now }
+	var ids []int
+	for _, value := range []string{"a", "b", "c"} {
+		ids = append(ids, todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: value}).ID)
+	}
+	for _, id := range ids[:2] {
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(time.Hour)
+	if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: ids[2]}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := scheduler.NewPurger(s, 24*time.Hour)
+	p.BatchSize = 1
+	p.Now = func() time.Time { return now }
+
+	// Nothing has been in the trash long enough yet.
+	if err := p.Tick(ctx); err != nil {
+		t.Fatal(err)
+	} else if got, err := s.ListTrash(ctx, todo.ListTrashRequest{}); err != nil {
+		t.Fatal(err)
+	} else if len(got) != 3 {
+		t.Fatalf("len=%d, want 3", len(got))
+	}
+
+	// Both expired todos are purged, even though only one is purged at a time.
+	now = now.Add(24*time.Hour - time.Minute)
+	if err := p.Tick(ctx); err != nil {
+		t.Fatal(err)
+	} else if got, err := s.ListTrash(ctx, todo.ListTrashRequest{}); err != nil {
+		t.Fatal(err)
+	} else if len(got) != 1 || got[0].ID != ids[2] {
+		t.Fatalf("unexpected todos: %#v", got)
+	}
+
+	// Purged todos can no longer be restored, also after a restart.
+	s = mustOpenService(t, dir)
+	if _, err := s.RestoreTodo(ctx, todo.RestoreTodoRequest{ID: ids[0]}); todo.ErrorCode(err) != todo.ENOTFOUND {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEnforcer_Tick(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	s := mustOpenService(t, t.TempDir())
+	s.Now = func() time.Time { return now }
+	l := todotest.MustCreateList(t, ctx, s, todo.CreateListRequest{Name: "l", Policies: []todo.Policy{{Action: todo.PolicyArchive, AfterDays: 1}}})
+	for _, value := range []string{"a", "b", "c"} {
+		todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: value, ListID: l.ID, Complete: true})
+	}
+
+	e := scheduler.NewEnforcer(s)
+	e.BatchSize = 2
+	e.Now = func() time.Time { return now }
+
+	// Nothing is archived before the policy applies.
+	if err := e.Tick(ctx); err != nil {
+		t.Fatal(err)
+	} else if got := todotest.MustListTodos(t, ctx, s, todo.ListTodosRequest{}); got.TotalCount != 3 {
+		t.Fatalf("count=%d, want 3", got.TotalCount)
+	}
+
+	// Every todo is archived, even though only two are archived at a time.
+	now = now.AddDate(0, 0, 1)
+	if err := e.Tick(ctx); err != nil {
+		t.Fatal(err)
+	} else if got := todotest.MustListTodos(t, ctx, s, todo.ListTodosRequest{}); got.TotalCount != 0 {
+		t.Fatalf("count=%d, want 0", got.TotalCount)
+	} else if got := todotest.MustListTodos(t, ctx, s, todo.ListTodosRequest{Archived: true}); got.TotalCount != 3 {
+		t.Fatalf("count=%d, want 3", got.TotalCount)
+	}
+}
+
+func TestRevisionPruner_Tick(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	s := mustOpenService(t, t.TempDir())
+	s.Now = func() time.Time { return now }
+	for _, id := range []int{1, 2, 3} {
+		if err := s.CreateRevision(ctx, &todo.Revision{TodoID: id, Todo: &todo.Todo{ID: id}}); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	p := scheduler.NewRevisionPruner(s, 24*time.Hour)
+	p.BatchSize = 1
+	p.Now = func() time.Time { return now }
+
+	// Nothing is old enough yet.
+	if err := p.Tick(ctx); err != nil {
+		t.Fatal(err)
+	} else if revs, err := s.FindRevisions(ctx, 1); err != nil {
+		t.Fatal(err)
+	} else if len(revs) != 1 {
+		t.Fatalf("len=%d, want 1", len(revs))
+	}
+
+	// Both expired revisions are deleted, even though only one is deleted at
+	// a time.
+	now = now.Add(22*time.Hour + time.Minute)
+	if err := p.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[int]int{1: 0, 2: 0, 3: 1} {
+		if revs, err := s.FindRevisions(ctx, id); err != nil {
+			t.Fatal(err)
+		} else if len(revs) != want {
+			t.Fatalf("todo %d: len=%d, want %d", id, len(revs), want)
+		}
+	}
+}
+
+// notifier records the todos it is notified of.
+type notifier struct {
+	mu    sync.Mutex
+	todos []*todo.Todo
+}
+
+func (n *notifier) Notify(_ context.Context, t *todo.Todo) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.todos = append(n.todos, t)
+	return nil
+}
+
+func (n *notifier) ids() []int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ids := make([]int, len(n.todos))
+	for i, t := range n.todos {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// mustOpenService returns a new, open service storing its data in dir.
+func mustOpenService(tb testing.TB, dir string) *inmem.Service {
+	tb.Helper()
+
+	s := inmem.NewService()
+	s.Dir = dir
+	if err := s.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = s.Close() })
+	return s
+}
@@ -0,0 +1,273 @@
+package todo
+
+import (
+	"context"
+	"html"
+	"math"
+	"sort"
+	"strings"
+)
+
+// SearchService finds todos by the words in their values.
+type SearchService interface {
+	SearchTodos(ctx context.Context, request SearchTodosRequest) (*SearchTodosResponse, error)
+}
+
+// SearchMiddleware describes a service middleware for the SearchService.
+type SearchMiddleware func(service SearchService) SearchService
+
+// Markers around the words matching a query in search snippets.
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// SearchTodosRequest searches the caller's todos.
+type SearchTodosRequest struct {
+	// Words todos must all contain, see ParseSearchQuery.
+	Query string `json:"q"`
+
+	// Maximum number of results to return. Defaults to DefaultListLimit and
+	// may not exceed MaxListLimit.
+	Limit int `json:"limit"`
+}
+
+// Normalize fills in defaults and validates the request.
+func (r *SearchTodosRequest) Normalize() error {
+	if r.Limit == 0 {
+		r.Limit = DefaultListLimit
+	} else if r.Limit < 0 || r.Limit > MaxListLimit {
+		return Errorf(EINVALID, "Limit must be between 1 and %d.", MaxListLimit)
+	}
+	return nil
+}
+
+// SearchTodosResponse lists the best matching todos first.
+type SearchTodosResponse struct {
+	Results []*SearchResult `json:"results"`
+
+	// Number of todos matching the query, including those beyond the limit.
+	TotalCount int `json:"totalCount"`
+}
+
+// SearchResult is a todo matching a search query.
+type SearchResult struct {
+	Todo *Todo `json:"todo"`
+
+	// Relevance of the todo, higher is better.
+	Score float64 `json:"score"`
+
+	// HTML excerpt of the value with the matching words highlighted.
+	Snippet string `json:"snippet"`
+}
+
+// Token is a word of a text along with its position in the text.
+type Token struct {
+	// Stem of the word, see Stem.
+	Term string
+
+	// Byte offsets of the word in the text.
+	Start, End int
+}
+
+// Tokenize splits text into words. Words are runs of ASCII letters, digits
+// and underscores, or any non-ASCII characters, so punctuation and spaces
+// separate words. Words are lowercased and stemmed. This is how the porter
+// tokenizer of SQLite FTS4 splits text, so every backend finds the same todos.
+func Tokenize(text string) []Token {
+	var tokens []Token
+	for i := 0; i < len(text); {
+		for i < len(text) && isDelimiter(text[i]) {
+			i++
+		}
+		start := i
+		for i < len(text) && !isDelimiter(text[i]) {
+			i++
+		}
+		if i > start {
+			tokens = append(tokens, Token{Term: Stem(lowerASCII(text[start:i])), Start: start, End: i})
+		}
+	}
+	return tokens
+}
+
+// isDelimiter returns true if c separates words. It must agree with isDelim of
+// the SQLite porter tokenizer.
+func isDelimiter(c byte) bool {
+	return c < 0x80 && !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_')
+}
+
+// lowerASCII lowercases the ASCII letters of s and leaves other characters as
+// they are.
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// SearchQuery is a parsed search query. Todos match if they match every term.
+type SearchQuery struct {
+	Terms []SearchTerm
+}
+
+// SearchTerm is a word, a prefix of a word or a phrase of consecutive words.
+type SearchTerm struct {
+	// Lowercase words of the term. More than one word makes a phrase.
+	Words []string
+
+	// Set if the term matches every word starting with its only word.
+	Prefix bool
+}
+
+// Stems returns the stems of the words of the term.
+func (t SearchTerm) Stems() []string {
+	stems := make([]string, len(t.Words))
+	for i, w := range t.Words {
+		stems[i] = Stem(w)
+	}
+	return stems
+}
+
+// ParseSearchQuery parses a query of words separated by spaces. Words are
+// matched regardless of case and inflection, so "meeting" also matches
+// "Meetings". A word ending with "*" matches any word starting with it, and
+// words in double quotes only match as a phrase. Returns EINVALID if the query
+// has no words or a phrase is not closed.
+func ParseSearchQuery(q string) (*SearchQuery, error) {
+	query := &SearchQuery{}
+	for i := 0; i < len(q); {
+		switch c := q[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			end := strings.IndexByte(q[i+1:], '"')
+			if end < 0 {
+				return nil, Errorf(EINVALID, "Unterminated phrase at offset %d.", i)
+			}
+			query.add(q[i+1:i+1+end], false)
+			i += end + 2
+		default:
+			end := strings.IndexAny(q[i:], " \t\n\r\"")
+			if end < 0 {
+				end = len(q) - i
+			}
+			chunk := q[i : i+end]
+			query.add(strings.TrimSuffix(chunk, "*"), strings.HasSuffix(chunk, "*"))
+			i += end
+		}
+	}
+
+	if len(query.Terms) == 0 {
+		return nil, Errorf(EINVALID, "Search query must contain at least one word.")
+	}
+	return query, nil
+}
+
+// add adds the words of text as a term. Text splitting into several words,
+// such as "e-mail", is a phrase and never a prefix.
+func (q *SearchQuery) add(text string, prefix bool) {
+	var words []string
+	for _, tok := range Tokenize(text) {
+		words = append(words, lowerASCII(text[tok.Start:tok.End]))
+	}
+	if len(words) == 0 {
+		return
+	}
+	q.Terms = append(q.Terms, SearchTerm{Words: words, Prefix: prefix && len(words) == 1})
+}
+
+// matchAt returns true if the stems occur in tokens starting at i.
+func matchAt(tokens []Token, i int, stems []string, prefix bool) bool {
+	if i+len(stems) > len(tokens) {
+		return false
+	}
+	for k, stem := range stems {
+		if term := tokens[i+k].Term; term != stem && !(prefix && strings.HasPrefix(term, stem)) {
+			return false
+		}
+	}
+	return true
+}
+
+// snippetLength is the maximum number of words in a snippet.
+const snippetLength = 16
+
+// Snippet returns an HTML excerpt of text around the first match of q, with
+// every matching word highlighted. Excerpts not starting or ending with text
+// are marked with an ellipsis.
+func (q *SearchQuery) Snippet(text string) string {
+	tokens := Tokenize(text)
+	highlight := make([]bool, len(tokens))
+	first := -1
+	for _, term := range q.Terms {
+		stems := term.Stems()
+		for i := range tokens {
+			if !matchAt(tokens, i, stems, term.Prefix) {
+				continue
+			}
+			for k := range stems {
+				highlight[i+k] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+
+	// Show a few words of context before the first match.
+	start := 0
+	if first > snippetLength/4 {
+		start = first - snippetLength/4
+	}
+	end := start + snippetLength
+	if end > len(tokens) {
+		end = len(tokens)
+	}
+
+	var b strings.Builder
+	offset := 0
+	if start > 0 {
+		b.WriteString("…")
+		offset = tokens[start].Start
+	}
+	for i := start; i < end; i++ {
+		b.WriteString(html.EscapeString(text[offset:tokens[i].Start]))
+		word := html.EscapeString(text[tokens[i].Start:tokens[i].End])
+		if highlight[i] {
+			word = HighlightStart + word + HighlightEnd
+		}
+		b.WriteString(word)
+		offset = tokens[i].End
+	}
+	if end < len(tokens) {
+		b.WriteString("…")
+	} else {
+		b.WriteString(html.EscapeString(text[offset:]))
+	}
+	return b.String()
+}
+
+// BM25 returns the relevance of a term occurring tf times in a document of the
+// given length, when df of n documents of an average length contain it.
+func BM25(tf, df, n int, length, avgLength float64) float64 {
+	const k1, b = 1.2, 0.75
+	if tf == 0 || avgLength == 0 {
+		return 0
+	}
+	idf := math.Log(1 + (float64(n)-float64(df)+0.5)/(float64(df)+0.5))
+	return idf * float64(tf) * (k1 + 1) / (float64(tf) + k1*(1-b+b*length/avgLength))
+}
+
+// SortSearchResults orders results by descending score, then by ID.
+func SortSearchResults(results []*SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Todo.ID < results[j].Todo.ID
+	})
+}
@@ -0,0 +1,83 @@
+package todo_test
+
+import (
+	"reflect"
+	"testing"
+	"todo"
+)
+
+func TestStem(t *testing.T) {
+	for word, want := range map[string]string{
+		"caresses":                           "caress",
+		"ponies":                             "poni",
+		"meetings":                           "meet",
+		"running":                            "run",
+		"hopping":                            "hop",
+		"relational":                         "relat",
+		"generalization":                     "gener",
+		"happy":                              "happi",
+		"by":                                 "by",
+		"abc123":                             "abc123",
+		"écrire":                             "écrire",
+		"supercalifragilisticexpialidocious": "supercalifalidocious",
+	} {
+		if got := todo.Stem(word); got != want {
+			t.Errorf("%q: got %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := todo.Tokenize("Call Bob's e-mail_box, re: meetings!")
+	var terms []string
+	for _, tok := range tokens {
+		terms = append(terms, tok.Term)
+	}
+	if want := []string{"call", "bob", "s", "e", "mail_box", "re", "meet"}; !reflect.DeepEqual(terms, want) {
+		t.Fatalf("terms=%q, want %q", terms, want)
+	} else if tok := tokens[6]; tok.Start != 27 || tok.End != 35 {
+		t.Fatalf("unexpected offsets: %d-%d", tok.Start, tok.End)
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	q, err := todo.ParseSearchQuery(`Meeting* "Quarterly REPORT" e-mail notes`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []todo.SearchTerm{
+		{Words: []string{"meeting"}, Prefix: true},
+		{Words: []string{"quarterly", "report"}},
+		{Words: []string{"e", "mail"}},
+		{Words: []string{"notes"}},
+	}
+	if !reflect.DeepEqual(q.Terms, want) {
+		t.Fatalf("terms=%#v, want %#v", q.Terms, want)
+	}
+
+	for _, s := range []string{"", " * ", `a "b`} {
+		if _, err := todo.ParseSearchQuery(s); todo.ErrorCode(err) != todo.EINVALID {
+			t.Errorf("%q: unexpected error: %#v", s, err)
+		}
+	}
+}
+
+func TestSearchQuery_Snippet(t *testing.T) {
+	q, err := todo.ParseSearchQuery(`"running shoes" sock*`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Snippet("Buy running shoes & socks, not running gear"), "Buy <mark>running</mark> <mark>shoes</mark> &amp; <mark>socks</mark>, not running gear"; got != want {
+		t.Fatalf("snippet=%q, want %q", got, want)
+	}
+}
+
+func TestBM25(t *testing.T) {
+	if todo.BM25(2, 1, 10, 5, 5) <= todo.BM25(1, 1, 10, 5, 5) {
+		t.Fatal("more occurrences must score higher")
+	} else if todo.BM25(1, 1, 10, 3, 5) <= todo.BM25(1, 1, 10, 8, 5) {
+		t.Fatal("shorter values must score higher")
+	} else if todo.BM25(1, 1, 10, 5, 5) <= todo.BM25(1, 5, 10, 5, 5) {
+		t.Fatal("rarer words must score higher")
+	}
+}
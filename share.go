@@ -0,0 +1,116 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// Roles a principal may have on a todo or list. Every role includes the permissions of
+// the roles below it.
+const (
+	// May read the todo.
+	RoleViewer = "viewer"
+
+	// May also change the value & state of the todo.
+	RoleEditor = "editor"
+
+	// May also delete the todo and manage who it is shared with.
+	RoleOwner = "owner"
+)
+
+// roleRanks orders roles by the permissions they grant.
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// ValidRole returns true if role is one of the known roles.
+func ValidRole(role string) bool {
+	return roleRanks[role] != 0
+}
+
+// RoleIncludes returns true if role grants at least the permissions of required.
+func RoleIncludes(role, required string) bool {
+	return ValidRole(role) && roleRanks[role] >= roleRanks[required]
+}
+
+type ShareService interface {
+	CreateShare(ctx context.Context, request CreateShareRequest) (*Share, error)
+	DeleteShare(ctx context.Context, request DeleteShareRequest) error
+	GetShare(ctx context.Context, request GetShareRequest) (*Share, error)
+	ListShares(ctx context.Context, request ListSharesRequest) ([]*Share, error)
+}
+
+// ShareMiddleware describes a service middleware for the ShareService.
+type ShareMiddleware func(service ShareService) ShareService
+
+// Share grants a principal a role on a todo owned by someone else, or on every
+// todo in a list owned by someone else.
+type Share struct {
+	TodoID int `json:"todoId"`
+
+	// Shared list, if the share covers the todos in a list instead of a
+	// single todo. TodoID is then zero.
+	ListID int `json:"listId,omitempty"`
+
+	// Owner of the shared todo or list.
+	OwnerID string `json:"ownerId"`
+
+	// Principal the todo or list is shared with & the role they were granted.
+	PrincipalID string `json:"principalId"`
+	Role        string `json:"role"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateShareRequest shares a todo, or a list, owned by the caller. Sharing a
+// todo or list with a principal again replaces their role. Smart lists cannot
+// be shared, as that would share every todo matching their query.
+type CreateShareRequest struct {
+	TodoID      int    `json:"todoId"`
+	ListID      int    `json:"listId"`
+	PrincipalID string `json:"principalId"`
+	Role        string `json:"role"`
+}
+
+// Validate returns EINVALID if the request is malformed. Exactly one of the
+// todo and the list must be set. The caller may not share a todo with
+// themselves.
+func (r *CreateShareRequest) Validate(ctx context.Context) error {
+	if (r.TodoID == 0) == (r.ListID == 0) {
+		return Errorf(EINVALID, "Either a todo or a list required.")
+	} else if r.PrincipalID == "" {
+		return Errorf(EINVALID, "Principal required.")
+	} else if r.PrincipalID == OwnerIDFromContext(ctx) {
+		return Errorf(EINVALID, "Todos cannot be shared with their owner.")
+	} else if !ValidRole(r.Role) {
+		return Errorf(EINVALID, "Invalid role '%s'.", r.Role)
+	}
+	return nil
+}
+
+// DeleteShareRequest revokes the share of a todo, or of a list if ListID is
+// set, owned by the caller.
+type DeleteShareRequest struct {
+	TodoID      int    `json:"todoId"`
+	ListID      int    `json:"listId"`
+	PrincipalID string `json:"principalId"`
+}
+
+// GetShareRequest looks up the share granting the caller access to a todo,
+// either directly or through the list the todo is in. If the caller has both,
+// the share with the higher role is returned. If ListID is set instead, the
+// share of that list is looked up.
+type GetShareRequest struct {
+	TodoID int `json:"todoId"`
+	ListID int `json:"listId"`
+}
+
+// ListSharesRequest lists who a todo, or a list if ListID is set, owned by the
+// caller is shared with. If both are zero, the todos and lists shared with the
+// caller are listed instead.
+type ListSharesRequest struct {
+	TodoID int `json:"todoId"`
+	ListID int `json:"listId"`
+}
@@ -0,0 +1,65 @@
+package sqlite
+
+import (
+	"context"
+	"todo"
+)
+
+// BatchTodos applies every operation of the batch in a single transaction. In
+// the best-effort mode each operation runs within a savepoint so a failed
+// operation is rolled back without undoing the others.
+func (s *TodoService) BatchTodos(ctx context.Context, request todo.BatchTodosRequest) (*todo.BatchTodosResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	resp := &todo.BatchTodosResponse{Results: make([]*todo.BatchResult, 0, len(request.Ops))}
+	for i, op := range request.Ops {
+		if request.Mode == todo.BatchAtomic {
+			t, err := s.applyOp(ctx, tx, op)
+			if err != nil {
+				return nil, todo.BatchOpError(i, err)
+			}
+			resp.Results = append(resp.Results, &todo.BatchResult{Todo: t})
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_op`); err != nil {
+			return nil, FormatError(err)
+		}
+		t, err := s.applyOp(ctx, tx, op)
+		if err != nil {
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO batch_op`); err != nil {
+				return nil, FormatError(err)
+			}
+			t = nil
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE batch_op`); err != nil {
+			return nil, FormatError(err)
+		}
+		resp.Results = append(resp.Results, &todo.BatchResult{Todo: t, Err: err})
+	}
+
+	return resp, tx.Commit()
+}
+
+// applyOp applies a single operation of a batch within tx and returns the todo
+// it created or changed, if any.
+func (s *TodoService) applyOp(ctx context.Context, tx *Tx, op todo.BatchOp) (*todo.Todo, error) {
+	switch op.Op {
+	case todo.BatchCreate:
+		return s.create(ctx, tx, *op.Create)
+	case todo.BatchUpdate:
+		return s.update(ctx, tx, *op.Update)
+	case todo.BatchPatch:
+		return s.patch(ctx, tx, *op.Patch)
+	default:
+		return nil, deleteTodo(ctx, tx, op.Delete.ID, op.Delete.Version, op.Delete.Cascade)
+	}
+}
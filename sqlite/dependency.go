@@ -0,0 +1,218 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.DependencyService = (*DependencyService)(nil)
+
+// DependencyService represents a service for managing which todos block
+// which other todos.
+type DependencyService struct {
+	db *DB
+}
+
+// NewDependencyService returns a new instance of DependencyService.
+func NewDependencyService(db *DB) *DependencyService {
+	return &DependencyService{db: db}
+}
+
+func (s *DependencyService) CreateDependency(ctx context.Context, request todo.CreateDependencyRequest) (*todo.Dependency, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := findTodoByID(ctx, tx, request.TodoID); err != nil {
+		return nil, err
+	} else if _, err := findTodoByID(ctx, tx, request.BlockedByID); todo.ErrorCode(err) == todo.ENOTFOUND {
+		return nil, todo.Errorf(todo.EINVALID, "Blocking todo with ID '%d' does not exist.", request.BlockedByID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if d, err := findDependency(ctx, tx, request.TodoID, request.BlockedByID); err == nil {
+		return d, nil
+	} else if todo.ErrorCode(err) != todo.ENOTFOUND {
+		return nil, err
+	}
+
+	if cycle, err := closesCycle(ctx, tx, request.TodoID, request.BlockedByID); err != nil {
+		return nil, err
+	} else if cycle {
+		return nil, todo.Errorf(todo.EINVALID, "Todo with ID '%d' cannot be blocked by todo with ID '%d' as it would create a cycle.", request.TodoID, request.BlockedByID)
+	}
+
+	d := &todo.Dependency{
+		TodoID:      request.TodoID,
+		BlockedByID: request.BlockedByID,
+		CreatedAt:   tx.now,
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO dependencies (todo_id, blocked_by_id, created_at)
+		VALUES (?, ?, ?)
+	`, d.TodoID, d.BlockedByID, d.CreatedAt.Format(time.RFC3339)); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return d, tx.Commit()
+}
+
+func (s *DependencyService) DeleteDependency(ctx context.Context, request todo.DeleteDependencyRequest) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := findTodoByID(ctx, tx, request.TodoID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM dependencies
+		WHERE todo_id = ? AND blocked_by_id = ?
+	`, request.TodoID, request.BlockedByID)
+	if err != nil {
+		return FormatError(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' is not blocked by todo with ID '%d'.", request.TodoID, request.BlockedByID)
+	}
+
+	return tx.Commit()
+}
+
+func (s *DependencyService) ListDependencies(ctx context.Context, request todo.ListDependenciesRequest) ([]*todo.Dependency, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if request.TodoID != 0 {
+		if _, err := findTodoByID(ctx, tx, request.TodoID); err != nil {
+			return nil, err
+		}
+		return findDependencies(ctx, tx, `dependencies.todo_id = ? OR dependencies.blocked_by_id = ?`, request.TodoID, request.TodoID)
+	}
+	return findDependencies(ctx, tx, `todos.owner_id = ?`, todo.OwnerIDFromContext(ctx))
+}
+
+func (s *DependencyService) ListActionableTodos(ctx context.Context, request todo.ListActionableTodosRequest) ([]*todo.Todo, error) {
+	if err := request.Normalize(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if request.ListID != 0 {
+		if _, err := findListByID(ctx, tx, request.ListID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Every open todo of the caller is read as blockers may be in any list.
+	ownerID := todo.OwnerIDFromContext(ctx)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+todoColumns+`
+		FROM todos
+		WHERE owner_id = ? AND complete = 0
+	`, ownerID)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	todos := make([]*todo.Todo, 0)
+	for rows.Next() {
+		t, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	deps, err := findDependencies(ctx, tx, `todos.owner_id = ?`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return todo.ActionableTodos(todos, deps, request), nil
+}
+
+// closesCycle returns true if blocking the todo with ID todoID by the todo
+// with ID blockedByID would make a todo transitively block itself.
+func closesCycle(ctx context.Context, tx *Tx, todoID, blockedByID int) (bool, error) {
+	// Walk the todos the blocking todo waits on. Reaching the blocked todo
+	// means the new dependency would close a cycle.
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		WITH RECURSIVE blockers (id) AS (
+			SELECT ?
+			UNION
+			SELECT dependencies.blocked_by_id FROM dependencies JOIN blockers ON dependencies.todo_id = blockers.id
+		)
+		SELECT COUNT(*) FROM blockers WHERE id = ?
+	`, blockedByID, todoID).Scan(&n); err != nil {
+		return false, FormatError(err)
+	}
+	return n > 0, nil
+}
+
+// findDependency returns the dependency between two todos.
+// Returns ENOTFOUND if there is none.
+func findDependency(ctx context.Context, tx *Tx, todoID, blockedByID int) (*todo.Dependency, error) {
+	deps, err := findDependencies(ctx, tx, `dependencies.todo_id = ? AND dependencies.blocked_by_id = ?`, todoID, blockedByID)
+	if err != nil {
+		return nil, err
+	} else if len(deps) == 0 {
+		return nil, todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' is not blocked by todo with ID '%d'.", todoID, blockedByID)
+	}
+	return deps[0], nil
+}
+
+// findDependencies returns the dependencies matching the where clause, which
+// may refer to the blocked todo as todos.
+func findDependencies(ctx context.Context, tx *Tx, where string, args ...interface{}) ([]*todo.Dependency, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT dependencies.todo_id, dependencies.blocked_by_id, dependencies.created_at
+		FROM dependencies
+		JOIN todos ON todos.id = dependencies.todo_id
+		WHERE `+where+`
+		ORDER BY dependencies.todo_id, dependencies.blocked_by_id
+	`, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	deps := make([]*todo.Dependency, 0)
+	for rows.Next() {
+		var createdAt string
+		d := &todo.Dependency{}
+		if err := rows.Scan(&d.TodoID, &d.BlockedByID, &createdAt); err != nil {
+			return nil, err
+		}
+		if d.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	return deps, rows.Err()
+}
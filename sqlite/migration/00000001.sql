@@ -0,0 +1,5 @@
+CREATE TABLE todos (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	value    TEXT NOT NULL,
+	complete INTEGER NOT NULL DEFAULT 0
+);
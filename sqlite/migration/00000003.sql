@@ -0,0 +1,3 @@
+ALTER TABLE todos ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX todos_owner_id_idx ON todos (owner_id, id);
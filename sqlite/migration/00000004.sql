@@ -0,0 +1,10 @@
+CREATE TABLE shares (
+	todo_id      INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	principal_id TEXT NOT NULL,
+	role         TEXT NOT NULL,
+	created_at   TEXT NOT NULL,
+
+	PRIMARY KEY (todo_id, principal_id)
+);
+
+CREATE INDEX shares_principal_id_idx ON shares (principal_id);
@@ -0,0 +1,12 @@
+CREATE TABLE lists (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	owner_id   TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+
+CREATE INDEX lists_owner_id_idx ON lists (owner_id, id);
+
+ALTER TABLE todos ADD COLUMN list_id INTEGER REFERENCES lists (id);
+
+CREATE INDEX todos_list_id_idx ON todos (list_id);
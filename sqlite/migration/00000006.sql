@@ -0,0 +1,9 @@
+ALTER TABLE todos ADD COLUMN due_at TEXT;
+ALTER TABLE todos ADD COLUMN remind_at TEXT;
+ALTER TABLE todos ADD COLUMN time_zone TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos ADD COLUMN reminded_at TEXT;
+
+CREATE INDEX todos_due_at_idx ON todos (owner_id, due_at);
+
+-- Only reminders that have not been sent yet are looked up by the scheduler.
+CREATE INDEX todos_remind_at_idx ON todos (remind_at) WHERE reminded_at IS NULL;
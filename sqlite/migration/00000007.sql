@@ -0,0 +1,5 @@
+ALTER TABLE todos ADD COLUMN recurrence TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos ADD COLUMN series_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE todos ADD COLUMN occurrence INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX todos_series_id_idx ON todos (owner_id, series_id);
@@ -0,0 +1,3 @@
+ALTER TABLE todos ADD COLUMN parent_id INTEGER REFERENCES todos (id);
+
+CREATE INDEX todos_parent_id_idx ON todos (parent_id);
@@ -0,0 +1,9 @@
+CREATE TABLE dependencies (
+	todo_id       INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	blocked_by_id INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	created_at    TEXT NOT NULL,
+
+	PRIMARY KEY (todo_id, blocked_by_id)
+);
+
+CREATE INDEX dependencies_blocked_by_id_idx ON dependencies (blocked_by_id);
@@ -0,0 +1,8 @@
+CREATE TABLE todo_tags (
+	todo_id INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	tag     TEXT NOT NULL,
+
+	PRIMARY KEY (todo_id, tag)
+);
+
+CREATE INDEX todo_tags_tag_idx ON todo_tags (tag, todo_id);
@@ -0,0 +1,18 @@
+ALTER TABLE todos ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE todos ADD COLUMN position TEXT NOT NULL DEFAULT '';
+
+-- Place existing todos in the order they were created. Their positions are
+-- integers with five base 62 digits, see todo.PositionBetween.
+UPDATE todos SET position = (
+	SELECT 'e' ||
+		substr(digits, n / 14776336 % 62 + 1, 1) ||
+		substr(digits, n / 238328 % 62 + 1, 1) ||
+		substr(digits, n / 3844 % 62 + 1, 1) ||
+		substr(digits, n / 62 % 62 + 1, 1) ||
+		substr(digits, n % 62 + 1, 1)
+	FROM (SELECT id, row_number() OVER (PARTITION BY owner_id ORDER BY id) AS n FROM todos) AS ranked,
+		(SELECT '0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz' AS digits)
+	WHERE ranked.id = todos.id
+);
+
+CREATE INDEX todos_owner_id_position_idx ON todos (owner_id, position);
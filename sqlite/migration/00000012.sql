@@ -0,0 +1,20 @@
+ALTER TABLE todos ADD COLUMN state TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos ADD COLUMN state_changed_at TEXT;
+
+-- Existing todos get the state of the default workflow matching Complete.
+UPDATE todos SET state = CASE WHEN complete THEN 'done' ELSE 'backlog' END;
+
+CREATE INDEX todos_list_id_state_idx ON todos (list_id, state);
+
+CREATE TABLE transitions (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	todo_id    INTEGER NOT NULL REFERENCES todos (id) ON DELETE CASCADE,
+	from_state TEXT NOT NULL,
+	to_state   TEXT NOT NULL,
+	at         TEXT NOT NULL
+);
+
+CREATE INDEX transitions_todo_id_idx ON transitions (todo_id, id);
+
+-- WIP limits of a list by state, encoded as a JSON object.
+ALTER TABLE lists ADD COLUMN wip_limits TEXT NOT NULL DEFAULT '{}';
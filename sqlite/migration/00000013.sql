@@ -0,0 +1,18 @@
+-- Full-text index of the values of todos. The porter tokenizer stems words
+-- the same way as todo.Stem.
+CREATE VIRTUAL TABLE todos_fts USING fts4 (value, tokenize=porter);
+
+INSERT INTO todos_fts (docid, value) SELECT id, value FROM todos;
+
+-- Triggers keep the index in line with every change to todos.
+CREATE TRIGGER todos_fts_insert AFTER INSERT ON todos BEGIN
+	INSERT INTO todos_fts (docid, value) VALUES (new.id, new.value);
+END;
+
+CREATE TRIGGER todos_fts_update AFTER UPDATE OF value ON todos BEGIN
+	UPDATE todos_fts SET value = new.value WHERE docid = new.id;
+END;
+
+CREATE TRIGGER todos_fts_delete AFTER DELETE ON todos BEGIN
+	DELETE FROM todos_fts WHERE docid = old.id;
+END;
@@ -0,0 +1,2 @@
+-- Query of a smart list, empty for a regular list.
+ALTER TABLE lists ADD COLUMN query TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+-- Revisions are kept when their todo is deleted, so they do not reference it.
+-- Changes & the todo are encoded as JSON.
+CREATE TABLE revisions (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	todo_id    INTEGER NOT NULL,
+	author_id  TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	changes    TEXT NOT NULL,
+	todo       TEXT NOT NULL
+);
+
+CREATE INDEX revisions_todo_id_idx ON revisions (todo_id, id);
+CREATE INDEX revisions_created_at_idx ON revisions (created_at);
@@ -0,0 +1,18 @@
+-- Deleted todos until they are restored or purged. The todo is stored along
+-- with its shares, dependencies & transitions, all encoded as JSON. Rows are
+-- inserted parents first, so subtasks follow their parent in id order.
+CREATE TABLE trash (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	todo_id      INTEGER NOT NULL UNIQUE,
+	owner_id     TEXT NOT NULL,
+	deleted_with INTEGER NOT NULL,
+	deleted_at   TEXT NOT NULL,
+	todo         TEXT NOT NULL,
+	shares       TEXT NOT NULL,
+	dependencies TEXT NOT NULL,
+	transitions  TEXT NOT NULL
+);
+
+CREATE INDEX trash_owner_id_idx ON trash (owner_id, deleted_at);
+CREATE INDEX trash_deleted_at_idx ON trash (deleted_at);
+CREATE INDEX trash_deleted_with_idx ON trash (deleted_with);
@@ -0,0 +1,7 @@
+-- When a todo was archived, NULL for todos that are not archived.
+ALTER TABLE todos ADD COLUMN archived_at TEXT;
+
+CREATE INDEX todos_list_id_archived_at_idx ON todos (list_id, archived_at);
+
+-- Retention policies of a list, encoded as a JSON array.
+ALTER TABLE lists ADD COLUMN policies TEXT NOT NULL DEFAULT '[]';
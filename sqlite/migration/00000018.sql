@@ -0,0 +1,11 @@
+-- Shares granting a role on every todo in a list.
+CREATE TABLE list_shares (
+	list_id      INTEGER NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+	principal_id TEXT NOT NULL,
+	role         TEXT NOT NULL,
+	created_at   TEXT NOT NULL,
+
+	PRIMARY KEY (list_id, principal_id)
+);
+
+CREATE INDEX list_shares_principal_id_idx ON list_shares (principal_id);
@@ -0,0 +1,5 @@
+-- An earlier version of migration 12 left the state of existing todos empty.
+-- Give them the state of the default workflow matching Complete like the
+-- current version does; DB.Open moves them into the configured workflow.
+UPDATE todos SET state = CASE WHEN complete THEN 'done' ELSE 'backlog' END
+WHERE state = '';
@@ -0,0 +1,182 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.PolicyService = (*PolicyService)(nil)
+var _ todo.PolicyEnforcer = (*PolicyService)(nil)
+
+// PolicyService represents a service for applying the retention policies of
+// lists.
+type PolicyService struct {
+	db *DB
+}
+
+// NewPolicyService returns a new instance of PolicyService.
+func NewPolicyService(db *DB) *PolicyService {
+	return &PolicyService{db: db}
+}
+
+func (s *PolicyService) ReportPolicies(ctx context.Context, request todo.ReportPoliciesRequest) (*todo.PolicyReport, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	where, args := "owner_id = ?", []interface{}{todo.OwnerIDFromContext(ctx)}
+	if request.ListID != 0 {
+		if _, err := findListByID(ctx, tx, request.ListID); err != nil {
+			return nil, err
+		}
+		where, args = where+" AND id = ?", append(args, request.ListID)
+	}
+
+	archive, purge, err := findPolicyTodos(ctx, tx, tx.now, -1, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &todo.PolicyReport{Archived: archive, Purged: purge}, nil
+}
+
+// EnforcePolicies archives and purges the todos the policies of their list
+// apply to in a single transaction.
+func (s *PolicyService) EnforcePolicies(ctx context.Context, now time.Time, limit int) (*todo.PolicyReport, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	archive, purge, err := findPolicyTodos(ctx, tx, now, limit, "1 = 1")
+	if err != nil {
+		return nil, err
+	}
+
+	// Purged todos are trashed on behalf of their owner.
+	for _, t := range purge {
+		if err := deleteTodo(todo.NewContextWithOwnerID(ctx, t.OwnerID), tx, t.ID, 0, false); err != nil {
+			return nil, err
+		}
+	}
+
+	archivedAt := now.UTC().Truncate(time.Second)
+	for _, t := range archive {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE todos
+			SET archived_at = ?, version = version + 1
+			WHERE id = ?
+		`, formatTime(&archivedAt), t.ID); err != nil {
+			return nil, FormatError(err)
+		}
+		t.ArchivedAt = &archivedAt
+		t.Version++
+	}
+
+	return &todo.PolicyReport{Archived: archive, Purged: purge}, tx.Commit()
+}
+
+// policyConditions maps policy actions to the condition todos must meet for the
+// action to apply. The condition takes the cutoff of the policy as argument.
+var policyConditions = map[string]string{
+	todo.PolicyPurge:   "archived_at IS NOT NULL AND archived_at <= ?",
+	todo.PolicyArchive: "archived_at IS NULL AND complete = 1 AND COALESCE(state_changed_at, '') <= ?",
+}
+
+// findPolicyTodos returns the todos to archive and to purge at now under the
+// policies of the lists matching where, ordered by ID. Todos to purge are
+// found first, and no more than limit todos are returned in total unless limit
+// is negative.
+func findPolicyTodos(ctx context.Context, tx *Tx, now time.Time, limit int, where string, args ...interface{}) (archive, purge []*todo.Todo, err error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+listColumns+`
+		FROM lists
+		WHERE policies != '[]' AND `+where+`
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	var lists []*todo.List
+	for rows.Next() {
+		l, err := scanList(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		lists = append(lists, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	archive, purge = make([]*todo.Todo, 0), make([]*todo.Todo, 0)
+	for _, action := range []string{todo.PolicyPurge, todo.PolicyArchive} {
+		n := limit - len(archive) - len(purge)
+		if limit < 0 {
+			n = -1
+		} else if n == 0 {
+			break
+		}
+
+		var conds []string
+		var condArgs []interface{}
+		for _, l := range lists {
+			for _, p := range l.Policies {
+				if p.Action == action {
+					cutoff := p.Cutoff(now)
+					conds = append(conds, "(list_id = ? AND "+policyConditions[action]+")")
+					condArgs = append(condArgs, l.ID, formatTime(&cutoff))
+				}
+			}
+		}
+		if len(conds) == 0 {
+			continue
+		}
+
+		todos, err := findTodosWhere(ctx, tx, strings.Join(conds, " OR "), n, condArgs...)
+		if err != nil {
+			return nil, nil, err
+		} else if action == todo.PolicyPurge {
+			purge = todos
+		} else {
+			archive = todos
+		}
+	}
+	return archive, purge, nil
+}
+
+// findTodosWhere returns up to limit todos of all owners matching where,
+// ordered by ID. A negative limit returns every todo.
+func findTodosWhere(ctx context.Context, tx *Tx, where string, limit int, args ...interface{}) ([]*todo.Todo, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+todoColumns+`
+		FROM todos
+		WHERE `+where+`
+		ORDER BY id
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	todos := make([]*todo.Todo, 0)
+	for rows.Next() {
+		t, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"todo"
+)
+
+func (s *TodoService) MoveTodo(ctx context.Context, request todo.MoveTodoRequest) (*todo.Todo, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := findTodoByID(ctx, tx, request.ID)
+	if err != nil {
+		return nil, err
+	} else if err := t.CheckVersion(request.Version); err != nil {
+		return nil, err
+	}
+
+	after, err := findAnchor(ctx, tx, request.AfterID)
+	if err != nil {
+		return nil, err
+	}
+	before, err := findAnchor(ctx, tx, request.BeforeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// With a single anchor, the todo goes between the anchor and its
+	// neighbour on the other side.
+	if before == nil {
+		before, err = findNeighbour(ctx, tx, after, t.ID, false)
+	} else if after == nil {
+		after, err = findNeighbour(ctx, tx, before, t.ID, true)
+	}
+	if err != nil {
+		return nil, err
+	} else if err := t.PlaceBetween(after, before); err != nil {
+		return nil, err
+	} else if err := updateTodo(ctx, tx, t); err != nil {
+		return nil, err
+	}
+
+	return t, tx.Commit()
+}
+
+// findAnchor returns the caller's todo a todo is moved next to, or nil if id
+// is zero. Returns EINVALID if the todo does not exist.
+func findAnchor(ctx context.Context, tx *Tx, id int) (*todo.Todo, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	t, err := findTodoByID(ctx, tx, id)
+	if todo.ErrorCode(err) == todo.ENOTFOUND {
+		return nil, todo.Errorf(todo.EINVALID, "Todo with ID '%d' does not exist.", id)
+	}
+	return t, err
+}
+
+// findNeighbour returns the todo of the same owner ordered right after t, or
+// right before it if previous is set. The todo with ID skip is ignored.
+// Returns nil if there is none.
+func findNeighbour(ctx context.Context, tx *Tx, t *todo.Todo, skip int, previous bool) (*todo.Todo, error) {
+	op, dir := ">", "ASC"
+	if previous {
+		op, dir = "<", "DESC"
+	}
+
+	other, err := scanTodo(tx.QueryRowContext(ctx, `
+		SELECT `+todoColumns+`
+		FROM todos
+		WHERE owner_id = ? AND position `+op+` ? AND id != ?
+		ORDER BY position `+dir+`
+		LIMIT 1
+	`, t.OwnerID, t.Position, skip))
+	if err = FormatError(err); todo.ErrorCode(err) == todo.ENOTFOUND {
+		return nil, nil
+	}
+	return other, err
+}
+
+// placeLast positions t after all other todos of its owner.
+func placeLast(ctx context.Context, tx *Tx, t *todo.Todo) error {
+	var last string
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(position), '')
+		FROM todos
+		WHERE owner_id = ?
+	`, t.OwnerID).Scan(&last); err != nil {
+		return FormatError(err)
+	}
+
+	p, err := todo.PositionBetween(last, "")
+	if err != nil {
+		return err
+	}
+	t.Position = p
+	return nil
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+	"todo"
+)
+
+// compileExpr translates the condition of a query into an SQL condition on
+// todos at time now, along with its arguments. Conditions never evaluate to
+// NULL so negating them matches the todos they do not match, like in memory.
+func compileExpr(expr todo.Expr, now time.Time) (string, []interface{}, error) {
+	switch expr := expr.(type) {
+	case *todo.BinaryExpr:
+		lhs, lhsArgs, err := compileExpr(expr.LHS, now)
+		if err != nil {
+			return "", nil, err
+		}
+		rhs, rhsArgs, err := compileExpr(expr.RHS, now)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + lhs + " " + expr.Op + " " + rhs + ")", append(lhsArgs, rhsArgs...), nil
+	case *todo.NotExpr:
+		cond, args, err := compileExpr(expr.Expr, now)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(NOT " + cond + ")", args, nil
+	case *todo.Condition:
+		return compileCondition(expr, now)
+	}
+	return "", nil, fmt.Errorf("sqlite: unsupported expression %T", expr)
+}
+
+// compileCondition translates a single condition, see compileExpr.
+func compileCondition(c *todo.Condition, now time.Time) (string, []interface{}, error) {
+	// Operators are the same in SQL, and only OpEq & OpNe are allowed for
+	// fields other than priorities & dates.
+	op := c.Op
+
+	switch c.Field {
+	case todo.FieldTag:
+		in := "IN"
+		if op == todo.OpNe {
+			in = "NOT IN"
+		}
+		return "id " + in + " (SELECT todo_id FROM todo_tags WHERE tag = ?)", []interface{}{c.Value}, nil
+	case todo.FieldState:
+		return "state " + op + " ?", []interface{}{c.Value}, nil
+	case todo.FieldText:
+		// Like lowerASCII, lower() only lowercases ASCII letters.
+		return "(instr(lower(value), ?) > 0) " + op + " 1", []interface{}{c.Value}, nil
+	case todo.FieldList:
+		return "COALESCE(list_id, 0) " + op + " ?", []interface{}{c.Value}, nil
+	case todo.FieldParent:
+		return "COALESCE(parent_id, 0) " + op + " ?", []interface{}{c.Value}, nil
+	case todo.FieldPriority:
+		return "priority " + op + " ?", []interface{}{c.Value}, nil
+	case todo.FieldComplete:
+		return "complete " + op + " ?", []interface{}{c.Value}, nil
+	case todo.FieldBlocked:
+		return "(" + blockedColumn + ") " + op + " ?", []interface{}{c.Value}, nil
+	case todo.FieldOverdue:
+		return "(complete = 0 AND due_at IS NOT NULL AND due_at < ?) " + op + " ?", []interface{}{formatTime(&now), c.Value}, nil
+	case todo.FieldDue:
+		if v := c.Time(now); v != nil {
+			return "(due_at IS NOT NULL AND due_at " + op + " ?)", []interface{}{formatTime(v)}, nil
+		} else if op == todo.OpEq {
+			return "due_at IS NULL", nil, nil
+		}
+		return "due_at IS NOT NULL", nil, nil
+	}
+	return "", nil, fmt.Errorf("sqlite: unsupported field %q", c.Field)
+}
@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"todo"
+)
+
+// Ensure store implements interface.
+var _ todo.RevisionStore = (*RevisionStore)(nil)
+
+// RevisionStore represents a store for the revisions of todos.
+type RevisionStore struct {
+	db *DB
+}
+
+// NewRevisionStore returns a new instance of RevisionStore.
+func NewRevisionStore(db *DB) *RevisionStore {
+	return &RevisionStore{db: db}
+}
+
+func (s *RevisionStore) CreateRevision(ctx context.Context, r *todo.Revision) error {
+	changes, err := json.Marshal(r.Changes)
+	if err != nil {
+		return err
+	}
+	t, err := json.Marshal(r.Todo)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var last int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(seq), 0)
+		FROM sqlite_sequence
+		WHERE name = 'revisions'
+	`).Scan(&last); err != nil {
+		return FormatError(err)
+	}
+	r.ID = todo.NextID(last, tx.db.Now())
+	r.CreatedAt = tx.now
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO revisions (id, todo_id, author_id, created_at, changes, todo)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, r.ID, r.TodoID, r.AuthorID, formatTime(&r.CreatedAt), string(changes), string(t)); err != nil {
+		return FormatError(err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *RevisionStore) FindRevisions(ctx context.Context, todoID int) ([]*todo.Revision, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, todo_id, author_id, created_at, changes, todo
+		FROM revisions
+		WHERE todo_id = ?
+		ORDER BY id
+	`, todoID)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	revs := make([]*todo.Revision, 0)
+	for rows.Next() {
+		var createdAt, changes, t string
+		r := &todo.Revision{}
+		if err := rows.Scan(&r.ID, &r.TodoID, &r.AuthorID, &createdAt, &changes, &t); err != nil {
+			return nil, err
+		} else if r.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal([]byte(changes), &r.Changes); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal([]byte(t), &r.Todo); err != nil {
+			return nil, err
+		}
+		revs = append(revs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revs, nil
+}
+
+func (s *RevisionStore) PruneRevisions(ctx context.Context, todoID int, policy todo.RetentionPolicy) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if policy.MaxRevisions > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM revisions
+			WHERE todo_id = ? AND id NOT IN (
+				SELECT id FROM revisions WHERE todo_id = ? ORDER BY id DESC LIMIT ?
+			)
+		`, todoID, todoID, policy.MaxRevisions); err != nil {
+			return FormatError(err)
+		}
+	}
+	if policy.MaxAge > 0 {
+		cutoff := tx.now.Add(-policy.MaxAge)
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM revisions WHERE todo_id = ? AND created_at < ?
+		`, todoID, formatTime(&cutoff)); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *RevisionStore) ExpireRevisions(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM revisions
+		WHERE id IN (SELECT id FROM revisions WHERE created_at < ? ORDER BY id LIMIT ?)
+	`, formatTime(&createdBefore), limit)
+	if err != nil {
+		return 0, FormatError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), tx.Commit()
+}
@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.ReminderService = (*ReminderService)(nil)
+
+// ReminderService represents a service for finding due reminders.
+type ReminderService struct {
+	db *DB
+}
+
+// NewReminderService returns a new instance of ReminderService.
+func NewReminderService(db *DB) *ReminderService {
+	return &ReminderService{db: db}
+}
+
+// ClaimDueReminders marks due reminders as sent and returns them, oldest first.
+// Each reminder is only claimed if it has not been claimed since it was read,
+// so concurrent schedulers never claim the same reminder twice.
+func (s *ReminderService) ClaimDueReminders(ctx context.Context, now time.Time, limit int) ([]*todo.Todo, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	remindedAt := now.UTC().Truncate(time.Second)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+todoColumns+`
+		FROM todos
+		WHERE reminded_at IS NULL AND remind_at <= ? AND complete = 0 AND archived_at IS NULL
+		ORDER BY remind_at, id
+		LIMIT ?
+	`, formatTime(&remindedAt), limit)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	var due []*todo.Todo
+	for rows.Next() {
+		t, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The claim changes the representation of the todo, so its version is
+	// bumped and stale updates cannot clear the claim.
+	claimed := make([]*todo.Todo, 0, len(due))
+	for _, t := range due {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE todos
+			SET reminded_at = ?, version = version + 1
+			WHERE id = ? AND reminded_at IS NULL
+		`, formatTime(&remindedAt), t.ID)
+		if err != nil {
+			return nil, FormatError(err)
+		} else if n, err := result.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 0 {
+			continue
+		}
+		t.RemindedAt = &remindedAt
+		t.Version++
+		claimed = append(claimed, t)
+	}
+
+	return claimed, tx.Commit()
+}
@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.SearchService = (*SearchService)(nil)
+
+// SearchService represents a service for searching todos.
+type SearchService struct {
+	db *DB
+}
+
+// NewSearchService returns a new instance of SearchService.
+func NewSearchService(db *DB) *SearchService {
+	return &SearchService{db: db}
+}
+
+func (s *SearchService) SearchTodos(ctx context.Context, request todo.SearchTodosRequest) (*todo.SearchTodosResponse, error) {
+	if err := request.Normalize(); err != nil {
+		return nil, err
+	}
+	query, err := todo.ParseSearchQuery(request.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// FTS cannot rank matches by itself, so every match is scored from its
+	// match statistics before the best ones are read.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT todos.id, matches.info
+		FROM todos
+		JOIN (
+			SELECT docid, matchinfo(todos_fts, 'pcnalx') AS info
+			FROM todos_fts
+			WHERE todos_fts MATCH ?
+		) AS matches ON matches.docid = todos.id
+		WHERE todos.owner_id = ?
+	`, matchExpr(query), todo.OwnerIDFromContext(ctx))
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	resp := &todo.SearchTodosResponse{Results: make([]*todo.SearchResult, 0)}
+	for rows.Next() {
+		var id int
+		var info []byte
+		if err := rows.Scan(&id, &info); err != nil {
+			return nil, err
+		}
+		score, err := scoreMatch(info)
+		if err != nil {
+			return nil, err
+		}
+		resp.Results = append(resp.Results, &todo.SearchResult{Todo: &todo.Todo{ID: id}, Score: score})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	resp.TotalCount = len(resp.Results)
+	todo.SortSearchResults(resp.Results)
+	if len(resp.Results) > request.Limit {
+		resp.Results = resp.Results[:request.Limit]
+	}
+	for _, r := range resp.Results {
+		if r.Todo, err = findTodoByID(ctx, tx, r.Todo.ID); err != nil {
+			return nil, err
+		}
+		r.Snippet = query.Snippet(r.Todo.Value)
+	}
+	return resp, nil
+}
+
+// matchExpr returns the FTS expression matching query. Words only consist of
+// characters the tokenizer keeps and are lowercase, so they are never taken
+// for operators.
+func matchExpr(query *todo.SearchQuery) string {
+	terms := make([]string, len(query.Terms))
+	for i, term := range query.Terms {
+		switch {
+		case len(term.Words) > 1:
+			terms[i] = `"` + strings.Join(term.Words, " ") + `"`
+		case term.Prefix:
+			terms[i] = term.Words[0] + "*"
+		default:
+			terms[i] = term.Words[0]
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// scoreMatch returns the BM25 score of a match from its matchinfo 'pcnalx'
+// statistics: the number of phrases & columns, the number of rows, the
+// average & actual length of the value, and for each phrase the hits in this
+// row, in all rows and the number of rows with hits.
+func scoreMatch(info []byte) (float64, error) {
+	// The statistics are 32-bit integers in the byte order of the machine,
+	// which is little endian on every platform the server is built for.
+	if len(info)%4 != 0 || len(info) < 20 {
+		return 0, fmt.Errorf("sqlite: invalid matchinfo of %d bytes", len(info))
+	}
+	v := make([]int, len(info)/4)
+	for i := range v {
+		v[i] = int(binary.LittleEndian.Uint32(info[4*i:]))
+	}
+	phrases, n, avg, length := v[0], v[2], v[3], v[4]
+	if len(v) != 5+3*phrases {
+		return 0, fmt.Errorf("sqlite: invalid matchinfo of %d values", len(v))
+	}
+
+	var score float64
+	for i := 0; i < phrases; i++ {
+		score += todo.BM25(v[5+3*i], v[7+3*i], n, float64(length), float64(avg))
+	}
+	return score, nil
+}
@@ -0,0 +1,237 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.ShareService = (*ShareService)(nil)
+
+// ShareService represents a service for managing who todos are shared with.
+type ShareService struct {
+	db *DB
+}
+
+// NewShareService returns a new instance of ShareService.
+func NewShareService(db *DB) *ShareService {
+	return &ShareService{db: db}
+}
+
+func (s *ShareService) CreateShare(ctx context.Context, request todo.CreateShareRequest) (*todo.Share, error) {
+	if err := request.Validate(ctx); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sh := &todo.Share{
+		TodoID:      request.TodoID,
+		ListID:      request.ListID,
+		PrincipalID: request.PrincipalID,
+		Role:        request.Role,
+		CreatedAt:   tx.now,
+	}
+	if request.ListID != 0 {
+		l, err := findListByID(ctx, tx, request.ListID)
+		if err != nil {
+			return nil, err
+		} else if l.Query != "" {
+			return nil, todo.Errorf(todo.EINVALID, "List with ID '%d' is a smart list and cannot be shared.", l.ID)
+		}
+		sh.OwnerID = l.OwnerID
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR REPLACE INTO list_shares (list_id, principal_id, role, created_at)
+			VALUES (?, ?, ?, ?)
+		`, sh.ListID, sh.PrincipalID, sh.Role, sh.CreatedAt.Format(time.RFC3339)); err != nil {
+			return nil, FormatError(err)
+		}
+		return sh, tx.Commit()
+	}
+
+	t, err := findTodoByID(ctx, tx, request.TodoID)
+	if err != nil {
+		return nil, err
+	}
+	sh.OwnerID = t.OwnerID
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO shares (todo_id, principal_id, role, created_at)
+		VALUES (?, ?, ?, ?)
+	`, sh.TodoID, sh.PrincipalID, sh.Role, sh.CreatedAt.Format(time.RFC3339)); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return sh, tx.Commit()
+}
+
+func (s *ShareService) DeleteShare(ctx context.Context, request todo.DeleteShareRequest) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query, args := `DELETE FROM shares WHERE todo_id = ? AND principal_id = ?`, []interface{}{request.TodoID, request.PrincipalID}
+	if request.ListID != 0 {
+		if _, err := findListByID(ctx, tx, request.ListID); err != nil {
+			return err
+		}
+		query, args = `DELETE FROM list_shares WHERE list_id = ? AND principal_id = ?`, []interface{}{request.ListID, request.PrincipalID}
+	} else if _, err := findTodoByID(ctx, tx, request.TodoID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return FormatError(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 && request.ListID != 0 {
+		return todo.Errorf(todo.ENOTFOUND, "List with ID '%d' is not shared with '%s'.", request.ListID, request.PrincipalID)
+	} else if n == 0 {
+		return todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' is not shared with '%s'.", request.TodoID, request.PrincipalID)
+	}
+
+	return tx.Commit()
+}
+
+func (s *ShareService) GetShare(ctx context.Context, request todo.GetShareRequest) (*todo.Share, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	principalID := todo.PrincipalIDFromContext(ctx)
+	if request.ListID != 0 {
+		sh, err := scanShare(tx.QueryRowContext(ctx, `
+			SELECT `+listShareColumns+`
+			FROM list_shares
+			JOIN lists ON lists.id = list_shares.list_id
+			WHERE list_shares.list_id = ? AND list_shares.principal_id = ?
+		`, request.ListID, principalID))
+		if err != nil {
+			if err = FormatError(err); todo.ErrorCode(err) == todo.ENOTFOUND {
+				return nil, todo.Errorf(todo.ENOTFOUND, "List with ID '%d' could not be found.", request.ListID)
+			}
+			return nil, err
+		}
+		return sh, nil
+	}
+
+	// The todo may be shared directly and through its list.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+shareColumns+`
+		FROM shares
+		JOIN todos ON todos.id = shares.todo_id
+		WHERE shares.todo_id = ? AND shares.principal_id = ?
+		UNION ALL
+		SELECT `+listShareColumns+`
+		FROM list_shares
+		JOIN lists ON lists.id = list_shares.list_id
+		JOIN todos ON todos.list_id = list_shares.list_id
+		WHERE todos.id = ? AND list_shares.principal_id = ?
+	`, request.TodoID, principalID, request.TodoID, principalID)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	var best *todo.Share
+	for rows.Next() {
+		sh, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		} else if best == nil || !todo.RoleIncludes(best.Role, sh.Role) {
+			best = sh
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	} else if best == nil {
+		return nil, todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' could not be found.", request.TodoID)
+	}
+	return best, nil
+}
+
+func (s *ShareService) ListShares(ctx context.Context, request todo.ListSharesRequest) ([]*todo.Share, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Shares of todos & lists are listed together, todos by ID first.
+	principalID := todo.PrincipalIDFromContext(ctx)
+	where, listWhere, args := "shares.principal_id = ?", "list_shares.principal_id = ?", []interface{}{principalID, principalID}
+	if request.ListID != 0 {
+		if _, err := findListByID(ctx, tx, request.ListID); err != nil {
+			return nil, err
+		}
+		where, listWhere, args = "0", "list_shares.list_id = ?", []interface{}{request.ListID}
+	} else if request.TodoID != 0 {
+		if _, err := findTodoByID(ctx, tx, request.TodoID); err != nil {
+			return nil, err
+		}
+		where, listWhere, args = "shares.todo_id = ?", "0", []interface{}{request.TodoID}
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+shareColumns+`
+		FROM shares
+		JOIN todos ON todos.id = shares.todo_id
+		WHERE `+where+`
+		UNION ALL
+		SELECT `+listShareColumns+`
+		FROM list_shares
+		JOIN lists ON lists.id = list_shares.list_id
+		WHERE `+listWhere+`
+		ORDER BY 1, 2, 4
+	`, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	shares := make([]*todo.Share, 0)
+	for rows.Next() {
+		sh, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, sh)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+// shareColumns lists the columns read by scanShare, in order. Queries must
+// join the shared todo to find its owner.
+const shareColumns = `shares.todo_id, 0, todos.owner_id, shares.principal_id, shares.role, shares.created_at`
+
+// listShareColumns lists the columns of list shares read by scanShare, in
+// order. Queries must join the shared list to find its owner.
+const listShareColumns = `0, list_shares.list_id, lists.owner_id, list_shares.principal_id, list_shares.role, list_shares.created_at`
+
+// scanShare reads a share from a row selecting shareColumns.
+func scanShare(row scanner) (*todo.Share, error) {
+	var createdAt string
+	sh := &todo.Share{}
+	if err := row.Scan(&sh.TodoID, &sh.ListID, &sh.OwnerID, &sh.PrincipalID, &sh.Role, &createdAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if sh.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
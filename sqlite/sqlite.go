@@ -0,0 +1,313 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"todo"
+)
+
+//go:embed migration/*.sql
+var migrationFS embed.FS
+
+// DB represents the database connection.
+type DB struct {
+	db     *sql.DB
+	ctx    context.Context // background context
+	cancel func()          // cancel background context
+
+	// Datasource name.
+	DSN string
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+
+	// Workflow todos follow. Defaults to todo.DefaultWorkflow.
+	Workflow *todo.Workflow
+}
+
+// NewDB returns a new instance of DB associated with the given datasource name.
+func NewDB(dsn string) *DB {
+	db := &DB{
+		DSN: dsn,
+		Now: time.Now,
+	}
+	db.ctx, db.cancel = context.WithCancel(context.Background())
+	return db
+}
+
+// Open opens the database connection and migrates the database. Todos in
+// states unknown to the workflow are moved into it.
+func (db *DB) Open() (err error) {
+	// Ensure a DSN is set before attempting to open the database.
+	if db.DSN == "" {
+		return fmt.Errorf("dsn required")
+	}
+
+	// Make the parent directory unless using an in-memory db.
+	if db.DSN != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(db.DSN), 0700); err != nil {
+			return err
+		}
+	}
+
+	// Connect to the database.
+	if db.db, err = sql.Open("sqlite3", db.DSN); err != nil {
+		return err
+	}
+
+	// SQLite only allows a single writer so serialize access through a single
+	// connection. This also keeps ":memory:" databases from being split across
+	// several connections.
+	db.db.SetMaxOpenConns(1)
+
+	// Enable WAL. SQLite performs better with the WAL because it allows
+	// multiple readers to operate while data is being written.
+	if _, err := db.db.Exec(`PRAGMA journal_mode = wal;`); err != nil {
+		return fmt.Errorf("enable wal: %w", err)
+	}
+
+	// Enable foreign key checks. For historical reasons, SQLite does not check
+	// foreign key constraints by default... which is kinda insane. There's some
+	// overhead on inserts to verify foreign key integrity but it's definitely
+	// worth it.
+	if _, err := db.db.Exec(`PRAGMA foreign_keys = ON;`); err != nil {
+		return fmt.Errorf("foreign keys pragma: %w", err)
+	}
+
+	if err := db.migrate(); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	if err := db.remapStates(); err != nil {
+		return fmt.Errorf("remap states: %w", err)
+	}
+
+	return nil
+}
+
+// remapStates moves todos in states the workflow does not know, e.g. after a
+// change of the workflow, to its initial state, or to its first closed state
+// if they are complete. The moves are recorded as transitions like any other.
+func (db *DB) remapStates() error {
+	tx, err := db.BeginTx(db.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	w := db.workflow()
+	args := []interface{}{w.Closed[0], w.States[0], formatTime(&tx.now)}
+	for _, state := range w.States {
+		args = append(args, state)
+	}
+	unknown := `state NOT IN (?` + strings.Repeat(", ?", len(w.States)-1) + `)`
+
+	// Record the transitions while the todos are still in their old states.
+	if _, err := tx.Exec(`
+		INSERT INTO transitions (todo_id, from_state, to_state, at)
+		SELECT id, state, CASE WHEN complete THEN ? ELSE ? END, ?
+		FROM todos
+		WHERE `+unknown+`
+	`, args...); err != nil {
+		return FormatError(err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE todos
+		SET state = CASE WHEN complete THEN ? ELSE ? END, state_changed_at = ?, version = version + 1
+		WHERE `+unknown+`
+	`, args...); err != nil {
+		return FormatError(err)
+	}
+	return tx.Commit()
+}
+
+// migrate sets up migration tracking and executes pending migration files.
+//
+// Migration files are embedded in the sqlite/migration folder and are executed
+// in lexigraphical order.
+//
+// Once a migration is run, its name is stored in the 'migrations' table so it
+// is not re-executed. Migrations run in a transaction to prevent partial
+// migrations.
+func (db *DB) migrate() error {
+	// Ensure the 'migrations' table exists so we don't duplicate migrations.
+	if _, err := db.db.Exec(`CREATE TABLE IF NOT EXISTS migrations (name TEXT PRIMARY KEY);`); err != nil {
+		return fmt.Errorf("cannot create migrations table: %w", err)
+	}
+
+	// Read migration files from our embedded file system.
+	// This uses Go 1.16's 'embed' package.
+	names, err := fs.Glob(migrationFS, "migration/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	// Loop over all migration files and execute them in order.
+	for _, name := range names {
+		if err := db.migrateFile(name); err != nil {
+			return fmt.Errorf("migration error: name=%q err=%w", name, err)
+		}
+	}
+	return nil
+}
+
+// migrateFile runs a single migration file within a transaction. On success,
+// the migration file name is saved to the "migrations" table to prevent re-running.
+func (db *DB) migrateFile(name string) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Ensure migration has not already been run.
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM migrations WHERE name = ?`, name).Scan(&n); err != nil {
+		return err
+	} else if n != 0 {
+		return nil // already run migration, skip
+	}
+
+	// Read and execute migration file.
+	if buf, err := fs.ReadFile(migrationFS, name); err != nil {
+		return err
+	} else if _, err := tx.Exec(string(buf)); err != nil {
+		return err
+	}
+
+	// Insert record into migrations to prevent re-running migration.
+	if _, err := tx.Exec(`INSERT INTO migrations (name) VALUES (?)`, name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Close closes the database connection.
+func (db *DB) Close() error {
+	// Cancel background context.
+	db.cancel()
+
+	// Close database.
+	if db.db != nil {
+		return db.db.Close()
+	}
+	return nil
+}
+
+// BeginTx starts a transaction and returns a wrapper Tx type. This type
+// provides a reference to the database and a fixed timestamp at the start of
+// the transaction. The timestamp allows us to mock time during tests as well.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return wrapper Tx that includes the transaction start time.
+	return &Tx{
+		Tx:  tx,
+		db:  db,
+		now: db.Now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// Tx wraps the SQL Tx object to provide a timestamp at the start of the transaction.
+type Tx struct {
+	*sql.Tx
+	db  *DB
+	now time.Time
+}
+
+// FormatError returns err as a todo error, if possible.
+// Otherwise returns the original error.
+func FormatError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return todo.Errorf(todo.ENOTFOUND, "Record not found.")
+	}
+
+	var e sqlite3.Error
+	if errors.As(err, &e) && e.Code == sqlite3.ErrConstraint {
+		switch e.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return todo.Errorf(todo.ECONFLICT, "Record already exists.")
+		case sqlite3.ErrConstraintForeignKey:
+			return todo.Errorf(todo.ECONFLICT, "Record is referenced by or references another record.")
+		case sqlite3.ErrConstraintNotNull, sqlite3.ErrConstraintCheck:
+			return todo.Errorf(todo.EINVALID, "Record violates a constraint.")
+		}
+		return todo.Errorf(todo.ECONFLICT, "Record violates a constraint.")
+	}
+
+	return err
+}
+
+// workflow returns the workflow todos follow.
+func (db *DB) workflow() *todo.Workflow {
+	if db.Workflow == nil {
+		return todo.DefaultWorkflow
+	}
+	return db.Workflow
+}
+
+// nullInt returns nil for zero so optional references are stored as NULL.
+func nullInt(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// formatTime returns an optional time in the format dates are stored in, or
+// nil so it is stored as NULL. Dates are stored as RFC 3339 strings in UTC at
+// second precision so they compare & sort as strings.
+func formatTime(v *time.Time) interface{} {
+	if v == nil {
+		return nil
+	}
+	return v.UTC().Format(time.RFC3339)
+}
+
+// nullTime scans an optional date stored by formatTime into dst.
+type nullTime struct {
+	dst **time.Time
+}
+
+// Scan implements the sql.Scanner interface.
+func (n nullTime) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*n.dst = nil
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("sqlite: cannot scan %T into time", value)
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return err
+	}
+	*n.dst = &t
+	return nil
+}
@@ -0,0 +1,192 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.TagService = (*TagService)(nil)
+
+// TagService represents a service for managing the tags of all todos at once.
+type TagService struct {
+	db *DB
+}
+
+// NewTagService returns a new instance of TagService.
+func NewTagService(db *DB) *TagService {
+	return &TagService{db: db}
+}
+
+func (s *TagService) ListTags(ctx context.Context, request todo.ListTagsRequest) ([]*todo.Tag, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT todo_tags.tag, COUNT(*)
+		FROM todo_tags
+		JOIN todos ON todos.id = todo_tags.todo_id
+		WHERE todos.owner_id = ?
+		GROUP BY todo_tags.tag
+		ORDER BY todo_tags.tag
+	`, todo.OwnerIDFromContext(ctx))
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	tags := make([]*todo.Tag, 0)
+	for rows.Next() {
+		var tag todo.Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (s *TagService) RenameTag(ctx context.Context, request todo.RenameTagRequest) (*todo.Tag, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if n, err := countTagged(ctx, tx, request.Name); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, todo.Errorf(todo.ENOTFOUND, "Tag '%s' could not be found.", request.Name)
+	} else if n, err := countTagged(ctx, tx, request.NewName); err != nil {
+		return nil, err
+	} else if n > 0 {
+		return nil, todo.Errorf(todo.ECONFLICT, "Tag '%s' already exists.", request.NewName)
+	}
+
+	tag, err := replaceTags(ctx, tx, []string{request.Name}, request.NewName)
+	if err != nil {
+		return nil, err
+	}
+	return tag, tx.Commit()
+}
+
+func (s *TagService) MergeTags(ctx context.Context, request todo.MergeTagsRequest) (*todo.Tag, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if n, err := countTagged(ctx, tx, request.Names...); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, todo.Errorf(todo.ENOTFOUND, "None of the tags could be found.")
+	}
+
+	tag, err := replaceTags(ctx, tx, request.Names, request.Into)
+	if err != nil {
+		return nil, err
+	}
+	return tag, tx.Commit()
+}
+
+// countTagged returns the number of the caller's todos carrying any of tags.
+func countTagged(ctx context.Context, tx *Tx, tags ...string) (int, error) {
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT todo_tags.todo_id)
+		FROM todo_tags
+		JOIN todos ON todos.id = todo_tags.todo_id
+		WHERE todos.owner_id = ? AND todo_tags.tag IN (`+placeholders(len(tags))+`)
+	`, append([]interface{}{todo.OwnerIDFromContext(ctx)}, stringArgs(tags)...)...).Scan(&n); err != nil {
+		return 0, FormatError(err)
+	}
+	return n, nil
+}
+
+// replaceTags replaces names with into on every todo of the caller and
+// increments the version of the todos whose tags change.
+func replaceTags(ctx context.Context, tx *Tx, names []string, into string) (*todo.Tag, error) {
+	ownerID := todo.OwnerIDFromContext(ctx)
+
+	// A todo only changes if it carries one of the other tags. Merging a
+	// tag into itself leaves it as is.
+	var others []string
+	for _, name := range names {
+		if name != into {
+			others = append(others, name)
+		}
+	}
+	if len(others) > 0 {
+		args := append([]interface{}{ownerID}, stringArgs(others)...)
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE todos
+			SET version = version + 1
+			WHERE owner_id = ? AND id IN (SELECT todo_id FROM todo_tags WHERE tag IN (`+placeholders(len(others))+`))
+		`, args...); err != nil {
+			return nil, FormatError(err)
+		} else if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO todo_tags (todo_id, tag)
+			SELECT todo_tags.todo_id, ?
+			FROM todo_tags
+			JOIN todos ON todos.id = todo_tags.todo_id
+			WHERE todos.owner_id = ? AND todo_tags.tag IN (`+placeholders(len(others))+`)
+		`, append([]interface{}{into}, args...)...); err != nil {
+			return nil, FormatError(err)
+		} else if _, err := tx.ExecContext(ctx, `
+			DELETE FROM todo_tags
+			WHERE todo_id IN (SELECT id FROM todos WHERE owner_id = ?) AND tag IN (`+placeholders(len(others))+`)
+		`, args...); err != nil {
+			return nil, FormatError(err)
+		}
+	}
+
+	n, err := countTagged(ctx, tx, into)
+	if err != nil {
+		return nil, err
+	}
+	return &todo.Tag{Name: into, Count: n}, nil
+}
+
+// saveTags replaces the stored tags of t with its current tags.
+func saveTags(ctx context.Context, tx *Tx, t *todo.Todo) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM todo_tags WHERE todo_id = ?`, t.ID); err != nil {
+		return FormatError(err)
+	}
+	for _, tag := range t.Tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO todo_tags (todo_id, tag) VALUES (?, ?)`, t.ID, tag); err != nil {
+			return FormatError(err)
+		}
+	}
+	return nil
+}
+
+// placeholders returns n comma separated query placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// stringArgs converts values to query arguments.
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
@@ -0,0 +1,593 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.Service = (*TodoService)(nil)
+
+// TodoService represents a service for managing todos.
+type TodoService struct {
+	db *DB
+
+	// If set, todos can be completed while some of their subtasks are
+	// still open. Otherwise completing them fails with ECONFLICT.
+	AllowOpenChildren bool
+}
+
+// NewTodoService returns a new instance of TodoService.
+func NewTodoService(db *DB) *TodoService {
+	return &TodoService{db: db}
+}
+
+func (s *TodoService) CreateTodo(ctx context.Context, request todo.CreateTodoRequest) (*todo.Todo, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := s.create(ctx, tx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, tx.Commit()
+}
+
+func (s *TodoService) UpdateTodo(ctx context.Context, request todo.UpdateTodoRequest) (*todo.Todo, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := s.update(ctx, tx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, tx.Commit()
+}
+
+func (s *TodoService) PatchTodo(ctx context.Context, request todo.PatchTodoRequest) (*todo.Todo, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := s.patch(ctx, tx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, tx.Commit()
+}
+
+// create creates the todo described by request within tx.
+func (s *TodoService) create(ctx context.Context, tx *Tx, request todo.CreateTodoRequest) (t *todo.Todo, err error) {
+	t = &todo.Todo{
+		OwnerID:    todo.OwnerIDFromContext(ctx),
+		ListID:     request.ListID,
+		Value:      request.Value,
+		Complete:   request.Complete,
+		DueAt:      request.DueAt,
+		RemindAt:   request.RemindAt,
+		TimeZone:   request.TimeZone,
+		ParentID:   request.ParentID,
+		Recurrence: request.Recurrence,
+		State:      request.State,
+		Tags:       request.Tags,
+		Priority:   request.Priority,
+		Version:    1,
+	}
+	if t.ID, err = nextTodoID(ctx, tx); err != nil {
+		return nil, err
+	} else if _, err := s.saveTodo(ctx, tx, t, nil, "", false); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// update replaces the todo described by request within tx.
+func (s *TodoService) update(ctx context.Context, tx *Tx, request todo.UpdateTodoRequest) (*todo.Todo, error) {
+	t, err := findTodoByID(ctx, tx, request.ID)
+	if err != nil {
+		return nil, err
+	} else if err := t.CheckVersion(request.Version); err != nil {
+		return nil, err
+	}
+	prev := *t
+	t.ListID = request.ListID
+	t.ParentID = request.ParentID
+	t.Value = request.Value
+	t.Complete = request.Complete
+	t.DueAt = request.DueAt
+	t.RemindAt = request.RemindAt
+	t.TimeZone = request.TimeZone
+	t.Recurrence = request.Recurrence
+	t.State = request.State
+	t.Tags = request.Tags
+	t.Priority = request.Priority
+
+	if _, err := s.saveTodo(ctx, tx, t, &prev, request.Scope, false); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// patch applies the partial update described by request within tx.
+func (s *TodoService) patch(ctx context.Context, tx *Tx, request todo.PatchTodoRequest) (*todo.Todo, error) {
+	t, err := findTodoByID(ctx, tx, request.ID)
+	if err != nil {
+		return nil, err
+	} else if err := t.CheckVersion(request.Version); err != nil {
+		return nil, err
+	}
+	prev := *t
+	request.Apply(t)
+
+	if _, err := s.saveTodo(ctx, tx, t, &prev, request.Scope, false); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *TodoService) CompleteTodo(ctx context.Context, request todo.CompleteTodoRequest) (*todo.CompleteTodoResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := findTodoByID(ctx, tx, request.ID)
+	if err != nil {
+		return nil, err
+	} else if err := t.CheckVersion(request.Version); err != nil {
+		return nil, err
+	}
+	prev := *t
+	t.Complete = true
+
+	next, err := s.saveTodo(ctx, tx, t, &prev, "", request.Force)
+	if err != nil {
+		return nil, err
+	}
+
+	return &todo.CompleteTodoResponse{Todo: t, Next: next}, tx.Commit()
+}
+
+func (s *TodoService) DeleteTodo(ctx context.Context, request todo.DeleteTodoRequest) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteTodo(ctx, tx, request.ID, request.Version, request.Cascade); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *TodoService) GetTodoByID(ctx context.Context, request todo.GetTodoByIDRequest) (*todo.Todo, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findTodoByID(ctx, tx, request.ID)
+}
+
+func (s *TodoService) ListTodos(ctx context.Context, request todo.ListTodosRequest) (*todo.ListTodosResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if request.ListID != 0 {
+		l, err := findListByID(ctx, tx, request.ListID)
+		if err != nil {
+			return nil, err
+		}
+		request.UseList(l)
+	}
+	if err := request.Normalize(); err != nil {
+		return nil, err
+	}
+
+	return findTodos(ctx, tx, request)
+}
+
+// todoColumns lists the columns read by scanTodo, in order.
+// Tags are read from todo_tags and the blocked state is computed from the
+// dependencies of each todo.
+const todoColumns = `id, owner_id, COALESCE(list_id, 0), value, complete, due_at, remind_at, time_zone, reminded_at, recurrence, series_id, occurrence, COALESCE(parent_id, 0), priority, position, state, state_changed_at, archived_at, ` + tagsColumn + `, ` + blockedColumn + `, version`
+
+// tagsColumn selects the tags of a row of todos separated by spaces, which
+// tags cannot contain.
+const tagsColumn = `COALESCE((SELECT group_concat(tag, ' ') FROM todo_tags WHERE todo_id = todos.id), '')`
+
+// blockedColumn selects whether a row of todos has an open blocker.
+const blockedColumn = `EXISTS (
+	SELECT 1
+	FROM dependencies
+	JOIN todos AS blockers ON blockers.id = dependencies.blocked_by_id
+	WHERE dependencies.todo_id = todos.id AND blockers.complete = 0
+)`
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTodo reads a todo from a row selecting todoColumns.
+func scanTodo(row scanner) (*todo.Todo, error) {
+	var tags string
+	t := &todo.Todo{}
+	if err := row.Scan(&t.ID, &t.OwnerID, &t.ListID, &t.Value, &t.Complete,
+		nullTime{&t.DueAt}, nullTime{&t.RemindAt}, &t.TimeZone, nullTime{&t.RemindedAt},
+		&t.Recurrence, &t.SeriesID, &t.Occurrence, &t.ParentID, &t.Priority, &t.Position, &t.State, nullTime{&t.StateChangedAt}, nullTime{&t.ArchivedAt}, &tags, &t.Blocked, &t.Version,
+	); err != nil {
+		return nil, err
+	}
+
+	// Stored tags are already normalized, this only sorts them.
+	t.Tags, _ = todo.NormalizeTags(strings.Fields(tags))
+	return t, nil
+}
+
+// findTodoByID is a helper function to fetch a todo by ID.
+// Returns ENOTFOUND if todo does not exist or is not owned by the caller.
+func findTodoByID(ctx context.Context, tx *Tx, id int) (*todo.Todo, error) {
+	t, err := scanTodo(tx.QueryRowContext(ctx, `
+		SELECT `+todoColumns+`
+		FROM todos
+		WHERE id = ? AND owner_id = ?
+	`, id, todo.OwnerIDFromContext(ctx)))
+	if err != nil {
+		if err = FormatError(err); todo.ErrorCode(err) == todo.ENOTFOUND {
+			return nil, todo.Errorf(todo.ENOTFOUND, "Todo with ID '%d' could not be found.", id)
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// sortColumns maps sort fields to the column they are stored in.
+var sortColumns = map[string]string{
+	todo.SortByID:       "id",
+	todo.SortByValue:    "value",
+	todo.SortByComplete: "complete",
+	todo.SortByPriority: "priority",
+	todo.SortByPosition: "position",
+}
+
+// findTodos returns a page of todos matching the request, which must already
+// be normalized and limited to an existing list, if any.
+func findTodos(ctx context.Context, tx *Tx, request todo.ListTodosRequest) (_ *todo.ListTodosResponse, err error) {
+	// Build WHERE clause from the filter fields. Callers only ever see
+	// their own todos.
+	where, args := []string{"owner_id = ?"}, []interface{}{todo.OwnerIDFromContext(ctx)}
+	if v := request.ListID; v != 0 {
+		where, args = append(where, "list_id = ?"), append(args, v)
+	}
+	if v := request.Complete; v != nil {
+		where, args = append(where, "complete = ?"), append(args, *v)
+	}
+	if v := request.State; v != "" {
+		where, args = append(where, "state = ?"), append(args, v)
+	}
+	if v := request.Contains; v != "" {
+		where, args = append(where, "instr(value, ?) > 0"), append(args, v)
+	}
+	if v := request.DueBefore; v != nil {
+		where, args = append(where, "due_at < ?"), append(args, formatTime(v))
+	}
+	if request.Overdue {
+		where, args = append(where, "complete = 0 AND due_at < ?"), append(args, formatTime(&tx.now))
+	}
+	if v := request.SeriesID; v != 0 {
+		where, args = append(where, "series_id = ?"), append(args, v)
+	}
+	if v := request.ParentID; v != 0 {
+		if _, err := findTodoByID(ctx, tx, v); err != nil {
+			return nil, err
+		}
+		where, args = append(where, "parent_id = ?"), append(args, v)
+	}
+	if request.Archived {
+		where = append(where, "archived_at IS NOT NULL")
+	} else {
+		where = append(where, "archived_at IS NULL")
+	}
+	for _, tag := range request.Tags {
+		where, args = append(where, "id IN (SELECT todo_id FROM todo_tags WHERE tag = ?)"), append(args, tag)
+	}
+	if v := request.AnyTags; len(v) > 0 {
+		where, args = append(where, "id IN (SELECT todo_id FROM todo_tags WHERE tag IN ("+placeholders(len(v))+"))"), append(args, stringArgs(v)...)
+	}
+	if v := request.NotTags; len(v) > 0 {
+		where, args = append(where, "id NOT IN (SELECT todo_id FROM todo_tags WHERE tag IN ("+placeholders(len(v))+"))"), append(args, stringArgs(v)...)
+	}
+	if expr := request.Where(); expr != nil {
+		cond, condArgs, err := compileExpr(expr, tx.now)
+		if err != nil {
+			return nil, err
+		}
+		where, args = append(where, cond), append(args, condArgs...)
+	}
+
+	resp := &todo.ListTodosResponse{Todos: make([]*todo.Todo, 0)}
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM todos
+		WHERE `+strings.Join(where, " AND "),
+		args...,
+	).Scan(&resp.TotalCount); err != nil {
+		return nil, FormatError(err)
+	}
+
+	// Start after the cursor position, if any. Ties on the sort column are
+	// broken by ID so every todo has a unique position.
+	col, op, dir := sortColumns[request.SortBy], ">", "ASC"
+	if request.SortDirection == todo.SortDesc {
+		op, dir = "<", "DESC"
+	}
+	if request.Cursor != "" {
+		c, err := todo.DecodeCursor(request.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if request.SortBy == todo.SortByID {
+			where, args = append(where, "id "+op+" ?"), append(args, c.ID)
+		} else {
+			where = append(where, "("+col+" "+op+" ? OR ("+col+" = ? AND id "+op+" ?))")
+			args = append(args, c.Key, c.Key, c.ID)
+		}
+	}
+
+	// Fetch one extra row to know whether there is a next page.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+todoColumns+`
+		FROM todos
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY `+col+` `+dir+`, id `+dir+`
+		LIMIT ?`,
+		append(args, request.Limit+1)...,
+	)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp.Todos = append(resp.Todos, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Todos) > request.Limit {
+		resp.Todos = resp.Todos[:request.Limit]
+		resp.NextCursor = todo.NewCursor(resp.Todos[len(resp.Todos)-1], request.SortBy, request.SortDirection).Encode()
+	}
+
+	return resp, nil
+}
+
+// saveTodo validates & writes t, which changed from prev or is new if prev is
+// nil. New todos must already have an ID. If the change continues a recurring
+// series, the next occurrence is created and returned. Blocked todos are only
+// completed if force is set.
+func (s *TodoService) saveTodo(ctx context.Context, tx *Tx, t, prev *todo.Todo, scope string, force bool) (*todo.Todo, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	} else if err := checkParentID(ctx, tx, t); err != nil {
+		return nil, err
+	}
+	tr, err := s.db.workflow().Transition(t, prev, tx.now)
+	if err != nil {
+		return nil, err
+	} else if err := checkLimit(ctx, tx, t, prev); err != nil {
+		return nil, err
+	}
+	if !s.AllowOpenChildren {
+		var open int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*)
+			FROM todos
+			WHERE parent_id = ? AND complete = 0
+		`, t.ID).Scan(&open); err != nil {
+			return nil, FormatError(err)
+		} else if err := t.CheckCompletable(prev, open); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.CheckUnblocked(prev, force); err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		t.Reschedule(prev)
+	} else if err := placeLast(ctx, tx, t); err != nil {
+		return nil, err
+	}
+
+	next, err := t.Recur(prev, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if prev == nil {
+		err = createTodo(ctx, tx, t)
+	} else {
+		err = updateTodo(ctx, tx, t)
+	}
+	if err != nil {
+		return nil, err
+	} else if err := createTransition(ctx, tx, tr); err != nil {
+		return nil, err
+	}
+
+	if next != nil {
+		if next.ID, err = nextTodoID(ctx, tx); err != nil {
+			return nil, err
+		} else if err := placeLast(ctx, tx, next); err != nil {
+			return nil, err
+		}
+		if tr, err = s.db.workflow().Transition(next, nil, tx.now); err != nil {
+			return nil, err
+		} else if err := checkLimit(ctx, tx, next, nil); err != nil {
+			return nil, err
+		} else if err := createTodo(ctx, tx, next); err != nil {
+			return nil, err
+		} else if err := createTransition(ctx, tx, tr); err != nil {
+			return nil, err
+		}
+	}
+	return next, nil
+}
+
+// nextTodoID returns a new ID for a todo.
+func nextTodoID(ctx context.Context, tx *Tx) (int, error) {
+	// The AUTOINCREMENT sequence records the largest ID ever inserted, even
+	// if that todo was deleted since, so IDs are never reused.
+	var last int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(seq), 0)
+		FROM sqlite_sequence
+		WHERE name = 'todos'
+	`).Scan(&last); err != nil {
+		return 0, FormatError(err)
+	}
+	return todo.NextID(last, tx.db.Now()), nil
+}
+
+// createTodo creates a new todo with the ID already assigned to t.
+func createTodo(ctx context.Context, tx *Tx, t *todo.Todo) error {
+	if err := checkListID(ctx, tx, t.ListID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO todos (id, owner_id, list_id, value, complete, due_at, remind_at, time_zone, reminded_at, recurrence, series_id, occurrence, parent_id, priority, position, state, state_changed_at, archived_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		t.ID, t.OwnerID, nullInt(t.ListID), t.Value, t.Complete,
+		formatTime(t.DueAt), formatTime(t.RemindAt), t.TimeZone, formatTime(t.RemindedAt),
+		t.Recurrence, t.SeriesID, t.Occurrence, nullInt(t.ParentID), t.Priority, t.Position, t.State, formatTime(t.StateChangedAt), formatTime(t.ArchivedAt), t.Version,
+	); err != nil {
+		return FormatError(err)
+	}
+
+	return saveTags(ctx, tx, t)
+}
+
+// updateTodo writes the fields of t to its existing row and increments the
+// version of t.
+func updateTodo(ctx context.Context, tx *Tx, t *todo.Todo) error {
+	if err := checkListID(ctx, tx, t.ListID); err != nil {
+		return err
+	}
+
+	t.Version++
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE todos
+		SET list_id = ?, value = ?, complete = ?, due_at = ?, remind_at = ?, time_zone = ?, reminded_at = ?,
+			recurrence = ?, series_id = ?, occurrence = ?, parent_id = ?, priority = ?, position = ?, state = ?, state_changed_at = ?, archived_at = ?, version = ?
+		WHERE id = ?
+	`,
+		nullInt(t.ListID), t.Value, t.Complete,
+		formatTime(t.DueAt), formatTime(t.RemindAt), t.TimeZone, formatTime(t.RemindedAt),
+		t.Recurrence, t.SeriesID, t.Occurrence, nullInt(t.ParentID), t.Priority, t.Position, t.State, formatTime(t.StateChangedAt), formatTime(t.ArchivedAt), t.Version, t.ID,
+	); err != nil {
+		return FormatError(err)
+	}
+	return saveTags(ctx, tx, t)
+}
+
+// deleteTodo moves a todo and, if cascade is set, its subtasks to the trash.
+// Returns ENOTFOUND if todo does not exist and ECONFLICT if version is set and
+// does not match the todo's version.
+func deleteTodo(ctx context.Context, tx *Tx, id, version int, cascade bool) error {
+	if t, err := findTodoByID(ctx, tx, id); err != nil {
+		return err
+	} else if err := t.CheckVersion(version); err != nil {
+		return err
+	} else if err := trashTodo(ctx, tx, id, cascade); err != nil {
+		return err
+	}
+	return removeTodo(ctx, tx, id, cascade)
+}
+
+// removeTodo deletes a todo without any checks. Its subtasks are deleted as
+// well if cascade is set, otherwise they are moved up to its parent.
+func removeTodo(ctx context.Context, tx *Tx, id int, cascade bool) error {
+	if cascade {
+		if _, err := tx.ExecContext(ctx, `
+			WITH RECURSIVE subtree (id) AS (
+				SELECT ?
+				UNION
+				SELECT todos.id FROM todos JOIN subtree ON todos.parent_id = subtree.id
+			)
+			DELETE FROM todos WHERE id IN subtree
+		`, id); err != nil {
+			return FormatError(err)
+		}
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE todos
+		SET parent_id = (SELECT parent_id FROM todos WHERE id = ?), version = version + 1
+		WHERE parent_id = ?
+	`, id, id); err != nil {
+		return FormatError(err)
+	} else if _, err := tx.ExecContext(ctx, `DELETE FROM todos WHERE id = ?`, id); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// checkParentID returns EINVALID if the parent of t is not one of the caller's
+// todos or is t itself or one of its subtasks.
+func checkParentID(ctx context.Context, tx *Tx, t *todo.Todo) error {
+	if t.ParentID == 0 {
+		return nil
+	} else if t.ParentID == t.ID {
+		return todo.Errorf(todo.EINVALID, "Todo with ID '%d' cannot be a subtask of itself.", t.ID)
+	} else if _, err := findTodoByID(ctx, tx, t.ParentID); todo.ErrorCode(err) == todo.ENOTFOUND {
+		return todo.Errorf(todo.EINVALID, "Parent todo with ID '%d' does not exist.", t.ParentID)
+	} else if err != nil {
+		return err
+	}
+
+	// Walk up from the new parent. Reaching t means t would become its
+	// own ancestor.
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		WITH RECURSIVE ancestors (id) AS (
+			SELECT ?
+			UNION
+			SELECT todos.parent_id FROM todos JOIN ancestors ON todos.id = ancestors.id
+			WHERE todos.parent_id IS NOT NULL
+		)
+		SELECT COUNT(*) FROM ancestors WHERE id = ?
+	`, t.ParentID, t.ID).Scan(&n); err != nil {
+		return FormatError(err)
+	} else if n > 0 {
+		return todo.Errorf(todo.EINVALID, "Todo with ID '%d' cannot be a subtask of itself.", t.ID)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+	"todo"
+	"todo/sqlite"
+	"todo/todotest"
+)
+
+func TestTodoService(t *testing.T) {
+	todotest.TestService(t, func(t *testing.T) todo.Service {
+		return sqlite.NewTodoService(MustOpenDB(t))
+	})
+}
+
+func TestShareService(t *testing.T) {
+	todotest.TestShareService(t, func(t *testing.T) (todo.Service, todo.ShareService) {
+		db := MustOpenDB(t)
+		return sqlite.NewTodoService(db), sqlite.NewShareService(db)
+	})
+}
+
+func TestListShares(t *testing.T) {
+	todotest.TestListShares(t, func(t *testing.T) (todo.Service, todo.ListService, todo.ShareService) {
+		db := MustOpenDB(t)
+		return sqlite.NewTodoService(db), sqlite.NewListService(db), sqlite.NewShareService(db)
+	})
+}
+
+func TestListService(t *testing.T) {
+	todotest.TestListService(t, func(t *testing.T) (todo.Service, todo.ListService) {
+		db := MustOpenDB(t)
+		return sqlite.NewTodoService(db), sqlite.NewListService(db)
+	})
+}
+
+func TestReminderService(t *testing.T) {
+	todotest.TestReminderService(t, func(t *testing.T) (todo.Service, todo.ReminderService) {
+		db := MustOpenDB(t)
+		return sqlite.NewTodoService(db), sqlite.NewReminderService(db)
+	})
+}
+
+func TestDependencyService(t *testing.T) {
+	todotest.TestDependencyService(t, func(t *testing.T) (todo.Service, todo.DependencyService) {
+		db := MustOpenDB(t)
+		return sqlite.NewTodoService(db), sqlite.NewDependencyService(db)
+	})
+}
+
+func TestTagService(t *testing.T) {
+	todotest.TestTagService(t, func(t *testing.T) (todo.Service, todo.TagService) {
+		db := MustOpenDB(t)
+		return sqlite.NewTodoService(db), sqlite.NewTagService(db)
+	})
+}
+
+func TestWorkflowService(t *testing.T) {
+	todotest.TestWorkflowService(t, func(t *testing.T) (todo.Service, todo.ListService, todo.WorkflowService) {
+		db := MustOpenDB(t)
+		return sqlite.NewTodoService(db), sqlite.NewListService(db), sqlite.NewWorkflowService(db)
+	})
+}
+
+func TestSearchService(t *testing.T) {
+	todotest.TestSearchService(t, func(t *testing.T) (todo.Service, todo.SearchService) {
+		db := MustOpenDB(t)
+		return sqlite.NewTodoService(db), sqlite.NewSearchService(db)
+	})
+}
+
+func TestTodoService_Queries(t *testing.T) {
+	todotest.TestQueries(t, func(t *testing.T) (todo.Service, todo.ListService, todo.DependencyService) {
+		db := MustOpenDB(t)
+		return sqlite.NewTodoService(db), sqlite.NewListService(db), sqlite.NewDependencyService(db)
+	})
+}
+
+func TestRevisionStore(t *testing.T) {
+	todotest.TestRevisionStore(t, func(t *testing.T, now func() time.Time) todo.RevisionStore {
+		db := MustOpenDB(t)
+		db.Now = now
+		return sqlite.NewRevisionStore(db)
+	})
+}
+
+func TestTrashService(t *testing.T) {
+	todotest.TestTrashService(t, func(t *testing.T) todotest.TrashServices {
+		db := MustOpenDB(t)
+		trash := sqlite.NewTrashService(db)
+		return todotest.TrashServices{
+			Todos:        sqlite.NewTodoService(db),
+			Lists:        sqlite.NewListService(db),
+			Shares:       sqlite.NewShareService(db),
+			Dependencies: sqlite.NewDependencyService(db),
+			Trash:        trash,
+			Purge:        trash,
+		}
+	})
+}
+
+func TestPolicyService(t *testing.T) {
+	todotest.TestPolicyService(t, func(t *testing.T, now func() time.Time) todotest.PolicyServices {
+		db := MustOpenDB(t)
+		db.Now = now
+		policies := sqlite.NewPolicyService(db)
+		return todotest.PolicyServices{
+			Todos:    sqlite.NewTodoService(db),
+			Lists:    sqlite.NewListService(db),
+			Trash:    sqlite.NewTrashService(db),
+			Policies: policies,
+			Enforcer: policies,
+		}
+	})
+}
+
+func TestTodoService_AllowOpenChildren(t *testing.T) {
+	s := sqlite.NewTodoService(MustOpenDB(t))
+	s.AllowOpenChildren = true
+	ctx := context.Background()
+
+	parent := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "parent"})
+	todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "child", ParentID: parent.ID})
+	if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: parent.ID}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// MustOpenDB returns a new, open DB in a temporary directory. The DB is
+// closed automatically when the test finishes.
+func MustOpenDB(tb testing.TB) *sqlite.DB {
+	tb.Helper()
+
+	db := sqlite.NewDB(filepath.Join(tb.TempDir(), "db"))
+	if err := db.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			tb.Fatal(err)
+		}
+	})
+	return db
+}
+
+func TestDB_Open_RemapStates(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "db")
+	ctx := context.Background()
+
+	db := sqlite.NewDB(dsn)
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	s := sqlite.NewTodoService(db)
+	a := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+	b := todotest.MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", Complete: true})
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening with another workflow moves todos into its states.
+	db = sqlite.NewDB(dsn)
+	db.Workflow = &todo.Workflow{States: []string{"todo", "done"}, Closed: []string{"done"}}
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	s = sqlite.NewTodoService(db)
+
+	if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+		t.Fatal(err)
+	} else if got.State != "todo" || got.Version != a.Version+1 || got.StateChangedAt == nil {
+		t.Fatalf("unexpected todo: %#v", got)
+	}
+	if trs, err := sqlite.NewWorkflowService(db).ListTransitions(ctx, todo.ListTransitionsRequest{TodoID: a.ID}); err != nil {
+		t.Fatal(err)
+	} else if tr := trs[len(trs)-1]; tr.From != todo.StateBacklog || tr.To != "todo" {
+		t.Fatalf("unexpected transitions: %#v", trs)
+	}
+	if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: b.ID}); err != nil {
+		t.Fatal(err)
+	} else if got.State != "done" || !got.Complete || got.Version != b.Version {
+		t.Fatalf("unexpected todo: %#v", got)
+	}
+}
@@ -0,0 +1,293 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.ListService = (*ListService)(nil)
+
+// ListService represents a service for managing lists of todos.
+type ListService struct {
+	db *DB
+}
+
+// NewListService returns a new instance of ListService.
+func NewListService(db *DB) *ListService {
+	return &ListService{db: db}
+}
+
+func (s *ListService) CreateList(ctx context.Context, request todo.CreateListRequest) (*todo.List, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	} else if err := s.db.workflow().ValidateLimits(request.WIPLimits); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	l := &todo.List{
+		OwnerID:   todo.OwnerIDFromContext(ctx),
+		Name:      request.Name,
+		WIPLimits: request.WIPLimits,
+		Query:     request.Query,
+		Policies:  request.Policies,
+		CreatedAt: tx.now,
+	}
+	if err := createList(ctx, tx, l); err != nil {
+		return nil, err
+	}
+
+	return l, tx.Commit()
+}
+
+func (s *ListService) UpdateList(ctx context.Context, request todo.UpdateListRequest) (*todo.List, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	} else if err := s.db.workflow().ValidateLimits(request.WIPLimits); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	l, err := findListByID(ctx, tx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Query != "" {
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos WHERE list_id = ?`, l.ID).Scan(&n); err != nil {
+			return nil, FormatError(err)
+		} else if n > 0 {
+			return nil, todo.Errorf(todo.ECONFLICT, "List with ID '%d' contains %d todos and cannot become a smart list.", l.ID, n)
+		}
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM list_shares WHERE list_id = ?`, l.ID).Scan(&n); err != nil {
+			return nil, FormatError(err)
+		} else if n > 0 {
+			return nil, todo.Errorf(todo.ECONFLICT, "List with ID '%d' is shared and cannot become a smart list.", l.ID)
+		}
+	}
+	l.Name = request.Name
+	l.WIPLimits = request.WIPLimits
+	l.Query = request.Query
+	l.Policies = request.Policies
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE lists
+		SET name = ?, wip_limits = ?, query = ?, policies = ?
+		WHERE id = ?
+	`, l.Name, formatLimits(l.WIPLimits), l.Query, formatPolicies(l.Policies), l.ID); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return l, tx.Commit()
+}
+
+func (s *ListService) DeleteList(ctx context.Context, request todo.DeleteListRequest) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := findListByID(ctx, tx, request.ID); err != nil {
+		return err
+	}
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos WHERE list_id = ?`, request.ID).Scan(&n); err != nil {
+		return FormatError(err)
+	} else if n > 0 && !request.Cascade {
+		return todo.Errorf(todo.ECONFLICT, "List with ID '%d' still contains %d todos.", request.ID, n)
+	}
+
+	// Todos are deleted one by one so subtasks in other lists are moved up
+	// to their nearest remaining ancestor.
+	ids, err := findTodoIDsInList(ctx, tx, request.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := removeTodo(ctx, tx, id, false); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM lists WHERE id = ?`, request.ID); err != nil {
+		return FormatError(err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *ListService) GetListByID(ctx context.Context, request todo.GetListByIDRequest) (*todo.List, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findListByID(ctx, tx, request.ID)
+}
+
+func (s *ListService) ListLists(ctx context.Context, request todo.ListListsRequest) ([]*todo.List, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+listColumns+`
+		FROM lists
+		WHERE owner_id = ?
+		ORDER BY id
+	`, todo.OwnerIDFromContext(ctx))
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	lists := make([]*todo.List, 0)
+	for rows.Next() {
+		l, err := scanList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+// listColumns lists the columns read by scanList, in order.
+const listColumns = `id, owner_id, name, wip_limits, query, policies, created_at`
+
+// scanList reads a list from a row selecting listColumns.
+func scanList(row scanner) (*todo.List, error) {
+	var limits, policies, createdAt string
+	l := &todo.List{}
+	if err := row.Scan(&l.ID, &l.OwnerID, &l.Name, &limits, &l.Query, &policies, &createdAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(limits), &l.WIPLimits); err != nil {
+		return nil, err
+	} else if len(l.WIPLimits) == 0 {
+		l.WIPLimits = nil
+	}
+	if err := json.Unmarshal([]byte(policies), &l.Policies); err != nil {
+		return nil, err
+	} else if len(l.Policies) == 0 {
+		l.Policies = nil
+	}
+	var err error
+	if l.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// findListByID is a helper function to fetch a list by ID.
+// Returns ENOTFOUND if list does not exist or is not owned by the caller.
+func findListByID(ctx context.Context, tx *Tx, id int) (*todo.List, error) {
+	l, err := scanList(tx.QueryRowContext(ctx, `
+		SELECT `+listColumns+`
+		FROM lists
+		WHERE id = ? AND owner_id = ?
+	`, id, todo.OwnerIDFromContext(ctx)))
+	if err != nil {
+		if err = FormatError(err); todo.ErrorCode(err) == todo.ENOTFOUND {
+			return nil, todo.Errorf(todo.ENOTFOUND, "List with ID '%d' could not be found.", id)
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+// createList creates a new list and assigns a new ID to l.
+func createList(ctx context.Context, tx *Tx, l *todo.List) error {
+	var last int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(seq), 0)
+		FROM sqlite_sequence
+		WHERE name = 'lists'
+	`).Scan(&last); err != nil {
+		return FormatError(err)
+	}
+	l.ID = todo.NextID(last, tx.db.Now())
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO lists (id, owner_id, name, wip_limits, query, policies, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, l.ID, l.OwnerID, l.Name, formatLimits(l.WIPLimits), l.Query, formatPolicies(l.Policies), l.CreatedAt.Format(time.RFC3339)); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// formatLimits returns the WIP limits of a list in the format they are stored in.
+func formatLimits(limits map[string]int) string {
+	if len(limits) == 0 {
+		return "{}"
+	}
+	buf, _ := json.Marshal(limits)
+	return string(buf)
+}
+
+// formatPolicies returns the policies of a list in the format they are stored in.
+func formatPolicies(policies []todo.Policy) string {
+	if len(policies) == 0 {
+		return "[]"
+	}
+	buf, _ := json.Marshal(policies)
+	return string(buf)
+}
+
+// findTodoIDsInList returns the IDs of the todos in a list, in order.
+func findTodoIDsInList(ctx context.Context, tx *Tx, listID int) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM todos WHERE list_id = ? ORDER BY id`, listID)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// checkListID returns EINVALID if a todo cannot be put in the list with the
+// given ID because the caller has no such list or it is a smart list. Zero
+// means no list.
+func checkListID(ctx context.Context, tx *Tx, id int) error {
+	if id == 0 {
+		return nil
+	} else if l, err := findListByID(ctx, tx, id); todo.ErrorCode(err) == todo.ENOTFOUND {
+		return todo.Errorf(todo.EINVALID, "List with ID '%d' does not exist.", id)
+	} else if err != nil {
+		return err
+	} else if l.Query != "" {
+		return todo.Errorf(todo.EINVALID, "Todos cannot be added to smart list with ID '%d'.", id)
+	}
+	return nil
+}
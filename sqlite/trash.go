@@ -0,0 +1,315 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.TrashService = (*TrashService)(nil)
+var _ todo.PurgeService = (*TrashService)(nil)
+
+// TrashService represents a service for restoring and purging deleted todos.
+type TrashService struct {
+	db *DB
+}
+
+// NewTrashService returns a new instance of TrashService.
+func NewTrashService(db *DB) *TrashService {
+	return &TrashService{db: db}
+}
+
+// trashed is a deleted todo along with the data deleted with it.
+type trashed struct {
+	Todo         *todo.Todo
+	DeletedWith  int
+	Shares       []*todo.Share
+	Dependencies []*todo.Dependency
+	Transitions  []*todo.Transition
+}
+
+func (s *TrashService) ListTrash(ctx context.Context, request todo.ListTrashRequest) ([]*todo.Todo, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entries, err := findTrash(ctx, tx, `owner_id = ? ORDER BY deleted_at DESC, todo_id DESC`, todo.OwnerIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	todos := make([]*todo.Todo, len(entries))
+	for i, e := range entries {
+		todos[i] = e.Todo
+	}
+	return todos, nil
+}
+
+func (s *TrashService) RestoreTodo(ctx context.Context, request todo.RestoreTodoRequest) (*todo.Todo, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var deletedWith int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT deleted_with
+		FROM trash
+		WHERE todo_id = ? AND owner_id = ?
+	`, request.ID, todo.OwnerIDFromContext(ctx)).Scan(&deletedWith); err != nil {
+		if err = FormatError(err); todo.ErrorCode(err) == todo.ENOTFOUND {
+			return nil, todo.Errorf(todo.ENOTFOUND, "Deleted todo with ID '%d' could not be found.", request.ID)
+		}
+		return nil, err
+	}
+	entries, err := findTrash(ctx, tx, `deleted_with = ? ORDER BY id`, deletedWith)
+	if err != nil {
+		return nil, err
+	}
+
+	// Subtasks are trashed after their parent, so a single pass finds every
+	// subtask deleted along with the todo.
+	restored := map[int]bool{request.ID: true}
+	var batch []*trashed
+	for _, e := range entries {
+		if e.Todo.ID == request.ID || restored[e.Todo.ParentID] {
+			restored[e.Todo.ID] = true
+			batch = append(batch, e)
+		}
+	}
+
+	for _, e := range batch {
+		t := e.Todo
+		t.DeletedAt = nil
+		t.Version++
+		if t.ParentID != 0 && !restored[t.ParentID] {
+			if _, err := findTodoByID(ctx, tx, t.ParentID); todo.ErrorCode(err) == todo.ENOTFOUND {
+				t.ParentID = 0
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if err := checkListID(ctx, tx, t.ListID); todo.ErrorCode(err) == todo.EINVALID {
+			t.ListID = 0
+		} else if err != nil {
+			return nil, err
+		} else if err := checkLimit(ctx, tx, t, nil); err != nil {
+			return nil, err
+		}
+		if err := createTodo(ctx, tx, t); err != nil {
+			return nil, err
+		}
+
+		for _, sh := range e.Shares {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT OR IGNORE INTO shares (todo_id, principal_id, role, created_at)
+				VALUES (?, ?, ?, ?)
+			`, sh.TodoID, sh.PrincipalID, sh.Role, formatTime(&sh.CreatedAt)); err != nil {
+				return nil, FormatError(err)
+			}
+		}
+		for _, tr := range e.Transitions {
+			if err := createTransition(ctx, tx, tr); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Dependencies are restored once both todos are.
+	for _, e := range batch {
+		for _, d := range e.Dependencies {
+			if ok, err := restorable(ctx, tx, d); err != nil {
+				return nil, err
+			} else if !ok {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT OR IGNORE INTO dependencies (todo_id, blocked_by_id, created_at)
+				VALUES (?, ?, ?)
+			`, d.TodoID, d.BlockedByID, formatTime(&d.CreatedAt)); err != nil {
+				return nil, FormatError(err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM trash WHERE todo_id = ?`, e.Todo.ID); err != nil {
+			return nil, FormatError(err)
+		}
+	}
+
+	t, err := findTodoByID(ctx, tx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	return t, tx.Commit()
+}
+
+func (s *TrashService) PurgeTrash(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM trash
+		WHERE id IN (SELECT id FROM trash WHERE deleted_at < ? ORDER BY deleted_at LIMIT ?)
+	`, formatTime(&deletedBefore), limit)
+	if err != nil {
+		return 0, FormatError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), tx.Commit()
+}
+
+// trashTodo moves the todo with the given ID and, if cascade is set, its
+// subtasks to the trash, parents first. The todos themselves are left for the
+// caller to delete.
+func trashTodo(ctx context.Context, tx *Tx, id int, cascade bool) error {
+	ids := []int{id}
+	if cascade {
+		rows, err := tx.QueryContext(ctx, `
+			WITH RECURSIVE subtree (id, depth) AS (
+				SELECT ?, 0
+				UNION
+				SELECT todos.id, subtree.depth + 1 FROM todos JOIN subtree ON todos.parent_id = subtree.id
+			)
+			SELECT id FROM subtree WHERE depth > 0 ORDER BY depth, id
+		`, id)
+		if err != nil {
+			return FormatError(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var childID int
+			if err := rows.Scan(&childID); err != nil {
+				return err
+			}
+			ids = append(ids, childID)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	for _, todoID := range ids {
+		t, err := findTodoByID(ctx, tx, todoID)
+		if err != nil {
+			return err
+		}
+		now := tx.now
+		t.Blocked = false
+		t.DeletedAt = &now
+
+		e := &trashed{Todo: t, DeletedWith: id}
+		if e.Shares, err = findShares(ctx, tx, todoID); err != nil {
+			return err
+		} else if e.Dependencies, err = findDependencies(ctx, tx, `dependencies.todo_id = ? OR dependencies.blocked_by_id = ?`, todoID, todoID); err != nil {
+			return err
+		} else if e.Transitions, err = findTransitions(ctx, tx, todoID); err != nil {
+			return err
+		} else if err := createTrash(ctx, tx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createTrash stores a trash entry.
+func createTrash(ctx context.Context, tx *Tx, e *trashed) error {
+	var columns [4][]byte
+	for i, v := range []interface{}{e.Todo, e.Shares, e.Dependencies, e.Transitions} {
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		columns[i] = buf
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO trash (todo_id, owner_id, deleted_with, deleted_at, todo, shares, dependencies, transitions)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.Todo.ID, e.Todo.OwnerID, e.DeletedWith, formatTime(e.Todo.DeletedAt),
+		string(columns[0]), string(columns[1]), string(columns[2]), string(columns[3]),
+	); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// findTrash returns the trash entries matching the where clause, which may
+// end with an ORDER BY clause.
+func findTrash(ctx context.Context, tx *Tx, where string, args ...interface{}) ([]*trashed, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT deleted_with, todo, shares, dependencies, transitions
+		FROM trash
+		WHERE `+where, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	entries := make([]*trashed, 0)
+	for rows.Next() {
+		var t, shares, deps, trans string
+		e := &trashed{}
+		if err := rows.Scan(&e.DeletedWith, &t, &shares, &deps, &trans); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal([]byte(t), &e.Todo); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal([]byte(shares), &e.Shares); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal([]byte(deps), &e.Dependencies); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal([]byte(trans), &e.Transitions); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// findShares returns the shares of the todo with the given ID.
+func findShares(ctx context.Context, tx *Tx, todoID int) ([]*todo.Share, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+shareColumns+`
+		FROM shares
+		JOIN todos ON todos.id = shares.todo_id
+		WHERE shares.todo_id = ?
+		ORDER BY shares.principal_id
+	`, todoID)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	shares := make([]*todo.Share, 0)
+	for rows.Next() {
+		sh, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, sh)
+	}
+	return shares, rows.Err()
+}
+
+// restorable returns true if both todos of d exist and restoring d would not
+// close a cycle.
+func restorable(ctx context.Context, tx *Tx, d *todo.Dependency) (bool, error) {
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM todos WHERE id IN (?, ?)
+	`, d.TodoID, d.BlockedByID).Scan(&n); err != nil {
+		return false, FormatError(err)
+	} else if n != 2 {
+		return false, nil
+	}
+	cycle, err := closesCycle(ctx, tx, d.TodoID, d.BlockedByID)
+	return !cycle, err
+}
@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+	"todo"
+)
+
+// Ensure service implements interface.
+var _ todo.WorkflowService = (*WorkflowService)(nil)
+
+// WorkflowService represents a service for the workflow todos follow.
+type WorkflowService struct {
+	db *DB
+}
+
+// NewWorkflowService returns a new instance of WorkflowService.
+func NewWorkflowService(db *DB) *WorkflowService {
+	return &WorkflowService{db: db}
+}
+
+func (s *WorkflowService) GetWorkflow(ctx context.Context, request todo.GetWorkflowRequest) (*todo.Workflow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.db.workflow(), nil
+}
+
+func (s *WorkflowService) ListTransitions(ctx context.Context, request todo.ListTransitionsRequest) ([]*todo.Transition, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := findTodoByID(ctx, tx, request.TodoID); err != nil {
+		return nil, err
+	}
+
+	return findTransitions(ctx, tx, request.TodoID)
+}
+
+// findTransitions returns the transitions of the todo with the given ID,
+// oldest first.
+func findTransitions(ctx context.Context, tx *Tx, todoID int) ([]*todo.Transition, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT todo_id, from_state, to_state, at
+		FROM transitions
+		WHERE todo_id = ?
+		ORDER BY id
+	`, todoID)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	trans := make([]*todo.Transition, 0)
+	for rows.Next() {
+		var at string
+		tr := &todo.Transition{}
+		if err := rows.Scan(&tr.TodoID, &tr.From, &tr.To, &at); err != nil {
+			return nil, err
+		} else if tr.At, err = time.Parse(time.RFC3339, at); err != nil {
+			return nil, err
+		}
+		trans = append(trans, tr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return trans, nil
+}
+
+// createTransition records a transition. Does nothing if tr is nil.
+func createTransition(ctx context.Context, tx *Tx, tr *todo.Transition) error {
+	if tr == nil {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO transitions (todo_id, from_state, to_state, at)
+		VALUES (?, ?, ?, ?)
+	`, tr.TodoID, tr.From, tr.To, formatTime(&tr.At)); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// checkLimit returns ECONFLICT if t, which changed from prev or is new if prev
+// is nil, cannot enter its state in its list because the list is at its limit
+// for that state.
+func checkLimit(ctx context.Context, tx *Tx, t, prev *todo.Todo) error {
+	if !t.EntersList(prev) {
+		return nil
+	}
+	l, err := findListByID(ctx, tx, t.ListID)
+	if todo.ErrorCode(err) == todo.ENOTFOUND {
+		// Reported as EINVALID when the todo is written.
+		return nil
+	} else if err != nil || len(l.WIPLimits) == 0 {
+		return err
+	}
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM todos
+		WHERE list_id = ? AND state = ? AND id != ?
+	`, t.ListID, t.State, t.ID).Scan(&n); err != nil {
+		return FormatError(err)
+	}
+	return l.CheckLimit(t.State, n)
+}
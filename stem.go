@@ -0,0 +1,341 @@
+package todo
+
+// Stem returns the Porter stem of a lowercase word. It follows the stemmer of
+// the SQLite FTS "porter" tokenizer so every backend indexes words the same
+// way: words shorter than 3 or longer than 20 bytes and words with characters
+// other than ASCII letters are not stemmed, and long words are shortened to
+// their first and last few bytes.
+func Stem(word string) string {
+	if len(word) < 3 || len(word) > 20 {
+		return copyStem(word)
+	}
+	for i := 0; i < len(word); i++ {
+		if c := word[i]; c < 'a' || c > 'z' {
+			return copyStem(word)
+		}
+	}
+
+	// The stemmer works on the word in reverse order, with room in front for
+	// endings longer than the ones they replace and zeros after it marking
+	// its start.
+	const slack = 4
+	p := &porter{b: make([]byte, slack+len(word)+5), z: slack}
+	for i := 0; i < len(word); i++ {
+		p.b[slack+len(word)-1-i] = word[i]
+	}
+	p.step1()
+	p.step2()
+	p.step3()
+	p.step4()
+	p.step5()
+
+	var out []byte
+	for i := p.z; p.b[i] != 0; i++ {
+		out = append(out, p.b[i])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// copyStem returns a word that is not stemmed. Words longer than 20 bytes, or
+// 6 bytes if they contain digits, are shortened to their first and last 10,
+// respectively 3, bytes.
+func copyStem(word string) string {
+	n := 10
+	for i := 0; i < len(word); i++ {
+		if c := word[i]; c >= '0' && c <= '9' {
+			n = 3
+			break
+		}
+	}
+	if len(word) > 2*n {
+		return word[:n] + word[len(word)-n:]
+	}
+	return word
+}
+
+// porter holds a word being stemmed. The word is stored in reverse order in b
+// starting at z and is terminated by a zero byte.
+type porter struct {
+	b []byte
+	z int
+}
+
+// letterTypes gives the type of each letter: 0 for vowels, 1 for consonants
+// and 2 for 'y', which depends on the letter before it.
+var letterTypes = [26]byte{
+	0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1, 1, 1, 1, 0, 1, 1, 1, 1, 1, 0, 1, 1, 1, 2, 1,
+}
+
+// consonant returns true if the letter at i is a consonant. As the word is
+// reversed, 'y' is a consonant unless it follows, i.e. is stored before, a
+// consonant.
+func (p *porter) consonant(i int) bool {
+	x := p.b[i]
+	if x == 0 {
+		return false
+	} else if t := letterTypes[x-'a']; t < 2 {
+		return t == 1
+	}
+	return p.b[i+1] == 0 || p.vowel(i+1)
+}
+
+func (p *porter) vowel(i int) bool {
+	x := p.b[i]
+	if x == 0 {
+		return false
+	} else if t := letterTypes[x-'a']; t < 2 {
+		return t == 0
+	}
+	return p.consonant(i + 1)
+}
+
+// measure returns the number of vowel-consonant sequences in the stem starting
+// at i, capped at 2.
+func (p *porter) measure(i int) int {
+	var m int
+	for m < 2 {
+		for p.vowel(i) {
+			i++
+		}
+		if p.b[i] == 0 {
+			return m
+		}
+		for p.consonant(i) {
+			i++
+		}
+		if p.b[i] == 0 {
+			return m
+		}
+		m++
+	}
+	return m
+}
+
+// measureOne returns true if the stem starting at i has a measure of exactly
+// one.
+func (p *porter) measureOne(i int) bool {
+	for p.vowel(i) {
+		i++
+	}
+	if p.b[i] == 0 {
+		return false
+	}
+	for p.consonant(i) {
+		i++
+	}
+	if p.b[i] == 0 {
+		return false
+	}
+	for p.vowel(i) {
+		i++
+	}
+	if p.b[i] == 0 {
+		return true
+	}
+	for p.consonant(i) {
+		i++
+	}
+	return p.b[i] == 0
+}
+
+func (p *porter) gt0(i int) bool { return p.measure(i) > 0 }
+func (p *porter) gt1(i int) bool { return p.measure(i) > 1 }
+
+// hasVowel returns true if the stem starting at i contains a vowel.
+func (p *porter) hasVowel(i int) bool {
+	for p.consonant(i) {
+		i++
+	}
+	return p.b[i] != 0
+}
+
+// doubleConsonant returns true if the stem starting at i ends with a double
+// consonant.
+func (p *porter) doubleConsonant(i int) bool {
+	return p.consonant(i) && p.b[i] == p.b[i+1]
+}
+
+// cvc returns true if the stem starting at i ends with a consonant, a vowel
+// and a consonant other than 'w', 'x' or 'y'.
+func (p *porter) cvc(i int) bool {
+	c := p.b[i]
+	return p.consonant(i) && c != 'w' && c != 'x' && c != 'y' && p.vowel(i+1) && p.consonant(i+2)
+}
+
+// stem replaces the ending from, given in reverse order, with to if the stem
+// before the ending satisfies cond, which may be nil. Returns true if the word
+// ends with from, even if cond is not satisfied.
+func (p *porter) stem(from, to string, cond func(i int) bool) bool {
+	i := p.z
+	for j := 0; j < len(from); j, i = j+1, i+1 {
+		if p.b[i] != from[j] {
+			return false
+		}
+	}
+	if cond != nil && !cond(i) {
+		return true
+	}
+	for j := 0; j < len(to); j++ {
+		i--
+		p.b[i] = to[j]
+	}
+	p.z = i
+	return true
+}
+
+// at returns the letter k positions from the end of the word, or zero.
+func (p *porter) at(k int) byte {
+	if p.z+k >= len(p.b) {
+		return 0
+	}
+	return p.b[p.z+k]
+}
+
+func (p *porter) step1() {
+	// Step 1a: plurals.
+	if p.at(0) == 's' {
+		if !p.stem("sess", "ss", nil) && !p.stem("sei", "i", nil) && !p.stem("ss", "ss", nil) {
+			p.z++
+		}
+	}
+
+	// Step 1b: past tenses and gerunds.
+	z := p.z
+	if p.stem("dee", "ee", p.gt0) {
+		// Done by the test.
+	} else if (p.stem("gni", "", p.hasVowel) || p.stem("de", "", p.hasVowel)) && p.z != z {
+		if p.stem("ta", "ate", nil) || p.stem("lb", "ble", nil) || p.stem("zi", "ize", nil) {
+			// Done by the test.
+		} else if c := p.at(0); p.doubleConsonant(p.z) && c != 'l' && c != 's' && c != 'z' {
+			p.z++
+		} else if p.measureOne(p.z) && p.cvc(p.z) {
+			p.z--
+			p.b[p.z] = 'e'
+		}
+	}
+
+	// Step 1c: a final 'y' after a vowel becomes 'i'.
+	if p.at(0) == 'y' && p.hasVowel(p.z+1) {
+		p.b[p.z] = 'i'
+	}
+}
+
+// stemAny tries the endings in order until one matches.
+func (p *porter) stemAny(cond func(i int) bool, endings ...string) {
+	for i := 0; i < len(endings); i += 2 {
+		if p.stem(endings[i], endings[i+1], cond) {
+			return
+		}
+	}
+}
+
+func (p *porter) step2() {
+	switch p.at(1) {
+	case 'a':
+		p.stemAny(p.gt0, "lanoita", "ate", "lanoit", "tion")
+	case 'c':
+		p.stemAny(p.gt0, "icne", "ence", "icna", "ance")
+	case 'e':
+		p.stemAny(p.gt0, "rezi", "ize")
+	case 'g':
+		p.stemAny(p.gt0, "igol", "log")
+	case 'l':
+		p.stemAny(p.gt0, "ilb", "ble", "illa", "al", "iltne", "ent", "ile", "e", "ilsuo", "ous")
+	case 'o':
+		p.stemAny(p.gt0, "noitazi", "ize", "noita", "ate", "rota", "ate")
+	case 's':
+		p.stemAny(p.gt0, "msila", "al", "ssenevi", "ive", "ssenluf", "ful", "ssensuo", "ous")
+	case 't':
+		p.stemAny(p.gt0, "itila", "al", "itivi", "ive", "itilib", "ble")
+	}
+}
+
+func (p *porter) step3() {
+	switch p.at(0) {
+	case 'e':
+		p.stemAny(p.gt0, "etaci", "ic", "evita", "", "ezila", "al")
+	case 'i':
+		p.stemAny(p.gt0, "itici", "ic")
+	case 'l':
+		p.stemAny(p.gt0, "laci", "ic", "luf", "")
+	case 's':
+		p.stemAny(p.gt0, "ssen", "")
+	}
+}
+
+func (p *porter) step4() {
+	z := p.z
+	switch p.at(1) {
+	case 'a':
+		if p.at(0) == 'l' && p.gt1(z+2) {
+			p.z += 2
+		}
+	case 'c':
+		if p.at(0) == 'e' && p.at(2) == 'n' && (p.at(3) == 'a' || p.at(3) == 'e') && p.gt1(z+4) {
+			p.z += 4
+		}
+	case 'e':
+		if p.at(0) == 'r' && p.gt1(z+2) {
+			p.z += 2
+		}
+	case 'i':
+		if p.at(0) == 'c' && p.gt1(z+2) {
+			p.z += 2
+		}
+	case 'l':
+		if p.at(0) == 'e' && p.at(2) == 'b' && (p.at(3) == 'a' || p.at(3) == 'i') && p.gt1(z+4) {
+			p.z += 4
+		}
+	case 'n':
+		if p.at(0) == 't' {
+			if p.at(2) == 'a' {
+				if p.gt1(z + 3) {
+					p.z += 3
+				}
+			} else if p.at(2) == 'e' {
+				p.stemAny(p.gt1, "tneme", "", "tnem", "", "tne", "")
+			}
+		}
+	case 'o':
+		if p.at(0) == 'u' {
+			if p.gt1(z + 2) {
+				p.z += 2
+			}
+		} else if p.at(3) == 's' || p.at(3) == 't' {
+			p.stem("noi", "", p.gt1)
+		}
+	case 's':
+		if p.at(0) == 'm' && p.at(2) == 'i' && p.gt1(z+3) {
+			p.z += 3
+		}
+	case 't':
+		p.stemAny(p.gt1, "eta", "", "iti", "")
+	case 'u':
+		if p.at(0) == 's' && p.at(2) == 'o' && p.gt1(z+3) {
+			p.z += 3
+		}
+	case 'v', 'z':
+		if p.at(0) == 'e' && p.at(2) == 'i' && p.gt1(z+3) {
+			p.z += 3
+		}
+	}
+}
+
+func (p *porter) step5() {
+	// Step 5a: a final 'e' is removed unless the stem is short.
+	if p.at(0) == 'e' {
+		if p.gt1(p.z + 1) {
+			p.z++
+		} else if p.measureOne(p.z+1) && !p.cvc(p.z+1) {
+			p.z++
+		}
+	}
+
+	// Step 5b: a final double 'l' is reduced.
+	if p.gt1(p.z) && p.at(0) == 'l' && p.at(1) == 'l' {
+		p.z++
+	}
+}
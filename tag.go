@@ -0,0 +1,155 @@
+package todo
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MaxTagLength is the maximum number of characters in a tag.
+const MaxTagLength = 50
+
+// TagService manages the tags of all todos of the caller at once. Tags are
+// set on individual todos with the Service.
+type TagService interface {
+	ListTags(ctx context.Context, request ListTagsRequest) ([]*Tag, error)
+	RenameTag(ctx context.Context, request RenameTagRequest) (*Tag, error)
+	MergeTags(ctx context.Context, request MergeTagsRequest) (*Tag, error)
+}
+
+// TagMiddleware describes a service middleware for the TagService.
+type TagMiddleware func(service TagService) TagService
+
+// Tag represents a tag in use and the number of todos carrying it.
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ListTagsRequest lists the tags used by the caller's todos, ordered by name.
+type ListTagsRequest struct{}
+
+// RenameTagRequest renames a tag on every todo of the caller. Renaming to a
+// tag that is already in use fails with ECONFLICT; use MergeTagsRequest to
+// combine tags instead. Every changed todo gets a new version.
+type RenameTagRequest struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName"`
+}
+
+// Validate normalizes the tags of the request and returns EINVALID if the
+// request is malformed.
+func (r *RenameTagRequest) Validate() (err error) {
+	if r.Name, err = NormalizeTag(r.Name); err != nil {
+		return err
+	} else if r.NewName, err = NormalizeTag(r.NewName); err != nil {
+		return err
+	} else if r.Name == r.NewName {
+		return Errorf(EINVALID, "Tag '%s' cannot be renamed to itself.", r.Name)
+	}
+	return nil
+}
+
+// MergeTagsRequest replaces the tags Names with the tag Into on every todo of
+// the caller. Into may be one of Names. Every changed todo gets a new version.
+type MergeTagsRequest struct {
+	Names []string `json:"names"`
+	Into  string   `json:"into"`
+}
+
+// Validate normalizes the tags of the request and returns EINVALID if the
+// request is malformed.
+func (r *MergeTagsRequest) Validate() (err error) {
+	if r.Names, err = NormalizeTags(r.Names); err != nil {
+		return err
+	} else if len(r.Names) == 0 {
+		return Errorf(EINVALID, "Tags to merge required.")
+	} else if r.Into, err = NormalizeTag(r.Into); err != nil {
+		return err
+	}
+	return nil
+}
+
+// NormalizeTag returns the canonical form of a tag: without a leading "#" and
+// in lower case. Returns EINVALID unless the tag consists of letters, digits
+// and the characters "-", "_", "/" and "." only.
+func NormalizeTag(s string) (string, error) {
+	tag := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	if tag == "" || len([]rune(tag)) > MaxTagLength {
+		return "", Errorf(EINVALID, "Invalid tag '%s'.", s)
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_/.", r) {
+			return "", Errorf(EINVALID, "Invalid tag '%s'.", s)
+		}
+	}
+	return tag, nil
+}
+
+// NormalizeTags normalizes every tag, removes duplicates and sorts the tags.
+// Returns nil if there are no tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	other := make([]string, 0, len(tags))
+	for _, s := range tags {
+		tag, err := NormalizeTag(s)
+		if err != nil {
+			return nil, err
+		} else if !seen[tag] {
+			seen[tag] = true
+			other = append(other, tag)
+		}
+	}
+	sort.Strings(other)
+	return other, nil
+}
+
+// HasTag returns true if t carries tag. The tags of t must be normalized.
+func (t *Todo) HasTag(tag string) bool {
+	i := sort.SearchStrings(t.Tags, tag)
+	return i < len(t.Tags) && t.Tags[i] == tag
+}
+
+// hasAnyTag returns true if t carries at least one of tags.
+func (t *Todo) hasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		if t.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceTags replaces any of names among the tags of t with into. Returns
+// true if the tags of t changed.
+func (t *Todo) ReplaceTags(names []string, into string) bool {
+	tags := make([]string, 0, len(t.Tags))
+	for _, tag := range t.Tags {
+		if !contains(names, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == len(t.Tags) {
+		return false
+	}
+
+	tags, _ = NormalizeTags(append(tags, into))
+	changed := strings.Join(tags, " ") != strings.Join(t.Tags, " ")
+	t.Tags = tags
+	return changed
+}
+
+// contains returns true if s is one of values.
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package todo_test
+
+import (
+	"reflect"
+	"testing"
+	"todo"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	if got, err := todo.NormalizeTags([]string{" #Work", "home", "work", "a/b.c-d_e"}); err != nil {
+		t.Fatal(err)
+	} else if want := []string{"a/b.c-d_e", "home", "work"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for _, s := range []string{"", "#", "two words", "semi;colon", string(make([]rune, todo.MaxTagLength+1))} {
+		if _, err := todo.NormalizeTags([]string{s}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Errorf("%q: unexpected error: %#v", s, err)
+		}
+	}
+}
+
+func TestTodo_ReplaceTags(t *testing.T) {
+	for _, tt := range []struct {
+		tags    []string
+		names   []string
+		into    string
+		want    []string
+		changed bool
+	}{
+		{[]string{"job", "urgent"}, []string{"job"}, "work", []string{"urgent", "work"}, true},
+		{[]string{"job", "work"}, []string{"job", "work"}, "work", []string{"work"}, true},
+		{[]string{"work"}, []string{"job", "work"}, "work", []string{"work"}, false},
+		{[]string{"home"}, []string{"job"}, "work", []string{"home"}, false},
+	} {
+		x := &todo.Todo{Tags: tt.tags}
+		if changed := x.ReplaceTags(tt.names, tt.into); changed != tt.changed || !reflect.DeepEqual(x.Tags, tt.want) {
+			t.Errorf("%v: got %v (changed=%v), want %v (changed=%v)", tt.tags, x.Tags, changed, tt.want, tt.changed)
+		}
+	}
+}
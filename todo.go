@@ -1,13 +1,20 @@
 package todo
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Service interface {
 	CreateTodo(ctx context.Context, request CreateTodoRequest) (*Todo, error)
 	UpdateTodo(ctx context.Context, request UpdateTodoRequest) (*Todo, error)
+	PatchTodo(ctx context.Context, request PatchTodoRequest) (*Todo, error)
 	DeleteTodo(ctx context.Context, request DeleteTodoRequest) error
 	GetTodoByID(ctx context.Context, request GetTodoByIDRequest) (*Todo, error)
-	GetAllTodos(ctx context.Context) ([]*Todo, error)
+	ListTodos(ctx context.Context, request ListTodosRequest) (*ListTodosResponse, error)
+	CompleteTodo(ctx context.Context, request CompleteTodoRequest) (*CompleteTodoResponse, error)
+	MoveTodo(ctx context.Context, request MoveTodoRequest) (*Todo, error)
+	BatchTodos(ctx context.Context, request BatchTodosRequest) (*BatchTodosResponse, error)
 }
 
 // Middleware describes a service (as opposed to endpoint) middleware for the Service.
@@ -16,16 +23,153 @@ type Middleware func(service Service) Service
 type CreateTodoRequest struct {
 	Value    string `json:"value"`
 	Complete bool   `json:"complete"`
+
+	// Optional workflow state. Defaults to the first state of the workflow,
+	// or the first closed one if Complete is set. See Todo.State.
+	State string `json:"state"`
+
+	// List the todo is added to. Zero if the todo is not in a list.
+	ListID int `json:"listId"`
+
+	// Todo the new todo is a subtask of. Zero for a top-level todo.
+	ParentID int `json:"parentId"`
+
+	// Optional due date, reminder & time zone. See Todo.
+	DueAt    *time.Time `json:"dueAt"`
+	RemindAt *time.Time `json:"remindAt"`
+	TimeZone string     `json:"timeZone"`
+
+	// Optional RFC 5545 recurrence rule. Requires a due date.
+	Recurrence string `json:"recurrence"`
+
+	// Optional tags, e.g. "work" or "#errand". See Todo.Tags.
+	Tags []string `json:"tags"`
+
+	// Optional priority. See Todo.Priority.
+	Priority int `json:"priority"`
 }
 
 type UpdateTodoRequest struct {
 	ID       int    `json:"id"`
 	Value    string `json:"value"`
 	Complete bool   `json:"complete"`
+	State    string `json:"state"`
+	ListID   int    `json:"listId"`
+	ParentID int    `json:"parentId"`
+
+	DueAt      *time.Time `json:"dueAt"`
+	RemindAt   *time.Time `json:"remindAt"`
+	TimeZone   string     `json:"timeZone"`
+	Recurrence string     `json:"recurrence"`
+	Tags       []string   `json:"tags"`
+	Priority   int        `json:"priority"`
+
+	// Whether a change to a recurring todo applies to this occurrence only
+	// or to all future occurrences. See ScopeThis & ScopeFuture.
+	Scope string `json:"scope"`
+
+	// Expected current version of the todo. If non-zero and the todo has
+	// been modified since, the update fails with ECONFLICT.
+	Version int `json:"version"`
+}
+
+// PatchTodoRequest represents a partial update of a todo. Only non-nil fields
+// are changed. Dates are removed by setting them to the zero time.
+type PatchTodoRequest struct {
+	ID         int        `json:"id"`
+	Value      *string    `json:"value"`
+	Complete   *bool      `json:"complete"`
+	State      *string    `json:"state"`
+	ListID     *int       `json:"listId"`
+	ParentID   *int       `json:"parentId"`
+	DueAt      *time.Time `json:"dueAt"`
+	RemindAt   *time.Time `json:"remindAt"`
+	TimeZone   *string    `json:"timeZone"`
+	Recurrence *string    `json:"recurrence"`
+	Tags       *[]string  `json:"tags"`
+	Priority   *int       `json:"priority"`
+
+	// Archives the todo as of the given time, or unarchives it if zero.
+	ArchivedAt *time.Time `json:"archivedAt"`
+
+	// See UpdateTodoRequest.Scope.
+	Scope string `json:"scope"`
+
+	// Expected current version of the todo. See UpdateTodoRequest.Version.
+	Version int `json:"version"`
+}
+
+// Apply updates t with the fields set on the request.
+func (r *PatchTodoRequest) Apply(t *Todo) {
+	if v := r.Value; v != nil {
+		t.Value = *v
+	}
+	if v := r.Complete; v != nil {
+		t.Complete = *v
+	}
+	if v := r.State; v != nil {
+		t.State = *v
+	}
+	if v := r.ListID; v != nil {
+		t.ListID = *v
+	}
+	if v := r.ParentID; v != nil {
+		t.ParentID = *v
+	}
+	if v := r.DueAt; v != nil {
+		t.DueAt = v
+	}
+	if v := r.RemindAt; v != nil {
+		t.RemindAt = v
+	}
+	if v := r.TimeZone; v != nil {
+		t.TimeZone = *v
+	}
+	if v := r.Recurrence; v != nil {
+		t.Recurrence = *v
+	}
+	if v := r.Tags; v != nil {
+		t.Tags = *v
+	}
+	if v := r.Priority; v != nil {
+		t.Priority = *v
+	}
+	if v := r.ArchivedAt; v != nil {
+		t.ArchivedAt = v
+	}
+}
+
+// CompleteTodoRequest marks a todo as complete. If the todo recurs, its next
+// occurrence is created.
+type CompleteTodoRequest struct {
+	ID int `json:"id"`
+
+	// Set to complete the todo even if it is blocked by open todos.
+	// Otherwise completing a blocked todo fails with ECONFLICT.
+	Force bool `json:"force"`
+
+	// Expected current version of the todo. See UpdateTodoRequest.Version.
+	Version int `json:"version"`
+}
+
+type CompleteTodoResponse struct {
+	Todo *Todo `json:"todo"`
+
+	// Next occurrence of a recurring todo. Nil if the todo does not recur
+	// or its series has ended.
+	Next *Todo `json:"next,omitempty"`
 }
 
+// DeleteTodoRequest moves a todo to the trash, see TrashService.
 type DeleteTodoRequest struct {
 	ID int `json:"id"`
+
+	// Set to also delete the subtasks of the todo. Otherwise they are moved
+	// up to the todo's parent.
+	Cascade bool `json:"cascade"`
+
+	// Expected current version of the todo. See UpdateTodoRequest.Version.
+	Version int `json:"version"`
 }
 
 type GetTodoByIDRequest struct {
@@ -33,7 +177,140 @@ type GetTodoByIDRequest struct {
 }
 
 type Todo struct {
-	ID       int    `json:"id"`
-	Value    string `json:"value"`
-	Complete bool   `json:"complete"`
+	ID int `json:"id"`
+
+	// ID of the principal who created the todo. Todos are only visible to
+	// their owner and to principals they are shared with.
+	OwnerID string `json:"ownerId"`
+
+	// List the todo belongs to. Zero if the todo is not in a list.
+	ListID int `json:"listId"`
+
+	// Todo this todo is a subtask of. Zero for a top-level todo. A parent
+	// must be owned by the same owner and cannot be one of the todo's own
+	// subtasks.
+	ParentID int `json:"parentId,omitempty"`
+
+	Value string `json:"value"`
+
+	// State of the todo in the workflow, and when it entered that state.
+	// Complete is true in the closed states of the workflow and kept for
+	// clients unaware of states. See Workflow.Transition. The state is set
+	// by hand and unrelated to Blocked.
+	State          string     `json:"state"`
+	StateChangedAt *time.Time `json:"stateChangedAt,omitempty"`
+	Complete       bool       `json:"complete"`
+
+	// When the todo is due & when its owner wants to be reminded of it.
+	// Both are optional and stored in UTC at second precision.
+	DueAt    *time.Time `json:"dueAt,omitempty"`
+	RemindAt *time.Time `json:"remindAt,omitempty"`
+
+	// IANA name of the time zone the dates were entered in, e.g.
+	// "Europe/Berlin", so clients can display them in that zone.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// When the reminder was sent. Cleared whenever RemindAt changes.
+	RemindedAt *time.Time `json:"remindedAt,omitempty"`
+
+	// RFC 5545 recurrence rule of an open occurrence of a recurring todo.
+	// When the occurrence is completed, the rule moves on to the next one.
+	Recurrence string `json:"recurrence,omitempty"`
+
+	// ID of the first todo of the series a recurring todo belongs to, and
+	// the position of the todo in the series starting at 1.
+	SeriesID   int `json:"seriesId,omitempty"`
+	Occurrence int `json:"occurrence,omitempty"`
+
+	// Tags of the todo in lower case without a leading "#", sorted and
+	// without duplicates. Tags are scoped to the owner of the todo.
+	Tags []string `json:"tags,omitempty"`
+
+	// Priority of the todo from PriorityNone to PriorityHigh.
+	Priority int `json:"priority"`
+
+	// Position of the todo in the manual order of its owner's todos. It is
+	// assigned when the todo is created, placing it last, and only changes
+	// when the todo is moved. See PositionBetween.
+	Position string `json:"position"`
+
+	// Set if the todo is blocked by open todos. It is computed from the
+	// todo's dependencies whenever the todo is read and never stored.
+	Blocked bool `json:"blocked"`
+
+	// Version starts at 1 and is incremented on every change to the todo.
+	Version int `json:"version"`
+
+	// When the todo was archived, either by hand or by a policy of its list.
+	// Archived todos are kept as they are but left out of listings unless
+	// asked for, see ListTodosRequest.Archived.
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+
+	// When the todo was deleted. Only set on todos in the trash, see
+	// TrashService.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// CheckVersion returns ECONFLICT if version is set and does not match the
+// current version of t.
+func (t *Todo) CheckVersion(version int) error {
+	if version != 0 && version != t.Version {
+		return Errorf(ECONFLICT, "Todo with ID '%d' has been modified (expected version %d, current version %d).", t.ID, version, t.Version)
+	}
+	return nil
+}
+
+// ListTodosRequest represents a filter, sort order and page used by ListTodos.
+type ListTodosRequest struct {
+	// Filtering fields. Zero values match every todo that is not archived.
+	ListID    int        `json:"listId"`
+	Complete  *bool      `json:"complete"`
+	State     string     `json:"state"`
+	Contains  string     `json:"contains"`
+	DueBefore *time.Time `json:"dueBefore"`
+	Overdue   bool       `json:"overdue"`
+	SeriesID  int        `json:"seriesId"`
+	ParentID  int        `json:"parentId"`
+
+	// Set to list archived todos instead of the others.
+	Archived bool `json:"archived"`
+
+	// Tag filters. Todos must carry all of Tags, at least one of AnyTags
+	// unless it is empty, and none of NotTags.
+	Tags    []string `json:"tags"`
+	AnyTags []string `json:"anyTags"`
+	NotTags []string `json:"notTags"`
+
+	// Optional query todos must also match, see ParseQuery. The ORDER BY
+	// clause of the query, if any, replaces SortBy & SortDirection.
+	Query string `json:"query"`
+
+	// Query of the smart list set by UseList, and the condition of both
+	// queries set by Normalize.
+	listQuery string
+	where     Expr
+
+	// Field & direction to sort by. Defaults to SortByID in ascending order.
+	// SortByPosition lists todos in their manual order.
+	SortBy        string `json:"sortBy"`
+	SortDirection string `json:"sortDirection"`
+
+	// Maximum number of todos to return. Defaults to DefaultListLimit and may
+	// not exceed MaxListLimit.
+	Limit int `json:"limit"`
+
+	// Opaque cursor returned as NextCursor by a previous call. It must be used
+	// with the same sort order it was issued for.
+	Cursor string `json:"cursor"`
+}
+
+// ListTodosResponse represents a single page of todos.
+type ListTodosResponse struct {
+	Todos []*Todo `json:"todos"`
+
+	// Cursor of the next page. Empty if this is the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+
+	// Number of todos matching the filter across all pages.
+	TotalCount int `json:"totalCount"`
 }
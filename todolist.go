@@ -0,0 +1,117 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+type ListService interface {
+	CreateList(ctx context.Context, request CreateListRequest) (*List, error)
+	UpdateList(ctx context.Context, request UpdateListRequest) (*List, error)
+	DeleteList(ctx context.Context, request DeleteListRequest) error
+	GetListByID(ctx context.Context, request GetListByIDRequest) (*List, error)
+	ListLists(ctx context.Context, request ListListsRequest) ([]*List, error)
+}
+
+// ListMiddleware describes a service middleware for the ListService.
+type ListMiddleware func(service ListService) ListService
+
+// List groups todos, e.g. by project. Every todo belongs to at most one list.
+type List struct {
+	ID int `json:"id"`
+
+	// ID of the principal who created the list. Lists are only visible to
+	// their owner.
+	OwnerID string `json:"ownerId"`
+
+	Name string `json:"name"`
+
+	// Maximum number of todos in the list per workflow state. Moving more
+	// todos into a state fails with ECONFLICT. States without a limit are
+	// unlimited.
+	WIPLimits map[string]int `json:"wipLimits,omitempty"`
+
+	// Query of a smart list, see ParseQuery. Smart lists contain the todos
+	// matching their query instead of todos added to them, so they cannot
+	// have todos of their own or WIP limits. Empty for a regular list.
+	Query string `json:"query,omitempty"`
+
+	// Retention policies archiving or purging the todos of the list, see
+	// Policy. Smart lists cannot have policies.
+	Policies []Policy `json:"policies,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreateListRequest struct {
+	Name      string         `json:"name"`
+	WIPLimits map[string]int `json:"wipLimits"`
+
+	// Optional query making the list a smart list. See List.Query.
+	Query string `json:"query"`
+
+	// Optional retention policies. See List.Policies.
+	Policies []Policy `json:"policies"`
+}
+
+// Validate returns EINVALID if the request is malformed.
+func (r *CreateListRequest) Validate() error {
+	if r.Name == "" {
+		return Errorf(EINVALID, "List name required.")
+	} else if err := ValidatePolicies(r.Policies); err != nil {
+		return err
+	}
+	return validateSmartList(r.Query, r.WIPLimits, r.Policies)
+}
+
+// UpdateListRequest replaces the name, limits, query and policies of a list. Lowering a
+// limit below the number of todos already in a state only keeps further todos
+// from entering the state. Lists that contain todos or are shared cannot
+// become smart lists.
+type UpdateListRequest struct {
+	ID        int            `json:"id"`
+	Name      string         `json:"name"`
+	WIPLimits map[string]int `json:"wipLimits"`
+	Query     string         `json:"query"`
+	Policies  []Policy       `json:"policies"`
+}
+
+// Validate returns EINVALID if the request is malformed.
+func (r *UpdateListRequest) Validate() error {
+	if r.Name == "" {
+		return Errorf(EINVALID, "List name required.")
+	} else if err := ValidatePolicies(r.Policies); err != nil {
+		return err
+	}
+	return validateSmartList(r.Query, r.WIPLimits, r.Policies)
+}
+
+// validateSmartList returns EINVALID if query is not a valid query or a smart
+// list has WIP limits or policies.
+func validateSmartList(query string, limits map[string]int, policies []Policy) error {
+	if query == "" {
+		return nil
+	} else if _, err := ParseQuery(query); err != nil {
+		return err
+	} else if len(limits) > 0 {
+		return Errorf(EINVALID, "Smart lists cannot have WIP limits.")
+	} else if len(policies) > 0 {
+		return Errorf(EINVALID, "Smart lists cannot have policies.")
+	}
+	return nil
+}
+
+// DeleteListRequest deletes a list. Lists that still contain todos are only
+// deleted, together with their todos, if Cascade is set. Otherwise deleting
+// them fails with ECONFLICT.
+type DeleteListRequest struct {
+	ID      int  `json:"id"`
+	Cascade bool `json:"cascade"`
+}
+
+type GetListByIDRequest struct {
+	ID int `json:"id"`
+}
+
+// ListListsRequest lists all lists of the caller, ordered by ID.
+type ListListsRequest struct{}
@@ -0,0 +1,134 @@
+package todotest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"todo"
+)
+
+func testBatch(t *testing.T, newService Factory) {
+	// value returns a pointer to v for patches.
+	value := func(v string) *string { return &v }
+
+	t.Run("Atomic", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+
+		resp, err := s.BatchTodos(ctx, todo.BatchTodosRequest{Ops: []todo.BatchOp{
+			{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "c"}},
+			{Op: todo.BatchUpdate, Update: &todo.UpdateTodoRequest{ID: a.ID, Value: "x", Complete: true}},
+			{Op: todo.BatchPatch, Patch: &todo.PatchTodoRequest{ID: a.ID, Value: value("y")}},
+			{Op: todo.BatchDelete, Delete: &todo.DeleteTodoRequest{ID: b.ID}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(resp.Results) != 4 {
+			t.Fatalf("unexpected results: %#v", resp.Results)
+		}
+		c := resp.Results[0].Todo
+		if c == nil || c.Value != "c" {
+			t.Fatalf("unexpected todo: %#v", c)
+		} else if got := resp.Results[2].Todo; got == nil || got.Value != "y" || !got.Complete || got.Version != 3 {
+			t.Fatalf("unexpected todo: %#v", got)
+		} else if resp.Results[3].Todo != nil {
+			t.Fatalf("unexpected todo: %#v", resp.Results[3].Todo)
+		}
+		if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{}); !equalIDs(got.Todos, a.ID, c.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{a.ID, c.ID})
+		}
+	})
+
+	t.Run("AtomicRollback", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+
+		_, err := s.BatchTodos(ctx, todo.BatchTodosRequest{Mode: todo.BatchAtomic, Ops: []todo.BatchOp{
+			{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "b"}},
+			{Op: todo.BatchPatch, Patch: &todo.PatchTodoRequest{ID: a.ID, Value: value("x")}},
+			{Op: todo.BatchDelete, Delete: &todo.DeleteTodoRequest{ID: a.ID + 1000}},
+		}})
+		if todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if msg := todo.ErrorMessage(err); !strings.HasPrefix(msg, "Operation 2: ") {
+			t.Fatalf("unexpected message: %q", msg)
+		}
+
+		// Nothing was changed, and the todo can still be changed afterwards.
+		if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{}); !equalIDs(got.Todos, a.ID) || got.Todos[0].Value != "a" || got.Todos[0].Version != 1 {
+			t.Fatalf("unexpected todos: %#v", got.Todos)
+		}
+		if got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, Value: value("x")}); err != nil {
+			t.Fatal(err)
+		} else if got.Version != 2 {
+			t.Fatalf("Version=%d, want 2", got.Version)
+		}
+
+		// Deleted todos come back with their subtasks and tags.
+		p := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "p", Tags: []string{"work"}})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c", ParentID: p.ID})
+		if _, err := s.BatchTodos(ctx, todo.BatchTodosRequest{Ops: []todo.BatchOp{
+			{Op: todo.BatchDelete, Delete: &todo.DeleteTodoRequest{ID: p.ID}},
+			{Op: todo.BatchPatch, Patch: &todo.PatchTodoRequest{ID: c.ID, Tags: &[]string{"work"}}},
+			{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "x", Priority: todo.PriorityHigh + 1}},
+		}}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+		if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{Tags: []string{"work"}}); !equalIDs(got.Todos, p.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{p.ID})
+		} else if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: c.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.ParentID != p.ID || got.Version != 1 || len(got.Tags) != 0 {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+	})
+
+	t.Run("BestEffort", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+
+		resp, err := s.BatchTodos(ctx, todo.BatchTodosRequest{Mode: todo.BatchBestEffort, Ops: []todo.BatchOp{
+			{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "x", Priority: todo.PriorityHigh + 1}},
+			{Op: todo.BatchPatch, Patch: &todo.PatchTodoRequest{ID: a.ID, Value: value("x")}},
+			{Op: todo.BatchUpdate, Update: &todo.UpdateTodoRequest{ID: a.ID, Value: "y", Version: 1}},
+			{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "b"}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(resp.Results) != 4 {
+			t.Fatalf("unexpected results: %#v", resp.Results)
+		}
+		for i, want := range []string{todo.EINVALID, "", todo.ECONFLICT, ""} {
+			if r := resp.Results[i]; todo.ErrorCode(r.Err) != want {
+				t.Fatalf("%d: unexpected error: %#v", i, r.Err)
+			} else if (r.Todo == nil) != (want != "") {
+				t.Fatalf("%d: unexpected todo: %#v", i, r.Todo)
+			}
+		}
+
+		b := resp.Results[3].Todo
+		if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{}); !equalIDs(got.Todos, a.ID, b.ID) || got.Todos[0].Value != "x" {
+			t.Fatalf("unexpected todos: %#v", got.Todos)
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		for _, request := range []todo.BatchTodosRequest{
+			{},
+			{Mode: "eventually", Ops: []todo.BatchOp{{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "a"}}}},
+			{Ops: []todo.BatchOp{{Op: "complete", Create: &todo.CreateTodoRequest{Value: "a"}}}},
+			{Ops: []todo.BatchOp{{Op: todo.BatchDelete, Create: &todo.CreateTodoRequest{Value: "a"}}}},
+			{Ops: []todo.BatchOp{{Op: todo.BatchCreate, Create: &todo.CreateTodoRequest{Value: "a"}, Delete: &todo.DeleteTodoRequest{ID: 1}}}},
+			{Ops: make([]todo.BatchOp, todo.MaxBatchOps+1)},
+		} {
+			if _, err := s.BatchTodos(ctx, request); todo.ErrorCode(err) != todo.EINVALID {
+				t.Fatalf("unexpected error for %#v: %#v", request, err)
+			}
+		}
+		if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{}); len(got.Todos) != 0 {
+			t.Fatalf("unexpected todos: %#v", got.Todos)
+		}
+	})
+}
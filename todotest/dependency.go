@@ -0,0 +1,192 @@
+package todotest
+
+import (
+	"context"
+	"testing"
+	"todo"
+)
+
+// DependencyFactory returns new, empty todo & dependency services sharing the
+// same storage for a single test.
+type DependencyFactory func(t *testing.T) (todo.Service, todo.DependencyService)
+
+// TestDependencyService runs the todo.DependencyService contract against
+// services returned by newServices. Each subtest receives its own services.
+func TestDependencyService(t *testing.T, newServices DependencyFactory) {
+	t.Run("CreateDependency", func(t *testing.T) {
+		s, deps := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+
+		d := MustCreateDependency(t, ctx, deps, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: b.ID})
+		if d.TodoID != a.ID || d.BlockedByID != b.ID || d.CreatedAt.IsZero() {
+			t.Fatalf("unexpected dependency: %#v", d)
+		}
+
+		// Creating the dependency again is a no-op.
+		if other := MustCreateDependency(t, ctx, deps, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: b.ID}); !other.CreatedAt.Equal(d.CreatedAt) {
+			t.Fatalf("unexpected dependency: %#v", other)
+		}
+
+		if got := MustListDependencies(t, ctx, deps, todo.ListDependenciesRequest{TodoID: b.ID}); len(got) != 1 || got[0].TodoID != a.ID {
+			t.Fatalf("unexpected dependencies: %#v", got)
+		} else if got := MustListDependencies(t, ctx, deps, todo.ListDependenciesRequest{}); len(got) != 1 {
+			t.Fatalf("unexpected dependencies: %#v", got)
+		}
+
+		// The blocked todo reports its state until its blocker is complete.
+		if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if !got.Blocked {
+			t.Fatal("expected todo to be blocked")
+		}
+		if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{}); resp.Todos[0].Blocked {
+			t.Fatal("expected todo not to be blocked")
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		s, deps := newServices(t)
+		alice := NewContextWithPrincipalID(context.Background(), "alice")
+		bob := NewContextWithPrincipalID(context.Background(), "bob")
+		a := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b"})
+		c := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "c"})
+		x := MustCreateTodo(t, bob, s, todo.CreateTodoRequest{Value: "x"})
+		MustCreateDependency(t, alice, deps, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: b.ID})
+		MustCreateDependency(t, alice, deps, todo.CreateDependencyRequest{TodoID: b.ID, BlockedByID: c.ID})
+
+		for _, req := range []todo.CreateDependencyRequest{
+			{TodoID: a.ID, BlockedByID: a.ID},
+			{TodoID: b.ID, BlockedByID: a.ID},
+			{TodoID: c.ID, BlockedByID: a.ID},
+			{TodoID: a.ID, BlockedByID: x.ID},
+		} {
+			if _, err := deps.CreateDependency(alice, req); todo.ErrorCode(err) != todo.EINVALID {
+				t.Fatalf("%#v: unexpected error: %#v", req, err)
+			}
+		}
+
+		// Todos of other owners are not found.
+		if _, err := deps.CreateDependency(bob, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: x.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := deps.ListDependencies(bob, todo.ListDependenciesRequest{TodoID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if got := MustListDependencies(t, bob, deps, todo.ListDependenciesRequest{}); len(got) != 0 {
+			t.Fatalf("unexpected dependencies: %#v", got)
+		}
+	})
+
+	t.Run("DeleteDependency", func(t *testing.T) {
+		s, deps := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+		MustCreateDependency(t, ctx, deps, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: b.ID})
+		MustCreateDependency(t, ctx, deps, todo.CreateDependencyRequest{TodoID: c.ID, BlockedByID: b.ID})
+
+		if err := deps.DeleteDependency(ctx, todo.DeleteDependencyRequest{TodoID: a.ID, BlockedByID: b.ID}); err != nil {
+			t.Fatal(err)
+		} else if err := deps.DeleteDependency(ctx, todo.DeleteDependencyRequest{TodoID: a.ID, BlockedByID: b.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		// Deleting a todo removes its dependencies.
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		} else if got := MustListDependencies(t, ctx, deps, todo.ListDependenciesRequest{}); len(got) != 0 {
+			t.Fatalf("unexpected dependencies: %#v", got)
+		}
+	})
+
+	t.Run("CompleteBlocked", func(t *testing.T) {
+		s, deps := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		MustCreateDependency(t, ctx, deps, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: b.ID})
+
+		if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: a.ID, Value: "a", Complete: true}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		if resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID, Force: true}); err != nil {
+			t.Fatal(err)
+		} else if !resp.Todo.Complete || !resp.Todo.Blocked {
+			t.Fatalf("unexpected todo: %#v", resp.Todo)
+		}
+	})
+
+	t.Run("ListActionableTodos", func(t *testing.T) {
+		s, deps := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+		d := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "d"})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "e", Complete: true})
+
+		// a waits on c, which waits on d.
+		MustCreateDependency(t, ctx, deps, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: c.ID})
+		MustCreateDependency(t, ctx, deps, todo.CreateDependencyRequest{TodoID: c.ID, BlockedByID: d.ID})
+
+		if got := MustListActionableTodos(t, ctx, deps, todo.ListActionableTodosRequest{}); !equalIDs(got, b.ID, d.ID) {
+			t.Fatalf("actionable=%v", ids(got))
+		}
+		if got := MustListActionableTodos(t, ctx, deps, todo.ListActionableTodosRequest{IncludeBlocked: true}); !equalIDs(got, b.ID, d.ID, c.ID, a.ID) {
+			t.Fatalf("plan=%v", ids(got))
+		} else if got[0].Blocked || !got[3].Blocked {
+			t.Fatalf("unexpected todos: %#v", got)
+		}
+		if got := MustListActionableTodos(t, ctx, deps, todo.ListActionableTodosRequest{IncludeBlocked: true, Limit: 3}); !equalIDs(got, b.ID, d.ID, c.ID) {
+			t.Fatalf("plan=%v", ids(got))
+		}
+
+		if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: d.ID}); err != nil {
+			t.Fatal(err)
+		} else if got := MustListActionableTodos(t, ctx, deps, todo.ListActionableTodosRequest{}); !equalIDs(got, b.ID, c.ID) {
+			t.Fatalf("actionable=%v", ids(got))
+		}
+
+		if _, err := deps.ListActionableTodos(ctx, todo.ListActionableTodosRequest{Limit: -1}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
+
+// MustCreateDependency creates a dependency or fails the test.
+func MustCreateDependency(tb testing.TB, ctx context.Context, s todo.DependencyService, request todo.CreateDependencyRequest) *todo.Dependency {
+	tb.Helper()
+	d, err := s.CreateDependency(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return d
+}
+
+// MustListDependencies lists dependencies or fails the test.
+func MustListDependencies(tb testing.TB, ctx context.Context, s todo.DependencyService, request todo.ListDependenciesRequest) []*todo.Dependency {
+	tb.Helper()
+	deps, err := s.ListDependencies(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return deps
+}
+
+// MustListActionableTodos lists actionable todos or fails the test.
+func MustListActionableTodos(tb testing.TB, ctx context.Context, s todo.DependencyService, request todo.ListActionableTodosRequest) []*todo.Todo {
+	tb.Helper()
+	todos, err := s.ListActionableTodos(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return todos
+}
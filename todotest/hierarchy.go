@@ -0,0 +1,118 @@
+package todotest
+
+import (
+	"context"
+	"testing"
+	"todo"
+)
+
+func testHierarchy(t *testing.T, newService Factory) {
+	t.Run("Children", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		parent := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "parent"})
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", ParentID: parent.ID})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", ParentID: parent.ID})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c", ParentID: a.ID})
+
+		if a.ParentID != parent.ID {
+			t.Fatalf("ParentID=%d, want %d", a.ParentID, parent.ID)
+		}
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{ParentID: parent.ID}); !equalIDs(resp.Todos, a.ID, b.ID) {
+			t.Fatalf("children=%v", ids(resp.Todos))
+		}
+		if _, err := s.ListTodos(ctx, todo.ListTodosRequest{ParentID: parent.ID + 1}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		s := newService(t)
+		alice := NewContextWithPrincipalID(context.Background(), "alice")
+		bob := NewContextWithPrincipalID(context.Background(), "bob")
+		a := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b", ParentID: a.ID})
+		c := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "c", ParentID: b.ID})
+
+		// Parents must exist and belong to the same owner.
+		if _, err := s.CreateTodo(alice, todo.CreateTodoRequest{Value: "x", ParentID: c.ID + 1}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := s.CreateTodo(bob, todo.CreateTodoRequest{Value: "x", ParentID: a.ID}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		// Cycles are rejected, however long they are.
+		for _, id := range []int{a.ID, c.ID} {
+			parentID := id
+			if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, ParentID: &parentID}); todo.ErrorCode(err) != todo.EINVALID {
+				t.Fatalf("parent %d: unexpected error: %#v", id, err)
+			}
+		}
+		if _, err := s.UpdateTodo(alice, todo.UpdateTodoRequest{ID: b.ID, Value: "b", ParentID: c.ID}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		// Moving a subtree elsewhere is fine.
+		parentID := 0
+		if got, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: b.ID, ParentID: &parentID}); err != nil {
+			t.Fatal(err)
+		} else if got.ParentID != 0 {
+			t.Fatalf("ParentID=%d, want 0", got.ParentID)
+		}
+	})
+
+	t.Run("OpenChildren", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		parent := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "parent"})
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", ParentID: parent.ID})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", ParentID: parent.ID, Complete: true})
+
+		if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: parent.ID}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: parent.ID, Value: "parent", Complete: true}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		// Other changes to the parent are still allowed.
+		value := "parent2"
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: parent.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: parent.ID}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("DeleteReparents", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", ParentID: a.ID})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c", ParentID: b.ID})
+
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: c.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.ParentID != a.ID || got.Version != c.Version+1 {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+	})
+
+	t.Run("DeleteCascade", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", ParentID: a.ID})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c", ParentID: b.ID})
+		d := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "d"})
+
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: a.ID, Cascade: true}); err != nil {
+			t.Fatal(err)
+		}
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{}); !equalIDs(resp.Todos, d.ID) {
+			t.Fatalf("todos=%v", ids(resp.Todos))
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package todotest
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo"
+)
+
+// PolicyServices are services sharing the same storage, see PolicyFactory.
+type PolicyServices struct {
+	Todos    todo.Service
+	Lists    todo.ListService
+	Trash    todo.TrashService
+	Policies todo.PolicyService
+	Enforcer todo.PolicyEnforcer
+}
+
+// PolicyFactory returns new, empty services sharing the same storage for a
+// single test, reading the current time from now.
+type PolicyFactory func(t *testing.T, now func() time.Time) PolicyServices
+
+// TestPolicyService runs the todo.PolicyService & todo.PolicyEnforcer
+// contracts, along with archiving todos, against services returned by
+// newServices. Each subtest receives its own services.
+func TestPolicyService(t *testing.T, newServices PolicyFactory) {
+	alice := NewContextWithPrincipalID(context.Background(), "alice")
+	bob := NewContextWithPrincipalID(context.Background(), "bob")
+	t0 := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	// mustComplete completes the todo with the given ID.
+	mustComplete := func(t *testing.T, ctx context.Context, s todo.Service, id int) {
+		t.Helper()
+		if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// mustEnforce applies the policies of all lists at now.
+	mustEnforce := func(t *testing.T, s todo.PolicyEnforcer, now time.Time, limit int) *todo.PolicyReport {
+		t.Helper()
+		report, err := s.EnforcePolicies(context.Background(), now, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	// mustReport reports on the policies of the caller's lists.
+	mustReport := func(t *testing.T, ctx context.Context, s todo.PolicyService, listID int) *todo.PolicyReport {
+		t.Helper()
+		report, err := s.ReportPolicies(ctx, todo.ReportPoliciesRequest{ListID: listID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	t.Run("ArchiveTodo", func(t *testing.T) {
+		now := t0
+		s := newServices(t, func() time.Time { return now })
+		a := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "b"})
+
+		archivedAt := t0.Add(-time.Hour)
+		if got, err := s.Todos.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, ArchivedAt: &archivedAt}); err != nil {
+			t.Fatal(err)
+		} else if got.ArchivedAt == nil || !got.ArchivedAt.Equal(archivedAt) || got.Version != 2 {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		// Archived todos are only listed when asked for.
+		if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{}); !equalIDs(got.Todos, b.ID) || got.TotalCount != 1 {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{b.ID})
+		} else if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{Archived: true}); !equalIDs(got.Todos, a.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{a.ID})
+		}
+
+		// Updates keep a todo archived, while a zero time unarchives it.
+		if got, err := s.Todos.UpdateTodo(alice, todo.UpdateTodoRequest{ID: a.ID, Value: "x"}); err != nil {
+			t.Fatal(err)
+		} else if got.ArchivedAt == nil {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+		if got, err := s.Todos.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, ArchivedAt: &time.Time{}}); err != nil {
+			t.Fatal(err)
+		} else if got.ArchivedAt != nil {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+		if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{}); !equalIDs(got.Todos, a.ID, b.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{a.ID, b.ID})
+		}
+	})
+
+	t.Run("Lists", func(t *testing.T) {
+		s := newServices(t, time.Now)
+		for _, policies := range [][]todo.Policy{
+			{{Action: "shred", AfterDays: 1}},
+			{{Action: todo.PolicyArchive, AfterDays: -1}},
+			{{Action: todo.PolicyArchive, AfterDays: 1}, {Action: todo.PolicyArchive, AfterDays: 2}},
+		} {
+			if _, err := s.Lists.CreateList(alice, todo.CreateListRequest{Name: "l", Policies: policies}); todo.ErrorCode(err) != todo.EINVALID {
+				t.Fatalf("unexpected error for %v: %v", policies, err)
+			}
+		}
+		if _, err := s.Lists.CreateList(alice, todo.CreateListRequest{
+			Name:     "smart",
+			Query:    "complete:true",
+			Policies: []todo.Policy{{Action: todo.PolicyArchive}},
+		}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		policies := []todo.Policy{{Action: todo.PolicyArchive, AfterDays: 7}, {Action: todo.PolicyPurge, AfterDays: 365}}
+		l := MustCreateList(t, alice, s.Lists, todo.CreateListRequest{Name: "l", Policies: policies})
+		if got, err := s.Lists.GetListByID(alice, todo.GetListByIDRequest{ID: l.ID}); err != nil {
+			t.Fatal(err)
+		} else if len(got.Policies) != 2 || got.Policies[0] != policies[0] || got.Policies[1] != policies[1] {
+			t.Fatalf("unexpected policies: %v", got.Policies)
+		}
+
+		if _, err := s.Lists.UpdateList(alice, todo.UpdateListRequest{ID: l.ID, Name: "l"}); err != nil {
+			t.Fatal(err)
+		} else if got, err := s.Lists.GetListByID(alice, todo.GetListByIDRequest{ID: l.ID}); err != nil {
+			t.Fatal(err)
+		} else if len(got.Policies) != 0 {
+			t.Fatalf("unexpected policies: %v", got.Policies)
+		}
+	})
+
+	t.Run("EnforcePolicies", func(t *testing.T) {
+		now := t0
+		s := newServices(t, func() time.Time { return now })
+		l := MustCreateList(t, alice, s.Lists, todo.CreateListRequest{Name: "l", Policies: []todo.Policy{
+			{Action: todo.PolicyArchive, AfterDays: 7},
+			{Action: todo.PolicyPurge, AfterDays: 30},
+		}})
+		other := MustCreateList(t, alice, s.Lists, todo.CreateListRequest{Name: "other"})
+		a := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "a", ListID: l.ID})
+		b := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "b", ListID: l.ID})
+		c := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "c", ListID: other.ID})
+		d := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "d", ListID: l.ID})
+		for _, id := range []int{a.ID, b.ID, c.ID} {
+			mustComplete(t, alice, s.Todos, id)
+		}
+
+		// Nothing is due until the todos have been complete long enough.
+		now = t0.Add(7*day - time.Second)
+		if got := mustEnforce(t, s.Enforcer, now, 10); len(got.Archived) != 0 || len(got.Purged) != 0 {
+			t.Fatalf("unexpected report: %#v", got)
+		}
+
+		// Reports show what would change without changing it.
+		now = t0.Add(7 * day)
+		if got := mustReport(t, alice, s.Policies, 0); !equalIDs(got.Archived, a.ID, b.ID) || len(got.Purged) != 0 {
+			t.Fatalf("unexpected report: %v, %v", ids(got.Archived), ids(got.Purged))
+		} else if got := mustReport(t, alice, s.Policies, other.ID); len(got.Archived) != 0 || len(got.Purged) != 0 {
+			t.Fatalf("unexpected report: %#v", got)
+		} else if got := mustReport(t, bob, s.Policies, 0); len(got.Archived) != 0 || len(got.Purged) != 0 {
+			t.Fatalf("unexpected report: %#v", got)
+		} else if _, err := s.Policies.ReportPolicies(bob, todo.ReportPoliciesRequest{ListID: l.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{}); !equalIDs(got.Todos, a.ID, b.ID, c.ID, d.ID) {
+			t.Fatalf("ids=%v", ids(got.Todos))
+		}
+
+		// Todos are archived in batches.
+		if got := mustEnforce(t, s.Enforcer, now, 1); !equalIDs(got.Archived, a.ID) {
+			t.Fatalf("unexpected report: %#v", got)
+		} else if got.Archived[0].ArchivedAt == nil || !got.Archived[0].ArchivedAt.Equal(now) {
+			t.Fatalf("unexpected todo: %#v", got.Archived[0])
+		}
+		if got := mustEnforce(t, s.Enforcer, now, 10); !equalIDs(got.Archived, b.ID) {
+			t.Fatalf("unexpected report: %#v", got)
+		}
+		if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{}); !equalIDs(got.Todos, c.ID, d.ID) {
+			t.Fatalf("ids=%v", ids(got.Todos))
+		} else if got, err := s.Todos.GetTodoByID(alice, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.ArchivedAt == nil || got.Version != 3 {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		// Archived todos are purged to the trash once they expire.
+		now = now.Add(30 * day)
+		if got := mustEnforce(t, s.Enforcer, now, 10); !equalIDs(got.Purged, a.ID, b.ID) || len(got.Archived) != 0 {
+			t.Fatalf("unexpected report: %v, %v", ids(got.Archived), ids(got.Purged))
+		}
+		if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{Archived: true}); len(got.Todos) != 0 {
+			t.Fatalf("ids=%v", ids(got.Todos))
+		} else if got, err := s.Trash.ListTrash(alice, todo.ListTrashRequest{}); err != nil {
+			t.Fatal(err)
+		} else if len(got) != 2 {
+			t.Fatalf("ids=%v", ids(got))
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package todotest
+
+import (
+	"context"
+	"testing"
+	"todo"
+)
+
+func testOrder(t *testing.T, newService Factory) {
+	t.Run("Priority", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", Priority: todo.PriorityLow})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", Priority: todo.PriorityHigh})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{SortBy: todo.SortByPriority, SortDirection: todo.SortDesc}); !equalIDs(resp.Todos, b.ID, a.ID, c.ID) {
+			t.Fatalf("unexpected order: %v", ids(resp.Todos))
+		}
+
+		priority := todo.PriorityMedium
+		if got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: c.ID, Priority: &priority}); err != nil {
+			t.Fatal(err)
+		} else if got.Priority != todo.PriorityMedium {
+			t.Fatalf("Priority=%d", got.Priority)
+		}
+
+		if _, err := s.CreateTodo(ctx, todo.CreateTodoRequest{Value: "d", Priority: todo.PriorityHigh + 1}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: a.ID, Value: "a", Priority: -1}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("Move", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+		d := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "d"})
+
+		// New todos are placed last.
+		order := func() []*todo.Todo {
+			return MustListTodos(t, ctx, s, todo.ListTodosRequest{SortBy: todo.SortByPosition}).Todos
+		}
+		if got := order(); !equalIDs(got, a.ID, b.ID, c.ID, d.ID) {
+			t.Fatalf("unexpected order: %v", ids(got))
+		}
+
+		for _, tt := range []struct {
+			request todo.MoveTodoRequest
+			want    []int
+		}{
+			{todo.MoveTodoRequest{ID: d.ID, BeforeID: a.ID}, []int{d.ID, a.ID, b.ID, c.ID}},
+			{todo.MoveTodoRequest{ID: d.ID, AfterID: a.ID}, []int{a.ID, d.ID, b.ID, c.ID}},
+			{todo.MoveTodoRequest{ID: a.ID, AfterID: c.ID}, []int{d.ID, b.ID, c.ID, a.ID}},
+			{todo.MoveTodoRequest{ID: c.ID, AfterID: d.ID, BeforeID: b.ID}, []int{d.ID, c.ID, b.ID, a.ID}},
+			{todo.MoveTodoRequest{ID: b.ID, BeforeID: c.ID}, []int{d.ID, b.ID, c.ID, a.ID}},
+		} {
+			if _, err := s.MoveTodo(ctx, tt.request); err != nil {
+				t.Fatalf("%#v: %s", tt.request, err)
+			} else if got := order(); !equalIDs(got, tt.want...) {
+				t.Fatalf("%#v: order=%v, want %v", tt.request, ids(got), tt.want)
+			}
+		}
+
+		// Only the moved todo changes.
+		if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: c.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.Version != c.Version+1 || got.Position == c.Position {
+			t.Fatalf("unexpected todo: %#v", got)
+		} else if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: d.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.Version != d.Version+2 {
+			t.Fatalf("Version=%d, want %d", got.Version, d.Version+2)
+		}
+	})
+
+	t.Run("MoveRepeatedly", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+
+		// Moving back and forth between the same neighbours keeps finding
+		// room between them.
+		for i := 0; i < 50; i++ {
+			req := todo.MoveTodoRequest{ID: c.ID, AfterID: a.ID}
+			if i%2 == 1 {
+				req = todo.MoveTodoRequest{ID: a.ID, AfterID: c.ID}
+			}
+			if _, err := s.MoveTodo(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{SortBy: todo.SortByPosition}); !equalIDs(resp.Todos, c.ID, a.ID, b.ID) {
+			t.Fatalf("unexpected order: %v", ids(resp.Todos))
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		s := newService(t)
+		alice := NewContextWithPrincipalID(context.Background(), "alice")
+		bob := NewContextWithPrincipalID(context.Background(), "bob")
+		a := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b"})
+		c := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "c"})
+		x := MustCreateTodo(t, bob, s, todo.CreateTodoRequest{Value: "x"})
+
+		for _, req := range []todo.MoveTodoRequest{
+			{ID: a.ID},
+			{ID: a.ID, AfterID: a.ID},
+			{ID: a.ID, AfterID: b.ID, BeforeID: b.ID},
+			{ID: a.ID, AfterID: c.ID, BeforeID: b.ID},
+			{ID: a.ID, AfterID: x.ID},
+		} {
+			if _, err := s.MoveTodo(alice, req); todo.ErrorCode(err) != todo.EINVALID {
+				t.Fatalf("%#v: unexpected error: %#v", req, err)
+			}
+		}
+
+		if _, err := s.MoveTodo(bob, todo.MoveTodoRequest{ID: a.ID, AfterID: x.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := s.MoveTodo(alice, todo.MoveTodoRequest{ID: a.ID, AfterID: b.ID, Version: a.Version + 1}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package todotest
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"todo"
+)
+
+// QueryFactory returns new, empty todo, list & dependency services sharing the
+// same storage for a single test.
+type QueryFactory func(t *testing.T) (todo.Service, todo.ListService, todo.DependencyService)
+
+// TestQueries runs the contract of ListTodosRequest.Query and of smart lists
+// against services returned by newServices. Each subtest receives its own
+// services.
+func TestQueries(t *testing.T, newServices QueryFactory) {
+	// mustCreateTodos creates todos covering every field of a query.
+	mustCreateTodos := func(t *testing.T, ctx context.Context, s todo.Service, lists todo.ListService, deps todo.DependencyService) (l *todo.List, a, b, c, d *todo.Todo) {
+		now := time.Now()
+		soon, later, past := now.Add(48*time.Hour), now.Add(240*time.Hour), now.Add(-48*time.Hour)
+		l = MustCreateList(t, ctx, lists, todo.CreateListRequest{Name: "reports"})
+		a = MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Write report", Tags: []string{"work"}, Priority: todo.PriorityHigh, DueAt: &soon, ListID: l.ID})
+		b = MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Buy milk", Tags: []string{"errand"}, Priority: todo.PriorityLow, DueAt: &later})
+		c = MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Call Bob", Tags: []string{"work"}, Priority: todo.PriorityMedium, Complete: true})
+		d = MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Fix bug", Tags: []string{"work"}, DueAt: &past})
+		MustCreateDependency(t, ctx, deps, todo.CreateDependencyRequest{TodoID: d.ID, BlockedByID: b.ID})
+		return l, a, b, c, d
+	}
+
+	t.Run("Conditions", func(t *testing.T) {
+		s, lists, deps := newServices(t)
+		ctx := context.Background()
+		l, a, b, c, d := mustCreateTodos(t, ctx, s, lists, deps)
+
+		for _, tt := range []struct {
+			query string
+			want  []int
+		}{
+			{"", []int{a.ID, b.ID, c.ID, d.ID}},
+			{"tag:work", []int{a.ID, c.ID, d.ID}},
+			{"tag:#WORK AND due<7d AND NOT state:done ORDER BY priority", []int{d.ID, a.ID}},
+			{"NOT due<7d", []int{b.ID, c.ID}},
+			{"due:none", []int{c.ID}},
+			{"due!=none", []int{a.ID, b.ID, d.ID}},
+			{"due<2999-01-01", []int{a.ID, b.ID, d.ID}},
+			{`due>"` + time.Now().Add(120*time.Hour).Format(time.RFC3339) + `"`, []int{b.ID}},
+			{"priority>=high", []int{a.ID}},
+			{"priority>1 or tag:errand", []int{a.ID, b.ID, c.ID}},
+			{"(tag:work OR tag:errand) NOT priority:3", []int{b.ID, c.ID, d.ID}},
+			{"text:MILK", []int{b.ID}},
+			{`text!="bu"`, []int{a.ID, c.ID}},
+			{"overdue:true", []int{d.ID}},
+			{"blocked:true", []int{d.ID}},
+			{"blocked:false tag:work", []int{a.ID, c.ID}},
+			{"list:" + strconv.Itoa(l.ID), []int{a.ID}},
+			{"list:none", []int{b.ID, c.ID, d.ID}},
+			{"complete:true", []int{c.ID}},
+			{"state!=done complete=false", []int{a.ID, b.ID, d.ID}},
+			{"parent:none", []int{a.ID, b.ID, c.ID, d.ID}},
+			{"ORDER BY priority DESC", []int{a.ID, c.ID, b.ID, d.ID}},
+			{"tag:work order by value", []int{c.ID, d.ID, a.ID}},
+		} {
+			if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{Query: tt.query}); !equalIDs(got.Todos, tt.want...) {
+				t.Fatalf("%q: ids=%v, want %v", tt.query, ids(got.Todos), tt.want)
+			} else if got.TotalCount != len(tt.want) {
+				t.Fatalf("%q: total=%d, want %d", tt.query, got.TotalCount, len(tt.want))
+			}
+		}
+
+		// Queries combine with the other filters.
+		complete := false
+		if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{Query: "tag:work", Complete: &complete}); !equalIDs(got.Todos, a.ID, d.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{a.ID, d.ID})
+		}
+	})
+
+	t.Run("Pages", func(t *testing.T) {
+		s, lists, deps := newServices(t)
+		ctx := context.Background()
+		_, a, _, c, _ := mustCreateTodos(t, ctx, s, lists, deps)
+
+		// The order of the query applies to cursors as well.
+		request := todo.ListTodosRequest{Query: "tag:work priority>0 ORDER BY priority DESC", Limit: 1}
+		first := MustListTodos(t, ctx, s, request)
+		if !equalIDs(first.Todos, a.ID) || first.NextCursor == "" || first.TotalCount != 2 {
+			t.Fatalf("unexpected page: %#v", first)
+		}
+		request.Cursor = first.NextCursor
+		if second := MustListTodos(t, ctx, s, request); !equalIDs(second.Todos, c.ID) || second.NextCursor != "" {
+			t.Fatalf("unexpected page: %#v", second)
+		}
+	})
+
+	t.Run("SmartLists", func(t *testing.T) {
+		s, lists, deps := newServices(t)
+		ctx := context.Background()
+		l, a, _, c, _ := mustCreateTodos(t, ctx, s, lists, deps)
+
+		smart := MustCreateList(t, ctx, lists, todo.CreateListRequest{Name: "important", Query: "priority>=medium ORDER BY priority DESC"})
+		if got, err := lists.GetListByID(ctx, todo.GetListByIDRequest{ID: smart.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.Query != smart.Query {
+			t.Fatalf("query=%q, want %q", got.Query, smart.Query)
+		}
+
+		// Smart lists are listed like regular lists, in their own order
+		// unless the request has one.
+		if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{ListID: smart.ID}); !equalIDs(got.Todos, a.ID, c.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{a.ID, c.ID})
+		} else if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{ListID: smart.ID, SortBy: todo.SortByValue}); !equalIDs(got.Todos, c.ID, a.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{c.ID, a.ID})
+		} else if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{ListID: smart.ID, Query: "complete:false"}); !equalIDs(got.Todos, a.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{a.ID})
+		}
+
+		// Todos cannot be put in smart lists, and lists with todos cannot
+		// become smart lists.
+		if _, err := s.CreateTodo(ctx, todo.CreateTodoRequest{Value: "x", ListID: smart.ID}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := lists.UpdateList(ctx, todo.UpdateListRequest{ID: l.ID, Name: l.Name, Query: "tag:work"}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		// A smart list without a query is an empty regular list.
+		if _, err := lists.UpdateList(ctx, todo.UpdateListRequest{ID: smart.ID, Name: smart.Name}); err != nil {
+			t.Fatal(err)
+		} else if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{ListID: smart.ID}); len(got.Todos) != 0 {
+			t.Fatalf("unexpected todos: %v", ids(got.Todos))
+		}
+	})
+
+	t.Run("ErrInvalidQuery", func(t *testing.T) {
+		s, lists, _ := newServices(t)
+		ctx := context.Background()
+
+		if _, err := s.ListTodos(ctx, todo.ListTodosRequest{Query: "tag:work AND priority:urgent"}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if msg := todo.ErrorMessage(err); !strings.Contains(msg, "offset 22") {
+			t.Fatalf("unexpected message: %s", msg)
+		}
+		if _, err := lists.CreateList(ctx, todo.CreateListRequest{Name: "x", Query: "tag:"}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := lists.CreateList(ctx, todo.CreateListRequest{Name: "x", Query: "tag:x", WIPLimits: map[string]int{todo.StateInProgress: 1}}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
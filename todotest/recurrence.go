@@ -0,0 +1,179 @@
+package todotest
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo"
+)
+
+func testRecurrence(t *testing.T, newService Factory) {
+	dueAt := time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC) // a Monday
+	remindAt := dueAt.Add(-time.Hour)
+
+	t.Run("CompleteTodo", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{
+			Value:      "chores",
+			DueAt:      &dueAt,
+			RemindAt:   &remindAt,
+			Recurrence: "RRULE:FREQ=WEEKLY;BYDAY=MO,TH",
+		})
+		if a.Recurrence != "FREQ=WEEKLY;BYDAY=MO,TH" || a.SeriesID != a.ID || a.Occurrence != 1 {
+			t.Fatalf("unexpected todo: %#v", a)
+		}
+
+		resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID, Version: a.Version})
+		if err != nil {
+			t.Fatal(err)
+		} else if got := resp.Todo; !got.Complete || got.Recurrence != "" || got.SeriesID != a.ID {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		next := resp.Next
+		wantDueAt := time.Date(2021, 3, 4, 9, 0, 0, 0, time.UTC)
+		if next == nil {
+			t.Fatal("expected next occurrence")
+		} else if next.ID == a.ID || next.Complete || next.Value != "chores" || next.Recurrence != a.Recurrence {
+			t.Fatalf("unexpected next occurrence: %#v", next)
+		} else if next.SeriesID != a.ID || next.Occurrence != 2 {
+			t.Fatalf("SeriesID=%d Occurrence=%d", next.SeriesID, next.Occurrence)
+		} else if !next.DueAt.Equal(wantDueAt) || !next.RemindAt.Equal(wantDueAt.Add(-time.Hour)) {
+			t.Fatalf("DueAt=%v RemindAt=%v", next.DueAt, next.RemindAt)
+		}
+
+		if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{SeriesID: a.ID}); !equalIDs(got.Todos, a.ID, next.ID) {
+			t.Fatalf("series=%v", ids(got.Todos))
+		}
+
+		// Completing a todo twice does not create another occurrence.
+		if resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if resp.Next != nil {
+			t.Fatalf("unexpected next occurrence: %#v", resp.Next)
+		}
+	})
+
+	t.Run("UpdateTodo", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "rent", DueAt: &dueAt, Recurrence: "FREQ=MONTHLY;BYDAY=-1FR"})
+
+		if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: a.ID, Value: "rent", Complete: true, DueAt: a.DueAt, Recurrence: a.Recurrence}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The last Friday of March 2021 is the 26th.
+		resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{SeriesID: a.ID, Complete: new(bool)})
+		if len(resp.Todos) != 1 {
+			t.Fatalf("open occurrences=%v", ids(resp.Todos))
+		} else if got := resp.Todos[0]; !got.DueAt.Equal(time.Date(2021, 3, 26, 9, 0, 0, 0, time.UTC)) {
+			t.Fatalf("DueAt=%v", got.DueAt)
+		}
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", DueAt: &dueAt, Recurrence: "FREQ=DAILY;COUNT=2"})
+
+		resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID})
+		if err != nil {
+			t.Fatal(err)
+		} else if resp.Next == nil {
+			t.Fatal("expected next occurrence")
+		}
+		if resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: resp.Next.ID}); err != nil {
+			t.Fatal(err)
+		} else if resp.Next != nil {
+			t.Fatalf("series should have ended: %#v", resp.Next)
+		}
+	})
+
+	t.Run("TimeZone", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		if _, err := time.LoadLocation("Europe/Berlin"); err != nil {
+			t.Skip(err)
+		}
+
+		// 9:00 in Berlin is 8:00 UTC in winter and 7:00 UTC in summer.
+		before := time.Date(2021, 3, 27, 8, 0, 0, 0, time.UTC)
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", DueAt: &before, TimeZone: "Europe/Berlin", Recurrence: "FREQ=DAILY"})
+		if resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if want := time.Date(2021, 3, 28, 7, 0, 0, 0, time.UTC); !resp.Next.DueAt.Equal(want) {
+			t.Fatalf("DueAt=%v, want %v", resp.Next.DueAt, want)
+		}
+	})
+
+	t.Run("ScopeThis", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "gym", DueAt: &dueAt, Recurrence: "FREQ=WEEKLY"})
+
+		// Moving a single occurrence splits it from the series, which
+		// continues unchanged.
+		moved := dueAt.Add(2 * time.Hour)
+		value := "gym (late)"
+		got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, Value: &value, DueAt: &moved, Scope: todo.ScopeThis})
+		if err != nil {
+			t.Fatal(err)
+		} else if got.Recurrence != "" || got.SeriesID != a.ID || !got.DueAt.Equal(moved) {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{SeriesID: a.ID})
+		if len(resp.Todos) != 2 {
+			t.Fatalf("series=%v", ids(resp.Todos))
+		}
+		next := resp.Todos[1]
+		if next.Value != "gym" || next.Recurrence != a.Recurrence || !next.DueAt.Equal(dueAt.AddDate(0, 0, 7)) {
+			t.Fatalf("unexpected next occurrence: %#v", next)
+		}
+
+		// Completing the exception does not continue the series again.
+		if resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if resp.Next != nil {
+			t.Fatalf("unexpected next occurrence: %#v", resp.Next)
+		}
+
+		// The rule can only be changed for all future occurrences.
+		rule := "FREQ=DAILY"
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: next.ID, Recurrence: &rule, Scope: todo.ScopeThis}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("ScopeFuture", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "gym", DueAt: &dueAt, Recurrence: "FREQ=WEEKLY"})
+
+		value, rule := "swim", "FREQ=WEEKLY;INTERVAL=2"
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, Value: &value, Recurrence: &rule, Scope: todo.ScopeFuture}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID})
+		if err != nil {
+			t.Fatal(err)
+		} else if next := resp.Next; next.Value != "swim" || !next.DueAt.Equal(dueAt.AddDate(0, 0, 14)) {
+			t.Fatalf("unexpected next occurrence: %#v", next)
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		for _, req := range []todo.CreateTodoRequest{
+			{Value: "a", Recurrence: "FREQ=DAILY"},
+			{Value: "a", DueAt: &dueAt, Recurrence: "FREQ=HOURLY"},
+			{Value: "a", DueAt: &dueAt, Recurrence: "FREQ=WEEKLY;BYDAY=1MO"},
+			{Value: "a", DueAt: &dueAt, Recurrence: "FREQ=DAILY;COUNT=2;UNTIL=20220101"},
+		} {
+			if _, err := s.CreateTodo(ctx, req); todo.ErrorCode(err) != todo.EINVALID {
+				t.Fatalf("%+v: unexpected error: %#v", req, err)
+			}
+		}
+
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: a.ID, Value: "a", Scope: "all"}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package todotest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+	"todo"
+)
+
+// RevisionFactory returns a new, empty revision store for a single test,
+// reading the current time from now.
+type RevisionFactory func(t *testing.T, now func() time.Time) todo.RevisionStore
+
+// TestRevisionStore runs the todo.RevisionStore contract against stores
+// returned by newStore. Each subtest receives its own store.
+func TestRevisionStore(t *testing.T, newStore RevisionFactory) {
+	// mustCreateRevision stores a revision of the todo with the given ID and
+	// value.
+	mustCreateRevision := func(t *testing.T, ctx context.Context, store todo.RevisionStore, todoID int, value string) *todo.Revision {
+		t.Helper()
+		t0 := &todo.Todo{ID: todoID, Value: value, Tags: []string{"work"}}
+		r := &todo.Revision{TodoID: todoID, AuthorID: "alice", Changes: todo.DiffTodos(nil, t0), Todo: t0}
+		if err := store.CreateRevision(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// mustFindRevisions returns the values of the revisions of the todo with
+	// the given ID.
+	mustFindRevisions := func(t *testing.T, ctx context.Context, store todo.RevisionStore, todoID int) []string {
+		t.Helper()
+		revs, err := store.FindRevisions(ctx, todoID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := make([]string, len(revs))
+		for i, r := range revs {
+			values[i] = r.Todo.Value
+		}
+		return values
+	}
+
+	t.Run("CreateRevision", func(t *testing.T) {
+		now := time.Date(2030, 1, 1, 12, 0, 0, 500, time.UTC)
+		store := newStore(t, func() time.Time { return now })
+		ctx := context.Background()
+
+		a := mustCreateRevision(t, ctx, store, 1, "a")
+		if a.ID == 0 || !a.CreatedAt.Equal(now.Truncate(time.Second)) {
+			t.Fatalf("unexpected revision: %#v", a)
+		}
+		b := mustCreateRevision(t, ctx, store, 1, "b")
+		mustCreateRevision(t, ctx, store, 2, "c")
+		if b.ID <= a.ID {
+			t.Fatalf("ID=%d, want > %d", b.ID, a.ID)
+		}
+
+		revs, err := store.FindRevisions(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(revs) != 2 || revs[0].ID != a.ID || revs[1].ID != b.ID {
+			t.Fatalf("unexpected revisions: %#v", revs)
+		} else if got := revs[0]; got.TodoID != 1 || got.AuthorID != "alice" || !got.CreatedAt.Equal(a.CreatedAt) {
+			t.Fatalf("unexpected revision: %#v", got)
+		} else if got.Todo == nil || got.Todo.Value != "a" || len(got.Todo.Tags) != 1 {
+			t.Fatalf("unexpected todo: %#v", got.Todo)
+		} else if len(got.Changes) != 2 || got.Changes[0].Field != "value" || string(got.Changes[0].From) != `""` || string(got.Changes[0].To) != `"a"` {
+			t.Fatalf("unexpected changes: %#v", got.Changes)
+		}
+
+		// Todos without revisions have an empty history.
+		if revs, err := store.FindRevisions(ctx, 3); err != nil {
+			t.Fatal(err)
+		} else if revs == nil || len(revs) != 0 {
+			t.Fatalf("unexpected revisions: %#v", revs)
+		}
+	})
+
+	t.Run("MaxRevisions", func(t *testing.T) {
+		store := newStore(t, time.Now)
+		ctx := context.Background()
+
+		for _, v := range []string{"a", "b", "c", "d"} {
+			mustCreateRevision(t, ctx, store, 1, v)
+		}
+		mustCreateRevision(t, ctx, store, 2, "x")
+
+		if err := store.PruneRevisions(ctx, 1, todo.RetentionPolicy{MaxRevisions: 2}); err != nil {
+			t.Fatal(err)
+		} else if got := mustFindRevisions(t, ctx, store, 1); !equalStrings(got, "c", "d") {
+			t.Fatalf("values=%v, want %v", got, []string{"c", "d"})
+		} else if got := mustFindRevisions(t, ctx, store, 2); !equalStrings(got, "x") {
+			t.Fatalf("values=%v, want %v", got, []string{"x"})
+		}
+
+		// A zero policy keeps every revision.
+		if err := store.PruneRevisions(ctx, 1, todo.RetentionPolicy{}); err != nil {
+			t.Fatal(err)
+		} else if got := mustFindRevisions(t, ctx, store, 1); !equalStrings(got, "c", "d") {
+			t.Fatalf("values=%v, want %v", got, []string{"c", "d"})
+		}
+	})
+
+	t.Run("MaxAge", func(t *testing.T) {
+		now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+		store := newStore(t, func() time.Time { return now })
+		ctx := context.Background()
+
+		mustCreateRevision(t, ctx, store, 1, "a")
+		mustCreateRevision(t, ctx, store, 2, "x")
+		now = now.Add(2 * time.Hour)
+		mustCreateRevision(t, ctx, store, 1, "b")
+
+		// Only old revisions of the given todo are deleted.
+		if err := store.PruneRevisions(ctx, 1, todo.RetentionPolicy{MaxAge: time.Hour}); err != nil {
+			t.Fatal(err)
+		} else if got := mustFindRevisions(t, ctx, store, 1); !equalStrings(got, "b") {
+			t.Fatalf("values=%v, want %v", got, []string{"b"})
+		} else if got := mustFindRevisions(t, ctx, store, 2); !equalStrings(got, "x") {
+			t.Fatalf("values=%v, want %v", got, []string{"x"})
+		}
+	})
+
+	t.Run("ExpireRevisions", func(t *testing.T) {
+		now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+		store := newStore(t, func() time.Time { return now })
+		ctx := context.Background()
+
+		mustCreateRevision(t, ctx, store, 1, "a")
+		mustCreateRevision(t, ctx, store, 2, "x")
+		mustCreateRevision(t, ctx, store, 1, "b")
+		now = now.Add(2 * time.Hour)
+		mustCreateRevision(t, ctx, store, 1, "c")
+
+		// Old revisions of all todos are deleted, oldest first.
+		createdBefore := now.Add(-time.Hour)
+		if n, err := store.ExpireRevisions(ctx, createdBefore, 2); err != nil {
+			t.Fatal(err)
+		} else if n != 2 {
+			t.Fatalf("n=%d, want 2", n)
+		} else if got := mustFindRevisions(t, ctx, store, 1); !equalStrings(got, "b", "c") {
+			t.Fatalf("values=%v, want %v", got, []string{"b", "c"})
+		} else if got := mustFindRevisions(t, ctx, store, 2); !equalStrings(got) {
+			t.Fatalf("values=%v, want none", got)
+		}
+
+		if n, err := store.ExpireRevisions(ctx, createdBefore, 2); err != nil {
+			t.Fatal(err)
+		} else if n != 1 {
+			t.Fatalf("n=%d, want 1", n)
+		} else if got := mustFindRevisions(t, ctx, store, 1); !equalStrings(got, "c") {
+			t.Fatalf("values=%v, want %v", got, []string{"c"})
+		}
+	})
+}
+
+// equalStrings returns true if a has exactly the given values, in order.
+func equalStrings(a []string, want ...string) bool {
+	return reflect.DeepEqual(a, append([]string{}, want...))
+}
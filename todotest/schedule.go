@@ -0,0 +1,181 @@
+package todotest
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo"
+)
+
+// ReminderFactory returns new, empty todo & reminder services sharing the same
+// storage for a single test.
+type ReminderFactory func(t *testing.T) (todo.Service, todo.ReminderService)
+
+// TestReminderService runs the due date & todo.ReminderService contract
+// against services returned by newServices. Each subtest receives its own
+// services.
+func TestReminderService(t *testing.T, newServices ReminderFactory) {
+	past := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	future := time.Date(2999, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Dates", func(t *testing.T) {
+		s, _ := newServices(t)
+		ctx := context.Background()
+
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		if err != nil {
+			t.Skip(err)
+		}
+		dueAt := time.Date(2030, 6, 1, 9, 30, 15, 500, berlin)
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", DueAt: &dueAt, TimeZone: "Europe/Berlin"})
+		if want := dueAt.UTC().Truncate(time.Second); a.DueAt == nil || !a.DueAt.Equal(want) || a.DueAt.Location() != time.UTC {
+			t.Fatalf("DueAt=%v, want %v", a.DueAt, want)
+		} else if a.RemindAt != nil || a.TimeZone != "Europe/Berlin" {
+			t.Fatalf("unexpected todo: %#v", a)
+		}
+
+		if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.DueAt == nil || !got.DueAt.Equal(*a.DueAt) || got.TimeZone != a.TimeZone {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		// Patching a date with the zero time removes it.
+		if got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, DueAt: &time.Time{}}); err != nil {
+			t.Fatal(err)
+		} else if got.DueAt != nil {
+			t.Fatalf("DueAt=%v, want nil", got.DueAt)
+		}
+
+		if _, err := s.CreateTodo(ctx, todo.CreateTodoRequest{Value: "b", TimeZone: "Mars/Olympus"}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		s, _ := newServices(t)
+		ctx := context.Background()
+		soon := past.Add(time.Hour)
+
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", DueAt: &past})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", DueAt: &past, Complete: true})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c", DueAt: &future})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "d"})
+
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{Overdue: true}); !equalIDs(resp.Todos, a.ID) {
+			t.Fatalf("overdue=%v", ids(resp.Todos))
+		}
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{DueBefore: &soon}); !equalIDs(resp.Todos, a.ID, b.ID) {
+			t.Fatalf("dueBefore=%v", ids(resp.Todos))
+		} else if resp.TotalCount != 2 {
+			t.Fatalf("TotalCount=%d, want 2", resp.TotalCount)
+		}
+		after := future.Add(time.Second)
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{DueBefore: &after}); !equalIDs(resp.Todos, a.ID, b.ID, c.ID) {
+			t.Fatalf("dueBefore=%v", ids(resp.Todos))
+		}
+	})
+
+	t.Run("ClaimDueReminders", func(t *testing.T) {
+		s, reminders := newServices(t)
+		alice := NewContextWithPrincipalID(context.Background(), "alice")
+		bob := NewContextWithPrincipalID(context.Background(), "bob")
+		now := past.Add(24 * time.Hour)
+		later := past.Add(time.Hour)
+
+		a := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", RemindAt: &later})
+		b := MustCreateTodo(t, bob, s, todo.CreateTodoRequest{Value: "b", RemindAt: &past})
+		MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "c", RemindAt: &past, Complete: true})
+		MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "d", RemindAt: &future})
+
+		// Reminders of every owner are claimed, oldest first.
+		claimed, err := reminders.ClaimDueReminders(context.Background(), now, 10)
+		if err != nil {
+			t.Fatal(err)
+		} else if !equalIDs(claimed, b.ID, a.ID) {
+			t.Fatalf("claimed=%v", ids(claimed))
+		} else if claimed[0].RemindedAt == nil || !claimed[0].RemindedAt.Equal(now) {
+			t.Fatalf("RemindedAt=%v, want %v", claimed[0].RemindedAt, now)
+		}
+
+		// Claimed reminders are never claimed again.
+		if claimed, err := reminders.ClaimDueReminders(context.Background(), now, 10); err != nil {
+			t.Fatal(err)
+		} else if len(claimed) != 0 {
+			t.Fatalf("claimed=%v, want none", ids(claimed))
+		}
+
+		// Claiming bumps the version, so updates made from the todo as it
+		// was before the claim conflict. Editing the todo keeps the reminder
+		// claimed unless RemindAt changes.
+		got, err := s.GetTodoByID(alice, todo.GetTodoByIDRequest{ID: a.ID})
+		if err != nil {
+			t.Fatal(err)
+		} else if got.RemindedAt == nil || got.Version != a.Version+1 || claimed[1].Version != got.Version {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+		value := "a2"
+		if _, err := s.UpdateTodo(alice, todo.UpdateTodoRequest{ID: a.ID, Value: value, RemindAt: a.RemindAt, Version: a.Version}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if got, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		} else if got.RemindedAt == nil {
+			t.Fatal("expected reminder to stay claimed")
+		}
+		if got, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: a.ID, RemindAt: &past}); err != nil {
+			t.Fatal(err)
+		} else if got.RemindedAt != nil {
+			t.Fatalf("RemindedAt=%v, want nil", got.RemindedAt)
+		}
+		if claimed, err := reminders.ClaimDueReminders(context.Background(), now, 10); err != nil {
+			t.Fatal(err)
+		} else if !equalIDs(claimed, a.ID) {
+			t.Fatalf("claimed=%v", ids(claimed))
+		}
+	})
+
+	t.Run("ArchivedAndDeleted", func(t *testing.T) {
+		s, reminders := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", RemindAt: &past})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", RemindAt: &past})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c", RemindAt: &past})
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, ArchivedAt: &past}); err != nil {
+			t.Fatal(err)
+		} else if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		if claimed, err := reminders.ClaimDueReminders(ctx, future, 10); err != nil {
+			t.Fatal(err)
+		} else if !equalIDs(claimed, c.ID) {
+			t.Fatalf("claimed=%v", ids(claimed))
+		}
+
+		// Unarchived todos are reminded again.
+		var zero time.Time
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, ArchivedAt: &zero}); err != nil {
+			t.Fatal(err)
+		} else if claimed, err := reminders.ClaimDueReminders(ctx, future, 10); err != nil {
+			t.Fatal(err)
+		} else if !equalIDs(claimed, a.ID) {
+			t.Fatalf("claimed=%v", ids(claimed))
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		s, reminders := newServices(t)
+		ctx := context.Background()
+		for i := 0; i < 3; i++ {
+			MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x", RemindAt: &past})
+		}
+
+		for _, want := range []int{2, 1, 0} {
+			if claimed, err := reminders.ClaimDueReminders(ctx, future, 2); err != nil {
+				t.Fatal(err)
+			} else if len(claimed) != want {
+				t.Fatalf("len=%d, want %d", len(claimed), want)
+			}
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package todotest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"todo"
+)
+
+// SearchFactory returns new, empty todo & search services sharing the same
+// storage for a single test.
+type SearchFactory func(t *testing.T) (todo.Service, todo.SearchService)
+
+// TestSearchService runs the todo.SearchService contract against services
+// returned by newServices. Each subtest receives its own services.
+func TestSearchService(t *testing.T, newServices SearchFactory) {
+	t.Run("Words", func(t *testing.T) {
+		s, ss := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Buy running shoes"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Run a marathon"})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Paint the fence, then run"})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Shoe shopping"})
+		d := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Rename the user_id column"})
+
+		for _, tt := range []struct {
+			query string
+			want  []int
+		}{
+			{"runs", []int{a.ID, b.ID, c.ID}},
+			{"RUN shoes", []int{a.ID}},
+			{"mara*", []int{b.ID}},
+			{"running*", []int{a.ID, b.ID, c.ID}},
+			{"fen* run", []int{c.ID}},
+			{`"running shoes"`, []int{a.ID}},
+			{`"shoes running"`, nil},
+			{"swim", nil},
+
+			// Underscores are part of words, as for the SQLite FTS
+			// tokenizer, and words with them are not stemmed.
+			{"user_id", []int{d.ID}},
+			{"USER_ID columns", []int{d.ID}},
+			{"user_*", []int{d.ID}},
+			{"user", nil},
+			{"user_ids", nil},
+		} {
+			if got := MustSearchTodos(t, ctx, ss, todo.SearchTodosRequest{Query: tt.query}); !equalResults(got.Results, tt.want...) {
+				t.Fatalf("%q: ids=%v, want %v", tt.query, resultIDs(got.Results), tt.want)
+			} else if got.TotalCount != len(tt.want) {
+				t.Fatalf("%q: total=%d, want %d", tt.query, got.TotalCount, len(tt.want))
+			}
+		}
+	})
+
+	t.Run("Ranking", func(t *testing.T) {
+		s, ss := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Write the quarterly budget review notes for the report"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Report, report, report"})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Water the plants"})
+
+		resp := MustSearchTodos(t, ctx, ss, todo.SearchTodosRequest{Query: "reports"})
+		if !equalResults(resp.Results, b.ID, a.ID) {
+			t.Fatalf("ids=%v, want %v", resultIDs(resp.Results), []int{b.ID, a.ID})
+		} else if resp.Results[0].Score <= resp.Results[1].Score || resp.Results[1].Score <= 0 {
+			t.Fatalf("unexpected scores: %v, %v", resp.Results[0].Score, resp.Results[1].Score)
+		}
+
+		// The limit keeps the best results but counts all of them.
+		if resp := MustSearchTodos(t, ctx, ss, todo.SearchTodosRequest{Query: "report", Limit: 1}); !equalResults(resp.Results, b.ID) || resp.TotalCount != 2 {
+			t.Fatalf("ids=%v total=%d", resultIDs(resp.Results), resp.TotalCount)
+		}
+	})
+
+	t.Run("Snippets", func(t *testing.T) {
+		s, ss := newServices(t)
+		ctx := context.Background()
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Buy <running> shoes & socks"})
+		long := strings.Repeat("word ", 20) + "target " + strings.Repeat("word ", 20)
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: long})
+
+		if resp := MustSearchTodos(t, ctx, ss, todo.SearchTodosRequest{Query: "run sock"}); len(resp.Results) != 1 {
+			t.Fatalf("unexpected results: %d", len(resp.Results))
+		} else if got, want := resp.Results[0].Snippet, "Buy &lt;<mark>running</mark>&gt; shoes &amp; <mark>socks</mark>"; got != want {
+			t.Fatalf("snippet=%q, want %q", got, want)
+		}
+		if resp := MustSearchTodos(t, ctx, ss, todo.SearchTodosRequest{Query: "target"}); len(resp.Results) != 1 {
+			t.Fatalf("unexpected results: %d", len(resp.Results))
+		} else if got := resp.Results[0].Snippet; !strings.HasPrefix(got, "…word") || !strings.HasSuffix(got, "word…") || !strings.Contains(got, "<mark>target</mark>") {
+			t.Fatalf("unexpected snippet: %q", got)
+		}
+	})
+
+	t.Run("Consistency", func(t *testing.T) {
+		s, ss := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Call the plumber"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "Call the bank"})
+
+		// Updates replace the words of a todo.
+		if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: a.ID, Value: "Email the electrician"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := MustSearchTodos(t, ctx, ss, todo.SearchTodosRequest{Query: "plumber"}); len(got.Results) != 0 {
+			t.Fatalf("unexpected results: %v", resultIDs(got.Results))
+		} else if got := MustSearchTodos(t, ctx, ss, todo.SearchTodosRequest{Query: "electrician"}); !equalResults(got.Results, a.ID) {
+			t.Fatalf("ids=%v, want %v", resultIDs(got.Results), []int{a.ID})
+		} else if got.Results[0].Todo.Value != "Email the electrician" {
+			t.Fatalf("unexpected todo: %#v", got.Results[0].Todo)
+		}
+
+		// Deleted todos and todos of other owners are not found.
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		} else if got := MustSearchTodos(t, ctx, ss, todo.SearchTodosRequest{Query: "call"}); len(got.Results) != 0 {
+			t.Fatalf("unexpected results: %v", resultIDs(got.Results))
+		}
+		other := NewContextWithPrincipalID(ctx, "other")
+		if got := MustSearchTodos(t, other, ss, todo.SearchTodosRequest{Query: "electrician"}); len(got.Results) != 0 {
+			t.Fatalf("unexpected results: %v", resultIDs(got.Results))
+		}
+	})
+
+	t.Run("ErrInvalidQuery", func(t *testing.T) {
+		_, ss := newServices(t)
+		ctx := context.Background()
+		for _, q := range []string{"", "  ", "* -", `"unterminated`} {
+			if _, err := ss.SearchTodos(ctx, todo.SearchTodosRequest{Query: q}); todo.ErrorCode(err) != todo.EINVALID {
+				t.Fatalf("%q: unexpected error: %#v", q, err)
+			}
+		}
+		if _, err := ss.SearchTodos(ctx, todo.SearchTodosRequest{Query: "a", Limit: -1}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
+
+// MustSearchTodos searches todos or fails the test.
+func MustSearchTodos(tb testing.TB, ctx context.Context, s todo.SearchService, request todo.SearchTodosRequest) *todo.SearchTodosResponse {
+	tb.Helper()
+	resp, err := s.SearchTodos(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return resp
+}
+
+// resultIDs returns the IDs of the todos of results.
+func resultIDs(results []*todo.SearchResult) []int {
+	var ids []int
+	for _, r := range results {
+		ids = append(ids, r.Todo.ID)
+	}
+	return ids
+}
+
+// equalResults returns true if results are the todos with the given IDs, in
+// order.
+func equalResults(results []*todo.SearchResult, want ...int) bool {
+	if len(results) != len(want) {
+		return false
+	}
+	for i, r := range results {
+		if r.Todo.ID != want[i] {
+			return false
+		}
+	}
+	return true
+}
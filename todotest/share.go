@@ -0,0 +1,280 @@
+package todotest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"todo"
+)
+
+// ShareFactory returns new, empty todo & share services sharing the same
+// storage for a single test.
+type ShareFactory func(t *testing.T) (todo.Service, todo.ShareService)
+
+// TestShareService runs the todo.ShareService contract against services
+// returned by newServices. Each subtest receives its own services.
+func TestShareService(t *testing.T, newServices ShareFactory) {
+	alice := NewContextWithPrincipalID(context.Background(), "alice")
+	bob := NewContextWithPrincipalID(context.Background(), "bob")
+
+	t.Run("CreateShare", func(t *testing.T) {
+		s, shares := newServices(t)
+		created := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+
+		sh, err := shares.CreateShare(alice, todo.CreateShareRequest{TodoID: created.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+		if err != nil {
+			t.Fatal(err)
+		} else if sh.TodoID != created.ID || sh.OwnerID != "alice" || sh.PrincipalID != "bob" || sh.Role != todo.RoleViewer || sh.CreatedAt.IsZero() {
+			t.Fatalf("unexpected share: %#v", sh)
+		}
+
+		if other, err := shares.GetShare(bob, todo.GetShareRequest{TodoID: created.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(other, sh) {
+			t.Fatalf("GetShare()=%#v, want %#v", other, sh)
+		}
+
+		// Sharing again replaces the role.
+		if _, err := shares.CreateShare(alice, todo.CreateShareRequest{TodoID: created.ID, PrincipalID: "bob", Role: todo.RoleEditor}); err != nil {
+			t.Fatal(err)
+		} else if list := MustListShares(t, alice, shares, todo.ListSharesRequest{TodoID: created.ID}); len(list) != 1 || list[0].Role != todo.RoleEditor {
+			t.Fatalf("unexpected shares: %#v", list)
+		}
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		s, shares := newServices(t)
+		created := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+
+		// Only the owner of a todo can manage its shares.
+		if _, err := shares.CreateShare(bob, todo.CreateShareRequest{TodoID: created.ID, PrincipalID: "carol", Role: todo.RoleViewer}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("CreateShare: unexpected error: %#v", err)
+		}
+		if _, err := shares.ListShares(bob, todo.ListSharesRequest{TodoID: created.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("ListShares: unexpected error: %#v", err)
+		}
+		if err := shares.DeleteShare(alice, todo.DeleteShareRequest{TodoID: created.ID, PrincipalID: "bob"}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("DeleteShare: unexpected error: %#v", err)
+		}
+		if _, err := shares.GetShare(bob, todo.GetShareRequest{TodoID: created.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("GetShare: unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		s, shares := newServices(t)
+		created := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+
+		for _, request := range []todo.CreateShareRequest{
+			{TodoID: created.ID, PrincipalID: "", Role: todo.RoleViewer},
+			{TodoID: created.ID, PrincipalID: "alice", Role: todo.RoleViewer},
+			{TodoID: created.ID, PrincipalID: "bob", Role: "admin"},
+		} {
+			if _, err := shares.CreateShare(alice, request); todo.ErrorCode(err) != todo.EINVALID {
+				t.Fatalf("%#v: unexpected error: %#v", request, err)
+			}
+		}
+	})
+
+	t.Run("ListShares", func(t *testing.T) {
+		s, shares := newServices(t)
+		a := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b"})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "carol", Role: todo.RoleViewer})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: b.ID, PrincipalID: "bob", Role: todo.RoleEditor})
+
+		if list := MustListShares(t, alice, shares, todo.ListSharesRequest{TodoID: a.ID}); len(list) != 2 || list[0].PrincipalID != "bob" || list[1].PrincipalID != "carol" {
+			t.Fatalf("unexpected shares of todo: %#v", list)
+		}
+		if list := MustListShares(t, bob, shares, todo.ListSharesRequest{}); len(list) != 2 || list[0].TodoID != a.ID || list[1].TodoID != b.ID {
+			t.Fatalf("unexpected shares with caller: %#v", list)
+		}
+	})
+
+	t.Run("DeleteShare", func(t *testing.T) {
+		s, shares := newServices(t)
+		created := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: created.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+
+		if err := shares.DeleteShare(alice, todo.DeleteShareRequest{TodoID: created.ID, PrincipalID: "bob"}); err != nil {
+			t.Fatal(err)
+		} else if _, err := shares.GetShare(bob, todo.GetShareRequest{TodoID: created.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("DeleteTodo", func(t *testing.T) {
+		s, shares := newServices(t)
+		created := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: created.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+
+		// Deleting a todo removes its shares.
+		if err := s.DeleteTodo(alice, todo.DeleteTodoRequest{ID: created.ID}); err != nil {
+			t.Fatal(err)
+		} else if list := MustListShares(t, bob, shares, todo.ListSharesRequest{}); len(list) != 0 {
+			t.Fatalf("unexpected shares: %#v", list)
+		}
+	})
+}
+
+// ListShareFactory returns new, empty todo, list & share services sharing the
+// same storage for a single test.
+type ListShareFactory func(t *testing.T) (todo.Service, todo.ListService, todo.ShareService)
+
+// TestListShares runs the todo.ShareService contract for shares of lists
+// against services returned by newServices.
+func TestListShares(t *testing.T, newServices ListShareFactory) {
+	alice := NewContextWithPrincipalID(context.Background(), "alice")
+	bob := NewContextWithPrincipalID(context.Background(), "bob")
+
+	t.Run("CreateShare", func(t *testing.T) {
+		s, lists, shares := newServices(t)
+		l := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "l"})
+		a := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", ListID: l.ID})
+		b := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b"})
+
+		sh, err := shares.CreateShare(alice, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+		if err != nil {
+			t.Fatal(err)
+		} else if sh.TodoID != 0 || sh.ListID != l.ID || sh.OwnerID != "alice" || sh.PrincipalID != "bob" || sh.Role != todo.RoleViewer || sh.CreatedAt.IsZero() {
+			t.Fatalf("unexpected share: %#v", sh)
+		}
+
+		if other, err := shares.GetShare(bob, todo.GetShareRequest{ListID: l.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(other, sh) {
+			t.Fatalf("GetShare()=%#v, want %#v", other, sh)
+		}
+
+		// The share covers the todos in the list only.
+		if other, err := shares.GetShare(bob, todo.GetShareRequest{TodoID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(other, sh) {
+			t.Fatalf("GetShare()=%#v, want %#v", other, sh)
+		}
+		if _, err := shares.GetShare(bob, todo.GetShareRequest{TodoID: b.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		// Of a share of the todo and one of its list, the higher role wins.
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleEditor})
+		if other, err := shares.GetShare(bob, todo.GetShareRequest{TodoID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if other.TodoID != a.ID || other.Role != todo.RoleEditor {
+			t.Fatalf("unexpected share: %#v", other)
+		}
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "bob", Role: todo.RoleOwner})
+		if other, err := shares.GetShare(bob, todo.GetShareRequest{TodoID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if other.ListID != l.ID || other.Role != todo.RoleOwner {
+			t.Fatalf("unexpected share: %#v", other)
+		}
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		_, lists, shares := newServices(t)
+		l := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "l"})
+
+		// Only the owner of a list can manage its shares.
+		if _, err := shares.CreateShare(bob, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "carol", Role: todo.RoleViewer}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("CreateShare: unexpected error: %#v", err)
+		}
+		if _, err := shares.ListShares(bob, todo.ListSharesRequest{ListID: l.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("ListShares: unexpected error: %#v", err)
+		}
+		if err := shares.DeleteShare(alice, todo.DeleteShareRequest{ListID: l.ID, PrincipalID: "bob"}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("DeleteShare: unexpected error: %#v", err)
+		}
+		if _, err := shares.GetShare(bob, todo.GetShareRequest{ListID: l.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("GetShare: unexpected error: %#v", err)
+		}
+		if _, err := shares.CreateShare(alice, todo.CreateShareRequest{TodoID: 1, ListID: l.ID, PrincipalID: "bob", Role: todo.RoleViewer}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("CreateShare: unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("ListShares", func(t *testing.T) {
+		s, lists, shares := newServices(t)
+		l := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "l"})
+		a := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", ListID: l.ID})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "carol", Role: todo.RoleViewer})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleEditor})
+
+		if list := MustListShares(t, alice, shares, todo.ListSharesRequest{ListID: l.ID}); len(list) != 2 || list[0].PrincipalID != "bob" || list[1].PrincipalID != "carol" {
+			t.Fatalf("unexpected shares of list: %#v", list)
+		}
+		if list := MustListShares(t, alice, shares, todo.ListSharesRequest{TodoID: a.ID}); len(list) != 1 || list[0].TodoID != a.ID {
+			t.Fatalf("unexpected shares of todo: %#v", list)
+		}
+		if list := MustListShares(t, bob, shares, todo.ListSharesRequest{}); len(list) != 2 || list[0].ListID != l.ID || list[1].TodoID != a.ID {
+			t.Fatalf("unexpected shares with caller: %#v", list)
+		}
+	})
+
+	t.Run("SmartLists", func(t *testing.T) {
+		s, lists, shares := newServices(t)
+		MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", Priority: todo.PriorityHigh})
+		smart := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "smart", Query: "priority>=medium"})
+
+		// Sharing a smart list would share every todo matching its query.
+		if _, err := shares.CreateShare(alice, todo.CreateShareRequest{ListID: smart.ID, PrincipalID: "bob", Role: todo.RoleViewer}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := s.ListTodos(bob, todo.ListTodosRequest{ListID: smart.ID}); err == nil {
+			t.Fatal("expected error")
+		}
+
+		// Nor can a shared list become a smart list.
+		l := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "l"})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+		if _, err := lists.UpdateList(alice, todo.UpdateListRequest{ID: l.ID, Name: l.Name, Query: "priority>=medium"}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("DeleteShare", func(t *testing.T) {
+		_, lists, shares := newServices(t)
+		l := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "l"})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+
+		if err := shares.DeleteShare(alice, todo.DeleteShareRequest{ListID: l.ID, PrincipalID: "bob"}); err != nil {
+			t.Fatal(err)
+		} else if _, err := shares.GetShare(bob, todo.GetShareRequest{ListID: l.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("DeleteList", func(t *testing.T) {
+		_, lists, shares := newServices(t)
+		l := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "l"})
+		MustCreateShare(t, alice, shares, todo.CreateShareRequest{ListID: l.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+
+		// Deleting a list removes its shares.
+		if err := lists.DeleteList(alice, todo.DeleteListRequest{ID: l.ID}); err != nil {
+			t.Fatal(err)
+		} else if list := MustListShares(t, bob, shares, todo.ListSharesRequest{}); len(list) != 0 {
+			t.Fatalf("unexpected shares: %#v", list)
+		}
+	})
+}
+
+// MustCreateShare creates a share or fails the test.
+func MustCreateShare(tb testing.TB, ctx context.Context, s todo.ShareService, request todo.CreateShareRequest) *todo.Share {
+	tb.Helper()
+	sh, err := s.CreateShare(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return sh
+}
+
+// MustListShares lists shares or fails the test.
+func MustListShares(tb testing.TB, ctx context.Context, s todo.ShareService, request todo.ListSharesRequest) []*todo.Share {
+	tb.Helper()
+	shares, err := s.ListShares(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return shares
+}
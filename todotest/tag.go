@@ -0,0 +1,209 @@
+package todotest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"todo"
+)
+
+// TagFactory returns new, empty todo & tag services sharing the same storage
+// for a single test.
+type TagFactory func(t *testing.T) (todo.Service, todo.TagService)
+
+// TestTagService runs the todo.TagService contract against services returned
+// by newServices. Each subtest receives its own services.
+func TestTagService(t *testing.T, newServices TagFactory) {
+	t.Run("Tags", func(t *testing.T) {
+		s, _ := newServices(t)
+		ctx := context.Background()
+
+		// Tags are normalized, deduplicated and sorted.
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", Tags: []string{"#Work", "home", "work"}})
+		if !reflect.DeepEqual(a.Tags, []string{"home", "work"}) {
+			t.Fatalf("unexpected tags: %#v", a.Tags)
+		} else if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(got.Tags, []string{"home", "work"}) {
+			t.Fatalf("unexpected tags: %#v", got.Tags)
+		}
+
+		// Updating replaces the tags; patching without tags keeps them.
+		if got, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: a.ID, Value: "a", Tags: []string{"errand"}}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(got.Tags, []string{"errand"}) {
+			t.Fatalf("unexpected tags: %#v", got.Tags)
+		}
+		value := "b"
+		if got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(got.Tags, []string{"errand"}) {
+			t.Fatalf("unexpected tags: %#v", got.Tags)
+		}
+		tags := []string{}
+		if got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, Tags: &tags}); err != nil {
+			t.Fatal(err)
+		} else if len(got.Tags) != 0 {
+			t.Fatalf("unexpected tags: %#v", got.Tags)
+		}
+	})
+
+	t.Run("ListTodos", func(t *testing.T) {
+		s, _ := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", Tags: []string{"work", "urgent"}})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", Tags: []string{"work"}})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c", Tags: []string{"home"}})
+		d := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "d"})
+
+		for _, tt := range []struct {
+			request todo.ListTodosRequest
+			want    []int
+		}{
+			{todo.ListTodosRequest{Tags: []string{"work"}}, []int{a.ID, b.ID}},
+			{todo.ListTodosRequest{Tags: []string{"#WORK", "urgent"}}, []int{a.ID}},
+			{todo.ListTodosRequest{AnyTags: []string{"urgent", "home"}}, []int{a.ID, c.ID}},
+			{todo.ListTodosRequest{NotTags: []string{"work"}}, []int{c.ID, d.ID}},
+			{todo.ListTodosRequest{Tags: []string{"work"}, NotTags: []string{"urgent"}}, []int{b.ID}},
+			{todo.ListTodosRequest{Tags: []string{"unknown"}}, nil},
+		} {
+			if got := MustListTodos(t, ctx, s, tt.request).Todos; !equalIDs(got, tt.want...) {
+				t.Fatalf("%#v: ids=%v, want %v", tt.request, ids(got), tt.want)
+			}
+		}
+
+		if _, err := s.ListTodos(ctx, todo.ListTodosRequest{Tags: []string{"no spaces"}}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("ListTags", func(t *testing.T) {
+		s, tags := newServices(t)
+		alice := NewContextWithPrincipalID(context.Background(), "alice")
+		bob := NewContextWithPrincipalID(context.Background(), "bob")
+		MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", Tags: []string{"work", "urgent"}})
+		MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b", Tags: []string{"work"}})
+		MustCreateTodo(t, bob, s, todo.CreateTodoRequest{Value: "x", Tags: []string{"home"}})
+
+		if got := MustListTags(t, alice, tags); !reflect.DeepEqual(got, []*todo.Tag{{Name: "urgent", Count: 1}, {Name: "work", Count: 2}}) {
+			t.Fatalf("unexpected tags: %v", tagNames(got))
+		} else if got := MustListTags(t, bob, tags); !reflect.DeepEqual(got, []*todo.Tag{{Name: "home", Count: 1}}) {
+			t.Fatalf("unexpected tags: %v", tagNames(got))
+		}
+	})
+
+	t.Run("RenameTag", func(t *testing.T) {
+		s, tags := newServices(t)
+		alice := NewContextWithPrincipalID(context.Background(), "alice")
+		bob := NewContextWithPrincipalID(context.Background(), "bob")
+		a := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a", Tags: []string{"job", "urgent"}})
+		b := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "b"})
+		x := MustCreateTodo(t, bob, s, todo.CreateTodoRequest{Value: "x", Tags: []string{"job"}})
+
+		if tag, err := tags.RenameTag(alice, todo.RenameTagRequest{Name: "#Job", NewName: "work"}); err != nil {
+			t.Fatal(err)
+		} else if *tag != (todo.Tag{Name: "work", Count: 1}) {
+			t.Fatalf("unexpected tag: %#v", tag)
+		}
+
+		// Only the caller's tagged todos change and get a new version.
+		if got, err := s.GetTodoByID(alice, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(got.Tags, []string{"urgent", "work"}) || got.Version != a.Version+1 {
+			t.Fatalf("unexpected todo: tags=%v version=%d", got.Tags, got.Version)
+		} else if got, err := s.GetTodoByID(alice, todo.GetTodoByIDRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.Version != b.Version {
+			t.Fatalf("unexpected version: %d", got.Version)
+		} else if got, err := s.GetTodoByID(bob, todo.GetTodoByIDRequest{ID: x.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(got.Tags, []string{"job"}) {
+			t.Fatalf("unexpected tags: %v", got.Tags)
+		}
+
+		if _, err := tags.RenameTag(alice, todo.RenameTagRequest{Name: "job", NewName: "other"}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := tags.RenameTag(alice, todo.RenameTagRequest{Name: "work", NewName: "urgent"}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := tags.RenameTag(alice, todo.RenameTagRequest{Name: "work", NewName: "#WORK"}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := tags.RenameTag(alice, todo.RenameTagRequest{Name: "work", NewName: "a b"}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("MergeTags", func(t *testing.T) {
+		s, tags := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", Tags: []string{"job", "work"}})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", Tags: []string{"office"}})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c", Tags: []string{"work"}})
+
+		if tag, err := tags.MergeTags(ctx, todo.MergeTagsRequest{Names: []string{"job", "office", "work"}, Into: "work"}); err != nil {
+			t.Fatal(err)
+		} else if *tag != (todo.Tag{Name: "work", Count: 3}) {
+			t.Fatalf("unexpected tag: %#v", tag)
+		}
+
+		for _, want := range []*todo.Todo{a, b, c} {
+			got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: want.ID})
+			if err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(got.Tags, []string{"work"}) {
+				t.Fatalf("%d: unexpected tags: %v", want.ID, got.Tags)
+			}
+
+			// Only todos whose tags changed get a new version.
+			version := want.Version + 1
+			if want.ID == c.ID {
+				version = want.Version
+			}
+			if got.Version != version {
+				t.Fatalf("%d: unexpected version: %d", want.ID, got.Version)
+			}
+		}
+		if got := MustListTags(t, ctx, tags); !reflect.DeepEqual(got, []*todo.Tag{{Name: "work", Count: 3}}) {
+			t.Fatalf("unexpected tags: %v", tagNames(got))
+		}
+
+		if _, err := tags.MergeTags(ctx, todo.MergeTagsRequest{Names: []string{"job"}, Into: "work"}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := tags.MergeTags(ctx, todo.MergeTagsRequest{Into: "work"}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("DeleteTodo", func(t *testing.T) {
+		s, tags := newServices(t)
+		ctx := context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", Tags: []string{"work"}})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", Tags: []string{"home"}})
+
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if got := MustListTags(t, ctx, tags); len(got) != 1 || got[0].Name != "home" {
+			t.Fatalf("unexpected tags: %v", tagNames(got))
+		} else if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{Tags: []string{"work"}}).Todos; len(got) != 0 {
+			t.Fatalf("unexpected todos: %v", ids(got))
+		}
+	})
+}
+
+// MustListTags lists the tags of the caller or fails the test.
+func MustListTags(tb testing.TB, ctx context.Context, s todo.TagService) []*todo.Tag {
+	tb.Helper()
+	tags, err := s.ListTags(ctx, todo.ListTagsRequest{})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return tags
+}
+
+// tagNames returns the names and counts of tags for failure messages.
+func tagNames(tags []*todo.Tag) []todo.Tag {
+	a := make([]todo.Tag, len(tags))
+	for i, tag := range tags {
+		a[i] = *tag
+	}
+	return a
+}
@@ -0,0 +1,168 @@
+package todotest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"todo"
+)
+
+// ListFactory returns new, empty todo & list services sharing the same storage
+// for a single test.
+type ListFactory func(t *testing.T) (todo.Service, todo.ListService)
+
+// TestListService runs the todo.ListService contract against services
+// returned by newServices. Each subtest receives its own services.
+func TestListService(t *testing.T, newServices ListFactory) {
+	t.Run("CreateList", func(t *testing.T) {
+		_, lists := newServices(t)
+		ctx := context.Background()
+
+		l, err := lists.CreateList(ctx, todo.CreateListRequest{Name: "work"})
+		if err != nil {
+			t.Fatal(err)
+		} else if l.ID == 0 || l.Name != "work" || l.CreatedAt.IsZero() {
+			t.Fatalf("unexpected list: %#v", l)
+		}
+
+		// IDs are opaque like todo IDs, see todo.NextID, rather than counting
+		// the lists created so far.
+		if l.ID < 1<<32 {
+			t.Fatalf("ID=%d, want an opaque ID", l.ID)
+		}
+
+		if other, err := lists.GetListByID(ctx, todo.GetListByIDRequest{ID: l.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(other, l) {
+			t.Fatalf("GetListByID()=%#v, want %#v", other, l)
+		}
+
+		if _, err := lists.CreateList(ctx, todo.CreateListRequest{}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("UpdateList", func(t *testing.T) {
+		_, lists := newServices(t)
+		ctx := context.Background()
+		l := MustCreateList(t, ctx, lists, todo.CreateListRequest{Name: "work"})
+
+		if got, err := lists.UpdateList(ctx, todo.UpdateListRequest{ID: l.ID, Name: "home"}); err != nil {
+			t.Fatal(err)
+		} else if got.Name != "home" || got.ID != l.ID {
+			t.Fatalf("unexpected list: %#v", got)
+		}
+
+		if _, err := lists.UpdateList(ctx, todo.UpdateListRequest{ID: l.ID + 1, Name: "x"}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("ListLists", func(t *testing.T) {
+		_, lists := newServices(t)
+		alice := NewContextWithPrincipalID(context.Background(), "alice")
+		bob := NewContextWithPrincipalID(context.Background(), "bob")
+		a := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "a"})
+		b := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "b"})
+		MustCreateList(t, bob, lists, todo.CreateListRequest{Name: "c"})
+
+		if got, err := lists.ListLists(alice, todo.ListListsRequest{}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(got, []*todo.List{a, b}) {
+			t.Fatalf("ListLists()=%#v, want %#v", got, []*todo.List{a, b})
+		}
+
+		// Lists of other owners are not found.
+		if _, err := lists.GetListByID(bob, todo.GetListByIDRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("Todos", func(t *testing.T) {
+		s, lists := newServices(t)
+		ctx := context.Background()
+		work := MustCreateList(t, ctx, lists, todo.CreateListRequest{Name: "work"})
+		home := MustCreateList(t, ctx, lists, todo.CreateListRequest{Name: "home"})
+
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", ListID: work.ID})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", ListID: home.ID})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+		if a.ListID != work.ID {
+			t.Fatalf("ListID=%d, want %d", a.ListID, work.ID)
+		}
+
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{ListID: work.ID}); !equalIDs(resp.Todos, a.ID) || resp.TotalCount != 1 {
+			t.Fatalf("unexpected todos: %v", ids(resp.Todos))
+		}
+
+		// Todos can be moved between lists.
+		if got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: b.ID, ListID: &work.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.ListID != work.ID {
+			t.Fatalf("ListID=%d, want %d", got.ListID, work.ID)
+		}
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{ListID: work.ID}); !equalIDs(resp.Todos, a.ID, b.ID) {
+			t.Fatalf("unexpected todos: %v", ids(resp.Todos))
+		}
+
+		if _, err := s.ListTodos(ctx, todo.ListTodosRequest{ListID: home.ID + work.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("ErrInvalidList", func(t *testing.T) {
+		s, lists := newServices(t)
+		alice := NewContextWithPrincipalID(context.Background(), "alice")
+		bob := NewContextWithPrincipalID(context.Background(), "bob")
+		l := MustCreateList(t, alice, lists, todo.CreateListRequest{Name: "a"})
+
+		// Todos can only be put in lists of their owner.
+		if _, err := s.CreateTodo(bob, todo.CreateTodoRequest{Value: "x", ListID: l.ID}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("CreateTodo: unexpected error: %#v", err)
+		}
+		created := MustCreateTodo(t, bob, s, todo.CreateTodoRequest{Value: "x"})
+		if _, err := s.UpdateTodo(bob, todo.UpdateTodoRequest{ID: created.ID, Value: "x", ListID: l.ID}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("UpdateTodo: unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("DeleteList", func(t *testing.T) {
+		s, lists := newServices(t)
+		ctx := context.Background()
+		l := MustCreateList(t, ctx, lists, todo.CreateListRequest{Name: "work"})
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", ListID: l.ID})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+
+		// Lists with todos are only deleted together with their todos.
+		if err := lists.DeleteList(ctx, todo.DeleteListRequest{ID: l.ID}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+		if err := lists.DeleteList(ctx, todo.DeleteListRequest{ID: l.ID, Cascade: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := lists.GetListByID(ctx, todo.GetListByIDRequest{ID: l.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Empty lists are deleted without the flag.
+		empty := MustCreateList(t, ctx, lists, todo.CreateListRequest{Name: "empty"})
+		if err := lists.DeleteList(ctx, todo.DeleteListRequest{ID: empty.ID}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// MustCreateList creates a list or fails the test.
+func MustCreateList(tb testing.TB, ctx context.Context, s todo.ListService, request todo.CreateListRequest) *todo.List {
+	tb.Helper()
+	l, err := s.CreateList(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return l
+}
@@ -0,0 +1,562 @@
+// Package todotest provides a conformance test suite for implementations of
+// todo.Service. Every backend should run it from its own tests:
+//
+//	func TestTodoService(t *testing.T) {
+//		todotest.TestService(t, func(t *testing.T) todo.Service { return inmem.NewService() })
+//	}
+package todotest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"todo"
+)
+
+// Factory returns a new, empty service for a single test. Implementations may
+// use t.Cleanup() to release any resources held by the service.
+type Factory func(t *testing.T) todo.Service
+
+// TestService runs the full todo.Service contract against services returned by
+// newService. Each subtest receives its own service.
+func TestService(t *testing.T, newService Factory) {
+	t.Run("CreateTodo", func(t *testing.T) { testCreateTodo(t, newService) })
+	t.Run("UpdateTodo", func(t *testing.T) { testUpdateTodo(t, newService) })
+	t.Run("PatchTodo", func(t *testing.T) { testPatchTodo(t, newService) })
+	t.Run("DeleteTodo", func(t *testing.T) { testDeleteTodo(t, newService) })
+	t.Run("GetTodoByID", func(t *testing.T) { testGetTodoByID(t, newService) })
+	t.Run("ListTodos", func(t *testing.T) { testListTodos(t, newService) })
+	t.Run("Versions", func(t *testing.T) { testVersions(t, newService) })
+	t.Run("Recurrence", func(t *testing.T) { testRecurrence(t, newService) })
+	t.Run("Hierarchy", func(t *testing.T) { testHierarchy(t, newService) })
+	t.Run("Order", func(t *testing.T) { testOrder(t, newService) })
+	t.Run("Batch", func(t *testing.T) { testBatch(t, newService) })
+	t.Run("Isolation", func(t *testing.T) { testIsolation(t, newService) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newService) })
+	t.Run("ContextCanceled", func(t *testing.T) { testContextCanceled(t, newService) })
+}
+
+func testCreateTodo(t *testing.T, newService Factory) {
+	t.Run("OK", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+
+		got, err := s.CreateTodo(ctx, todo.CreateTodoRequest{Value: "buy milk", Complete: true})
+		if err != nil {
+			t.Fatal(err)
+		} else if got.ID == 0 {
+			t.Fatal("expected ID to be assigned")
+		} else if got.Value != "buy milk" {
+			t.Fatalf("Value=%q, want %q", got.Value, "buy milk")
+		} else if !got.Complete {
+			t.Fatal("expected Complete to be true")
+		}
+
+		if other, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: got.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(other, got) {
+			t.Fatalf("GetTodoByID()=%#v, want %#v", other, got)
+		}
+	})
+
+	t.Run("UniqueIDs", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+
+		seen := make(map[int]bool)
+		for i := 0; i < 10; i++ {
+			got := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "x"})
+			if seen[got.ID] {
+				t.Fatalf("duplicate ID %d", got.ID)
+			}
+			seen[got.ID] = true
+		}
+	})
+
+	t.Run("IDsNotReusedAfterDelete", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+
+		first := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		second := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: second.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		third := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+		if third.ID == first.ID || third.ID == second.ID {
+			t.Fatalf("ID %d reused", third.ID)
+		}
+	})
+}
+
+func testUpdateTodo(t *testing.T, newService Factory) {
+	t.Run("OK", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		created := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+
+		got, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: created.ID, Value: "b", Complete: true})
+		if err != nil {
+			t.Fatal(err)
+		} else if got.ID != created.ID || got.Value != "b" || !got.Complete {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		if other, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: created.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(other, got) {
+			t.Fatalf("GetTodoByID()=%#v, want %#v", other, got)
+		}
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: 1, Value: "b"}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("ReturnedTodoIsNotShared", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		created := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+
+		// Mutating a returned todo must not change what the service stores.
+		created.Value = "mutated"
+		if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: created.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.Value != "a" {
+			t.Fatalf("Value=%q, want %q", got.Value, "a")
+		}
+	})
+}
+
+func testPatchTodo(t *testing.T, newService Factory) {
+	t.Run("OnlyPresentFields", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		created := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+
+		complete := true
+		got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: created.ID, Complete: &complete})
+		if err != nil {
+			t.Fatal(err)
+		} else if got.Value != "a" || !got.Complete {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		value := "b"
+		if got, err = s.PatchTodo(ctx, todo.PatchTodoRequest{ID: created.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		} else if got.Value != "b" || !got.Complete {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		if other, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: created.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(other, got) {
+			t.Fatalf("GetTodoByID()=%#v, want %#v", other, got)
+		}
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: 1}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
+
+func testDeleteTodo(t *testing.T, newService Factory) {
+	t.Run("OK", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+		if _, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: 1}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
+
+func testGetTodoByID(t *testing.T, newService Factory) {
+	t.Run("ErrNotFound", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		if _, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: 1}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
+
+func testListTodos(t *testing.T, newService Factory) {
+	t.Run("Empty", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{}); len(resp.Todos) != 0 || resp.TotalCount != 0 || resp.NextCursor != "" {
+			t.Fatalf("unexpected response: %#v", resp)
+		}
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{})
+		if resp.TotalCount != 2 || resp.NextCursor != "" {
+			t.Fatalf("unexpected response: %#v", resp)
+		} else if len(resp.Todos) != 2 {
+			t.Fatalf("len=%d, want 2", len(resp.Todos))
+		} else if !reflect.DeepEqual(resp.Todos[0], a) || !reflect.DeepEqual(resp.Todos[1], c) {
+			t.Fatalf("unexpected todos: %#v, %#v", resp.Todos[0], resp.Todos[1])
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "buy milk"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "buy bread", Complete: true})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "walk dog", Complete: true})
+
+		complete := true
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{Complete: &complete}); !equalIDs(resp.Todos, b.ID, c.ID) || resp.TotalCount != 2 {
+			t.Fatalf("unexpected response: %#v", resp)
+		}
+		if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{Complete: &complete, Contains: "buy"}); !equalIDs(resp.Todos, b.ID) || resp.TotalCount != 1 {
+			t.Fatalf("unexpected response: %#v", resp)
+		}
+	})
+
+	t.Run("Sort", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", Complete: true})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+
+		for _, tt := range []struct {
+			sortBy, sortDirection string
+			want                  []int
+		}{
+			{todo.SortByID, todo.SortDesc, []int{c.ID, b.ID, a.ID}},
+			{todo.SortByValue, todo.SortAsc, []int{b.ID, a.ID, c.ID}},
+			{todo.SortByValue, todo.SortDesc, []int{c.ID, a.ID, b.ID}},
+			{todo.SortByComplete, todo.SortAsc, []int{a.ID, c.ID, b.ID}},
+		} {
+			resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{SortBy: tt.sortBy, SortDirection: tt.sortDirection})
+			if !equalIDs(resp.Todos, tt.want...) {
+				t.Errorf("%s %s: unexpected order: %v", tt.sortBy, tt.sortDirection, ids(resp.Todos))
+			}
+		}
+	})
+
+	t.Run("Paginate", func(t *testing.T) {
+		for _, sortBy := range []string{todo.SortByID, todo.SortByValue, todo.SortByComplete, todo.SortByPriority, todo.SortByPosition} {
+			for _, sortDirection := range []string{todo.SortAsc, todo.SortDesc} {
+				t.Run(sortBy+"_"+sortDirection, func(t *testing.T) {
+					s, ctx := newService(t), context.Background()
+					for _, v := range []string{"c", "a", "b", "a", "c", "b", "a"} {
+						MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: v, Complete: v == "b", Priority: int(v[0] - 'a')})
+					}
+					want := MustListTodos(t, ctx, s, todo.ListTodosRequest{SortBy: sortBy, SortDirection: sortDirection})
+
+					var got []*todo.Todo
+					req := todo.ListTodosRequest{SortBy: sortBy, SortDirection: sortDirection, Limit: 3}
+					for {
+						resp := MustListTodos(t, ctx, s, req)
+						if resp.TotalCount != 7 {
+							t.Fatalf("TotalCount=%d, want 7", resp.TotalCount)
+						} else if len(resp.Todos) > 3 {
+							t.Fatalf("len=%d, want at most 3", len(resp.Todos))
+						}
+						got = append(got, resp.Todos...)
+						if resp.NextCursor == "" {
+							break
+						}
+						req.Cursor = resp.NextCursor
+					}
+
+					if !reflect.DeepEqual(ids(got), ids(want.Todos)) {
+						t.Fatalf("paginated=%v, want %v", ids(got), ids(want.Todos))
+					}
+				})
+			}
+		}
+	})
+
+	t.Run("StableCursor", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c"})
+
+		first := MustListTodos(t, ctx, s, todo.ListTodosRequest{Limit: 1})
+		if !equalIDs(first.Todos, a.ID) {
+			t.Fatalf("unexpected first page: %v", ids(first.Todos))
+		}
+
+		// Changes before the cursor must not shift the next page.
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		}
+		d := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "d"})
+
+		second := MustListTodos(t, ctx, s, todo.ListTodosRequest{Limit: 10, Cursor: first.NextCursor})
+		if !equalIDs(second.Todos, b.ID, c.ID, d.ID) {
+			t.Fatalf("unexpected second page: %v", ids(second.Todos))
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b"})
+		page := MustListTodos(t, ctx, s, todo.ListTodosRequest{Limit: 1})
+
+		for _, req := range []todo.ListTodosRequest{
+			{SortBy: "bad"},
+			{SortDirection: "sideways"},
+			{Limit: -1},
+			{Limit: todo.MaxListLimit + 1},
+			{Cursor: "!!!"},
+			{Cursor: page.NextCursor, SortDirection: todo.SortDesc},
+		} {
+			if _, err := s.ListTodos(ctx, req); todo.ErrorCode(err) != todo.EINVALID {
+				t.Errorf("%#v: unexpected error: %#v", req, err)
+			}
+		}
+	})
+}
+
+func testVersions(t *testing.T, newService Factory) {
+	t.Run("Increment", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+
+		created := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		if created.Version != 1 {
+			t.Fatalf("Version=%d, want 1", created.Version)
+		}
+
+		updated, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: created.ID, Value: "b", Version: 1})
+		if err != nil {
+			t.Fatal(err)
+		} else if updated.Version != 2 {
+			t.Fatalf("Version=%d, want 2", updated.Version)
+		}
+
+		complete := true
+		patched, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: created.ID, Complete: &complete})
+		if err != nil {
+			t.Fatal(err)
+		} else if patched.Version != 3 {
+			t.Fatalf("Version=%d, want 3", patched.Version)
+		}
+	})
+
+	t.Run("ErrConflict", func(t *testing.T) {
+		s, ctx := newService(t), context.Background()
+
+		created := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: created.ID, Value: "b"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Every write based on version 1 must now be rejected.
+		value := "c"
+		if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: created.ID, Value: "c", Version: 1}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("UpdateTodo: unexpected error: %#v", err)
+		} else if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: created.ID, Value: &value, Version: 1}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("PatchTodo: unexpected error: %#v", err)
+		} else if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: created.ID, Version: 1}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("DeleteTodo: unexpected error: %#v", err)
+		}
+
+		if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: created.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.Value != "b" || got.Version != 2 {
+			t.Fatalf("unexpected todo: %#v", got)
+		}
+
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: created.ID, Version: 2}); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func testIsolation(t *testing.T, newService Factory) {
+	s := newService(t)
+	alice := NewContextWithPrincipalID(context.Background(), "alice")
+	bob := NewContextWithPrincipalID(context.Background(), "bob")
+
+	a := MustCreateTodo(t, alice, s, todo.CreateTodoRequest{Value: "a"})
+	b := MustCreateTodo(t, bob, s, todo.CreateTodoRequest{Value: "b"})
+	if a.OwnerID != "alice" {
+		t.Fatalf("OwnerID=%q, want %q", a.OwnerID, "alice")
+	}
+
+	t.Run("List", func(t *testing.T) {
+		if resp := MustListTodos(t, alice, s, todo.ListTodosRequest{}); !equalIDs(resp.Todos, a.ID) || resp.TotalCount != 1 {
+			t.Fatalf("unexpected todos: %v (total %d)", ids(resp.Todos), resp.TotalCount)
+		}
+		if resp := MustListTodos(t, context.Background(), s, todo.ListTodosRequest{}); len(resp.Todos) != 0 {
+			t.Fatalf("unexpected todos for anonymous caller: %v", ids(resp.Todos))
+		}
+	})
+
+	// Another owner's todos are indistinguishable from missing ones.
+	t.Run("ErrNotFound", func(t *testing.T) {
+		value := "x"
+		if _, err := s.GetTodoByID(alice, todo.GetTodoByIDRequest{ID: b.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("GetTodoByID: unexpected error: %#v", err)
+		}
+		if _, err := s.UpdateTodo(alice, todo.UpdateTodoRequest{ID: b.ID, Value: "x"}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("UpdateTodo: unexpected error: %#v", err)
+		}
+		if _, err := s.PatchTodo(alice, todo.PatchTodoRequest{ID: b.ID, Value: &value}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("PatchTodo: unexpected error: %#v", err)
+		}
+		if err := s.DeleteTodo(alice, todo.DeleteTodoRequest{ID: b.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("DeleteTodo: unexpected error: %#v", err)
+		}
+
+		if got, err := s.GetTodoByID(bob, todo.GetTodoByIDRequest{ID: b.ID}); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(got, b) {
+			t.Fatalf("GetTodoByID()=%#v, want %#v", got, b)
+		}
+	})
+}
+
+func testConcurrency(t *testing.T, newService Factory) {
+	s, ctx := newService(t), context.Background()
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n*4)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			created, err := s.CreateTodo(ctx, todo.CreateTodoRequest{Value: "x"})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: created.ID, Value: "y", Complete: true}); err != nil {
+				errs <- err
+			}
+			if _, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: created.ID}); err != nil {
+				errs <- err
+			}
+			if resp, err := s.ListTodos(ctx, todo.ListTodosRequest{}); err != nil {
+				errs <- err
+			} else {
+				// Read every field to surface data races on shared todos.
+				for _, t := range resp.Todos {
+					_, _, _ = t.ID, t.Value, t.Complete
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if resp := MustListTodos(t, ctx, s, todo.ListTodosRequest{}); len(resp.Todos) != n {
+		t.Fatalf("len=%d, want %d", len(resp.Todos), n)
+	}
+}
+
+func testContextCanceled(t *testing.T, newService Factory) {
+	s := newService(t)
+	created := MustCreateTodo(t, context.Background(), s, todo.CreateTodoRequest{Value: "a"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.CreateTodo(ctx, todo.CreateTodoRequest{Value: "b"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreateTodo: unexpected error: %#v", err)
+	}
+	if _, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: created.ID, Value: "b"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("UpdateTodo: unexpected error: %#v", err)
+	}
+	if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: created.ID}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("PatchTodo: unexpected error: %#v", err)
+	}
+	if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: created.ID}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("DeleteTodo: unexpected error: %#v", err)
+	}
+	if _, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: created.ID}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetTodoByID: unexpected error: %#v", err)
+	}
+	if _, err := s.ListTodos(ctx, todo.ListTodosRequest{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ListTodos: unexpected error: %#v", err)
+	}
+	if _, err := s.MoveTodo(ctx, todo.MoveTodoRequest{ID: created.ID, AfterID: created.ID + 1}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("MoveTodo: unexpected error: %#v", err)
+	}
+	if _, err := s.BatchTodos(ctx, todo.BatchTodosRequest{Ops: []todo.BatchOp{{Op: todo.BatchDelete, Delete: &todo.DeleteTodoRequest{ID: created.ID}}}}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("BatchTodos: unexpected error: %#v", err)
+	}
+
+	// Canceled calls must not have changed anything.
+	if resp := MustListTodos(t, context.Background(), s, todo.ListTodosRequest{}); len(resp.Todos) != 1 || !reflect.DeepEqual(resp.Todos[0], created) {
+		t.Fatalf("unexpected todos after canceled calls: %#v", resp.Todos)
+	}
+}
+
+// MustCreateTodo creates a todo or fails the test.
+func MustCreateTodo(tb testing.TB, ctx context.Context, s todo.Service, request todo.CreateTodoRequest) *todo.Todo {
+	tb.Helper()
+	t, err := s.CreateTodo(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return t
+}
+
+// NewContextWithPrincipalID returns a context authenticated as the given principal.
+func NewContextWithPrincipalID(ctx context.Context, id string) context.Context {
+	return todo.NewContextWithPrincipal(ctx, &todo.Principal{ID: id})
+}
+
+// MustListTodos lists todos or fails the test.
+func MustListTodos(tb testing.TB, ctx context.Context, s todo.Service, request todo.ListTodosRequest) *todo.ListTodosResponse {
+	tb.Helper()
+	resp, err := s.ListTodos(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return resp
+}
+
+// ids returns the IDs of todos, in order.
+func ids(todos []*todo.Todo) []int {
+	a := make([]int, len(todos))
+	for i, t := range todos {
+		a[i] = t.ID
+	}
+	return a
+}
+
+// equalIDs returns true if todos have exactly the given IDs, in order.
+func equalIDs(todos []*todo.Todo, want ...int) bool {
+	return reflect.DeepEqual(ids(todos), append([]int{}, want...))
+}
@@ -0,0 +1,201 @@
+package todotest
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo"
+)
+
+// TrashServices are services sharing the same storage, see TrashFactory.
+type TrashServices struct {
+	Todos        todo.Service
+	Lists        todo.ListService
+	Shares       todo.ShareService
+	Dependencies todo.DependencyService
+	Trash        todo.TrashService
+	Purge        todo.PurgeService
+}
+
+// TrashFactory returns new, empty services sharing the same storage for a
+// single test.
+type TrashFactory func(t *testing.T) TrashServices
+
+// TestTrashService runs the todo.TrashService & todo.PurgeService contracts
+// against services returned by newServices. Each subtest receives its own
+// services.
+func TestTrashService(t *testing.T, newServices TrashFactory) {
+	alice := NewContextWithPrincipalID(context.Background(), "alice")
+	bob := NewContextWithPrincipalID(context.Background(), "bob")
+
+	// mustDeleteTodo deletes the todo with the given ID.
+	mustDeleteTodo := func(t *testing.T, ctx context.Context, s todo.Service, id int, cascade bool) {
+		t.Helper()
+		if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: id, Cascade: cascade}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// mustRestoreTodo restores the todo with the given ID.
+	mustRestoreTodo := func(t *testing.T, ctx context.Context, trash todo.TrashService, id int) *todo.Todo {
+		t.Helper()
+		got, err := trash.RestoreTodo(ctx, todo.RestoreTodoRequest{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	// mustListTrash returns the IDs of the todos in the trash.
+	mustListTrash := func(t *testing.T, ctx context.Context, trash todo.TrashService) []int {
+		t.Helper()
+		todos, err := trash.ListTrash(ctx, todo.ListTrashRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, other := range todos {
+			if other.DeletedAt == nil {
+				t.Fatalf("unexpected todo: %#v", other)
+			}
+		}
+		return ids(todos)
+	}
+
+	t.Run("DeleteTodo", func(t *testing.T) {
+		s := newServices(t)
+		a := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "a"})
+		b := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "b"})
+		mustDeleteTodo(t, alice, s.Todos, a.ID, false)
+
+		// Deleted todos are hidden from every other call.
+		if _, err := s.Todos.GetTodoByID(alice, todo.GetTodoByIDRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{}); !equalIDs(got.Todos, b.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{b.ID})
+		}
+
+		mustDeleteTodo(t, alice, s.Todos, b.ID, false)
+		if got := mustListTrash(t, alice, s.Trash); len(got) != 2 || got[0] != b.ID || got[1] != a.ID {
+			t.Fatalf("ids=%v, want %v", got, []int{b.ID, a.ID})
+		} else if got := mustListTrash(t, bob, s.Trash); len(got) != 0 {
+			t.Fatalf("unexpected ids: %v", got)
+		}
+	})
+
+	t.Run("RestoreTodo", func(t *testing.T) {
+		s := newServices(t)
+		l := MustCreateList(t, alice, s.Lists, todo.CreateListRequest{Name: "l"})
+		a := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "a", ListID: l.ID, Tags: []string{"work"}, Priority: todo.PriorityHigh})
+		b := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "b"})
+		MustCreateDependency(t, alice, s.Dependencies, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: b.ID})
+		MustCreateShare(t, alice, s.Shares, todo.CreateShareRequest{TodoID: a.ID, PrincipalID: "bob", Role: todo.RoleViewer})
+		a, _ = s.Todos.GetTodoByID(alice, todo.GetTodoByIDRequest{ID: a.ID})
+		mustDeleteTodo(t, alice, s.Todos, a.ID, false)
+
+		// Only the owner can restore a todo.
+		if _, err := s.Trash.RestoreTodo(bob, todo.RestoreTodoRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		got := mustRestoreTodo(t, alice, s.Trash, a.ID)
+		if got.Value != "a" || got.ListID != l.ID || len(got.Tags) != 1 || got.Priority != todo.PriorityHigh || got.Position != a.Position {
+			t.Fatalf("unexpected todo: %#v", got)
+		} else if got.Version != a.Version+1 || got.DeletedAt != nil || !got.Blocked {
+			t.Fatalf("unexpected todo: %#v", got)
+		} else if other, err := s.Todos.GetTodoByID(alice, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if other.Version != got.Version {
+			t.Fatalf("unexpected todo: %#v", other)
+		}
+
+		// Shares are restored as well, and the todo leaves the trash.
+		if _, err := s.Shares.GetShare(bob, todo.GetShareRequest{TodoID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if got := mustListTrash(t, alice, s.Trash); len(got) != 0 {
+			t.Fatalf("unexpected ids: %v", got)
+		} else if _, err := s.Trash.RestoreTodo(alice, todo.RestoreTodoRequest{ID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("Cascade", func(t *testing.T) {
+		s := newServices(t)
+		p := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "p"})
+		c := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "c", ParentID: p.ID})
+		g := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "g", ParentID: c.ID})
+		mustDeleteTodo(t, alice, s.Todos, p.ID, true)
+		if got := mustListTrash(t, alice, s.Trash); len(got) != 3 {
+			t.Fatalf("unexpected ids: %v", got)
+		}
+
+		// Subtasks deleted along with a todo are restored with it.
+		mustRestoreTodo(t, alice, s.Trash, p.ID)
+		if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{ParentID: p.ID}); !equalIDs(got.Todos, c.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{c.ID})
+		} else if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{ParentID: c.ID}); !equalIDs(got.Todos, g.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{g.ID})
+		}
+
+		// A subtask restored on its own becomes a top-level todo.
+		mustDeleteTodo(t, alice, s.Todos, p.ID, true)
+		if got := mustRestoreTodo(t, alice, s.Trash, c.ID); got.ParentID != 0 {
+			t.Fatalf("ParentID=%d, want 0", got.ParentID)
+		} else if got := MustListTodos(t, alice, s.Todos, todo.ListTodosRequest{ParentID: c.ID}); !equalIDs(got.Todos, g.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got.Todos), []int{g.ID})
+		} else if got := mustListTrash(t, alice, s.Trash); len(got) != 1 || got[0] != p.ID {
+			t.Fatalf("ids=%v, want %v", got, []int{p.ID})
+		}
+	})
+
+	t.Run("MissingReferences", func(t *testing.T) {
+		s := newServices(t)
+		l := MustCreateList(t, alice, s.Lists, todo.CreateListRequest{Name: "l"})
+		a := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "a", ListID: l.ID})
+		b := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "b"})
+		MustCreateDependency(t, alice, s.Dependencies, todo.CreateDependencyRequest{TodoID: a.ID, BlockedByID: b.ID})
+		mustDeleteTodo(t, alice, s.Todos, a.ID, false)
+		mustDeleteTodo(t, alice, s.Todos, b.ID, false)
+		if err := s.Lists.DeleteList(alice, todo.DeleteListRequest{ID: l.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The todo no longer has its list nor its blocker.
+		if got := mustRestoreTodo(t, alice, s.Trash, a.ID); got.ListID != 0 || got.Blocked {
+			t.Fatalf("unexpected todo: %#v", got)
+		} else if deps, err := s.Dependencies.ListDependencies(alice, todo.ListDependenciesRequest{TodoID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if len(deps) != 0 {
+			t.Fatalf("unexpected dependencies: %#v", deps)
+		}
+	})
+
+	t.Run("PurgeTrash", func(t *testing.T) {
+		s := newServices(t)
+		ctx := context.Background()
+		for i := 0; i < 3; i++ {
+			a := MustCreateTodo(t, alice, s.Todos, todo.CreateTodoRequest{Value: "a"})
+			mustDeleteTodo(t, alice, s.Todos, a.ID, false)
+		}
+
+		// Only todos deleted before the given time are purged, in batches.
+		if n, err := s.Purge.PurgeTrash(ctx, time.Now().Add(-time.Hour), 10); err != nil {
+			t.Fatal(err)
+		} else if n != 0 {
+			t.Fatalf("n=%d, want 0", n)
+		}
+		if n, err := s.Purge.PurgeTrash(ctx, time.Now().Add(time.Hour), 2); err != nil {
+			t.Fatal(err)
+		} else if n != 2 {
+			t.Fatalf("n=%d, want 2", n)
+		} else if got := mustListTrash(t, alice, s.Trash); len(got) != 1 {
+			t.Fatalf("unexpected ids: %v", got)
+		}
+		if n, err := s.Purge.PurgeTrash(ctx, time.Now().Add(time.Hour), 2); err != nil {
+			t.Fatal(err)
+		} else if n != 1 {
+			t.Fatalf("n=%d, want 1", n)
+		} else if got := mustListTrash(t, alice, s.Trash); len(got) != 0 {
+			t.Fatalf("unexpected ids: %v", got)
+		}
+	})
+}
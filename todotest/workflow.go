@@ -0,0 +1,215 @@
+package todotest
+
+import (
+	"context"
+	"testing"
+	"todo"
+)
+
+// WorkflowFactory returns new, empty todo, list & workflow services sharing
+// the same storage for a single test. The services follow the default
+// workflow.
+type WorkflowFactory func(t *testing.T) (todo.Service, todo.ListService, todo.WorkflowService)
+
+// TestWorkflowService runs the todo.WorkflowService contract against services
+// returned by newServices. Each subtest receives its own services.
+func TestWorkflowService(t *testing.T, newServices WorkflowFactory) {
+	t.Run("GetWorkflow", func(t *testing.T) {
+		_, _, ws := newServices(t)
+		w, err := ws.GetWorkflow(context.Background(), todo.GetWorkflowRequest{})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(w.States) == 0 || w.States[0] != todo.StateBacklog {
+			t.Fatalf("unexpected states: %#v", w.States)
+		}
+	})
+
+	t.Run("States", func(t *testing.T) {
+		s, _, _ := newServices(t)
+		ctx := context.Background()
+
+		// New todos start in the first state, or the first closed state
+		// if they are created complete.
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		if a.State != todo.StateBacklog || a.StateChangedAt == nil {
+			t.Fatalf("unexpected state: %q at %v", a.State, a.StateChangedAt)
+		}
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", Complete: true})
+		if b.State != todo.StateDone {
+			t.Fatalf("unexpected state: %q", b.State)
+		}
+		c := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "c", State: todo.StateInProgress})
+		if c.State != todo.StateInProgress || c.Complete {
+			t.Fatalf("unexpected state: %q, complete=%v", c.State, c.Complete)
+		}
+
+		// Closed states complete todos.
+		state := todo.StateCancelled
+		if got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: c.ID, State: &state}); err != nil {
+			t.Fatal(err)
+		} else if got.State != todo.StateCancelled || !got.Complete {
+			t.Fatalf("unexpected state: %q, complete=%v", got.State, got.Complete)
+		}
+
+		// Completing & reopening moves todos between the first closed state
+		// and the first state.
+		if resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if resp.Todo.State != todo.StateDone {
+			t.Fatalf("unexpected state: %q", resp.Todo.State)
+		}
+		complete := false
+		if got, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, Complete: &complete}); err != nil {
+			t.Fatal(err)
+		} else if got.State != todo.StateBacklog || got.Complete {
+			t.Fatalf("unexpected state: %q, complete=%v", got.State, got.Complete)
+		}
+
+		// Updates without a state keep the current one.
+		if got, err := s.UpdateTodo(ctx, todo.UpdateTodoRequest{ID: b.ID, Value: "b2", Complete: true}); err != nil {
+			t.Fatal(err)
+		} else if got.State != todo.StateDone {
+			t.Fatalf("unexpected state: %q", got.State)
+		}
+
+		// Todos are filtered by state.
+		if got := MustListTodos(t, ctx, s, todo.ListTodosRequest{State: todo.StateDone}).Todos; !equalIDs(got, b.ID) {
+			t.Fatalf("ids=%v, want %v", ids(got), []int{b.ID})
+		}
+	})
+
+	t.Run("ErrInvalidState", func(t *testing.T) {
+		s, _, _ := newServices(t)
+		ctx := context.Background()
+
+		if _, err := s.CreateTodo(ctx, todo.CreateTodoRequest{Value: "a", State: "unknown"}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		state := "unknown"
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, State: &state}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+
+	t.Run("ErrTransitionNotAllowed", func(t *testing.T) {
+		s, _, _ := newServices(t)
+		ctx := context.Background()
+
+		// Cancelled todos can only go back to the backlog.
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", State: todo.StateCancelled})
+		state := todo.StateInProgress
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, State: &state}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if got, err := s.GetTodoByID(ctx, todo.GetTodoByIDRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.State != todo.StateCancelled {
+			t.Fatalf("unexpected state: %q", got.State)
+		}
+	})
+
+	t.Run("WIPLimits", func(t *testing.T) {
+		s, ls, _ := newServices(t)
+		ctx := context.Background()
+
+		if _, err := ls.CreateList(ctx, todo.CreateListRequest{Name: "l", WIPLimits: map[string]int{"unknown": 1}}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := ls.CreateList(ctx, todo.CreateListRequest{Name: "l", WIPLimits: map[string]int{todo.StateInProgress: 0}}); todo.ErrorCode(err) != todo.EINVALID {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		l := MustCreateList(t, ctx, ls, todo.CreateListRequest{Name: "l", WIPLimits: map[string]int{todo.StateInProgress: 1}})
+		if got, err := ls.GetListByID(ctx, todo.GetListByIDRequest{ID: l.ID}); err != nil {
+			t.Fatal(err)
+		} else if got.WIPLimits[todo.StateInProgress] != 1 {
+			t.Fatalf("unexpected limits: %#v", got.WIPLimits)
+		}
+
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a", ListID: l.ID, State: todo.StateInProgress})
+		b := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "b", ListID: l.ID})
+		state := todo.StateInProgress
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: b.ID, State: &state}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if _, err := s.CreateTodo(ctx, todo.CreateTodoRequest{Value: "c", ListID: l.ID, State: todo.StateInProgress}); todo.ErrorCode(err) != todo.ECONFLICT {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+
+		// Todos already in the state can still be changed, and todos outside
+		// the list are not limited.
+		value := "a2"
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		}
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "d", State: todo.StateInProgress})
+
+		// Once a todo leaves the state, another one can enter it.
+		if _, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: b.ID, State: &state}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Removing the limit lifts it.
+		if _, err := ls.UpdateList(ctx, todo.UpdateListRequest{ID: l.ID, Name: "l"}); err != nil {
+			t.Fatal(err)
+		}
+		MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "e", ListID: l.ID, State: todo.StateInProgress})
+	})
+
+	t.Run("ListTransitions", func(t *testing.T) {
+		s, _, ws := newServices(t)
+		ctx := context.Background()
+
+		a := MustCreateTodo(t, ctx, s, todo.CreateTodoRequest{Value: "a"})
+		state := todo.StateInProgress
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, State: &state}); err != nil {
+			t.Fatal(err)
+		}
+		value := "a2"
+		if _, err := s.PatchTodo(ctx, todo.PatchTodoRequest{ID: a.ID, Value: &value}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := s.CompleteTodo(ctx, todo.CompleteTodoRequest{ID: a.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trans := MustListTransitions(t, ctx, ws, todo.ListTransitionsRequest{TodoID: a.ID})
+		if len(trans) != 3 {
+			t.Fatalf("unexpected transitions: %d", len(trans))
+		}
+		for i, want := range [][2]string{
+			{"", todo.StateBacklog},
+			{todo.StateBacklog, todo.StateInProgress},
+			{todo.StateInProgress, todo.StateDone},
+		} {
+			if tr := trans[i]; tr.TodoID != a.ID || tr.From != want[0] || tr.To != want[1] || tr.At.IsZero() {
+				t.Fatalf("unexpected transition %d: %#v", i, tr)
+			}
+		}
+		if !trans[2].At.Equal(*resp.Todo.StateChangedAt) {
+			t.Fatalf("unexpected time: %v, want %v", trans[2].At, *resp.Todo.StateChangedAt)
+		}
+
+		// Transitions of deleted todos and of todos of other owners are not
+		// found.
+		other := NewContextWithPrincipalID(ctx, "other")
+		if _, err := ws.ListTransitions(other, todo.ListTransitionsRequest{TodoID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		} else if err := s.DeleteTodo(ctx, todo.DeleteTodoRequest{ID: a.ID}); err != nil {
+			t.Fatal(err)
+		} else if _, err := ws.ListTransitions(ctx, todo.ListTransitionsRequest{TodoID: a.ID}); todo.ErrorCode(err) != todo.ENOTFOUND {
+			t.Fatalf("unexpected error: %#v", err)
+		}
+	})
+}
+
+// MustListTransitions lists the transitions of a todo or fails the test.
+func MustListTransitions(tb testing.TB, ctx context.Context, s todo.WorkflowService, request todo.ListTransitionsRequest) []*todo.Transition {
+	tb.Helper()
+	trans, err := s.ListTransitions(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return trans
+}
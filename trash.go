@@ -0,0 +1,39 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// TrashService gives access to deleted todos. DeleteTodo moves a todo to the
+// trash of its owner along with its shares, dependencies & transitions, where
+// it stays until it is restored or purged. Todos deleted along with their list
+// are deleted permanently.
+type TrashService interface {
+	ListTrash(ctx context.Context, request ListTrashRequest) ([]*Todo, error)
+	RestoreTodo(ctx context.Context, request RestoreTodoRequest) (*Todo, error)
+}
+
+// TrashMiddleware describes a service middleware for the TrashService.
+type TrashMiddleware func(service TrashService) TrashService
+
+// PurgeService permanently deletes todos from the trash of all owners. It is
+// used by background jobs and not exposed to callers of the API.
+type PurgeService interface {
+	// PurgeTrash deletes up to limit todos deleted before deletedBefore and
+	// returns the number of todos deleted.
+	PurgeTrash(ctx context.Context, deletedBefore time.Time, limit int) (int, error)
+}
+
+// ListTrashRequest lists the deleted todos of the caller, most recently
+// deleted first.
+type ListTrashRequest struct{}
+
+// RestoreTodoRequest moves a todo out of the trash along with the subtasks
+// deleted with it. A todo whose parent is not restored becomes a top-level
+// todo, and a todo whose list no longer accepts it is restored outside of any
+// list. Dependencies on todos that no longer exist or that would close a cycle
+// are dropped.
+type RestoreTodoRequest struct {
+	ID int `json:"id"`
+}
@@ -0,0 +1,187 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// States of the default workflow.
+const (
+	StateBacklog    = "backlog"
+	StateInProgress = "in-progress"
+	StateBlocked    = "blocked"
+	StateDone       = "done"
+	StateCancelled  = "cancelled"
+)
+
+// WorkflowService exposes the workflow todos follow and the history of their
+// states. States are set on individual todos with the Service.
+type WorkflowService interface {
+	GetWorkflow(ctx context.Context, request GetWorkflowRequest) (*Workflow, error)
+	ListTransitions(ctx context.Context, request ListTransitionsRequest) ([]*Transition, error)
+}
+
+// WorkflowMiddleware describes a service middleware for the WorkflowService.
+type WorkflowMiddleware func(service WorkflowService) WorkflowService
+
+// Workflow defines the states of todos and the transitions between them.
+type Workflow struct {
+	// States in board order. New todos start in the first one.
+	States []string `json:"states"`
+
+	// States in which todos count as complete. Completing a todo moves it to
+	// the first one, reopening it moves it back to the first state.
+	Closed []string `json:"closed"`
+
+	// States each state can move to. If nil, every transition is allowed.
+	// Todos in states that are no longer part of the workflow can move to
+	// any state.
+	Transitions map[string][]string `json:"transitions"`
+}
+
+// DefaultWorkflow is used unless a backend is configured with another one.
+var DefaultWorkflow = &Workflow{
+	States: []string{StateBacklog, StateInProgress, StateBlocked, StateDone, StateCancelled},
+	Closed: []string{StateDone, StateCancelled},
+	Transitions: map[string][]string{
+		StateBacklog:    {StateInProgress, StateBlocked, StateDone, StateCancelled},
+		StateInProgress: {StateBacklog, StateBlocked, StateDone, StateCancelled},
+		StateBlocked:    {StateBacklog, StateInProgress, StateDone, StateCancelled},
+		StateDone:       {StateBacklog, StateInProgress},
+		StateCancelled:  {StateBacklog},
+	},
+}
+
+// Validate returns EINVALID if the workflow is malformed.
+func (w *Workflow) Validate() error {
+	if len(w.States) == 0 {
+		return Errorf(EINVALID, "Workflow states required.")
+	} else if len(w.Closed) == 0 {
+		return Errorf(EINVALID, "Workflow closed states required.")
+	}
+
+	seen := make(map[string]bool, len(w.States))
+	for _, state := range w.States {
+		if state == "" || seen[state] {
+			return Errorf(EINVALID, "Invalid workflow state '%s'.", state)
+		}
+		seen[state] = true
+	}
+	for _, state := range w.Closed {
+		if !seen[state] {
+			return Errorf(EINVALID, "Unknown closed state '%s'.", state)
+		} else if state == w.States[0] {
+			return Errorf(EINVALID, "Initial state '%s' cannot be closed.", state)
+		}
+	}
+	for from, states := range w.Transitions {
+		if !seen[from] {
+			return Errorf(EINVALID, "Unknown transition state '%s'.", from)
+		}
+		for _, to := range states {
+			if !seen[to] {
+				return Errorf(EINVALID, "Unknown transition state '%s'.", to)
+			}
+		}
+	}
+	return nil
+}
+
+// HasState returns true if state is part of the workflow.
+func (w *Workflow) HasState(state string) bool {
+	return contains(w.States, state)
+}
+
+// IsClosed returns true if todos in state count as complete.
+func (w *Workflow) IsClosed(state string) bool {
+	return contains(w.Closed, state)
+}
+
+// Allows returns true if todos can move from one state to the other.
+func (w *Workflow) Allows(from, to string) bool {
+	if w.Transitions == nil || !w.HasState(from) {
+		return true
+	}
+	return contains(w.Transitions[from], to)
+}
+
+// Transition resolves the state of t, which changed from prev or is new if
+// prev is nil, and keeps Complete in line with it. Clients unaware of states
+// only change Complete, so if the state is unchanged but Complete is not, the
+// state follows Complete. Returns the transition made, or nil if the state did
+// not change. Returns EINVALID for unknown states and ECONFLICT if the
+// workflow does not allow the transition.
+func (w *Workflow) Transition(t, prev *Todo, now time.Time) (*Transition, error) {
+	var from string
+	if prev != nil {
+		from = prev.State
+	}
+	if t.State == "" {
+		t.State = from
+	}
+	if t.State == from && (prev == nil || t.Complete != prev.Complete) {
+		if t.Complete {
+			t.State = w.Closed[0]
+		} else {
+			t.State = w.States[0]
+		}
+	}
+
+	if !w.HasState(t.State) {
+		return nil, Errorf(EINVALID, "Invalid state '%s'.", t.State)
+	}
+	t.Complete = w.IsClosed(t.State)
+	if t.State == from {
+		return nil, nil
+	} else if prev != nil && !w.Allows(from, t.State) {
+		return nil, Errorf(ECONFLICT, "Todo with ID '%d' cannot move from state '%s' to '%s'.", t.ID, from, t.State)
+	}
+
+	now = now.UTC().Truncate(time.Second)
+	t.StateChangedAt = &now
+	return &Transition{TodoID: t.ID, From: from, To: t.State, At: now}, nil
+}
+
+// ValidateLimits returns EINVALID if limits are not positive or refer to states
+// that are not part of the workflow.
+func (w *Workflow) ValidateLimits(limits map[string]int) error {
+	for state, n := range limits {
+		if !w.HasState(state) {
+			return Errorf(EINVALID, "Invalid state '%s'.", state)
+		} else if n <= 0 {
+			return Errorf(EINVALID, "Limit of state '%s' must be positive.", state)
+		}
+	}
+	return nil
+}
+
+// CheckLimit returns ECONFLICT if another todo cannot enter state in l because
+// n todos already are in that state.
+func (l *List) CheckLimit(state string, n int) error {
+	if limit, ok := l.WIPLimits[state]; ok && n >= limit {
+		return Errorf(ECONFLICT, "List with ID '%d' cannot have more than %d todos in state '%s'.", l.ID, limit, state)
+	}
+	return nil
+}
+
+// EntersList returns true if t, which changed from prev or is new if prev is
+// nil, enters a state in a list and so counts against the list's limits.
+func (t *Todo) EntersList(prev *Todo) bool {
+	return t.ListID != 0 && (prev == nil || prev.ListID != t.ListID || prev.State != t.State)
+}
+
+// Transition records a todo entering a state. Todos enter their first state
+// when they are created, which is recorded with an empty From.
+type Transition struct {
+	TodoID int       `json:"todoId"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+}
+
+type GetWorkflowRequest struct{}
+
+// ListTransitionsRequest lists the transitions of a todo, oldest first.
+type ListTransitionsRequest struct {
+	TodoID int `json:"todoId"`
+}
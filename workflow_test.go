@@ -0,0 +1,83 @@
+package todo_test
+
+import (
+	"testing"
+	"time"
+	"todo"
+)
+
+func TestWorkflow_Validate(t *testing.T) {
+	if err := todo.DefaultWorkflow.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, w := range []*todo.Workflow{
+		{Closed: []string{"done"}},
+		{States: []string{"open", "done"}},
+		{States: []string{"open", "open", "done"}, Closed: []string{"done"}},
+		{States: []string{"open", "done"}, Closed: []string{"closed"}},
+		{States: []string{"open", "done"}, Closed: []string{"open"}},
+		{States: []string{"open", "done"}, Closed: []string{"done"}, Transitions: map[string][]string{"open": {"closed"}}},
+	} {
+		if err := w.Validate(); todo.ErrorCode(err) != todo.EINVALID {
+			t.Errorf("%#v: unexpected error: %#v", w, err)
+		}
+	}
+}
+
+func TestWorkflow_Transition(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	w := todo.DefaultWorkflow
+
+	for _, tt := range []struct {
+		name     string
+		t        todo.Todo
+		prev     *todo.Todo
+		state    string
+		complete bool
+		changed  bool
+		code     string
+	}{
+		{name: "New", t: todo.Todo{}, state: todo.StateBacklog, changed: true},
+		{name: "NewComplete", t: todo.Todo{Complete: true}, state: todo.StateDone, complete: true, changed: true},
+		{name: "NewState", t: todo.Todo{State: todo.StateCancelled}, state: todo.StateCancelled, complete: true, changed: true},
+		{name: "Unchanged", t: todo.Todo{}, prev: &todo.Todo{State: todo.StateInProgress}, state: todo.StateInProgress},
+		{name: "Complete", t: todo.Todo{State: todo.StateInProgress, Complete: true}, prev: &todo.Todo{State: todo.StateInProgress}, state: todo.StateDone, complete: true, changed: true},
+		{name: "Reopen", t: todo.Todo{State: todo.StateCancelled}, prev: &todo.Todo{State: todo.StateCancelled, Complete: true}, state: todo.StateBacklog, changed: true},
+		{name: "StateWins", t: todo.Todo{State: todo.StateDone}, prev: &todo.Todo{State: todo.StateBacklog}, state: todo.StateDone, complete: true, changed: true},
+		{name: "Unknown", t: todo.Todo{State: "unknown"}, prev: &todo.Todo{State: todo.StateBacklog}, code: todo.EINVALID},
+		{name: "NotAllowed", t: todo.Todo{State: todo.StateDone}, prev: &todo.Todo{State: todo.StateCancelled, Complete: true}, code: todo.ECONFLICT},
+		{name: "Retired", t: todo.Todo{State: todo.StateDone}, prev: &todo.Todo{State: "review"}, state: todo.StateDone, complete: true, changed: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := w.Transition(&tt.t, tt.prev, now)
+			if tt.code != "" {
+				if todo.ErrorCode(err) != tt.code {
+					t.Fatalf("unexpected error: %#v", err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.t.State != tt.state || tt.t.Complete != tt.complete {
+				t.Fatalf("state=%q complete=%v, want %q %v", tt.t.State, tt.t.Complete, tt.state, tt.complete)
+			} else if changed := tr != nil; changed != tt.changed {
+				t.Fatalf("changed=%v, want %v", changed, tt.changed)
+			} else if tr != nil && (tr.To != tt.state || (tt.prev != nil && tr.From != tt.prev.State) || !tr.At.Equal(now) || !tt.t.StateChangedAt.Equal(now)) {
+				t.Fatalf("unexpected transition: %#v", tr)
+			}
+		})
+	}
+}
+
+func TestList_CheckLimit(t *testing.T) {
+	l := &todo.List{ID: 1, WIPLimits: map[string]int{todo.StateInProgress: 2}}
+	if err := l.CheckLimit(todo.StateInProgress, 1); err != nil {
+		t.Fatal(err)
+	} else if err := l.CheckLimit(todo.StateInProgress, 2); todo.ErrorCode(err) != todo.ECONFLICT {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if err := l.CheckLimit(todo.StateBacklog, 100); err != nil {
+		t.Fatal(err)
+	}
+}